@@ -28,17 +28,18 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
-	// Create and start daemon
-	d, err := daemon.New()
-	if err != nil {
-		log.Error("Failed to create daemon: %v", err)
-		os.Exit(1)
+	// Manage a single instance when SILO_INSTANCE is set, otherwise all of them
+	var mgr *daemon.Manager
+	if instance := os.Getenv("SILO_INSTANCE"); instance != "" {
+		mgr = daemon.NewManager(instance)
+	} else {
+		mgr = daemon.NewManager()
 	}
 
-	// Start daemon in goroutine
+	// Start daemons in goroutine
 	errChan := make(chan error, 1)
 	go func() {
-		if err := d.Start(ctx); err != nil {
+		if err := mgr.Start(ctx); err != nil {
 			errChan <- err
 		}
 	}()
@@ -55,7 +56,7 @@ func main() {
 	}
 
 	// Graceful shutdown
-	if err := d.Stop(); err != nil {
+	if err := mgr.Stop(); err != nil {
 		log.Error("Error during shutdown: %v", err)
 		os.Exit(1)
 	}
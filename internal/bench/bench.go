@@ -0,0 +1,396 @@
+// Package bench load-tests an OpenAI compatible inference server with
+// concurrent chat or completions requests and summarizes the latencies and
+// throughput.
+package bench
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Endpoints that can be benchmarked
+const (
+	EndpointChat        = "chat"
+	EndpointCompletions = "completions"
+)
+
+// maxErrorSamples is the number of distinct errors kept in the result
+const maxErrorSamples = 5
+
+// Options configure a benchmark run
+type Options struct {
+	// URL is the base URL of the OpenAI compatible API, e.g.
+	// http://127.0.0.1:30000/v1
+	URL    string
+	Model  string
+	APIKey string
+
+	// Endpoint is EndpointChat or EndpointCompletions
+	Endpoint string
+	Stream   bool
+
+	// Requests are sent by Concurrency workers at a time
+	Requests    int
+	Concurrency int
+
+	// Prompt is sent with every request; when empty a prompt of about
+	// PromptTokens tokens is generated
+	Prompt       string
+	PromptTokens int
+	MaxTokens    int
+
+	// Timeout bounds every request
+	Timeout time.Duration
+
+	Client *http.Client
+}
+
+// Stats summarizes samples in milliseconds
+type Stats struct {
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+}
+
+// Result is the summary of a benchmark run. TTFT and ITL are only measured
+// when streaming.
+type Result struct {
+	URL         string `json:"url"`
+	Model       string `json:"model"`
+	Endpoint    string `json:"endpoint"`
+	Stream      bool   `json:"stream"`
+	Concurrency int    `json:"concurrency"`
+
+	Requests  int     `json:"requests"`
+	Errors    int     `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+
+	DurationSeconds   float64 `json:"duration_seconds"`
+	RequestsPerSecond float64 `json:"requests_per_second"`
+	TokensPerSecond   float64 `json:"output_tokens_per_second"`
+	PromptTokens      int     `json:"prompt_tokens"`
+	OutputTokens      int     `json:"output_tokens"`
+
+	TTFT    *Stats `json:"ttft_ms,omitempty"`
+	ITL     *Stats `json:"itl_ms,omitempty"`
+	Latency *Stats `json:"latency_ms,omitempty"`
+
+	ErrorSamples []string `json:"error_samples,omitempty"`
+}
+
+// sample is the measurement of one request
+type sample struct {
+	err          error
+	ttft         time.Duration
+	gaps         []time.Duration
+	latency      time.Duration
+	promptTokens int
+	outputTokens int
+}
+
+// Run sends the requests and summarizes them. progress, if set, is called
+// after every request with the number of finished requests.
+func Run(ctx context.Context, opts Options, progress func(done int)) (*Result, error) {
+	if opts.Endpoint != EndpointChat && opts.Endpoint != EndpointCompletions {
+		return nil, fmt.Errorf("unknown endpoint %q, expected %s or %s", opts.Endpoint, EndpointChat, EndpointCompletions)
+	}
+	if opts.Requests < 1 || opts.Concurrency < 1 {
+		return nil, fmt.Errorf("requests and concurrency must be at least 1")
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	if opts.Prompt == "" {
+		opts.Prompt = generatePrompt(opts.PromptTokens)
+	}
+
+	jobs := make(chan struct{})
+	samples := make([]sample, 0, opts.Requests)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	start := time.Now()
+	for range min(opts.Concurrency, opts.Requests) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range jobs {
+				s := send(ctx, opts)
+				mu.Lock()
+				samples = append(samples, s)
+				done := len(samples)
+				mu.Unlock()
+				if progress != nil {
+					progress(done)
+				}
+			}
+		}()
+	}
+	for range opts.Requests {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- struct{}{}
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return summarize(opts, samples, time.Since(start)), nil
+}
+
+// send sends one request and measures it
+func send(ctx context.Context, opts Options) sample {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	body := map[string]any{
+		"model":      opts.Model,
+		"max_tokens": opts.MaxTokens,
+		"stream":     opts.Stream,
+	}
+	path := "/chat/completions"
+	if opts.Endpoint == EndpointChat {
+		body["messages"] = []map[string]string{{"role": "user", "content": opts.Prompt}}
+	} else {
+		path = "/completions"
+		body["prompt"] = opts.Prompt
+	}
+	if opts.Stream {
+		// Ask for the token counts in the last chunk
+		body["stream_options"] = map[string]bool{"include_usage": true}
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return sample{err: err}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(opts.URL, "/")+path, bytes.NewReader(data))
+	if err != nil {
+		return sample{err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if opts.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+opts.APIKey)
+	}
+
+	start := time.Now()
+	resp, err := opts.Client.Do(req)
+	if err != nil {
+		return sample{err: err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return sample{err: fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))}
+	}
+
+	var s sample
+	if opts.Stream {
+		s = readStream(resp.Body, start)
+	} else {
+		s = readResponse(resp.Body)
+	}
+	s.latency = time.Since(start)
+	return s
+}
+
+// response is a completion or a chunk of a streamed one
+type response struct {
+	Choices []struct {
+		Text    string `json:"text"`
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		Delta struct {
+			Content          string `json:"content"`
+			ReasoningContent string `json:"reasoning_content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+// readResponse reads a non-streamed completion
+func readResponse(body io.Reader) sample {
+	var r response
+	if err := json.NewDecoder(body).Decode(&r); err != nil {
+		return sample{err: fmt.Errorf("invalid response: %w", err)}
+	}
+	if len(r.Choices) == 0 {
+		return sample{err: errors.New("response has no choices")}
+	}
+	var s sample
+	if r.Usage != nil {
+		s.promptTokens = r.Usage.PromptTokens
+		s.outputTokens = r.Usage.CompletionTokens
+	}
+	return s
+}
+
+// readStream reads a server-sent event stream, timing the chunks that carry
+// text. Each chunk counts as a token unless the server reports usage.
+func readStream(body io.Reader, start time.Time) sample {
+	var s sample
+	var last time.Time
+	chunks := 0
+	usage := false
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var r response
+		if err := json.Unmarshal([]byte(data), &r); err != nil {
+			s.err = fmt.Errorf("invalid stream chunk: %w", err)
+			return s
+		}
+		if r.Usage != nil {
+			usage = true
+			s.promptTokens = r.Usage.PromptTokens
+			s.outputTokens = r.Usage.CompletionTokens
+		}
+		if len(r.Choices) == 0 {
+			continue
+		}
+		c := r.Choices[0]
+		if c.Text == "" && c.Delta.Content == "" && c.Delta.ReasoningContent == "" {
+			continue
+		}
+
+		now := time.Now()
+		if chunks == 0 {
+			s.ttft = now.Sub(start)
+		} else {
+			s.gaps = append(s.gaps, now.Sub(last))
+		}
+		last = now
+		chunks++
+	}
+	if err := scanner.Err(); err != nil {
+		s.err = fmt.Errorf("stream interrupted: %w", err)
+		return s
+	}
+	if chunks == 0 {
+		s.err = errors.New("stream returned no tokens")
+		return s
+	}
+	if !usage {
+		s.outputTokens = chunks
+	}
+	return s
+}
+
+// summarize computes the result of the samples of a run that took elapsed
+func summarize(opts Options, samples []sample, elapsed time.Duration) *Result {
+	r := &Result{
+		URL:             opts.URL,
+		Model:           opts.Model,
+		Endpoint:        opts.Endpoint,
+		Stream:          opts.Stream,
+		Concurrency:     opts.Concurrency,
+		Requests:        len(samples),
+		DurationSeconds: elapsed.Seconds(),
+	}
+
+	var ttft, itl, latency []time.Duration
+	seen := make(map[string]bool)
+	for _, s := range samples {
+		if s.err != nil {
+			r.Errors++
+			if msg := s.err.Error(); !seen[msg] && len(r.ErrorSamples) < maxErrorSamples {
+				seen[msg] = true
+				r.ErrorSamples = append(r.ErrorSamples, msg)
+			}
+			continue
+		}
+		r.PromptTokens += s.promptTokens
+		r.OutputTokens += s.outputTokens
+		latency = append(latency, s.latency)
+		if opts.Stream {
+			ttft = append(ttft, s.ttft)
+			itl = append(itl, s.gaps...)
+		}
+	}
+
+	if r.Requests > 0 {
+		r.ErrorRate = float64(r.Errors) / float64(r.Requests)
+	}
+	if elapsed > 0 {
+		r.RequestsPerSecond = float64(r.Requests-r.Errors) / elapsed.Seconds()
+		r.TokensPerSecond = float64(r.OutputTokens) / elapsed.Seconds()
+	}
+	r.TTFT = stats(ttft)
+	r.ITL = stats(itl)
+	r.Latency = stats(latency)
+	return r
+}
+
+// stats summarizes durations, nil when there are none
+func stats(samples []time.Duration) *Stats {
+	if len(samples) == 0 {
+		return nil
+	}
+	ms := make([]float64, len(samples))
+	sum := 0.0
+	for i, d := range samples {
+		ms[i] = float64(d) / float64(time.Millisecond)
+		sum += ms[i]
+	}
+	sort.Float64s(ms)
+	return &Stats{
+		Mean: sum / float64(len(ms)),
+		P50:  percentile(ms, 50),
+		P90:  percentile(ms, 90),
+		P99:  percentile(ms, 99),
+		Max:  ms[len(ms)-1],
+	}
+}
+
+// percentile returns the nearest-rank percentile p of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank-1, 0)]
+}
+
+// generatePrompt returns a prompt of about tokens tokens, counting a word
+// as a token
+func generatePrompt(tokens int) string {
+	words := []string{"Summarize", "the", "history", "of", "the", "printing", "press", "and", "its", "effect", "on", "science", "in", "Europe."}
+	var b strings.Builder
+	for i := range max(tokens, 1) {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(words[i%len(words)])
+	}
+	return b.String()
+}
@@ -0,0 +1,200 @@
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeOpenAI is an OpenAI compatible server that answers with tokens
+// tokens, delay apart, and fails every failEvery-th request
+type fakeOpenAI struct {
+	tokens    int
+	delay     time.Duration
+	usage     bool
+	failEvery int64
+
+	requests    atomic.Int64
+	active      atomic.Int64
+	peak        atomic.Int64
+	chat        atomic.Int64
+	completions atomic.Int64
+}
+
+func (f *fakeOpenAI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n := f.requests.Add(1)
+	active := f.active.Add(1)
+	defer f.active.Add(-1)
+	for peak := f.peak.Load(); active > peak && !f.peak.CompareAndSwap(peak, active); peak = f.peak.Load() {
+	}
+
+	var req struct {
+		Model         string `json:"model"`
+		Stream        bool   `json:"stream"`
+		Prompt        string `json:"prompt"`
+		StreamOptions *struct {
+			IncludeUsage bool `json:"include_usage"`
+		} `json:"stream_options"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Model != "qwen" {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	chat := r.URL.Path == "/v1/chat/completions"
+	if chat {
+		f.chat.Add(1)
+	} else {
+		f.completions.Add(1)
+	}
+	if f.failEvery > 0 && n%f.failEvery == 0 {
+		http.Error(w, `{"error": "overloaded"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if !req.Stream {
+		time.Sleep(time.Duration(f.tokens) * f.delay)
+		fmt.Fprintf(w, `{"choices": [{"text": "hi", "message": {"content": "hi"}}], "usage": {"prompt_tokens": 7, "completion_tokens": %d}}`, f.tokens)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	flusher := w.(http.Flusher)
+	for range f.tokens {
+		time.Sleep(f.delay)
+		if chat {
+			fmt.Fprint(w, "data: {\"choices\": [{\"delta\": {\"content\": \"tok\"}}]}\n\n")
+		} else {
+			fmt.Fprint(w, "data: {\"choices\": [{\"text\": \"tok\"}]}\n\n")
+		}
+		flusher.Flush()
+	}
+	if f.usage && req.StreamOptions != nil && req.StreamOptions.IncludeUsage {
+		fmt.Fprintf(w, "data: {\"choices\": [], \"usage\": {\"prompt_tokens\": 7, \"completion_tokens\": %d}}\n\n", f.tokens*2)
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
+func TestRun_StreamingChat(t *testing.T) {
+	fake := &fakeOpenAI{tokens: 5, delay: 2 * time.Millisecond, usage: true}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	var done atomic.Int64
+	result, err := Run(context.Background(), Options{
+		URL:          srv.URL + "/v1",
+		Model:        "qwen",
+		Endpoint:     EndpointChat,
+		Stream:       true,
+		Requests:     12,
+		Concurrency:  3,
+		PromptTokens: 64,
+		MaxTokens:    16,
+	}, func(int) { done.Add(1) })
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if result.Requests != 12 || result.Errors != 0 || done.Load() != 12 {
+		t.Errorf("Expected 12 successful requests, got %+v", result)
+	}
+	if fake.peak.Load() > 3 {
+		t.Errorf("Expected at most 3 concurrent requests, got %d", fake.peak.Load())
+	}
+	if fake.chat.Load() != 12 {
+		t.Errorf("Expected chat completions requests, got %d", fake.chat.Load())
+	}
+	// The usage chunk reports the tokens, not the chunk count
+	if result.OutputTokens != 12*10 || result.PromptTokens != 12*7 {
+		t.Errorf("Expected the reported usage, got %d output and %d prompt tokens", result.OutputTokens, result.PromptTokens)
+	}
+	if result.TTFT == nil || result.ITL == nil || result.Latency == nil {
+		t.Fatalf("Expected TTFT, ITL and latency stats, got %+v", result)
+	}
+	if result.ITL.P50 < 1 || result.TTFT.Mean < 1 || result.Latency.Max < result.TTFT.Max {
+		t.Errorf("Unexpected stats: ttft %+v itl %+v latency %+v", result.TTFT, result.ITL, result.Latency)
+	}
+	if result.TokensPerSecond <= 0 || result.RequestsPerSecond <= 0 {
+		t.Errorf("Expected throughput, got %+v", result)
+	}
+}
+
+func TestRun_CompletionsErrors(t *testing.T) {
+	fake := &fakeOpenAI{tokens: 3, failEvery: 4}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	result, err := Run(context.Background(), Options{
+		URL:         srv.URL + "/v1/",
+		Model:       "qwen",
+		Endpoint:    EndpointCompletions,
+		Requests:    8,
+		Concurrency: 2,
+		Prompt:      "Hello",
+	}, nil)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if fake.completions.Load() != 8 {
+		t.Errorf("Expected completions requests, got %d", fake.completions.Load())
+	}
+	if result.Errors != 2 || result.ErrorRate != 0.25 {
+		t.Errorf("Expected 2 of 8 requests to fail, got %d (%.2f)", result.Errors, result.ErrorRate)
+	}
+	if len(result.ErrorSamples) != 1 || !strings.Contains(result.ErrorSamples[0], "500") {
+		t.Errorf("Expected one distinct error sample, got %v", result.ErrorSamples)
+	}
+	if result.TTFT != nil || result.ITL != nil || result.Latency == nil {
+		t.Errorf("Expected only latency without streaming, got %+v", result)
+	}
+	if result.OutputTokens != 6*3 {
+		t.Errorf("Expected %d output tokens, got %d", 6*3, result.OutputTokens)
+	}
+}
+
+func TestRun_StreamCountsChunks(t *testing.T) {
+	srv := httptest.NewServer(&fakeOpenAI{tokens: 4})
+	defer srv.Close()
+
+	result, err := Run(context.Background(), Options{
+		URL:         srv.URL + "/v1",
+		Model:       "qwen",
+		Endpoint:    EndpointCompletions,
+		Stream:      true,
+		Requests:    2,
+		Concurrency: 4,
+	}, nil)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.OutputTokens != 8 || result.ITL == nil {
+		t.Errorf("Expected each chunk to count as a token, got %+v", result)
+	}
+}
+
+func TestRun_Invalid(t *testing.T) {
+	if _, err := Run(context.Background(), Options{Endpoint: "embeddings", Requests: 1, Concurrency: 1}, nil); err == nil {
+		t.Error("Expected an unknown endpoint to fail")
+	}
+	if _, err := Run(context.Background(), Options{Endpoint: EndpointChat, Requests: 0, Concurrency: 1}, nil); err == nil {
+		t.Error("Expected zero requests to fail")
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	values := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	for p, want := range map[float64]float64{50: 5, 90: 9, 99: 10, 100: 10, 1: 1} {
+		if got := percentile(values, p); got != want {
+			t.Errorf("percentile(%v) = %v, want %v", p, got, want)
+		}
+	}
+	if got := generatePrompt(20); len(strings.Fields(got)) != 20 {
+		t.Errorf("Expected a 20 word prompt, got %q", got)
+	}
+}
@@ -0,0 +1,198 @@
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// archiveWriter writes a gzipped tar bundle and records the checksum of each
+// entry for the manifest
+type archiveWriter struct {
+	file  *os.File
+	gz    *gzip.Writer
+	tw    *tar.Writer
+	files []File
+}
+
+func createArchive(output string) (*archiveWriter, error) {
+	f, err := os.Create(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bundle: %w", err)
+	}
+
+	// Images are already compressed, so favour speed over ratio
+	gz, err := gzip.NewWriterLevel(f, gzip.BestSpeed)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &archiveWriter{
+		file: f,
+		gz:   gz,
+		tw:   tar.NewWriter(gz),
+	}, nil
+}
+
+// addFile copies the file at src into the archive under name
+func (a *archiveWriter) addFile(name, kind, src string) error {
+	if err := checkEntryPath(name); err != nil {
+		return err
+	}
+
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    int64(info.Mode().Perm()),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}
+	if err := a.tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("failed to add %s: %w", name, err)
+	}
+
+	hw := newHashingWriter(a.tw)
+	if _, err := io.Copy(hw, f); err != nil {
+		return fmt.Errorf("failed to add %s: %w", name, err)
+	}
+
+	a.files = append(a.files, File{
+		Path:   name,
+		Kind:   kind,
+		Size:   hw.size,
+		SHA256: hw.sum(),
+		Mode:   info.Mode().Perm(),
+	})
+	return nil
+}
+
+// close writes the manifest as the last entry and closes the archive
+func (a *archiveWriter) close(m *Manifest) error {
+	m.Files = a.files
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		a.abort()
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	hdr := &tar.Header{
+		Name: ManifestName,
+		Mode: 0644,
+		Size: int64(len(data)),
+	}
+	if err := a.tw.WriteHeader(hdr); err != nil {
+		a.abort()
+		return err
+	}
+	if _, err := a.tw.Write(data); err != nil {
+		a.abort()
+		return err
+	}
+
+	if err := a.tw.Close(); err != nil {
+		a.abort()
+		return err
+	}
+	if err := a.gz.Close(); err != nil {
+		a.abort()
+		return err
+	}
+	return a.file.Close()
+}
+
+// abort closes the archive and removes the partial file
+func (a *archiveWriter) abort() {
+	a.file.Close()
+	os.Remove(a.file.Name())
+}
+
+// extractArchive unpacks the bundle at archive into dir and returns the
+// checksum of every entry
+func extractArchive(archive, dir string) (map[string]File, error) {
+	f, err := os.Open(archive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("not a bundle archive: %w", err)
+	}
+	defer gz.Close()
+
+	entries := make(map[string]File)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle: %w", err)
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			return nil, fmt.Errorf("unsupported entry %s in bundle", hdr.Name)
+		}
+		if err := checkEntryPath(hdr.Name); err != nil {
+			return nil, err
+		}
+		if _, ok := entries[hdr.Name]; ok {
+			return nil, fmt.Errorf("duplicate entry %s in bundle", hdr.Name)
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(hdr.Name))
+		if !strings.HasPrefix(target, filepath.Clean(dir)+string(os.PathSeparator)) {
+			return nil, fmt.Errorf("invalid path %q in bundle", hdr.Name)
+		}
+
+		entry, err := extractFile(tr, target, hdr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract %s: %w", hdr.Name, err)
+		}
+		entries[hdr.Name] = entry
+	}
+
+	return entries, nil
+}
+
+func extractFile(r io.Reader, target string, hdr *tar.Header) (File, error) {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return File{}, err
+	}
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return File{}, err
+	}
+	defer out.Close()
+
+	hw := newHashingWriter(out)
+	if _, err := io.Copy(hw, r); err != nil {
+		return File{}, err
+	}
+
+	return File{
+		Path:   hdr.Name,
+		Size:   hw.size,
+		SHA256: hw.sum(),
+		Mode:   os.FileMode(hdr.Mode).Perm(),
+	}, nil
+}
@@ -0,0 +1,213 @@
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eternisai/silo/internal/config"
+)
+
+func writeTestBundle(t *testing.T, dir string) string {
+	t.Helper()
+
+	image := filepath.Join(dir, "image.tar")
+	if err := os.WriteFile(image, []byte("image layers"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	binary := filepath.Join(dir, "silo")
+	if err := os.WriteFile(binary, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	model := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(model, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	output := filepath.Join(dir, "bundle.tar.gz")
+	archive, err := createArchive(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := archive.addFile("images/00-backend.tar", KindImage, image); err != nil {
+		t.Fatal(err)
+	}
+	if err := archive.addFile("bin/silo", KindBinary, binary); err != nil {
+		t.Fatal(err)
+	}
+	if err := archive.addFile("models/config.json", KindModel, model); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest := &Manifest{
+		FormatVersion: FormatVersion,
+		CLIVersion:    "1.2.3",
+		ImageTag:      "0.2.0",
+		Images: []Image{
+			{Ref: "eternis/silo-box-backend:0.2.0", File: "images/00-backend.tar"},
+		},
+	}
+	if err := archive.close(manifest); err != nil {
+		t.Fatal(err)
+	}
+	return output
+}
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	output := writeTestBundle(t, dir)
+
+	b, err := Open(output, dir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer b.Close()
+
+	if b.Manifest.ImageTag != "0.2.0" {
+		t.Errorf("Expected image tag 0.2.0, got %s", b.Manifest.ImageTag)
+	}
+	if len(b.Manifest.Files) != 3 {
+		t.Fatalf("Expected 3 files in manifest, got %d", len(b.Manifest.Files))
+	}
+
+	binDir := filepath.Join(dir, "bin")
+	installed, err := b.InstallBinaries(binDir)
+	if err != nil {
+		t.Fatalf("InstallBinaries failed: %v", err)
+	}
+	if len(installed) != 1 {
+		t.Fatalf("Expected 1 installed binary, got %v", installed)
+	}
+	info, err := os.Stat(installed[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm()&0100 == 0 {
+		t.Errorf("Expected installed binary to be executable, got %v", info.Mode())
+	}
+
+	modelDir := filepath.Join(dir, "models")
+	if n, err := b.InstallModels(modelDir); err != nil || n != 1 {
+		t.Fatalf("InstallModels returned %d, %v", n, err)
+	}
+	if _, err := os.Stat(filepath.Join(modelDir, "config.json")); err != nil {
+		t.Errorf("Expected model file to be installed: %v", err)
+	}
+
+	extracted := b.Dir
+	b.Close()
+	if _, err := os.Stat(extracted); !os.IsNotExist(err) {
+		t.Errorf("Expected Close to remove %s", extracted)
+	}
+}
+
+// rewriteEntry copies the bundle at src to dst, replacing the content of the
+// named entry
+func rewriteEntry(t *testing.T, src, dst, name, content string) {
+	t.Helper()
+
+	in, err := os.Open(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	gzr, err := gzip.NewReader(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gzr)
+
+	out, err := os.Create(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	gzw := gzip.NewWriter(out)
+	tw := tar.NewWriter(gzw)
+
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Name == name {
+			data = []byte(content)
+			hdr.Size = int64(len(data))
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw.Close()
+	gzw.Close()
+}
+
+func TestOpen_ChecksumMismatch(t *testing.T) {
+	dir := t.TempDir()
+	output := writeTestBundle(t, dir)
+
+	tampered := filepath.Join(dir, "tampered.tar.gz")
+	rewriteEntry(t, output, tampered, "bin/silo", "#!/bin/sh\n") // same size
+	if _, err := Open(tampered, dir); err != nil {
+		t.Fatalf("Expected identical content to verify, got %v", err)
+	}
+
+	rewriteEntry(t, output, tampered, "bin/silo", "#!/bin/ba\n")
+	_, err := Open(tampered, dir)
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("Expected checksum mismatch, got %v", err)
+	}
+}
+
+func TestCheckEntryPath(t *testing.T) {
+	valid := []string{"manifest.json", "images/00-backend.tar", "models/sub/weights.safetensors"}
+	for _, name := range valid {
+		if err := checkEntryPath(name); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", name, err)
+		}
+	}
+
+	invalid := []string{"", "/etc/passwd", "../escape", "images/../../escape", "./manifest.json", `bin\silo`}
+	for _, name := range invalid {
+		if err := checkEntryPath(name); err == nil {
+			t.Errorf("Expected %q to be rejected", name)
+		}
+	}
+}
+
+func TestImages(t *testing.T) {
+	paths := config.NewPaths(t.TempDir(), t.TempDir())
+	cfg := config.NewDefaultConfig(paths)
+	cfg.EnableProxyAgent = false
+
+	images := Images(cfg)
+
+	want := []string{
+		"eternis/silo-box-backend:" + cfg.ImageTag,
+		"eternis/silo-proxy-agent",
+		cfg.DeepResearchImage,
+		cfg.SGLang.Image,
+	}
+	for _, image := range want {
+		found := false
+		for _, got := range images {
+			if got == image {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected %s in bundle images %v", image, images)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package bundle
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/eternisai/silo/internal/config"
+	"github.com/eternisai/silo/internal/docker"
+	"github.com/eternisai/silo/internal/inference"
+	"github.com/eternisai/silo/pkg/logger"
+)
+
+// Exporter writes an offline bundle for a configuration
+type Exporter struct {
+	config    *config.Config
+	logger    *logger.Logger
+	version   string
+	binaries  map[string]string
+	modelsDir string
+	workDir   string
+}
+
+// NewExporter creates an exporter for the images used by cfg
+func NewExporter(cfg *config.Config, log *logger.Logger) *Exporter {
+	return &Exporter{
+		config:   cfg,
+		logger:   log,
+		binaries: make(map[string]string),
+	}
+}
+
+// SetVersion sets the CLI version recorded in the manifest
+func (e *Exporter) SetVersion(version string) {
+	e.version = version
+}
+
+// AddBinary includes the executable at file in the bundle under name
+func (e *Exporter) AddBinary(name, file string) {
+	e.binaries[name] = file
+}
+
+// SetModelsDir includes the model files under dir in the bundle
+func (e *Exporter) SetModelsDir(dir string) {
+	e.modelsDir = dir
+}
+
+// SetWorkDir sets where images are saved before being archived; the system
+// temp directory is used by default
+func (e *Exporter) SetWorkDir(dir string) {
+	e.workDir = dir
+}
+
+// Images returns every image the configuration can run: all compose
+// services, whether enabled or not, and the inference engine. Images are
+// returned by tag, since loaded images lose their registry digests.
+func Images(cfg *config.Config) []string {
+	all := *cfg
+	all.EnableProxyAgent = true
+	all.EnableDeepResearch = true
+	all.SetInferenceImage(inference.New(cfg, nil).ImageRef())
+	return all.ImageRefs()
+}
+
+// Export writes the bundle to output
+func (e *Exporter) Export(ctx context.Context, output string) (*Manifest, error) {
+	images := Images(e.config)
+
+	if err := docker.Login(ctx, e.config.Registry); err != nil {
+		return nil, err
+	}
+
+	workDir, err := os.MkdirTemp(e.workDir, "silo-bundle-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create work directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	archive, err := createArchive(output)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{
+		FormatVersion:     FormatVersion,
+		CreatedAt:         time.Now().Format(time.RFC3339),
+		CLIVersion:        e.version,
+		Platform:          runtime.GOOS + "/" + runtime.GOARCH,
+		ImageTag:          e.config.ImageTag,
+		DeepResearchImage: e.config.DeepResearchImage,
+		InferenceImage:    inference.New(e.config, nil).ImageRef(),
+	}
+
+	for i, image := range images {
+		img, err := e.saveImage(ctx, archive, workDir, i, image)
+		if err != nil {
+			archive.abort()
+			return nil, err
+		}
+		manifest.Images = append(manifest.Images, *img)
+	}
+
+	for name, file := range e.binaries {
+		e.logger.Info("Adding binary %s", name)
+		if err := archive.addFile(path.Join("bin", name), KindBinary, file); err != nil {
+			archive.abort()
+			return nil, fmt.Errorf("failed to add binary %s: %w", name, err)
+		}
+	}
+
+	if e.modelsDir != "" {
+		if err := e.addModels(archive); err != nil {
+			archive.abort()
+			return nil, err
+		}
+	}
+
+	if err := archive.close(manifest); err != nil {
+		return nil, fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// saveImage pulls the image if it is missing locally, saves it and adds it to
+// the archive. Images are used under their registry mirror names if any.
+func (e *Exporter) saveImage(ctx context.Context, archive *archiveWriter, workDir string, index int, ref string) (*Image, error) {
+	local := e.config.Registry.Rewrite(ref)
+	info, err := docker.InspectImage(ctx, local)
+	if err != nil {
+		e.logger.Info("Pulling %s...", local)
+		if err := docker.PullImage(ctx, local); err != nil {
+			return nil, err
+		}
+		if info, err = docker.InspectImage(ctx, local); err != nil {
+			return nil, err
+		}
+	}
+
+	e.logger.Info("Saving %s...", local)
+	name := fmt.Sprintf("%02d-%s.tar", index, imageFileName(ref))
+	file := filepath.Join(workDir, name)
+	if err := docker.SaveImage(ctx, local, file); err != nil {
+		return nil, err
+	}
+	defer os.Remove(file)
+
+	entry := path.Join("images", name)
+	if err := archive.addFile(entry, KindImage, file); err != nil {
+		return nil, fmt.Errorf("failed to add image %s: %w", ref, err)
+	}
+
+	return &Image{
+		Ref:         ref,
+		Name:        local,
+		ID:          info.ID,
+		RepoDigests: info.RepoDigests,
+		File:        entry,
+	}, nil
+}
+
+// addModels adds every regular file under the models directory
+func (e *Exporter) addModels(archive *archiveWriter) error {
+	e.logger.Info("Adding model files from %s...", e.modelsDir)
+
+	return filepath.Walk(e.modelsDir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(e.modelsDir, file)
+		if err != nil {
+			return err
+		}
+		if err := archive.addFile(path.Join("models", filepath.ToSlash(rel)), KindModel, file); err != nil {
+			return fmt.Errorf("failed to add model file %s: %w", rel, err)
+		}
+		return nil
+	})
+}
+
+// imageFileName turns an image reference into a file name
+func imageFileName(image string) string {
+	return strings.NewReplacer("/", "_", ":", "_", "@", "_").Replace(image)
+}
@@ -0,0 +1,180 @@
+package bundle
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/eternisai/silo/internal/docker"
+	"github.com/eternisai/silo/internal/registry"
+	"github.com/eternisai/silo/pkg/logger"
+)
+
+// Bundle is an extracted and verified bundle
+type Bundle struct {
+	Dir      string
+	Manifest *Manifest
+}
+
+// Open extracts the bundle at archive into a temporary directory under
+// workDir and verifies every entry against the manifest. The system temp
+// directory is used when workDir is empty. Close removes the extracted files.
+func Open(archive, workDir string) (*Bundle, error) {
+	dir, err := os.MkdirTemp(workDir, "silo-bundle-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create work directory: %w", err)
+	}
+
+	b := &Bundle{Dir: dir}
+	if err := b.extract(archive); err != nil {
+		b.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *Bundle) extract(archive string) error {
+	entries, err := extractArchive(archive, b.Dir)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := entries[ManifestName]; !ok {
+		return fmt.Errorf("bundle has no manifest")
+	}
+	manifest, err := readManifest(filepath.Join(b.Dir, ManifestName))
+	if err != nil {
+		return err
+	}
+	if err := verify(manifest, entries); err != nil {
+		return err
+	}
+
+	b.Manifest = manifest
+	return nil
+}
+
+// verify checks that the extracted entries match the manifest exactly
+func verify(m *Manifest, entries map[string]File) error {
+	listed := make(map[string]bool, len(m.Files))
+	for _, f := range m.Files {
+		listed[f.Path] = true
+
+		got, ok := entries[f.Path]
+		if !ok {
+			return fmt.Errorf("bundle is missing %s", f.Path)
+		}
+		if got.Size != f.Size {
+			return fmt.Errorf("size mismatch for %s: expected %d bytes, got %d", f.Path, f.Size, got.Size)
+		}
+		if got.SHA256 != f.SHA256 {
+			return fmt.Errorf("checksum mismatch for %s", f.Path)
+		}
+	}
+
+	for name := range entries {
+		if name != ManifestName && !listed[name] {
+			return fmt.Errorf("unexpected file %s in bundle", name)
+		}
+	}
+	return nil
+}
+
+// Close removes the extracted bundle
+func (b *Bundle) Close() error {
+	return os.RemoveAll(b.Dir)
+}
+
+// LoadImages loads every image of the bundle into docker and names it as
+// the importing config's registry settings expect
+func (b *Bundle) LoadImages(ctx context.Context, reg registry.Config, log *logger.Logger) error {
+	for _, img := range b.Manifest.Images {
+		log.Info("Loading %s...", img.Ref)
+		if err := docker.LoadImage(ctx, filepath.Join(b.Dir, filepath.FromSlash(img.File))); err != nil {
+			return err
+		}
+
+		name := img.Name
+		if name == "" {
+			name = img.Ref
+		}
+		if target := reg.Rewrite(img.Ref); target != name {
+			if err := docker.TagImage(ctx, name, target); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// CanInstallBinaries reports whether the bundled binaries were built for
+// this platform
+func (b *Bundle) CanInstallBinaries() bool {
+	return b.Manifest.Platform == runtime.GOOS+"/"+runtime.GOARCH
+}
+
+// InstallBinaries copies the bundled binaries into dir, replacing existing
+// ones, and returns the installed paths
+func (b *Bundle) InstallBinaries(dir string) ([]string, error) {
+	var installed []string
+	for _, f := range b.Manifest.FilesOfKind(KindBinary) {
+		target := filepath.Join(dir, filepath.Base(f.Path))
+		if err := installFile(filepath.Join(b.Dir, filepath.FromSlash(f.Path)), target, 0755); err != nil {
+			return installed, fmt.Errorf("failed to install %s: %w", target, err)
+		}
+		installed = append(installed, target)
+	}
+	return installed, nil
+}
+
+// InstallModels copies the bundled model files into dir and returns the
+// number of files copied
+func (b *Bundle) InstallModels(dir string) (int, error) {
+	models := b.Manifest.FilesOfKind(KindModel)
+	for _, f := range models {
+		rel := strings.TrimPrefix(f.Path, "models/")
+		target := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := installFile(filepath.Join(b.Dir, filepath.FromSlash(f.Path)), target, f.Mode|0644); err != nil {
+			return 0, fmt.Errorf("failed to install model file %s: %w", rel, err)
+		}
+	}
+	return len(models), nil
+}
+
+// installFile copies src to a temporary file next to target and renames it
+// into place, so running binaries can be replaced
+func installFile(src, target string, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), target)
+}
@@ -0,0 +1,171 @@
+package bundle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/eternisai/silo/internal/config"
+)
+
+const (
+	// ManifestName is the name of the manifest entry, always the last entry
+	// of the archive
+	ManifestName = "manifest.json"
+
+	// FormatVersion is the bundle layout version written by this CLI
+	FormatVersion = 1
+)
+
+// File kinds
+const (
+	KindImage  = "image"
+	KindBinary = "binary"
+	KindModel  = "model"
+)
+
+// Manifest describes the contents of a bundle
+type Manifest struct {
+	FormatVersion     int     `json:"format_version"`
+	CreatedAt         string  `json:"created_at"`
+	CLIVersion        string  `json:"cli_version"`
+	Platform          string  `json:"platform"`
+	ImageTag          string  `json:"image_tag"`
+	DeepResearchImage string  `json:"deep_research_image"`
+	InferenceImage    string  `json:"inference_image"`
+	Images            []Image `json:"images"`
+	Files             []File  `json:"files"`
+}
+
+// Image is a docker image saved in the bundle. Ref is the configured
+// reference and Name the one it was saved under, which differs when it was
+// pulled from a registry mirror.
+type Image struct {
+	Ref         string   `json:"ref"`
+	Name        string   `json:"name"`
+	ID          string   `json:"id"`
+	RepoDigests []string `json:"repo_digests,omitempty"`
+	File        string   `json:"file"`
+}
+
+// File is an entry of the bundle archive with its checksum
+type File struct {
+	Path   string      `json:"path"`
+	Kind   string      `json:"kind"`
+	Size   int64       `json:"size"`
+	SHA256 string      `json:"sha256"`
+	Mode   os.FileMode `json:"mode"`
+}
+
+// Info returns the bundle details recorded in the installation state
+func (m *Manifest) Info() *config.BundleInfo {
+	return &config.BundleInfo{
+		CreatedAt:  m.CreatedAt,
+		CLIVersion: m.CLIVersion,
+		ImageTag:   m.ImageTag,
+	}
+}
+
+// Apply points the config at the images shipped in the bundle
+func (m *Manifest) Apply(cfg *config.Config) {
+	cfg.ImageTag = m.ImageTag
+	if m.DeepResearchImage != "" {
+		cfg.DeepResearchImage = m.DeepResearchImage
+	}
+	if m.InferenceImage != "" {
+		cfg.SetInferenceImage(m.InferenceImage)
+	}
+}
+
+// FilesOfKind returns the files of the given kind
+func (m *Manifest) FilesOfKind(kind string) []File {
+	var files []File
+	for _, f := range m.Files {
+		if f.Kind == kind {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// validate checks the manifest format and that every image and file path is
+// a clean relative path that stays inside the bundle
+func (m *Manifest) validate() error {
+	if m.FormatVersion != FormatVersion {
+		return fmt.Errorf("unsupported bundle format version %d (expected %d)", m.FormatVersion, FormatVersion)
+	}
+	if m.ImageTag == "" {
+		return fmt.Errorf("manifest has no image tag")
+	}
+
+	files := make(map[string]bool, len(m.Files))
+	for _, f := range m.Files {
+		if err := checkEntryPath(f.Path); err != nil {
+			return err
+		}
+		if files[f.Path] {
+			return fmt.Errorf("duplicate file %s in manifest", f.Path)
+		}
+		files[f.Path] = true
+	}
+	for _, img := range m.Images {
+		if !files[img.File] {
+			return fmt.Errorf("image %s refers to missing file %s", img.Ref, img.File)
+		}
+	}
+	return nil
+}
+
+// checkEntryPath rejects archive paths that are absolute or escape the
+// extraction directory
+func checkEntryPath(name string) error {
+	if name == "" || path.IsAbs(name) || strings.Contains(name, `\`) ||
+		path.Clean(name) != name || name == ".." || strings.HasPrefix(name, "../") {
+		return fmt.Errorf("invalid path %q in bundle", name)
+	}
+	return nil
+}
+
+func readManifest(file string) (*Manifest, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("bundle has no manifest: %w", err)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// hashingWriter counts and hashes the bytes written through it
+type hashingWriter struct {
+	w    io.Writer
+	hash hash.Hash
+	size int64
+}
+
+func newHashingWriter(w io.Writer) *hashingWriter {
+	return &hashingWriter{w: w, hash: sha256.New()}
+}
+
+func (h *hashingWriter) Write(p []byte) (int, error) {
+	n, err := h.w.Write(p)
+	h.hash.Write(p[:n])
+	h.size += int64(n)
+	return n, err
+}
+
+func (h *hashingWriter) sum() string {
+	return hex.EncodeToString(h.hash.Sum(nil))
+}
@@ -0,0 +1,218 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/eternisai/silo/internal/bench"
+	"github.com/eternisai/silo/internal/config"
+	"github.com/eternisai/silo/internal/inference"
+	"github.com/spf13/cobra"
+)
+
+var (
+	benchURL          string
+	benchModel        string
+	benchAPIKey       string
+	benchEndpoint     string
+	benchStream       bool
+	benchRequests     int
+	benchConcurrency  int
+	benchPrompt       string
+	benchPromptTokens int
+	benchMaxTokens    int
+	benchTimeout      time.Duration
+	benchJSON         bool
+)
+
+var inferenceBenchCmd = &cobra.Command{
+	Use:   "bench [model]",
+	Short: "Benchmark the inference engine",
+	Long: `Send concurrent OpenAI compatible chat or completions requests to the
+inference engine and report the time to first token, the inter-token
+latency, the throughput and the error rate with their percentiles.
+
+The requests go to the engine of the named model, or of the only model,
+on the host, unless llm_base_url points at another server or --url names
+one. Time to first token and inter-token latency are measured on streamed
+responses; use --stream=false to measure whole responses only.
+
+Run it with the concurrency your users produce while tuning
+max_running_requests, context_length and mem_fraction_static, e.g.:
+
+  silo inference bench --concurrency 16 --requests 200 --prompt-tokens 2000`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: modelNames,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+
+		paths := instancePaths()
+		cfg, err := config.LoadOrDefault(paths.ConfigFile, paths)
+		if err != nil {
+			log.Error("Failed to load config: %v", err)
+			return err
+		}
+
+		target, model, err := benchTarget(cfg, args)
+		if err != nil {
+			log.Error("%v", err)
+			return err
+		}
+		if benchModel != "" {
+			model = benchModel
+		}
+
+		opts := bench.Options{
+			URL:          target,
+			Model:        model,
+			APIKey:       benchAPIKey,
+			Endpoint:     benchEndpoint,
+			Stream:       benchStream,
+			Requests:     benchRequests,
+			Concurrency:  benchConcurrency,
+			Prompt:       benchPrompt,
+			PromptTokens: benchPromptTokens,
+			MaxTokens:    benchMaxTokens,
+			Timeout:      benchTimeout,
+		}
+
+		var progress func(int)
+		if !benchJSON {
+			log.Info("Sending %d requests to %s at %s, %d at a time...", benchRequests, model, target, benchConcurrency)
+			step := max(benchRequests/10, 1)
+			progress = func(done int) {
+				if done%step == 0 || done == benchRequests {
+					log.Info("  %d/%d requests done", done, benchRequests)
+				}
+			}
+		}
+
+		result, err := bench.Run(ctx, opts, progress)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				log.Warn("Benchmark interrupted")
+			} else {
+				log.Error("Benchmark failed: %v", err)
+			}
+			return err
+		}
+
+		if benchJSON {
+			jsonData, err := json.MarshalIndent(result, "", "  ")
+			if err != nil {
+				log.Error("Failed to marshal JSON: %v", err)
+				return err
+			}
+			fmt.Println(string(jsonData))
+		} else {
+			printBenchResult(result)
+		}
+
+		if result.Errors == result.Requests {
+			err := fmt.Errorf("all %d requests failed", result.Requests)
+			log.Error("%v", err)
+			return err
+		}
+		return nil
+	},
+}
+
+// benchTarget returns the base URL of the OpenAI compatible API to
+// benchmark and the model to request. llm_base_url is used when it points
+// away from the host, since host.docker.internal only resolves inside
+// containers; otherwise the engine is reached on its host port.
+func benchTarget(cfg *config.Config, args []string) (string, string, error) {
+	if benchURL != "" {
+		if u, err := url.Parse(benchURL); err != nil || u.Host == "" {
+			return "", "", fmt.Errorf("invalid --url %q, expected e.g. http://127.0.0.1:30000/v1", benchURL)
+		}
+		return benchURL, cfg.DefaultModel, nil
+	}
+	if len(args) == 0 && cfg.LLMBaseURL != "" && !strings.Contains(cfg.LLMBaseURL, "host.docker.internal") {
+		return cfg.LLMBaseURL, cfg.DefaultModel, nil
+	}
+
+	engines := inference.Engines(cfg, log)
+	if len(args) == 0 && len(engines) > 1 {
+		return "", "", fmt.Errorf("several models are configured, name one of %s", strings.Join(engineModels(engines), ", "))
+	}
+	engines, err := selectEngines(cfg, args, engines)
+	if err != nil {
+		return "", "", err
+	}
+	return engines[0].URL() + "/v1", engines[0].Model(), nil
+}
+
+// printBenchResult prints the result as a summary and a table of the
+// latency percentiles
+func printBenchResult(r *bench.Result) {
+	mode := "non-streaming"
+	if r.Stream {
+		mode = "streaming"
+	}
+
+	fmt.Println()
+	fmt.Printf("Benchmark of %s at %s\n", r.Model, r.URL)
+	fmt.Printf("  Endpoint:    %s (%s), concurrency %d\n", r.Endpoint, mode, r.Concurrency)
+	fmt.Printf("  Requests:    %d, %d failed (%.1f%%)\n", r.Requests, r.Errors, r.ErrorRate*100)
+	fmt.Printf("  Duration:    %.2fs\n", r.DurationSeconds)
+	fmt.Printf("  Throughput:  %.2f requests/s, %.1f output tokens/s\n", r.RequestsPerSecond, r.TokensPerSecond)
+	fmt.Printf("  Tokens:      %d prompt, %d output\n", r.PromptTokens, r.OutputTokens)
+
+	if r.Latency != nil {
+		fmt.Println()
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "\tMEAN\tP50\tP90\tP99\tMAX\t")
+		for _, row := range []struct {
+			name  string
+			stats *bench.Stats
+		}{
+			{"Time to first token (ms)", r.TTFT},
+			{"Inter-token latency (ms)", r.ITL},
+			{"Request latency (ms)", r.Latency},
+		} {
+			if row.stats == nil {
+				continue
+			}
+			s := row.stats
+			fmt.Fprintf(w, "%s\t%.1f\t%.1f\t%.1f\t%.1f\t%.1f\t\n", row.name, s.Mean, s.P50, s.P90, s.P99, s.Max)
+		}
+		w.Flush()
+	}
+
+	if len(r.ErrorSamples) > 0 {
+		fmt.Println()
+		fmt.Println("Errors:")
+		for _, msg := range r.ErrorSamples {
+			fmt.Printf("  %s\n", msg)
+		}
+	}
+}
+
+func init() {
+	inferenceCmd.AddCommand(inferenceBenchCmd)
+
+	flags := inferenceBenchCmd.Flags()
+	flags.StringVar(&benchURL, "url", "", "Base URL of the OpenAI compatible API (default: the engine on this host)")
+	flags.StringVar(&benchModel, "model", "", "Model to request (default: the model of the engine)")
+	flags.StringVar(&benchAPIKey, "api-key", "", "Bearer token sent with every request")
+	flags.StringVar(&benchEndpoint, "endpoint", bench.EndpointChat, "API to call: chat or completions")
+	flags.BoolVar(&benchStream, "stream", true, "Stream the responses to measure time to first token and inter-token latency")
+	flags.IntVarP(&benchRequests, "requests", "n", 100, "Number of requests to send")
+	flags.IntVarP(&benchConcurrency, "concurrency", "c", 8, "Number of requests in flight at a time")
+	flags.StringVar(&benchPrompt, "prompt", "", "Prompt to send (default: a generated prompt of --prompt-tokens words)")
+	flags.IntVar(&benchPromptTokens, "prompt-tokens", 256, "Approximate length of the generated prompt")
+	flags.IntVar(&benchMaxTokens, "max-tokens", 256, "Maximum number of tokens to generate per request")
+	flags.DurationVar(&benchTimeout, "timeout", 5*time.Minute, "Timeout of a single request")
+	flags.BoolVar(&benchJSON, "json", false, "Output in JSON format")
+}
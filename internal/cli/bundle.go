@@ -0,0 +1,238 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/eternisai/silo/internal/bundle"
+	"github.com/eternisai/silo/internal/config"
+	"github.com/eternisai/silo/internal/installer"
+	"github.com/eternisai/silo/internal/updater"
+	"github.com/spf13/cobra"
+)
+
+var (
+	bundleOutput       string
+	bundleModels       bool
+	bundleWorkDir      string
+	bundleBinDir       string
+	bundleSkipBinaries bool
+)
+
+var bundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Export and import offline bundles",
+	Long: `Export and import offline bundles for hosts without internet access.
+
+A bundle is a single archive containing every Docker image the configuration
+uses, the silo and silod binaries, a manifest with image digests and file
+checksums, and optionally the inference model files.`,
+}
+
+var bundleExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Write an offline bundle for the current configuration",
+	Long: `Write an offline bundle for the current configuration.
+
+Images missing locally are pulled first, so export needs registry access.
+The bundle includes all optional services (proxy agent, deep research) and
+the inference engine image, whether enabled or not.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		paths := instancePaths()
+
+		cfg, err := config.LoadOrDefault(paths.ConfigFile, paths)
+		if err != nil {
+			log.Error("Failed to load config: %v", err)
+			return err
+		}
+
+		output := bundleOutput
+		if output == "" {
+			output = fmt.Sprintf("silo-bundle-%s.tar.gz", cfg.ImageTag)
+		}
+
+		exp := bundle.NewExporter(cfg, log)
+		exp.SetVersion(version)
+		exp.SetWorkDir(bundleWorkDir)
+		addBundleBinaries(exp)
+		if bundleModels {
+			exp.SetModelsDir(cfg.InferenceModelPath())
+		}
+
+		log.Info("Exporting bundle for image tag %s...", cfg.ImageTag)
+		manifest, err := exp.Export(ctx, output)
+		if err != nil {
+			log.Error("Bundle export failed: %v", err)
+			return err
+		}
+
+		log.Success("Bundle written to %s (%d images, %d files)", output, len(manifest.Images), len(manifest.Files))
+		return nil
+	},
+}
+
+var bundleImportCmd = &cobra.Command{
+	Use:   "import <bundle>",
+	Short: "Install or upgrade Silo from an offline bundle",
+	Long: `Install or upgrade Silo from an offline bundle without registry access.
+
+This command will:
+  - Verify the checksum of every file in the bundle
+  - Load the bundled images into Docker
+  - Install the bundled silo and silod binaries (unless --skip-binaries)
+  - Copy bundled model files to the configured model path
+  - Install Silo, or upgrade an existing installation to the bundle's images`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		paths := instancePaths()
+
+		lock, err := lockInstance(paths, "bundle import")
+		if err != nil {
+			return err
+		}
+		defer lock.Release()
+
+		if _, err := migrateConfig(paths); err != nil {
+			return err
+		}
+
+		log.Info("Verifying bundle %s...", args[0])
+		b, err := bundle.Open(args[0], bundleWorkDir)
+		if err != nil {
+			log.Error("Invalid bundle: %v", err)
+			return err
+		}
+		defer b.Close()
+		log.Success("Bundle verified (image tag %s, created %s)", b.Manifest.ImageTag, b.Manifest.CreatedAt)
+
+		if !bundleSkipBinaries {
+			installBundleBinaries(b)
+		}
+
+		_, statErr := os.Stat(paths.ComposeFile)
+		installed := statErr == nil
+
+		// config.d drop-ins, such as a registry mirror, apply to the first
+		// install too
+		cfg, err := config.LoadOrDefault(paths.ConfigFile, paths)
+		if err != nil {
+			log.Error("Failed to load config: %v", err)
+			return err
+		}
+		if !installed {
+			reserved, err := config.ReservedPorts(paths)
+			if err != nil {
+				log.Warn("Failed to read ports of other instances: %v", err)
+			} else {
+				config.AllocatePorts(cfg, reserved)
+			}
+		}
+
+		if err := b.LoadImages(ctx, cfg.Registry, log); err != nil {
+			log.Error("Failed to load images: %v", err)
+			return err
+		}
+
+		if n, err := b.InstallModels(cfg.InferenceModelPath()); err != nil {
+			log.Error("%v", err)
+			return err
+		} else if n > 0 {
+			log.Success("Installed %d model files to %s", n, cfg.InferenceModelPath())
+		}
+
+		b.Manifest.Apply(cfg)
+		info := b.Manifest.Info()
+		info.ImportedAt = time.Now().Format(time.RFC3339)
+
+		if installed {
+			upd := updater.New(cfg, paths, log)
+			upd.SetBundle(info)
+			if err := upd.Update(ctx); err != nil {
+				log.Error("Upgrade failed: %v", err)
+				return err
+			}
+			return nil
+		}
+
+		if err := config.Validate(cfg); err != nil {
+			log.Error("Invalid configuration: %v", err)
+			return err
+		}
+
+		inst := installer.New(cfg, paths, log)
+		inst.SetBundle(info)
+		if err := inst.Install(ctx); err != nil {
+			log.Error("Installation failed: %v", err)
+			return err
+		}
+		return nil
+	},
+}
+
+// addBundleBinaries adds the running silo binary and the silod binary next
+// to it, or on PATH, to the export
+func addBundleBinaries(exp *bundle.Exporter) {
+	self, err := os.Executable()
+	if err != nil {
+		log.Warn("Could not locate the silo binary, it will not be bundled: %v", err)
+		return
+	}
+	exp.AddBinary("silo", self)
+
+	daemon := filepath.Join(filepath.Dir(self), "silod")
+	if _, err := os.Stat(daemon); err != nil {
+		if daemon, err = exec.LookPath("silod"); err != nil {
+			log.Warn("silod binary not found, it will not be bundled")
+			return
+		}
+	}
+	exp.AddBinary("silod", daemon)
+}
+
+// installBundleBinaries installs the bundled binaries into --bin-dir, or the
+// directory of the running silo binary
+func installBundleBinaries(b *bundle.Bundle) {
+	if !b.CanInstallBinaries() {
+		log.Warn("Bundled binaries are for %s, skipping binary install", b.Manifest.Platform)
+		return
+	}
+
+	dir := bundleBinDir
+	if dir == "" {
+		self, err := os.Executable()
+		if err != nil {
+			log.Warn("Could not locate the silo binary, skipping binary install: %v", err)
+			return
+		}
+		dir = filepath.Dir(self)
+	}
+
+	installed, err := b.InstallBinaries(dir)
+	if err != nil {
+		log.Warn("%v", err)
+		log.Info("Re-run with --bin-dir pointing to a writable directory, or --skip-binaries")
+	}
+	for _, file := range installed {
+		log.Success("Installed %s", file)
+	}
+}
+
+func init() {
+	bundleExportCmd.Flags().StringVarP(&bundleOutput, "output", "o", "", "Bundle file to write (default silo-bundle-<image tag>.tar.gz)")
+	bundleExportCmd.Flags().BoolVar(&bundleModels, "models", false, "Include model files from the configured model path")
+	bundleExportCmd.Flags().StringVar(&bundleWorkDir, "work-dir", "", "Directory for temporary files (default system temp)")
+
+	bundleImportCmd.Flags().StringVar(&bundleBinDir, "bin-dir", "", "Directory to install the bundled binaries to (default directory of silo)")
+	bundleImportCmd.Flags().BoolVar(&bundleSkipBinaries, "skip-binaries", false, "Do not install the bundled binaries")
+	bundleImportCmd.Flags().StringVar(&bundleWorkDir, "work-dir", "", "Directory for temporary files (default system temp)")
+
+	bundleCmd.AddCommand(bundleExportCmd)
+	bundleCmd.AddCommand(bundleImportCmd)
+	rootCmd.AddCommand(bundleCmd)
+}
@@ -1,9 +1,16 @@
 package cli
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/eternisai/silo/internal/config"
+	"github.com/eternisai/silo/internal/docker"
+	"github.com/eternisai/silo/internal/inference"
 	"github.com/spf13/cobra"
 )
 
@@ -14,10 +21,18 @@ var checkCmd = &cobra.Command{
 
 This command will:
   - Verify the config file exists and can be parsed
-  - Validate all configuration values
-  - Check if installation files exist (docker-compose.yml, state.json)`,
+  - Migrate the config file to the current schema version, keeping a backup
+  - Report unknown fields at every level of config.yml and config.d/*.yml,
+    with line and column
+  - List SILO_* environment and --set overrides, and unknown SILO_* variables
+  - Validate all configuration values and report every violation at once
+  - Check the gpu_devices of the inference engines exist and have the GPU
+    memory the engines claim free
+  - Check if installation files exist (docker-compose.yml, state.json)
+  - Check the secrets file is private and no legacy default password is used
+  - Report running images whose digest differs from the one recorded in state.json`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		paths := config.NewPaths(configDir, "")
+		paths := instancePaths()
 
 		// Check config file exists
 		if _, err := os.Stat(paths.ConfigFile); os.IsNotExist(err) {
@@ -27,23 +42,60 @@ This command will:
 		}
 		log.Success("Config file exists: %s", paths.ConfigFile)
 
-		// Load and parse config
-		cfg, err := config.Load(paths.ConfigFile)
+		// Parse config and apply pending schema migrations before looking at
+		// fields, so legacy keys are not reported as unknown
+		schemaVersion, pending, err := config.PendingMigrations(paths.ConfigFile)
 		if err != nil {
 			log.Error("Failed to load config: %v", err)
 			return err
 		}
 		log.Success("Config file parsed successfully")
 
-		// Check for unknown fields
-		unknownFields, err := config.FindUnknownFields(paths.ConfigFile)
+		if len(pending) > 0 {
+			lock, err := lockInstance(paths, "check")
+			if err != nil {
+				return err
+			}
+			_, err = migrateConfig(paths)
+			lock.Release()
+			if err != nil {
+				return err
+			}
+		} else {
+			log.Success("Config schema version %d is current", schemaVersion)
+		}
+
+		// Check for unknown fields at every level, in config.yml and the
+		// config.d drop-ins
+		files, err := configFiles(paths)
 		if err != nil {
-			log.Error("Failed to check for unknown fields: %v", err)
+			log.Error("Failed to list config.d drop-ins: %v", err)
 			return err
 		}
-		if len(unknownFields) > 0 {
+		for _, file := range files[1:] {
+			log.Info("Config drop-in: %s", file)
+		}
+		for _, file := range files {
+			unknownFields, err := config.FindUnknownFields(file)
+			if err != nil {
+				log.Error("Failed to check %s for unknown fields: %v", file, err)
+				return err
+			}
 			for _, field := range unknownFields {
-				log.Warn("Unknown config field: %s", field)
+				log.Warn("Unknown config field in %s: %s (line %d, column %d)", file, field.Path, field.Line, field.Column)
+			}
+		}
+
+		// Report environment and flag overrides, and SILO_* variables that
+		// name no setting, most likely typos
+		_, unknownEnv := config.EnvOverrides(os.Environ())
+		for _, name := range unknownEnv {
+			log.Warn("Unknown environment variable %s does not override any config key", name)
+		}
+		if overrides := config.ActiveOverrides(); len(overrides) > 0 {
+			log.Info("Overridden fields:")
+			for _, o := range overrides {
+				log.Info("  - %s (%s)", o.Key, o.Origin())
 			}
 		}
 
@@ -60,13 +112,36 @@ This command will:
 			}
 		}
 
-		// Validate config values
-		if err := config.Validate(cfg); err != nil {
-			log.Error("Invalid configuration: %v", err)
+		// Validate config values as written, then with defaults applied for
+		// missing fields, and report every problem at once
+		cfg, problems, err := validateConfigFile(paths)
+		if err != nil {
+			log.Error("Failed to validate config: %v", err)
 			return err
 		}
+		var errorCount int
+		for _, p := range problems {
+			if p.IsWarning() {
+				log.Warn("%s: %s", p.File, p.Problem)
+			}
+		}
+		for _, p := range problems {
+			if !p.IsWarning() {
+				log.Error("%s: %s", p.File, p.Problem)
+				errorCount++
+			}
+		}
+		if errorCount > 0 {
+			return fmt.Errorf("configuration has %d error(s)", errorCount)
+		}
 		log.Success("Configuration values are valid")
 
+		if cfg != nil {
+			if err := checkGPUs(cfg); err != nil {
+				return err
+			}
+		}
+
 		// Check generated files exist (indicates Silo was installed)
 		composeExists := true
 		if _, err := os.Stat(paths.ComposeFile); os.IsNotExist(err) {
@@ -90,11 +165,195 @@ This command will:
 			log.Warn("State file not found: %s (run 'silo up' to install)", paths.StateFile)
 		}
 
+		if composeExists {
+			checkSecrets(paths)
+			checkComposeFile(paths)
+		}
+
+		if composeExists && stateExists {
+			checkImageDigests(cfg, paths)
+		}
+
 		log.Success("Configuration check passed")
 		return nil
 	},
 }
 
+// fileProblem is a config problem and the file it was found in
+type fileProblem struct {
+	File string
+	config.Problem
+}
+
+// configFiles returns config.yml followed by the config.d drop-ins
+func configFiles(paths *config.Paths) ([]string, error) {
+	dropIns, err := config.DropInFiles(paths.ConfigDropInDir)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string{paths.ConfigFile}, dropIns...), nil
+}
+
+// validateConfigFile checks config.yml and the config.d drop-ins against the
+// schema, with line and column positions, and then the loaded config for
+// problems the files themselves do not show, such as rules spanning several
+// fields
+func validateConfigFile(paths *config.Paths) (*config.Config, []fileProblem, error) {
+	files, err := configFiles(paths)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var problems []fileProblem
+	reported := make(map[string]bool)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		found, err := config.ValidateDocument(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", file, err)
+		}
+		for _, p := range found {
+			problems = append(problems, fileProblem{File: file, Problem: p})
+			reported[p.Path] = true
+		}
+	}
+
+	cfg, err := config.LoadOrDefault(paths.ConfigFile, paths)
+	if err != nil {
+		// Values of the wrong type fail to load; they are already reported
+		if len(problems) > 0 {
+			return nil, problems, nil
+		}
+		return nil, nil, err
+	}
+
+	more, err := config.ValidateConfig(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, p := range more {
+		if reported[p.Path] {
+			continue
+		}
+		// Attribute the problem to the layer that set the value
+		file := cfg.ValueSource(p.Path)
+		switch {
+		case file == string(config.SourceFile) || file == string(config.SourceDefault):
+			file = paths.ConfigFile
+		case strings.HasPrefix(file, config.DropInDirName):
+			file = filepath.Join(paths.ConfigDir, file)
+		}
+		problems = append(problems, fileProblem{File: file, Problem: p})
+	}
+	return cfg, problems, nil
+}
+
+// checkGPUs checks the gpu_devices of the enabled inference engines that
+// are not running against the GPUs of the host
+func checkGPUs(cfg *config.Config) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var engines []*inference.Engine
+	for _, engine := range inference.DefaultEngines(cfg, log) {
+		if engine.Enabled() {
+			engines = append(engines, engine)
+		}
+	}
+	if len(engines) == 0 {
+		return nil
+	}
+	if err := checkEngineGPUs(ctx, engines); err != nil {
+		return err
+	}
+	log.Success("GPU devices of the inference engines are available")
+	return nil
+}
+
+// checkComposeFile validates the generated compose file together with the
+// user's override file
+func checkComposeFile(paths *config.Paths) {
+	if _, err := os.Stat(paths.ComposeOverrideFile); err == nil {
+		log.Info("Compose override file: %s", paths.ComposeOverrideFile)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := docker.Validate(ctx, paths.ComposeFile); err != nil {
+		log.Warn("Docker Compose rejected the configuration: %v", err)
+		return
+	}
+	log.Success("Docker Compose configuration is valid")
+}
+
+// checkImageDigests warns about running containers whose image differs from
+// the digest pinned at install or upgrade
+func checkImageDigests(cfg *config.Config, paths *config.Paths) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	state, err := config.LoadState(paths.StateFile)
+	if err != nil || len(state.Images) == 0 {
+		log.Info("No image digests recorded (run 'silo upgrade' to pin images)")
+		return
+	}
+
+	containers, err := docker.Ps(ctx, paths.ComposeFile)
+	if err != nil {
+		log.Warn("Could not check image digests: %v", err)
+		return
+	}
+
+	running := make(map[string]string)
+	for _, c := range containers {
+		if ref := cfg.ImageRef(c.Service); ref != "" && c.State == "running" {
+			running[c.Name] = ref
+		}
+	}
+	for _, engine := range inference.Engines(cfg, log) {
+		if ok, _ := engine.IsRunning(ctx); ok {
+			running[engine.ContainerName()] = engine.ImageRef()
+		}
+	}
+
+	drifted := 0
+	for container, ref := range running {
+		pinned, ok := state.Images[ref]
+		if !ok {
+			log.Warn("Image of %s is not pinned: %s", container, ref)
+			continue
+		}
+
+		info, err := docker.ContainerImage(ctx, container)
+		if err != nil {
+			log.Warn("Could not inspect image of %s: %v", container, err)
+			continue
+		}
+		if !info.HasDigest(pinned) {
+			log.Warn("Image digest drift in %s: running %s, recorded %s", container, runningDigest(info), pinned)
+			drifted++
+		}
+	}
+
+	if drifted == 0 {
+		log.Success("Running images match the recorded digests")
+	} else {
+		log.Info("Run 'silo up' to recreate containers from the recorded digests")
+	}
+}
+
+// runningDigest describes the image a container runs by its registry
+// digest, or by image ID if it has none
+func runningDigest(info *docker.ImageInfo) string {
+	if len(info.RepoDigests) > 0 {
+		return info.RepoDigests[0]
+	}
+	return info.ID
+}
+
 func init() {
 	rootCmd.AddCommand(checkCmd)
 }
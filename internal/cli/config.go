@@ -0,0 +1,582 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/eternisai/silo/internal/config"
+	"github.com/eternisai/silo/internal/inference"
+	"github.com/spf13/cobra"
+)
+
+var (
+	configEffective bool
+	configSource    bool
+	configBackup    bool
+	configAgainst   string
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "View and change the Silo configuration",
+	Long: `View and change the Silo configuration file (config.yml).
+
+Keys are dotted paths into nested sections, e.g. port, sglang.tp_size or
+registry.mirrors.docker.io. Every change is validated against the schema
+before it is written, and the file is replaced atomically. Afterwards the
+services whose definition changed are listed so they can be recreated.
+
+Fragments in config.d/*.yml are deep-merged over config.yml in lexical
+order. Every key can also be overridden without changing a file, by a SILO_*
+environment variable named after the key (SILO_SGLANG_TP_SIZE for
+sglang.tp_size) or by the --set flag of any command. Flags win over the
+environment, which wins over config.d, then config.yml, then the defaults.
+Changes made with these commands are written to config.yml only.`,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the effective value of a setting",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		paths := instancePaths()
+
+		cfg, err := config.LoadOrDefault(paths.ConfigFile, paths)
+		if err != nil {
+			log.Error("Failed to load config: %v", err)
+			return err
+		}
+
+		node, err := config.EffectiveValue(cfg, args[0])
+		if err != nil {
+			log.Error("%v", err)
+			return err
+		}
+		value, err := config.FormatValue(node)
+		if err != nil {
+			return err
+		}
+		fmt.Println(value)
+		return nil
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Change a setting",
+	Long: `Change a setting. The value is parsed as YAML, so lists can be given
+in flow style:
+
+  silo config set sglang.tp_size 2
+  silo config set sglang.gpu_devices '["0", "1"]'`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		err := editConfig(func(doc *config.Document) error {
+			return doc.Set(args[0], args[1])
+		})
+		if err == nil {
+			warnOverridden(args[0])
+		}
+		return err
+	},
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <key>",
+	Short: "Remove a setting so its default applies",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		err := editConfig(func(doc *config.Document) error {
+			removed, err := doc.Unset(args[0])
+			if err == nil && !removed {
+				log.Info("%s is not set, the default already applies", args[0])
+			}
+			return err
+		})
+		if err == nil {
+			warnOverridden(args[0])
+		}
+		return err
+	},
+}
+
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Edit config.yml in $EDITOR",
+	Long: `Open config.yml in $VISUAL or $EDITOR (default vi). The edited file is
+validated before it replaces config.yml; on problems the editor can be
+reopened with the edits kept.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		paths := instancePaths()
+
+		// The file is edited as migrated, so persist the migrations first
+		lock, err := lockInstance(paths, "config edit")
+		if err != nil {
+			return err
+		}
+		_, err = migrateConfig(paths)
+		lock.Release()
+		if err != nil {
+			return err
+		}
+
+		before, err := config.OpenDocument(paths.ConfigFile)
+		if err != nil {
+			log.Error("Failed to load config: %v", err)
+			return err
+		}
+		original, err := before.Bytes()
+		if err != nil {
+			return err
+		}
+
+		tmp, err := os.CreateTemp("", "silo-config-*.yml")
+		if err != nil {
+			return fmt.Errorf("failed to create temporary file: %w", err)
+		}
+		defer os.Remove(tmp.Name())
+		if _, err := tmp.Write(original); err != nil {
+			tmp.Close()
+			return err
+		}
+		tmp.Close()
+
+		for {
+			if err := runEditor(tmp.Name()); err != nil {
+				log.Error("Editor failed: %v", err)
+				return err
+			}
+
+			data, err := os.ReadFile(tmp.Name())
+			if err != nil {
+				return err
+			}
+			if bytes.Equal(data, original) {
+				log.Info("No changes")
+				return nil
+			}
+
+			doc, err := config.ParseDocument(paths.ConfigFile, data)
+			if err != nil {
+				log.Error("%v", err)
+			} else {
+				err = saveEditedConfig(paths, before, original, doc)
+			}
+			if err == nil {
+				return nil
+			}
+
+			if !promptReopen() {
+				return err
+			}
+		}
+	},
+}
+
+var configDiffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show settings that differ from the defaults or a backup",
+	Long: `Show the effective settings that differ from the defaults, from the
+latest backup written by a schema migration (--backup) or from another config
+file (--against).`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		paths := instancePaths()
+
+		cfg, err := config.LoadOrDefault(paths.ConfigFile, paths)
+		if err != nil {
+			log.Error("Failed to load config: %v", err)
+			return err
+		}
+
+		base := config.NewDefaultConfig(paths)
+		against := configAgainst
+		if configBackup {
+			if against, err = latestBackup(paths.ConfigFile); err != nil {
+				log.Error("%v", err)
+				return err
+			}
+		}
+		if against != "" {
+			data, err := os.ReadFile(against)
+			if err != nil {
+				log.Error("Failed to read %s: %v", against, err)
+				return err
+			}
+			doc, err := config.ParseDocument(against, data)
+			if err != nil {
+				log.Error("Failed to parse %s: %v", against, err)
+				return err
+			}
+			if base, err = doc.Config(paths); err != nil {
+				log.Error("Failed to load %s: %v", against, err)
+				return err
+			}
+			log.Info("Comparing with %s", against)
+		}
+
+		changes, err := config.Diff(base, cfg)
+		if err != nil {
+			return err
+		}
+		if len(changes) == 0 {
+			log.Info("No differences")
+			return nil
+		}
+		for _, c := range changes {
+			fmt.Printf("- %s: %s\n+ %s: %s\n", c.Key, c.Old, c.Key, c.New)
+		}
+		return nil
+	},
+}
+
+var configMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Migrate config.yml to the current schema version",
+	Long: `Apply pending schema migrations to config.yml. The file is backed up
+next to it first, and settings that move to secrets.env are saved there.
+
+Commands that only read the configuration migrate it in memory; up, upgrade
+and the commands that change config.yml persist the migrations.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		paths := instancePaths()
+
+		lock, err := lockInstance(paths, "config migrate")
+		if err != nil {
+			return err
+		}
+		defer lock.Release()
+
+		result, err := migrateConfig(paths)
+		if err != nil {
+			return err
+		}
+		if result == nil {
+			log.Info("No config file at %s", paths.ConfigFile)
+		} else if len(result.Applied) == 0 {
+			log.Success("Config schema version %d is current", result.To)
+		}
+		return nil
+	},
+}
+
+// migrateConfig applies pending schema migrations to config.yml and reports
+// them. Callers hold the instance lock. A missing config.yml has nothing to
+// migrate and gives a nil result.
+func migrateConfig(paths *config.Paths) (*config.MigrationResult, error) {
+	result, err := config.Migrate(paths.ConfigFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		log.Error("Failed to migrate config: %v", err)
+		return nil, err
+	}
+	if len(result.Applied) > 0 {
+		log.Success("Migrated config from schema version %d to %d (backup: %s)", result.From, result.To, result.Backup)
+		for _, m := range result.Applied {
+			log.Info("  - %d: %s", m.Version, m.Description)
+		}
+	}
+	return result, nil
+}
+
+var configViewCmd = &cobra.Command{
+	Use:   "view",
+	Short: "Print config.yml",
+	Long: `Print config.yml as written, or with --effective the complete
+configuration with defaults and environment and flag overrides applied. An
+empty llm_base_url shows the inference backend URL it follows.
+--show-source adds a comment naming where each value came from: default,
+file (config.yml), config.d/<fragment>, env SILO_* or flag --set.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		paths := instancePaths()
+
+		if !configEffective && !configSource {
+			data, err := os.ReadFile(paths.ConfigFile)
+			if err != nil {
+				log.Error("Failed to read config: %v", err)
+				return err
+			}
+			fmt.Print(string(data))
+			return nil
+		}
+
+		cfg, err := config.LoadOrDefault(paths.ConfigFile, paths)
+		if err != nil {
+			log.Error("Failed to load config: %v", err)
+			return err
+		}
+		data, err := config.EffectiveYAML(cfg)
+		if configSource {
+			data, err = config.AnnotatedYAML(cfg)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(data))
+		return nil
+	},
+}
+
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema of config.yml",
+	Long: `Print the JSON Schema of config.yml with the type, allowed values, range
+and description of every setting.
+
+Editors with YAML language server support can use it for completion and
+validation, e.g. by saving it and adding this line at the top of config.yml:
+
+  # yaml-language-server: $schema=./config.schema.json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := config.ConfigSchema().JSON()
+		if err != nil {
+			log.Error("Failed to generate schema: %v", err)
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	},
+}
+
+// saveEditedConfig saves doc under the instance lock, unless config.yml was
+// changed by someone else since it was opened for editing
+func saveEditedConfig(paths *config.Paths, before *config.Document, original []byte, doc *config.Document) error {
+	lock, err := lockInstance(paths, "config edit")
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	current, err := config.OpenDocument(paths.ConfigFile)
+	if err != nil {
+		log.Error("Failed to load config: %v", err)
+		return err
+	}
+	if data, err := current.Bytes(); err != nil || !bytes.Equal(data, original) {
+		err = fmt.Errorf("%s was changed while editing", paths.ConfigFile)
+		log.Error("%v", err)
+		return err
+	}
+
+	return saveConfig(paths, before, doc)
+}
+
+// editConfig applies change to the config file, then validates and saves it
+func editConfig(change func(doc *config.Document) error) error {
+	paths := instancePaths()
+
+	lock, err := lockInstance(paths, "config change")
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	before, err := config.OpenDocument(paths.ConfigFile)
+	if err != nil {
+		log.Error("Failed to load config: %v", err)
+		return err
+	}
+	data, err := before.Bytes()
+	if err != nil {
+		return err
+	}
+	doc, err := config.ParseDocument(paths.ConfigFile, data)
+	if err != nil {
+		return err
+	}
+
+	if err := change(doc); err != nil {
+		log.Error("%v", err)
+		return err
+	}
+	return saveConfig(paths, before, doc)
+}
+
+// saveConfig validates and writes doc, then reports which services need to
+// be recreated for the change to apply
+func saveConfig(paths *config.Paths, before, doc *config.Document) error {
+	if err := doc.Save(paths, before); err != nil {
+		var verr *config.ValidationError
+		if errors.As(err, &verr) {
+			for _, p := range verr.Problems {
+				log.Error("%s", p)
+			}
+			return fmt.Errorf("configuration not saved, %d problem(s)", len(verr.Problems))
+		}
+		log.Error("Failed to save config: %v", err)
+		return err
+	}
+	log.Success("Saved %s", paths.ConfigFile)
+	if problems, err := doc.Validate(paths); err == nil {
+		_, warnings := config.SplitProblems(problems)
+		for _, p := range warnings {
+			log.Warn("%s", p)
+		}
+	}
+
+	oldCfg, err := before.Config(paths)
+	if err != nil {
+		return nil
+	}
+	newCfg, err := doc.Config(paths)
+	if err != nil {
+		return nil
+	}
+	reportAffected(oldCfg, newCfg, paths)
+	return nil
+}
+
+// reportAffected lists the services and inference engine whose definition
+// changed between two configs
+func reportAffected(oldCfg, newCfg *config.Config, paths *config.Paths) {
+	services, err := config.AffectedServices(oldCfg, newCfg)
+	if err != nil {
+		log.Warn("Could not determine affected services: %v", err)
+		return
+	}
+
+	inferenceChanged := changedModels(oldCfg, newCfg)
+
+	if len(services) == 0 && len(inferenceChanged) == 0 {
+		log.Info("No running services are affected")
+		return
+	}
+
+	if len(services) > 0 {
+		if _, err := os.Stat(paths.ComposeFile); err == nil {
+			log.Info("Services to recreate: %s (run 'silo up')", strings.Join(services, ", "))
+		} else {
+			log.Info("Services affected: %s (applied on install with 'silo up')", strings.Join(services, ", "))
+		}
+	}
+	if oldCfg.Inference.Backend != newCfg.Inference.Backend {
+		// The new backend runs in another container, the old one is no
+		// longer managed
+		log.Info("Inference backend changed from %s to %s (stop the old engine with 'docker stop %s', then run 'silo inference up')",
+			oldCfg.Inference.Backend, newCfg.Inference.Backend, strings.Join(engineContainers(inference.Engines(oldCfg, nil)), " "))
+	} else if len(newCfg.Models) == 0 && len(inferenceChanged) > 0 {
+		log.Info("Inference engine changed (run 'silo inference down && silo inference up')")
+	} else if len(inferenceChanged) > 0 {
+		log.Info("Inference models changed: %s (run 'silo inference down <model> && silo inference up <model>')", strings.Join(inferenceChanged, ", "))
+	}
+}
+
+// changedModels returns the models whose inference container differs
+// between two configs, including models added or removed, in config order
+func changedModels(oldCfg, newCfg *config.Config) []string {
+	if len(oldCfg.Models) == 0 && len(newCfg.Models) == 0 {
+		// The single engine is named after default_model, which may change
+		if inference.New(oldCfg, nil).GetDockerRunCommand() != inference.New(newCfg, nil).GetDockerRunCommand() {
+			return []string{newCfg.DefaultModel}
+		}
+		return nil
+	}
+
+	commands := func(cfg *config.Config) map[string]string {
+		m := make(map[string]string)
+		for _, engine := range inference.Engines(cfg, nil) {
+			m[engine.Model()] = engine.GetDockerRunCommand()
+		}
+		return m
+	}
+	oldCommands, newCommands := commands(oldCfg), commands(newCfg)
+
+	var changed []string
+	for _, cfg := range []*config.Config{newCfg, oldCfg} {
+		for _, m := range cfg.InferenceModels() {
+			if oldCommands[m.Name] != newCommands[m.Name] && !slices.Contains(changed, m.Name) {
+				changed = append(changed, m.Name)
+			}
+		}
+	}
+	return changed
+}
+
+// warnOverridden warns when a config.d drop-in or an environment or flag
+// override hides the value of key that was just changed in config.yml
+func warnOverridden(key string) {
+	paths := instancePaths()
+	cfg, err := config.LoadOrDefault(paths.ConfigFile, paths)
+	if err != nil {
+		return
+	}
+	if source := cfg.ValueSource(key); source != string(config.SourceFile) && source != string(config.SourceDefault) {
+		log.Warn("%s is set by %s, which takes precedence over config.yml", key, source)
+	}
+}
+
+// runEditor opens file in the user's editor
+func runEditor(file string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// The editor may carry arguments, e.g. "code --wait"
+	fields := strings.Fields(editor)
+	cmd := exec.Command(fields[0], append(fields[1:], file)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// promptReopen asks whether to reopen the editor after a failed validation
+func promptReopen() bool {
+	fmt.Print("Reopen the editor to fix the problems? [Y/n] ")
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "" || answer == "y" || answer == "yes"
+}
+
+// latestBackup returns the most recent backup of the config file
+func latestBackup(configFile string) (string, error) {
+	backups, err := filepath.Glob(configFile + ".*.bak")
+	if err != nil || len(backups) == 0 {
+		return "", fmt.Errorf("no backups of %s found", configFile)
+	}
+	latest := ""
+	var latestTime time.Time
+	for _, backup := range backups {
+		info, err := os.Stat(backup)
+		if err == nil && !info.ModTime().Before(latestTime) {
+			latest, latestTime = backup, info.ModTime()
+		}
+	}
+	if latest == "" {
+		return "", fmt.Errorf("no backups of %s found", configFile)
+	}
+	return latest, nil
+}
+
+func init() {
+	configViewCmd.Flags().BoolVar(&configEffective, "effective", false, "Print the complete configuration with defaults applied")
+	configViewCmd.Flags().BoolVar(&configSource, "show-source", false, "With --effective, annotate each value with its source")
+	configDiffCmd.Flags().BoolVar(&configBackup, "backup", false, "Compare with the latest config backup")
+	configDiffCmd.Flags().StringVar(&configAgainst, "against", "", "Compare with another config file")
+
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+	configCmd.AddCommand(configEditCmd)
+	configCmd.AddCommand(configDiffCmd)
+	configCmd.AddCommand(configMigrateCmd)
+	configCmd.AddCommand(configViewCmd)
+	configCmd.AddCommand(configSchemaCmd)
+	rootCmd.AddCommand(configCmd)
+}
@@ -24,7 +24,13 @@ To completely remove Silo including data:
   silo down --all && rm -rf ~/.config/silo ~/.local/share/silo && docker volume prune`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := context.Background()
-		paths := config.NewPaths(configDir, "")
+		paths := instancePaths()
+
+		lock, err := lockInstance(paths, "down")
+		if err != nil {
+			return err
+		}
+		defer lock.Release()
 
 		if _, err := os.Stat(paths.ComposeFile); os.IsNotExist(err) {
 			log.Warn("Silo is not installed")
@@ -47,9 +53,10 @@ To completely remove Silo including data:
 				log.Warn("Failed to load config: %v", err)
 			} else {
 				log.Info("Stopping inference engine...")
-				engine := inference.New(cfg, log)
-				if err := engine.Down(ctx); err != nil {
-					log.Warn("Failed to stop inference engine: %v", err)
+				for _, engine := range inference.Engines(cfg, log) {
+					if err := engine.Down(ctx); err != nil {
+						log.Warn("Failed to stop inference engine %s: %v", engine.Model(), err)
+					}
 				}
 
 				// Update state to track that inference was stopped
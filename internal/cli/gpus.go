@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/eternisai/silo/internal/config"
+	"github.com/eternisai/silo/internal/gpu"
+	"github.com/eternisai/silo/internal/inference"
+	"github.com/eternisai/silo/internal/units"
+	"github.com/spf13/cobra"
+)
+
+var gpusJSON bool
+
+var gpusCmd = &cobra.Command{
+	Use:   "gpus",
+	Short: "List the GPUs of this host",
+	Long: `List the NVIDIA GPUs of this host with their memory, as reported by
+nvidia-smi, the driver and CUDA versions, and whether the NVIDIA container
+toolkit lets docker pass GPUs to containers.
+
+The ENGINES column shows the inference containers whose gpu_devices name
+each GPU. 'silo inference up' and 'silo check' verify that these GPUs exist
+and have the memory the engines claim free.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		inv := gpu.Detect(context.Background())
+
+		if gpusJSON {
+			jsonData, err := json.MarshalIndent(inv, "", "  ")
+			if err != nil {
+				log.Error("Failed to marshal JSON: %v", err)
+				return err
+			}
+			fmt.Println(string(jsonData))
+			return nil
+		}
+
+		if !inv.Available() {
+			log.Warn("No NVIDIA GPUs found: %s", inv.Error)
+		} else {
+			log.Info("NVIDIA driver %s, CUDA %s", inv.DriverVersion, valueOr(inv.CUDAVersion, "unknown"))
+
+			assigned := gpuEngines(inv)
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "INDEX\tNAME\tMEMORY\tUSED\tFREE\tUUID\tENGINES")
+			for _, g := range inv.GPUs {
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", g.Index, g.Name,
+					units.FormatBytes(g.MemoryTotal), units.FormatBytes(g.MemoryUsed), units.FormatBytes(g.MemoryFree), g.UUID,
+					valueOr(strings.Join(assigned[g.Index], ", "), "-"))
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+
+		switch {
+		case inv.DockerError != "":
+			log.Warn("Could not list the docker runtimes: %s", inv.DockerError)
+		case inv.ContainerToolkit:
+			log.Success("NVIDIA container toolkit is installed")
+		default:
+			log.Warn("NVIDIA container toolkit is not installed, docker cannot pass GPUs to containers")
+		}
+		return nil
+	},
+}
+
+// gpuEngines returns the containers of the inference engines by the index
+// of the GPUs they run on
+func gpuEngines(inv *gpu.Inventory) map[int][]string {
+	paths := instancePaths()
+	cfg, err := config.LoadOrDefault(paths.ConfigFile, paths)
+	if err != nil {
+		return nil
+	}
+
+	assigned := make(map[int][]string)
+	for _, engine := range inference.Engines(cfg, nil) {
+		for _, device := range engine.GPUDevices() {
+			if g := inv.Lookup(device); g != nil {
+				assigned[g.Index] = append(assigned[g.Index], engine.ContainerName())
+			}
+		}
+	}
+	return assigned
+}
+
+// valueOr returns s, or fallback when s is empty
+func valueOr(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
+
+func init() {
+	rootCmd.AddCommand(gpusCmd)
+	gpusCmd.Flags().BoolVar(&gpusJSON, "json", false, "Output in JSON format")
+}
@@ -2,42 +2,93 @@ package cli
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/eternisai/silo/internal/config"
+	"github.com/eternisai/silo/internal/docker"
 	"github.com/eternisai/silo/internal/inference"
 	"github.com/spf13/cobra"
 )
 
 var (
-	inferenceFollow bool
-	inferenceLines  int
+	inferenceFollow  bool
+	inferenceLines   int
+	inferenceWait    bool
+	inferenceTimeout time.Duration
 )
 
 var inferenceCmd = &cobra.Command{
 	Use:   "inference",
 	Short: "Manage the inference engine",
-	Long: `Manage the SGLang inference engine container.
+	Long: `Manage the inference engine containers.
 
 The inference engine runs separately from the main Silo services and is
 not affected by 'silo up', 'silo down', or 'silo upgrade' commands.
+inference.backend in config.yml selects the engine: sglang, vllm, llamacpp
+(GGUF models on the CPU or GPUs) or ollama.
+
+With a models list in config.yml every model runs in its own container, and
+each command takes a model name to manage a single one.
 
 Use 'silo up --all' or 'silo down --all' to include the inference engine.`,
 }
 
+// selectEngines returns the engine of the model named in args, or defaults
+// when no model is named
+func selectEngines(cfg *config.Config, args []string, defaults []*inference.Engine) ([]*inference.Engine, error) {
+	if len(args) == 0 {
+		return defaults, nil
+	}
+	engine, err := inference.ForModel(cfg, args[0], log)
+	if err != nil {
+		log.Error("%v", err)
+		return nil, err
+	}
+	return []*inference.Engine{engine}, nil
+}
+
+// modelNames completes the model names of the config
+func modelNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	paths := instancePaths()
+	cfg, err := config.LoadOrDefault(paths.ConfigFile, paths)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return engineModels(inference.Engines(cfg, nil)), cobra.ShellCompDirectiveNoFileComp
+}
+
 var inferenceUpCmd = &cobra.Command{
-	Use:   "up",
+	Use:   "up [model]",
 	Short: "Start the inference engine",
-	Long: `Start the SGLang inference engine container.
+	Long: `Start the inference engine container of the configured backend.
 
 This will start the inference engine with the configuration from config.yml.
+With a models list, every enabled model is started unless a model is named.
 The container runs with --restart unless-stopped, so it will automatically
-restart after system reboots.`,
+restart after system reboots.
+
+With --wait the command returns once every engine answers its health check
+and lists the configured model at /v1/models, or fails after --timeout.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: modelNames,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := context.Background()
-		paths := config.NewPaths(configDir, "")
+		paths := instancePaths()
+
+		lock, err := lockInstance(paths, "inference up")
+		if err != nil {
+			return err
+		}
+		defer lock.Release()
 
 		cfg, err := config.LoadOrDefault(paths.ConfigFile, paths)
 		if err != nil {
@@ -45,33 +96,177 @@ restart after system reboots.`,
 			return err
 		}
 
-		engine := inference.New(cfg, log)
+		engines, err := selectEngines(cfg, args, inference.DefaultEngines(cfg, log))
+		if err != nil {
+			return err
+		}
+		if inferenceWait {
+			trackProgress(engines)
+		}
+		if err := startEngines(ctx, cfg, paths, engines); err != nil {
+			return err
+		}
+		if inferenceWait {
+			return waitForEngines(ctx, engines, inferenceTimeout)
+		}
+		return nil
+	},
+}
+
+// waitForEngines waits for every engine to become ready, until timeout or
+// an interrupt
+func waitForEngines(ctx context.Context, engines []*inference.Engine, timeout time.Duration) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	for _, engine := range engines {
+		if err := engine.WaitForHealthy(ctx, timeout); err != nil {
+			log.Error("%v", err)
+			var startup *inference.StartupError
+			if errors.As(err, &startup) {
+				reportDiagnostics(startup.Diagnostics)
+			}
+			log.Info("Check the engine output with 'silo inference logs %s'", engine.Model())
+			return err
+		}
+	}
+	return nil
+}
+
+// trackProgress logs the startup progress of the engines as they start
+func trackProgress(engines []*inference.Engine) {
+	for _, engine := range engines {
+		model := engine.Model()
+		engine.SetProgress(func(p inference.Progress) {
+			if p.Phase != inference.PhaseFailed {
+				log.Info("  %s: %s", model, p)
+			}
+		})
+	}
+}
+
+// reportHealth probes the engine and logs whether it is ready, or how far
+// it got and why it failed according to its logs
+func reportHealth(ctx context.Context, engine *inference.Engine) {
+	err := engine.Ready(ctx)
+	var mismatch *inference.ModelMismatchError
+	switch {
+	case err == nil:
+		log.Success("  Health: OK")
+	case errors.As(err, &mismatch):
+		log.Warn("  Health: Not ready, %v", err)
+	default:
+		progress, perr := engine.Progress(ctx)
+		switch {
+		case perr != nil || progress.Phase == inference.PhaseReady:
+			log.Warn("  Health: Not responding (may still be starting)")
+		case progress.Phase == inference.PhaseFailed:
+			log.Error("  Health: Failed")
+			reportDiagnostics(progress.Diagnostics)
+		default:
+			log.Warn("  Health: Not ready, %s", progress)
+		}
+	}
+}
+
+// reportStopped logs why a stopped engine failed, if its logs tell
+func reportStopped(ctx context.Context, engine *inference.Engine, info *inference.ContainerInfo) {
+	if info.State == "not found" {
+		return
+	}
+	if progress, err := engine.Progress(ctx); err == nil && progress.Phase == inference.PhaseFailed {
+		reportDiagnostics(progress.Diagnostics)
+	}
+}
+
+// reportDiagnostics logs known failures with the hint that fixes them
+func reportDiagnostics(diagnostics []inference.Diagnostic) {
+	for _, d := range diagnostics {
+		log.Error("    %s", d.Message)
+		log.Info("      %s", d.Hint)
+		log.Debug("      %s", d.Line)
+	}
+}
+
+// startEngines starts the inference engines and records in state that
+// inference is running
+func startEngines(ctx context.Context, cfg *config.Config, paths *config.Paths, engines []*inference.Engine) error {
+	if len(engines) == 0 {
+		log.Warn("No enabled models to start, name a model or enable one in config.yml")
+		return nil
+	}
+
+	if err := checkEngineGPUs(ctx, engines); err != nil {
+		return err
+	}
+
+	state, _ := config.LoadState(paths.StateFile)
+	if state == nil {
+		state = &config.State{}
+	}
+	for _, engine := range engines {
 		if err := engine.Up(ctx); err != nil {
-			log.Error("Failed to start inference engine: %v", err)
+			log.Error("Failed to start inference engine %s: %v", engine.Model(), err)
 			return err
 		}
 
 		// Update state to track that inference was running
-		state, _ := config.LoadState(paths.StateFile)
-		if state == nil {
-			state = &config.State{}
-		}
 		state.InferenceWasRunning = true
+		pinInferenceImage(ctx, cfg, engine, state)
 		if err := config.SaveState(paths.StateFile, state); err != nil {
 			log.Warn("Failed to save state: %v", err)
 		}
+	}
+	return nil
+}
 
-		return nil
-	},
+// checkEngineGPUs checks the gpu_devices of the engines that are not
+// running yet against the GPUs of the host, failing before docker run when
+// a GPU does not exist or lacks the memory the engine claims
+func checkEngineGPUs(ctx context.Context, engines []*inference.Engine) error {
+	errorCount := 0
+	for _, p := range inference.PreflightGPUs(ctx, engines) {
+		if p.IsWarning() {
+			log.Warn("%s", p)
+		} else {
+			log.Error("%s", p)
+			errorCount++
+		}
+	}
+	if errorCount > 0 {
+		log.Info("Run 'silo gpus' to list the GPUs of this host")
+		return fmt.Errorf("GPU check found %d problem(s)", errorCount)
+	}
+	return nil
+}
+
+// pinInferenceImage records the digest of the inference image in state if
+// it is not pinned yet, e.g. when docker run pulled it on first start
+func pinInferenceImage(ctx context.Context, cfg *config.Config, engine *inference.Engine, state *config.State) {
+	ref := engine.ImageRef()
+	if state.Images[ref] != "" {
+		return
+	}
+	if digest, err := docker.ResolveDigest(ctx, ref, cfg.Registry.Rewrite(ref)); err == nil {
+		state.PinImage(ref, digest)
+	}
 }
 
 var inferenceDownCmd = &cobra.Command{
-	Use:   "down",
-	Short: "Stop the inference engine",
-	Long:  `Stop and remove the SGLang inference engine container.`,
+	Use:               "down [model]",
+	Short:             "Stop the inference engine",
+	Long:              `Stop and remove the inference engine container, or every model's container unless a model is named.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: modelNames,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := context.Background()
-		paths := config.NewPaths(configDir, "")
+		paths := instancePaths()
+
+		lock, err := lockInstance(paths, "inference down")
+		if err != nil {
+			return err
+		}
+		defer lock.Release()
 
 		cfg, err := config.LoadOrDefault(paths.ConfigFile, paths)
 		if err != nil {
@@ -79,18 +274,23 @@ var inferenceDownCmd = &cobra.Command{
 			return err
 		}
 
-		engine := inference.New(cfg, log)
-		if err := engine.Down(ctx); err != nil {
-			log.Error("Failed to stop inference engine: %v", err)
+		engines, err := selectEngines(cfg, args, inference.Engines(cfg, log))
+		if err != nil {
 			return err
 		}
+		for _, engine := range engines {
+			if err := engine.Down(ctx); err != nil {
+				log.Error("Failed to stop inference engine %s: %v", engine.Model(), err)
+				return err
+			}
+		}
 
-		// Update state to track that inference was stopped
+		// Update state to track whether inference is still running
 		state, _ := config.LoadState(paths.StateFile)
 		if state == nil {
 			state = &config.State{}
 		}
-		state.InferenceWasRunning = false
+		state.InferenceWasRunning = anyRunning(ctx, inference.Engines(cfg, log))
 		if err := config.SaveState(paths.StateFile, state); err != nil {
 			log.Warn("Failed to save state: %v", err)
 		}
@@ -99,13 +299,25 @@ var inferenceDownCmd = &cobra.Command{
 	},
 }
 
+// anyRunning reports whether any of the engines is running
+func anyRunning(ctx context.Context, engines []*inference.Engine) bool {
+	for _, engine := range engines {
+		if running, _ := engine.IsRunning(ctx); running {
+			return true
+		}
+	}
+	return false
+}
+
 var inferenceStatusCmd = &cobra.Command{
-	Use:   "status",
-	Short: "Show inference engine status",
-	Long:  `Display the current status of the inference engine container.`,
+	Use:               "status [model]",
+	Short:             "Show inference engine status",
+	Long:              `Display the current status of the inference engine container of every model, or of the named model.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: modelNames,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := context.Background()
-		paths := config.NewPaths(configDir, "")
+		paths := instancePaths()
 
 		cfg, err := config.LoadOrDefault(paths.ConfigFile, paths)
 		if err != nil {
@@ -113,27 +325,32 @@ var inferenceStatusCmd = &cobra.Command{
 			return err
 		}
 
-		engine := inference.New(cfg, log)
-		info, err := engine.Status(ctx)
+		engines, err := selectEngines(cfg, args, inference.Engines(cfg, log))
 		if err != nil {
-			log.Error("Failed to get status: %v", err)
 			return err
 		}
 
 		log.Info("Inference Engine:")
-		if info.Running {
-			log.Info("  ✓ %s (running)", info.Name)
-			log.Info("    Image:  %s", info.Image)
-			log.Info("    Status: %s", info.Status)
-
-			// Try health check
-			if err := engine.HealthCheck(ctx); err == nil {
-				log.Success("  Health: OK")
+		for _, engine := range engines {
+			info, err := engine.Status(ctx)
+			if err != nil {
+				log.Error("Failed to get status: %v", err)
+				return err
+			}
+
+			if info.Running {
+				log.Info("  ✓ %s (running)", info.Name)
+				if len(cfg.Models) > 0 {
+					log.Info("    Model:  %s", engine.Model())
+				}
+				log.Info("    Image:  %s", info.Image)
+				log.Info("    Status: %s", info.Status)
+
+				reportHealth(ctx, engine)
 			} else {
-				log.Warn("  Health: Not responding (may still be starting)")
+				log.Warn("  ✗ %s (%s)", info.Name, info.State)
+				reportStopped(ctx, engine, info)
 			}
-		} else {
-			log.Warn("  ✗ %s (%s)", info.Name, info.State)
 		}
 
 		return nil
@@ -141,9 +358,11 @@ var inferenceStatusCmd = &cobra.Command{
 }
 
 var inferenceLogsCmd = &cobra.Command{
-	Use:   "logs",
-	Short: "View inference engine logs",
-	Long:  `View logs from the inference engine container.`,
+	Use:               "logs [model]",
+	Short:             "View inference engine logs",
+	Long:              `View logs from the inference engine container. With several models, name the model to view.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: modelNames,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
@@ -156,7 +375,7 @@ var inferenceLogsCmd = &cobra.Command{
 			cancel()
 		}()
 
-		paths := config.NewPaths(configDir, "")
+		paths := instancePaths()
 
 		cfg, err := config.LoadOrDefault(paths.ConfigFile, paths)
 		if err != nil {
@@ -164,8 +383,17 @@ var inferenceLogsCmd = &cobra.Command{
 			return err
 		}
 
-		engine := inference.New(cfg, log)
-		if err := engine.Logs(ctx, inferenceFollow, inferenceLines); err != nil {
+		engines := inference.Engines(cfg, log)
+		if len(args) == 0 && len(engines) > 1 {
+			err := fmt.Errorf("several models are configured, name one of %s", strings.Join(engineModels(engines), ", "))
+			log.Error("%v", err)
+			return err
+		}
+		engines, err = selectEngines(cfg, args, engines)
+		if err != nil {
+			return err
+		}
+		if err := engines[0].Logs(ctx, inferenceFollow, inferenceLines); err != nil {
 			if ctx.Err() == context.Canceled {
 				return nil
 			}
@@ -178,11 +406,13 @@ var inferenceLogsCmd = &cobra.Command{
 }
 
 var inferenceShowConfigCmd = &cobra.Command{
-	Use:   "show-config",
-	Short: "Show the docker run command",
-	Long:  `Display the full docker run command that would be used to start the inference engine.`,
+	Use:               "show-config [model]",
+	Short:             "Show the docker run command",
+	Long:              `Display the exact docker run command, built from the config section of the inference backend, that starts the inference engine of every model or of the named model.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: modelNames,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		paths := config.NewPaths(configDir, "")
+		paths := instancePaths()
 
 		cfg, err := config.LoadOrDefault(paths.ConfigFile, paths)
 		if err != nil {
@@ -190,14 +420,41 @@ var inferenceShowConfigCmd = &cobra.Command{
 			return err
 		}
 
-		engine := inference.New(cfg, log)
-		log.Info("Docker run command:")
-		log.Info(engine.GetDockerRunCommand())
+		engines, err := selectEngines(cfg, args, inference.Engines(cfg, log))
+		if err != nil {
+			return err
+		}
+		for _, engine := range engines {
+			if len(cfg.Models) > 0 {
+				log.Info("Docker run command for %s:", engine.Model())
+			} else {
+				log.Info("Docker run command:")
+			}
+			log.Info(engine.GetDockerRunCommand())
+		}
 
 		return nil
 	},
 }
 
+// engineModels returns the model names of the engines
+func engineModels(engines []*inference.Engine) []string {
+	names := make([]string, len(engines))
+	for i, engine := range engines {
+		names[i] = engine.Model()
+	}
+	return names
+}
+
+// engineContainers returns the container names of the engines
+func engineContainers(engines []*inference.Engine) []string {
+	names := make([]string, len(engines))
+	for i, engine := range engines {
+		names[i] = engine.ContainerName()
+	}
+	return names
+}
+
 func init() {
 	rootCmd.AddCommand(inferenceCmd)
 
@@ -207,6 +464,8 @@ func init() {
 	inferenceCmd.AddCommand(inferenceLogsCmd)
 	inferenceCmd.AddCommand(inferenceShowConfigCmd)
 
+	inferenceUpCmd.Flags().BoolVar(&inferenceWait, "wait", false, "Wait until the engine is ready to serve requests")
+	inferenceUpCmd.Flags().DurationVar(&inferenceTimeout, "timeout", inference.DefaultReadyTimeout, "How long --wait waits for the engine to load its model")
 	inferenceLogsCmd.Flags().BoolVarP(&inferenceFollow, "follow", "f", false, "Follow log output")
 	inferenceLogsCmd.Flags().IntVarP(&inferenceLines, "tail", "n", 100, "Number of lines to show")
 }
@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/eternisai/silo/internal/config"
+	"github.com/eternisai/silo/internal/docker"
+	"github.com/spf13/cobra"
+)
+
+var instancesCmd = &cobra.Command{
+	Use:   "instances",
+	Short: "Manage Silo instances on this host",
+	Long: `Manage the Silo instances installed on this host.
+
+Each instance has its own configuration, data, compose project, daemon socket,
+ports and inference container. Select an instance for any command with
+--instance <name> or the SILO_INSTANCE environment variable.`,
+}
+
+var instancesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List instances",
+	Long:  `List all Silo instances with their status, frontend port and directories.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+
+		names, err := config.ListInstances(configDir)
+		if err != nil {
+			log.Error("Failed to list instances: %v", err)
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSTATUS\tPORT\tCONFIG DIR\tDATA DIR")
+		for _, name := range names {
+			paths := config.NewInstancePaths(name, configDir, config.BaseDataDir())
+
+			status := instanceStatus(ctx, paths)
+			port := "-"
+			if _, err := os.Stat(paths.ConfigFile); err == nil {
+				if cfg, err := config.LoadOrDefault(paths.ConfigFile, paths); err == nil {
+					port = fmt.Sprintf("%d", cfg.Port)
+				}
+			}
+
+			marker := ""
+			if name == instance {
+				marker = " *"
+			}
+			fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\t%s\n", name, marker, status, port, paths.ConfigDir, paths.DataDir)
+		}
+		return w.Flush()
+	},
+}
+
+// instanceStatus describes whether the instance is installed and running.
+func instanceStatus(ctx context.Context, paths *config.Paths) string {
+	if _, err := os.Stat(paths.ComposeFile); os.IsNotExist(err) {
+		return "not installed"
+	}
+
+	running, err := docker.IsRunning(ctx, paths.ComposeFile)
+	if err != nil {
+		return "unknown"
+	}
+	if running {
+		return "running"
+	}
+	return "stopped"
+}
+
+func init() {
+	rootCmd.AddCommand(instancesCmd)
+	instancesCmd.AddCommand(instancesListCmd)
+}
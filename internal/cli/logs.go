@@ -4,7 +4,6 @@ import (
 	"os"
 	"strconv"
 
-	"github.com/eternisai/silo/internal/config"
 	"github.com/eternisai/silo/internal/docker"
 	"github.com/spf13/cobra"
 )
@@ -28,7 +27,7 @@ func init() {
 }
 
 func runLogs(cmd *cobra.Command, args []string) error {
-	paths := config.NewPaths(configDir, "")
+	paths := instancePaths()
 
 	if _, err := os.Stat(paths.ComposeFile); os.IsNotExist(err) {
 		log.Error("Silo is not installed")
@@ -0,0 +1,433 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/eternisai/silo/internal/config"
+	"github.com/eternisai/silo/internal/docker"
+	"github.com/eternisai/silo/internal/inference"
+	"github.com/eternisai/silo/internal/model"
+	"github.com/eternisai/silo/internal/units"
+	"github.com/spf13/cobra"
+)
+
+var (
+	modelPullName        string
+	modelPullInclude     []string
+	modelPullConcurrency int
+	modelPullEndpoint    string
+	modelPullSHA256      string
+	modelRemoveForce     bool
+	modelUseFile         string
+	modelUseNoRestart    bool
+)
+
+var modelCmd = &cobra.Command{
+	Use:   "model",
+	Short: "Manage models in the models directory",
+	Long: `Manage the models in models_dir, one subdirectory per model.
+
+Models are pulled from a Hugging Face compatible endpoint (HF_ENDPOINT,
+authenticated with HF_TOKEN) or from a plain HTTP URL. Every pulled model
+has a manifest with the size and sha256 of its files, which 'silo model
+verify' checks. 'silo model use' points the inference engine at a model.`,
+}
+
+// modelStore returns the store of the models directory of cfg
+func modelStore(cfg *config.Config) *model.Store {
+	return model.NewStore(cfg.ModelsDir)
+}
+
+// modelInUse reports whether the inference engine serves the model in dir
+func modelInUse(cfg *config.Config, dir string) bool {
+	if cfg.Inference.Backend == config.BackendOllama {
+		return false
+	}
+	return filepath.Clean(config.ExpandPath(cfg.InferenceModelPath())) == filepath.Clean(dir)
+}
+
+// storedModels completes the model names of the models directory
+func storedModels(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	paths := instancePaths()
+	cfg, err := config.LoadOrDefault(paths.ConfigFile, paths)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	models, _ := modelStore(cfg).List()
+	var names []string
+	for _, m := range models {
+		names = append(names, m.Name)
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+var modelListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the models in the models directory",
+	Long:  `List the models in models_dir with their size and source. The model the inference engine serves is marked with *.`,
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		paths := instancePaths()
+		cfg, err := config.LoadOrDefault(paths.ConfigFile, paths)
+		if err != nil {
+			log.Error("Failed to load config: %v", err)
+			return err
+		}
+
+		store := modelStore(cfg)
+		models, err := store.List()
+		if err != nil {
+			log.Error("%v", err)
+			return err
+		}
+		if len(models) == 0 {
+			log.Info("No models in %s (pull one with 'silo model pull <org/name>')", store.Dir)
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSIZE\tFILES\tSOURCE\tIN USE")
+		for _, m := range models {
+			source := "-"
+			if m.Manifest != nil {
+				source = m.Manifest.Source
+				if m.Manifest.Revision != "" && m.Manifest.Revision != model.DefaultRevision {
+					source += "@" + m.Manifest.Revision
+				}
+			}
+			inUse := ""
+			if modelInUse(cfg, m.Dir) {
+				inUse = "*"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", m.Name, units.FormatBytes(m.Size), m.Files, source, inUse)
+		}
+		return w.Flush()
+	},
+}
+
+var modelPullCmd = &cobra.Command{
+	Use:   "pull <source>",
+	Short: "Download a model into the models directory",
+	Long: `Download a model into models_dir.
+
+The source is a repository of a Hugging Face compatible endpoint, as
+org/name or hf:org/name with an optional @revision, or an http(s) URL of a
+single file. Use --include to download only some files of a repository,
+e.g. one GGUF quantization.
+
+Files already downloaded are kept and interrupted downloads resume where
+they stopped. Checksums published by the endpoint, or given with --sha256
+for a URL, are verified, and the free disk space is checked first.`,
+	Example: `  silo model pull Qwen/Qwen3-8B
+  silo model pull unsloth/Qwen3-8B-GGUF --include '*Q4_K_M.gguf'
+  silo model pull https://example.com/model.gguf --name my-model --sha256 <sum>`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+		paths := instancePaths()
+
+		src, err := model.ParseSource(args[0])
+		if err != nil {
+			log.Error("%v", err)
+			return err
+		}
+		if modelPullSHA256 != "" && src.URL == "" {
+			err := fmt.Errorf("--sha256 applies to URL sources, repositories publish their checksums")
+			log.Error("%v", err)
+			return err
+		}
+		name := modelPullName
+		if name == "" {
+			name = src.DefaultName()
+		}
+		if err := config.ValidateModelName(name); err != nil {
+			err = fmt.Errorf("invalid model name %q: %w (choose one with --name)", name, err)
+			log.Error("%v", err)
+			return err
+		}
+
+		lock, err := lockInstance(paths, "model pull")
+		if err != nil {
+			return err
+		}
+		defer lock.Release()
+
+		cfg, err := config.LoadOrDefault(paths.ConfigFile, paths)
+		if err != nil {
+			log.Error("Failed to load config: %v", err)
+			return err
+		}
+
+		puller := model.NewPuller(log)
+		if modelPullEndpoint != "" {
+			puller.Endpoint = modelPullEndpoint
+		}
+		puller.Concurrency = modelPullConcurrency
+		puller.Include = modelPullInclude
+		puller.SHA256 = strings.ToLower(modelPullSHA256)
+
+		store := modelStore(cfg)
+		log.Info("Pulling %s into %s...", src, store.Path(name))
+		manifest, err := puller.Pull(ctx, store, src, name)
+		if err != nil {
+			log.Error("Failed to pull %s: %v", src, err)
+			return err
+		}
+
+		log.Success("Pulled %s (%d files)", name, len(manifest.Files))
+		log.Info("Serve it with 'silo model use %s'", name)
+		return nil
+	},
+}
+
+var modelRemoveCmd = &cobra.Command{
+	Use:               "rm <name>",
+	Aliases:           []string{"remove"},
+	Short:             "Remove a model from the models directory",
+	Long:              `Delete a model and its files from models_dir. The model the inference engine serves is only removed with --force.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: storedModels,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		paths := instancePaths()
+
+		lock, err := lockInstance(paths, "model rm")
+		if err != nil {
+			return err
+		}
+		defer lock.Release()
+
+		cfg, err := config.LoadOrDefault(paths.ConfigFile, paths)
+		if err != nil {
+			log.Error("Failed to load config: %v", err)
+			return err
+		}
+
+		store := modelStore(cfg)
+		m, err := store.Get(args[0])
+		if err != nil {
+			log.Error("%v", err)
+			return err
+		}
+		if modelInUse(cfg, m.Dir) && !modelRemoveForce {
+			err := fmt.Errorf("model %s is served by the inference engine, select another with 'silo model use' or pass --force", m.Name)
+			log.Error("%v", err)
+			return err
+		}
+
+		if err := store.Remove(m.Name); err != nil {
+			log.Error("%v", err)
+			return err
+		}
+		log.Success("Removed %s (%s)", m.Name, units.FormatBytes(m.Size))
+		return nil
+	},
+}
+
+var modelVerifyCmd = &cobra.Command{
+	Use:               "verify <name>",
+	Short:             "Check a model's files against its manifest",
+	Long:              `Check that every file of a pulled model exists with the size and sha256 recorded when it was pulled.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: storedModels,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		paths := instancePaths()
+		cfg, err := config.LoadOrDefault(paths.ConfigFile, paths)
+		if err != nil {
+			log.Error("Failed to load config: %v", err)
+			return err
+		}
+
+		log.Info("Verifying %s...", args[0])
+		problems, err := modelStore(cfg).Verify(args[0])
+		if err != nil {
+			log.Error("%v", err)
+			return err
+		}
+		if len(problems) > 0 {
+			for _, p := range problems {
+				log.Error("%s", p)
+			}
+			return fmt.Errorf("model %s failed verification, %d problem(s) (pull it again to repair)", args[0], len(problems))
+		}
+		log.Success("Model %s verified", args[0])
+		return nil
+	},
+}
+
+var modelUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Serve a model with the inference engine",
+	Long: `Point the inference engine at a model of models_dir.
+
+This sets the model path of the configured backend and default_model, and
+for llama.cpp the GGUF file, chosen with --file when the model has several.
+Running Silo services and inference engines are then restarted to pick up
+the model, unless --no-restart is given.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: storedModels,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		paths := instancePaths()
+
+		cfg, err := config.LoadOrDefault(paths.ConfigFile, paths)
+		if err != nil {
+			log.Error("Failed to load config: %v", err)
+			return err
+		}
+
+		values, err := modelUseValues(cfg, args[0])
+		if err != nil {
+			log.Error("%v", err)
+			return err
+		}
+
+		// Engines serving the old model are restarted with the new one
+		var running []string
+		for _, engine := range inference.DefaultEngines(cfg, log) {
+			if ok, _ := engine.IsRunning(ctx); ok {
+				running = append(running, engine.Model())
+			}
+		}
+
+		err = editConfig(func(doc *config.Document) error {
+			for _, kv := range values {
+				if err := doc.Set(kv[0], kv[1]); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if modelUseNoRestart {
+			return nil
+		}
+		return restartForModel(ctx, paths, len(running) > 0)
+	},
+}
+
+// modelUseValues returns the config keys and values that make the
+// configured backend serve the named model
+func modelUseValues(cfg *config.Config, name string) ([][2]string, error) {
+	if len(cfg.Models) > 0 {
+		return nil, fmt.Errorf("config.yml has a models list, set models[].model_path instead")
+	}
+	store := modelStore(cfg)
+	m, err := store.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var values [][2]string
+	switch cfg.Inference.Backend {
+	case config.BackendOllama:
+		return nil, fmt.Errorf("the ollama backend pulls its own models, set ollama.model instead")
+	case config.BackendVLLM:
+		values = append(values, [2]string{"vllm.model_path", m.Dir})
+	case config.BackendLlamaCpp:
+		file, err := ggufFile(store, m.Name)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, [2]string{"llamacpp.model_path", m.Dir}, [2]string{"llamacpp.model_file", file})
+	default:
+		values = append(values, [2]string{"sglang.model_path", m.Dir})
+	}
+	return append(values, [2]string{"default_model", m.Name}), nil
+}
+
+// ggufFile returns the GGUF file of the model llama.cpp serves: the one
+// named with --file, or the only one
+func ggufFile(store *model.Store, name string) (string, error) {
+	files, err := store.GGUFFiles(name)
+	if err != nil {
+		return "", err
+	}
+	if modelUseFile != "" {
+		for _, f := range files {
+			if f == modelUseFile {
+				return f, nil
+			}
+		}
+		return "", fmt.Errorf("model %s has no GGUF file %s", name, modelUseFile)
+	}
+	switch len(files) {
+	case 0:
+		return "", fmt.Errorf("model %s has no .gguf file for llama.cpp", name)
+	case 1:
+		return files[0], nil
+	default:
+		return "", fmt.Errorf("model %s has several GGUF files, choose one with --file: %s", name, strings.Join(files, ", "))
+	}
+}
+
+// restartForModel recreates the running services, which are configured
+// with default_model, and restarts the inference engine if it was running
+func restartForModel(ctx context.Context, paths *config.Paths, engineRunning bool) error {
+	lock, err := lockInstance(paths, "model use")
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	cfg, err := config.LoadOrDefault(paths.ConfigFile, paths)
+	if err != nil {
+		log.Error("Failed to load config: %v", err)
+		return err
+	}
+
+	if _, err := os.Stat(paths.ComposeFile); err == nil {
+		log.Info("Regenerating docker-compose.yml from current configuration...")
+		if err := config.GenerateDockerCompose(cfg, paths.ComposeFile); err != nil {
+			log.Error("Failed to generate docker-compose: %v", err)
+			return err
+		}
+		if err := docker.Up(ctx, paths.ComposeFile); err != nil {
+			log.Error("Failed to recreate services: %v", err)
+			return err
+		}
+	}
+
+	if !engineRunning {
+		log.Info("Start the inference engine with 'silo inference up'")
+		return nil
+	}
+	engines := inference.DefaultEngines(cfg, log)
+	for _, engine := range engines {
+		if err := engine.Down(ctx); err != nil {
+			log.Error("Failed to stop inference engine %s: %v", engine.Model(), err)
+			return err
+		}
+	}
+	return startEngines(ctx, cfg, paths, engines)
+}
+
+func init() {
+	modelPullCmd.Flags().StringVar(&modelPullName, "name", "", "Model name in the models directory (default: the repository or file name)")
+	modelPullCmd.Flags().StringArrayVar(&modelPullInclude, "include", nil, "Only download repository files matching the pattern, e.g. '*Q4_K_M.gguf' (repeatable)")
+	modelPullCmd.Flags().IntVar(&modelPullConcurrency, "concurrency", model.DefaultConcurrency, "Number of files downloaded at once")
+	modelPullCmd.Flags().StringVar(&modelPullEndpoint, "endpoint", "", "Hugging Face compatible endpoint (default: $HF_ENDPOINT or "+model.DefaultEndpoint+")")
+	modelPullCmd.Flags().StringVar(&modelPullSHA256, "sha256", "", "Expected sha256 of a file pulled from a URL")
+	modelRemoveCmd.Flags().BoolVarP(&modelRemoveForce, "force", "f", false, "Remove the model even if the inference engine serves it")
+	modelUseCmd.Flags().StringVar(&modelUseFile, "file", "", "GGUF file llama.cpp serves, when the model has several")
+	modelUseCmd.Flags().BoolVar(&modelUseNoRestart, "no-restart", false, "Only update config.yml")
+
+	modelCmd.AddCommand(modelListCmd)
+	modelCmd.AddCommand(modelPullCmd)
+	modelCmd.AddCommand(modelRemoveCmd)
+	modelCmd.AddCommand(modelVerifyCmd)
+	modelCmd.AddCommand(modelUseCmd)
+	rootCmd.AddCommand(modelCmd)
+}
@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/eternisai/silo/internal/docker"
+	"github.com/eternisai/silo/internal/units"
+	"github.com/mattn/go-isatty"
+)
+
+const (
+	progressBarWidth    = 30
+	progressRedrawDelay = 100 * time.Millisecond
+)
+
+// pullProgressBar renders image pull progress. On a terminal it redraws a
+// progress bar per image; otherwise it prints a line every 10 percent.
+type pullProgressBar struct {
+	out      io.Writer
+	tty      bool
+	lastDraw time.Time
+	lastStep map[string]int
+}
+
+// newPullProgressBar creates a progress renderer writing to stdout
+func newPullProgressBar() *pullProgressBar {
+	return &pullProgressBar{
+		out:      os.Stdout,
+		tty:      isatty.IsTerminal(os.Stdout.Fd()),
+		lastStep: make(map[string]int),
+	}
+}
+
+// Handle renders a pull progress event
+func (b *pullProgressBar) Handle(e docker.PullEvent) {
+	if b.tty {
+		b.draw(e)
+		return
+	}
+
+	percent := e.Percent()
+	if percent < 0 {
+		return
+	}
+	step := percent / 10
+	if last, ok := b.lastStep[e.Service]; ok && step <= last && !e.Done {
+		return
+	}
+	b.lastStep[e.Service] = step
+	fmt.Fprintf(b.out, "  %s: %3d%% %s\n", e.Service, percent, progressDetail(e))
+}
+
+// draw redraws the progress bar line of the event's image
+func (b *pullProgressBar) draw(e docker.PullEvent) {
+	if !e.Done && time.Since(b.lastDraw) < progressRedrawDelay {
+		return
+	}
+	b.lastDraw = time.Now()
+
+	percent := e.Percent()
+	filled := 0
+	if percent > 0 {
+		filled = percent * progressBarWidth / 100
+	}
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+
+	label := "  ?%"
+	if percent >= 0 {
+		label = fmt.Sprintf("%3d%%", percent)
+	}
+
+	fmt.Fprintf(b.out, "\r\033[K  %-16s [%s] %s %s", e.Service, bar, label, progressDetail(e))
+	if e.Done {
+		fmt.Fprintln(b.out)
+	}
+}
+
+// progressDetail describes downloaded bytes, layers and remaining time
+func progressDetail(e docker.PullEvent) string {
+	detail := fmt.Sprintf("%s / %s (%d/%d layers)",
+		units.FormatBytes(e.ImageCurrent), units.FormatBytes(e.ImageTotal), e.LayersDone, e.Layers)
+	if e.ETASeconds > 0 {
+		detail += fmt.Sprintf(" ETA %s", time.Duration(e.ETASeconds)*time.Second)
+	}
+	return detail
+}
+
+// jsonPullProgress writes each pull progress event as a JSON line to w
+func jsonPullProgress(w io.Writer) docker.PullProgressFunc {
+	enc := json.NewEncoder(w)
+	return func(e docker.PullEvent) {
+		_ = enc.Encode(e)
+	}
+}
@@ -11,9 +11,11 @@ import (
 var (
 	verbose          bool
 	configDir        string
+	instance         string
 	imageTag         string
 	port             int
 	enableProxyAgent bool
+	setValues        []string
 	log              *logger.Logger
 )
 
@@ -23,8 +25,14 @@ var rootCmd = &cobra.Command{
 	Long: `Silo is a CLI tool to deploy, manage, and upgrade the Silo application
 with PostgreSQL and pgvector.`,
 	Version: version,
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 		log = logger.New(verbose)
+		overrides, err := config.ParseSetFlags(setValues)
+		if err != nil {
+			return err
+		}
+		config.SetFlagOverrides(overrides)
+		return config.ValidateInstanceName(instance)
 	},
 }
 
@@ -36,7 +44,35 @@ func Execute() {
 
 func init() {
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "enable debug logging")
-	rootCmd.PersistentFlags().StringVar(&configDir, "config-dir", config.DefaultConfigDir(), "configuration directory")
+	rootCmd.PersistentFlags().StringVar(&configDir, "config-dir", config.BaseConfigDir(), "configuration directory (env: SILO_CONFIG_DIR)")
+	rootCmd.PersistentFlags().StringVar(&instance, "instance", defaultInstance(), "instance to manage (env: SILO_INSTANCE)")
+	rootCmd.PersistentFlags().StringArrayVar(&setValues, "set", nil, "override a config key for this command, e.g. --set sglang.tp_size=2 (repeatable)")
 
 	rootCmd.SetVersionTemplate("silo version {{.Version}}\n")
 }
+
+// defaultInstance returns the instance selected by SILO_INSTANCE, or the
+// default instance when it is unset.
+func defaultInstance() string {
+	if name := os.Getenv("SILO_INSTANCE"); name != "" {
+		return name
+	}
+	return config.DefaultInstance
+}
+
+// instancePaths returns the paths of the instance selected with --instance,
+// under the data directory silod uses as well
+func instancePaths() *config.Paths {
+	return config.NewInstancePaths(instance, configDir, config.BaseDataDir())
+}
+
+// lockInstance takes the instance lock for a mutating operation, so the CLI
+// and silod never change config, state or containers at the same time
+func lockInstance(paths *config.Paths, operation string) (*config.Lock, error) {
+	lock, err := config.AcquireLock(paths, operation)
+	if err != nil {
+		log.Error("%v", err)
+		return nil, err
+	}
+	return lock, nil
+}
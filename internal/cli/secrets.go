@@ -0,0 +1,246 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/eternisai/silo/internal/config"
+	"github.com/eternisai/silo/internal/docker"
+	"github.com/spf13/cobra"
+)
+
+var secretsCmd = &cobra.Command{
+	Use:   "secrets",
+	Short: "Manage generated credentials and API keys",
+	Long: `Manage the credentials and API keys of the Silo services.
+
+Secrets are kept in secrets.env next to config.yml, readable by the owner
+only. Generated secrets (the PostgreSQL password and the deep research API
+key) are created at install; the others are provided with 'silo secrets set'.
+Services receive them through env files in the data directory, never through
+docker-compose.yml.`,
+}
+
+var secretsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List secrets without their values",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		paths := instancePaths()
+
+		secrets, err := config.LoadSecrets(paths.SecretsFile)
+		if err != nil {
+			log.Error("%v", err)
+			return err
+		}
+
+		log.Info("Secrets (%s):", paths.SecretsFile)
+		for _, spec := range config.SecretSpecs {
+			status := "set"
+			switch value := secrets[spec.Name]; {
+			case value == "":
+				status = "not set"
+			case spec.Name == config.SecretPostgresPassword && value == config.LegacyPostgresPassword:
+				status = "legacy default, rotate it"
+			}
+			log.Info("  %-22s %-26s %s (%s)", spec.Name, status, spec.Description, strings.Join(spec.Services, ", "))
+		}
+		return nil
+	},
+}
+
+var secretsSetCmd = &cobra.Command{
+	Use:   "set <name> [value]",
+	Short: "Set a secret",
+	Long: `Set a secret and recreate the services that use it. Without a value
+argument the value is read from stdin, which keeps it out of the shell
+history:
+
+  silo secrets set perplexity_api_key < perplexity.key`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		spec, err := config.LookupSecret(args[0])
+		if err != nil {
+			log.Error("%v", err)
+			return err
+		}
+
+		var value string
+		if len(args) == 2 {
+			value = args[1]
+		} else {
+			line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+			if err != nil && line == "" {
+				return fmt.Errorf("failed to read secret from stdin: %w", err)
+			}
+			value = strings.TrimSpace(line)
+		}
+		if err := config.ValidateSecretValue(value); err != nil {
+			log.Error("%s: %v", spec.Name, err)
+			return err
+		}
+
+		return updateSecret(spec, value, "secrets set")
+	},
+}
+
+var secretsRotateCmd = &cobra.Command{
+	Use:   "rotate <name>",
+	Short: "Replace a generated secret with a new random value",
+	Long: `Replace a generated secret with a new random value and recreate the
+services that use it.
+
+Rotating postgres_password changes the password in the running database
+first, so PostgreSQL must be running. If that fails, the old password is
+kept.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		spec, err := config.LookupSecret(args[0])
+		if err != nil {
+			log.Error("%v", err)
+			return err
+		}
+		if !spec.Generated {
+			err := fmt.Errorf("%s is not generated by silo, use 'silo secrets set %s'", spec.Name, spec.Name)
+			log.Error("%v", err)
+			return err
+		}
+
+		value, err := config.GenerateSecret()
+		if err != nil {
+			return err
+		}
+		return updateSecret(spec, value, "secrets rotate")
+	},
+}
+
+// updateSecret stores a new secret value, applies it to the database when it
+// is the postgres password, and recreates the running services that use it.
+// The old value is restored when the database rejects the new one.
+func updateSecret(spec config.SecretSpec, value, operation string) error {
+	ctx := context.Background()
+	paths := instancePaths()
+
+	lock, err := lockInstance(paths, operation)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	cfg, err := config.LoadOrDefault(paths.ConfigFile, paths)
+	if err != nil {
+		log.Error("Failed to load config: %v", err)
+		return err
+	}
+
+	secrets, err := config.EnsureSecrets(cfg)
+	if err != nil {
+		log.Error("%v", err)
+		return err
+	}
+	old := secrets[spec.Name]
+
+	running := make(map[string]bool)
+	if _, err := os.Stat(paths.ComposeFile); err == nil {
+		containers, err := docker.Ps(ctx, paths.ComposeFile)
+		if err != nil {
+			log.Error("Failed to list containers: %v", err)
+			return err
+		}
+		for _, c := range containers {
+			running[c.Service] = c.State == "running"
+		}
+	}
+
+	alterDatabase := spec.Name == config.SecretPostgresPassword && config.DatabaseExists(cfg)
+	if alterDatabase && !running["postgres"] {
+		err := fmt.Errorf("postgres is not running, start Silo with 'silo up' before changing its password")
+		log.Error("%v", err)
+		return err
+	}
+
+	secrets[spec.Name] = value
+	if err := saveSecrets(cfg, secrets); err != nil {
+		return err
+	}
+
+	if alterDatabase {
+		log.Info("Changing the PostgreSQL password...")
+		sql := strings.NewReader(config.PostgresPasswordSQL(value))
+		if err := docker.ExecInput(ctx, paths.ComposeFile, "postgres", config.PostgresPSQLCommand(), sql); err != nil {
+			log.Error("Failed to change the PostgreSQL password, keeping the old one: %v", err)
+			secrets[spec.Name] = old
+			if restoreErr := saveSecrets(cfg, secrets); restoreErr != nil {
+				log.Error("Failed to restore %s: %v", paths.SecretsFile, restoreErr)
+			}
+			return err
+		}
+	}
+	log.Success("Updated %s", spec.Name)
+
+	var affected []string
+	for _, service := range spec.Services {
+		if running[service] {
+			affected = append(affected, service)
+		}
+	}
+	if len(affected) == 0 {
+		log.Info("Takes effect the next time Silo starts")
+		return nil
+	}
+
+	log.Info("Recreating %s...", strings.Join(affected, ", "))
+	if err := docker.Up(ctx, paths.ComposeFile); err != nil {
+		log.Error("Failed to recreate services: %v", err)
+		return err
+	}
+	log.Success("Services recreated")
+	return nil
+}
+
+// saveSecrets writes the secrets file and the env files rendered from it
+func saveSecrets(cfg *config.Config, secrets config.Secrets) error {
+	if err := config.SaveSecrets(cfg.SecretsFile, secrets); err != nil {
+		log.Error("%v", err)
+		return err
+	}
+	if err := config.WriteEnvFiles(cfg, secrets); err != nil {
+		log.Error("%v", err)
+		return err
+	}
+	return nil
+}
+
+// checkSecrets reports a secrets file others can read and the legacy
+// postgres password of installs from before secrets were generated
+func checkSecrets(paths *config.Paths) {
+	info, err := os.Stat(paths.SecretsFile)
+	if err != nil {
+		log.Warn("Secrets file not found: %s (run 'silo upgrade' to generate it)", paths.SecretsFile)
+		return
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		log.Warn("Secrets file %s is readable by others (mode %04o), run 'chmod 600 %s'", paths.SecretsFile, info.Mode().Perm(), paths.SecretsFile)
+	} else {
+		log.Success("Secrets file exists: %s", paths.SecretsFile)
+	}
+
+	secrets, err := config.LoadSecrets(paths.SecretsFile)
+	if err != nil {
+		log.Warn("%v", err)
+		return
+	}
+	if secrets[config.SecretPostgresPassword] == config.LegacyPostgresPassword {
+		log.Warn("PostgreSQL uses the legacy default password, run 'silo secrets rotate %s'", config.SecretPostgresPassword)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(secretsCmd)
+
+	secretsCmd.AddCommand(secretsListCmd)
+	secretsCmd.AddCommand(secretsSetCmd)
+	secretsCmd.AddCommand(secretsRotateCmd)
+}
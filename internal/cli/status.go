@@ -17,7 +17,7 @@ var statusCmd = &cobra.Command{
 	Long:  "Display the status of Silo containers, versions, and health information.",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := context.Background()
-		paths := config.NewPaths(configDir, "")
+		paths := instancePaths()
 
 		if _, err := os.Stat(paths.ComposeFile); os.IsNotExist(err) {
 			log.Warn("Silo is not installed")
@@ -75,26 +75,27 @@ var statusCmd = &cobra.Command{
 		// Show inference engine status
 		fmt.Println()
 		if cfg != nil {
-			engine := inference.New(cfg, log)
-			info, err := engine.Status(ctx)
-			if err != nil {
-				log.Warn("Failed to get inference engine status: %v", err)
-			} else {
-				log.Info("Inference Engine:")
+			log.Info("Inference Engine:")
+			for _, engine := range inference.Engines(cfg, log) {
+				info, err := engine.Status(ctx)
+				if err != nil {
+					log.Warn("Failed to get inference engine status: %v", err)
+					continue
+				}
 				if info.Running {
 					log.Info("  ✓ %s (running)", info.Name)
 					log.Info("    Image:  %s", info.Image)
 					log.Info("    Status: %s", info.Status)
 
-					// Try health check
-					if err := engine.HealthCheck(ctx); err == nil {
-						log.Success("  Health: OK")
-					} else {
-						log.Warn("  Health: Not responding (may still be starting)")
-					}
+					reportHealth(ctx, engine)
 				} else {
 					log.Info("  ✗ %s (%s)", info.Name, info.State)
-					log.Info("    Use 'silo inference up' to start")
+					reportStopped(ctx, engine, info)
+					if len(cfg.Models) > 0 {
+						log.Info("    Use 'silo inference up %s' to start", engine.Model())
+					} else {
+						log.Info("    Use 'silo inference up' to start")
+					}
 				}
 			}
 		}
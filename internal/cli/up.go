@@ -2,6 +2,7 @@ package cli
 
 import (
 	"context"
+	"fmt"
 	"os"
 
 	"github.com/eternisai/silo/internal/config"
@@ -11,7 +12,10 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var upAll bool
+var (
+	upAll  bool
+	upDiff bool
+)
 
 var upCmd = &cobra.Command{
 	Use:   "up",
@@ -20,32 +24,70 @@ var upCmd = &cobra.Command{
 
 This command will:
   - First run: perform full installation
-  - Subsequent runs: start existing containers
+  - Subsequent runs: regenerate docker-compose.yml and start containers
+
+Use --diff to print the changes to docker-compose.yml before they are
+applied. The last versions are kept in the compose-history directory next
+to it.
 
 By default, the inference engine is NOT started. Use --all to include it.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := context.Background()
-		paths := config.NewPaths(configDir, "")
+		paths := instancePaths()
+
+		lock, err := lockInstance(paths, "up")
+		if err != nil {
+			return err
+		}
+		defer lock.Release()
+
+		if _, err := migrateConfig(paths); err != nil {
+			return err
+		}
 
 		if _, err := os.Stat(paths.ComposeFile); os.IsNotExist(err) {
 			log.Info("First run detected, installing Silo...")
 
-			cfg := config.NewDefaultConfig(paths)
+			// A config.yml or config.d written before the first run, the
+			// environment and --set apply as on every later run
+			cfg, err := config.LoadOrDefault(paths.ConfigFile, paths)
+			if err != nil {
+				log.Error("Failed to load config: %v", err)
+				return err
+			}
 
-			if imageTag != "" {
+			if cmd.Flags().Changed("image-tag") {
 				cfg.ImageTag = imageTag
 			}
-			if port > 0 {
+			if cmd.Flags().Changed("port") {
 				cfg.Port = port
 			}
-			cfg.EnableProxyAgent = enableProxyAgent
+			if cmd.Flags().Changed("enable-proxy-agent") {
+				cfg.EnableProxyAgent = enableProxyAgent
+			}
+
+			// Keep host ports clear of the other instances on this host
+			reserved, err := config.ReservedPorts(paths)
+			if err != nil {
+				log.Warn("Failed to read ports of other instances: %v", err)
+			} else {
+				config.AllocatePorts(cfg, reserved)
+			}
 
 			if err := config.Validate(cfg); err != nil {
 				log.Error("Invalid configuration: %v", err)
 				return err
 			}
+			warnConfig(cfg)
+
+			if upDiff {
+				if err := showComposeDiff(cfg, paths); err != nil {
+					return err
+				}
+			}
 
 			inst := installer.New(cfg, paths, log)
+			inst.SetPullProgress(newPullProgressBar().Handle)
 			if err := inst.Install(ctx); err != nil {
 				log.Error("Installation failed: %v", err)
 				return err
@@ -74,11 +116,34 @@ By default, the inference engine is NOT started. Use --all to include it.`,
 			return err
 		}
 
+		if err := config.Validate(cfg); err != nil {
+			log.Error("Invalid configuration: %v", err)
+			return err
+		}
+		warnConfig(cfg)
+
 		// Save merged config back to ensure any new fields are persisted
 		if err := config.Save(paths.ConfigFile, cfg); err != nil {
 			log.Warn("Failed to save merged config: %v", err)
 		}
 
+		if upDiff {
+			if err := showComposeDiff(cfg, paths); err != nil {
+				return err
+			}
+		}
+
+		// Ports the running containers already hold cannot be probed
+		newPorts, err := config.NewlyPublishedPorts(cfg, paths.ComposeFile)
+		if err != nil {
+			log.Error("Failed to read published ports: %v", err)
+			return err
+		}
+		if err := installer.CheckPublishedPorts(newPorts, log); err != nil {
+			log.Error("Preflight check failed: %v", err)
+			return err
+		}
+
 		log.Info("Regenerating docker-compose.yml from current configuration...")
 		if err := config.GenerateDockerCompose(cfg, paths.ComposeFile); err != nil {
 			log.Error("Failed to generate docker-compose: %v", err)
@@ -103,27 +168,40 @@ By default, the inference engine is NOT started. Use --all to include it.`,
 	},
 }
 
-func startInferenceEngine(ctx context.Context, cfg *config.Config, paths *config.Paths) error {
-	log.Info("Starting inference engine...")
-	engine := inference.New(cfg, log)
-	if err := engine.Up(ctx); err != nil {
-		log.Error("Failed to start inference engine: %v", err)
-		return err
+// warnConfig logs the warnings of the config; callers reject its errors
+// with config.Validate first
+func warnConfig(cfg *config.Config) {
+	problems, err := config.ValidateConfig(cfg)
+	if err != nil {
+		return
+	}
+	_, warnings := config.SplitProblems(problems)
+	for _, p := range warnings {
+		log.Warn("Config: %s", p)
 	}
+}
 
-	// Update state to track that inference was running
-	state, _ := config.LoadState(paths.StateFile)
-	if state == nil {
-		state = &config.State{}
+// showComposeDiff prints the changes the next generation makes to
+// docker-compose.yml
+func showComposeDiff(cfg *config.Config, paths *config.Paths) error {
+	diff, err := config.ComposeDiff(cfg, paths.ComposeFile)
+	if err != nil {
+		log.Error("Failed to render docker-compose: %v", err)
+		return err
 	}
-	state.InferenceWasRunning = true
-	if err := config.SaveState(paths.StateFile, state); err != nil {
-		log.Warn("Failed to save state: %v", err)
+	if diff == "" {
+		log.Info("docker-compose.yml is unchanged")
+		return nil
 	}
-
+	fmt.Print(diff)
 	return nil
 }
 
+func startInferenceEngine(ctx context.Context, cfg *config.Config, paths *config.Paths) error {
+	log.Info("Starting inference engine...")
+	return startEngines(ctx, cfg, paths, inference.DefaultEngines(cfg, log))
+}
+
 func init() {
 	rootCmd.AddCommand(upCmd)
 
@@ -131,4 +209,5 @@ func init() {
 	upCmd.Flags().IntVar(&port, "port", config.DefaultPort, "Application port (first install only)")
 	upCmd.Flags().BoolVar(&enableProxyAgent, "enable-proxy-agent", config.DefaultEnableProxyAgent, "Enable proxy agent (first install only)")
 	upCmd.Flags().BoolVar(&upAll, "all", false, "Include inference engine")
+	upCmd.Flags().BoolVar(&upDiff, "diff", false, "Show the changes to docker-compose.yml before applying them")
 }
@@ -4,9 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/eternisai/silo/internal/config"
+	"github.com/eternisai/silo/internal/docker"
 	"github.com/eternisai/silo/internal/updater"
 	versionpkg "github.com/eternisai/silo/internal/version"
 	"github.com/eternisai/silo/pkg/logger"
@@ -16,10 +18,19 @@ import (
 type UpgradeOutput struct {
 	PreCheck PreCheckInfo `json:"pre_check"`
 	Upgrade  UpgradeInfo  `json:"upgrade"`
+	Pulls    []PullInfo   `json:"pulls,omitempty"`
 	Error    *ErrorInfo   `json:"error,omitempty"`
 	Success  bool         `json:"success"`
 }
 
+type PullInfo struct {
+	Service string `json:"service"`
+	Bytes   int64  `json:"bytes"`
+	Layers  int    `json:"layers"`
+	Done    bool   `json:"done"`
+	Error   string `json:"error,omitempty"`
+}
+
 type PreCheckInfo struct {
 	CLI    *CLICheckInfo    `json:"cli,omitempty"`
 	Images []ImageCheckInfo `json:"images,omitempty"`
@@ -63,7 +74,17 @@ This command will:
   - Preserve all data`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := context.Background()
-		paths := config.NewPaths(configDir, "")
+		paths := instancePaths()
+
+		lock, err := lockInstance(paths, "upgrade")
+		if err != nil {
+			return err
+		}
+		defer lock.Release()
+
+		if _, err := migrateConfig(paths); err != nil {
+			return err
+		}
 
 		startTime := time.Now()
 		var output UpgradeOutput
@@ -103,7 +124,7 @@ This command will:
 		}
 
 		checkCtx, cancel = context.WithTimeout(ctx, 5*time.Second)
-		imageVersions, err := versionpkg.CheckImageVersions(checkCtx, cfg.ImageTag)
+		imageVersions, err := versionpkg.CheckImageVersions(checkCtx, cfg.Registry, cfg.ImageTag)
 		cancel()
 
 		anyImageUpdates := false
@@ -165,9 +186,35 @@ This command will:
 			log = logger.NewSilent()
 		}
 
+		// Record the final state of each pull; in JSON mode progress events
+		// are streamed to stderr as JSON lines
+		var pulls []*PullInfo
+		pullsByService := make(map[string]*PullInfo)
+		render := newPullProgressBar().Handle
+		if upgradeJSONOutput {
+			render = jsonPullProgress(os.Stderr)
+		}
+
 		upd := updater.New(cfg, paths, log)
+		upd.SetPullProgress(func(e docker.PullEvent) {
+			info, ok := pullsByService[e.Service]
+			if !ok {
+				info = &PullInfo{Service: e.Service}
+				pullsByService[e.Service] = info
+				pulls = append(pulls, info)
+			}
+			info.Bytes = e.ImageTotal
+			info.Layers = e.Layers
+			info.Done = e.Done
+			info.Error = e.Error
+			render(e)
+		})
 		err = upd.Update(ctx)
 
+		for _, info := range pulls {
+			output.Pulls = append(output.Pulls, *info)
+		}
+
 		output.Upgrade.CompletedAt = time.Now().Format(time.RFC3339)
 
 		if err != nil {
@@ -25,6 +25,7 @@ type VersionOutput struct {
 	CLI    CLIInfo                 `json:"cli"`
 	Latest *versionpkg.VersionInfo `json:"latest,omitempty"`
 	Images []ImageOutput           `json:"images,omitempty"`
+	Bundle *config.BundleInfo      `json:"bundle,omitempty"`
 }
 
 type CLIInfo struct {
@@ -58,31 +59,40 @@ var versionCmd = &cobra.Command{
 			BuildDate: buildDate,
 		}
 
-		versionInfo, err := versionpkg.Check(ctx, version)
-		if err != nil {
-			log.Debug("Failed to check for CLI updates: %v", err)
-		} else {
-			output.Latest = versionInfo
+		paths := instancePaths()
+
+		// Installations from an offline bundle have no registry access
+		state, _ := config.LoadState(paths.StateFile)
+		if state.Offline() {
+			output.Bundle = state.Bundle
 		}
 
-		paths := config.NewPaths(configDir, "")
-		cfg, err := config.Load(paths.ConfigFile)
 		var imageTag string
-		if err != nil {
-			log.Warn("Failed to load config: %v", err)
-		} else {
-			imageTag = cfg.ImageTag
-			imageVersions, err := versionpkg.CheckImageVersions(ctx, cfg.ImageTag)
+		if output.Bundle == nil {
+			versionInfo, err := versionpkg.Check(ctx, version)
 			if err != nil {
-				log.Warn("Failed to check Docker image versions: %v", err)
+				log.Debug("Failed to check for CLI updates: %v", err)
 			} else {
-				for _, img := range imageVersions {
-					output.Images = append(output.Images, ImageOutput{
-						Name:        img.ImageName,
-						Current:     img.Current,
-						Latest:      img.Latest,
-						NeedsUpdate: img.NeedsUpdate,
-					})
+				output.Latest = versionInfo
+			}
+
+			cfg, err := config.Load(paths.ConfigFile)
+			if err != nil {
+				log.Warn("Failed to load config: %v", err)
+			} else {
+				imageTag = cfg.ImageTag
+				imageVersions, err := versionpkg.CheckImageVersions(ctx, cfg.Registry, cfg.ImageTag)
+				if err != nil {
+					log.Warn("Failed to check Docker image versions: %v", err)
+				} else {
+					for _, img := range imageVersions {
+						output.Images = append(output.Images, ImageOutput{
+							Name:        img.ImageName,
+							Current:     img.Current,
+							Latest:      img.Latest,
+							NeedsUpdate: img.NeedsUpdate,
+						})
+					}
 				}
 			}
 		}
@@ -101,6 +111,13 @@ var versionCmd = &cobra.Command{
 			fmt.Printf("  Build Date: %s\n", output.CLI.BuildDate)
 			fmt.Println()
 
+			if output.Bundle != nil {
+				fmt.Printf("Offline installation\n")
+				fmt.Printf("  Image Tag:  %s\n", output.Bundle.ImageTag)
+				fmt.Printf("  Bundle:     created %s, imported %s\n", output.Bundle.CreatedAt, output.Bundle.ImportedAt)
+				fmt.Printf("Version checks skipped; import a newer bundle to upgrade\n")
+			}
+
 			if output.Latest != nil {
 				if output.Latest.NeedsUpdate {
 					fmt.Printf("⚠ New CLI version available: %s (current: %s)\n", output.Latest.Latest, output.Latest.Current)
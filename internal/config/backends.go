@@ -0,0 +1,332 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/eternisai/silo/internal/registry"
+)
+
+// Inference backends, selected by inference.backend
+const (
+	BackendSGLang   = "sglang"
+	BackendVLLM     = "vllm"
+	BackendLlamaCpp = "llamacpp"
+	BackendOllama   = "ollama"
+)
+
+// Backends are the supported inference backends
+var Backends = []string{BackendSGLang, BackendVLLM, BackendLlamaCpp, BackendOllama}
+
+// hostnamePattern matches DNS host names such as localhost or gpu-1.lan
+var hostnamePattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?)*$`)
+
+const (
+	DefaultVLLMImage         = "vllm/vllm-openai:latest"
+	DefaultVLLMContainerName = "silo-vllm"
+	DefaultVLLMPort          = 8000
+
+	// The llama.cpp server image depends on the mode
+	DefaultLlamaCppCPUImage      = "ghcr.io/ggml-org/llama.cpp:server"
+	DefaultLlamaCppGPUImage      = "ghcr.io/ggml-org/llama.cpp:server-cuda"
+	DefaultLlamaCppContainerName = "silo-llamacpp"
+	DefaultLlamaCppPort          = 8081
+
+	DefaultOllamaImage         = "ollama/ollama:latest"
+	DefaultOllamaContainerName = "silo-ollama"
+	DefaultOllamaPort          = 11434
+)
+
+// InferenceConfig selects the inference backend
+type InferenceConfig struct {
+	Backend   string `yaml:"backend" json:"backend" desc:"Inference backend serving the models, configured in the section of the same name" enum:"sglang,vllm,llamacpp,ollama"`
+	ProbeHost string `yaml:"probe_host,omitempty" json:"probe_host,omitempty" desc:"Host address the CLI and daemon reach the inference ports at for health checks, 127.0.0.1 when empty"`
+}
+
+// ContainerSettings are the docker settings every inference backend shares
+type ContainerSettings struct {
+	Enabled       bool     `yaml:"enabled" json:"enabled" desc:"Run the inference engine"`
+	Image         string   `yaml:"image" json:"image" desc:"Docker image, the backend default when empty"`
+	ContainerName string   `yaml:"container_name" json:"container_name" desc:"Name of the inference container" nonempty:"true"`
+	Port          int      `yaml:"port" json:"port" desc:"Inference host port" min:"1" max:"65535"`
+	GPUDevices    []string `yaml:"gpu_devices" json:"gpu_devices" desc:"GPU device IDs passed to the container"`
+
+	Resources ResourceConfig `yaml:"resources,omitempty" json:"resources,omitempty" desc:"CPU, memory, process and ulimit limits of the inference container"`
+	Restart   string         `yaml:"restart,omitempty" json:"restart,omitempty" desc:"Restart policy of the inference container, defaults to unless-stopped" enum:"no,always,on-failure,unless-stopped"`
+
+	// Escape hatches for settings without a field
+	ExtraArgs []string          `yaml:"extra_args" json:"extra_args" desc:"Arguments appended to the inference server command"`
+	ExtraEnv  map[string]string `yaml:"extra_env" json:"extra_env" desc:"Environment variables added to the inference container"`
+}
+
+// VLLMConfig holds configuration for the vLLM inference engine
+type VLLMConfig struct {
+	ContainerSettings `yaml:",inline"`
+
+	ShmSize          string `yaml:"shm_size" json:"shm_size" desc:"Shared memory size, e.g. 16g"`
+	ModelPath        string `yaml:"model_path" json:"model_path" desc:"Host directory with the model files" nonempty:"true"`
+	HuggingFaceCache string `yaml:"huggingface_cache" json:"huggingface_cache" desc:"Host directory of the Hugging Face cache"`
+
+	TPSize               int     `yaml:"tp_size" json:"tp_size" desc:"Tensor parallel size" min:"1"`
+	MaxModelLen          int     `yaml:"max_model_len" json:"max_model_len" desc:"Model context length in tokens, 0 for the model default" min:"0"`
+	MaxNumSeqs           int     `yaml:"max_num_seqs" json:"max_num_seqs" desc:"Maximum number of concurrent sequences, 0 for the vLLM default" min:"0"`
+	GPUMemoryUtilization float64 `yaml:"gpu_memory_utilization" json:"gpu_memory_utilization" desc:"Fraction of GPU memory vLLM may use" min:"0" max:"1"`
+	DType                string  `yaml:"dtype" json:"dtype" desc:"Weight and activation data type" enum:"auto,half,float16,bfloat16,float,float32"`
+	KVCacheDtype         string  `yaml:"kv_cache_dtype" json:"kv_cache_dtype" desc:"KV cache data type" enum:"auto,fp8,fp8_e4m3,fp8_e5m2"`
+	ReasoningParser      string  `yaml:"reasoning_parser" json:"reasoning_parser" desc:"Parser for model reasoning output"`
+	ToolCallParser       string  `yaml:"tool_call_parser" json:"tool_call_parser" desc:"Parser for model tool calls, enables automatic tool choice"`
+	TrustRemoteCode      bool    `yaml:"trust_remote_code" json:"trust_remote_code" desc:"Allow custom model code from the model repository"`
+}
+
+// LlamaCppConfig holds configuration for the llama.cpp server, which runs
+// GGUF models on the CPU or offloads layers to GPUs
+type LlamaCppConfig struct {
+	ContainerSettings `yaml:",inline"`
+
+	Mode        string `yaml:"mode" json:"mode" desc:"cpu, or gpu to offload layers to the GPU devices" enum:"cpu,gpu"`
+	ModelPath   string `yaml:"model_path" json:"model_path" desc:"Host directory with the model files" nonempty:"true"`
+	ModelFile   string `yaml:"model_file" json:"model_file" desc:"GGUF file in model_path to serve" nonempty:"true"`
+	ContextSize int    `yaml:"context_size" json:"context_size" desc:"Context size in tokens, 0 for the model default" min:"0"`
+	GPULayers   int    `yaml:"gpu_layers" json:"gpu_layers" desc:"Layers offloaded to the GPUs in gpu mode, -1 for all" min:"-1"`
+	Threads     int    `yaml:"threads" json:"threads" desc:"CPU threads, 0 for the llama.cpp default" min:"0"`
+	Parallel    int    `yaml:"parallel" json:"parallel" desc:"Number of requests served in parallel" min:"1"`
+}
+
+// OllamaConfig holds configuration for the Ollama inference engine
+type OllamaConfig struct {
+	ContainerSettings `yaml:",inline"`
+
+	ModelsDir     string `yaml:"models_dir" json:"models_dir" desc:"Host directory Ollama keeps pulled models in" nonempty:"true"`
+	Model         string `yaml:"model" json:"model" desc:"Model pulled when the engine starts, e.g. qwen2.5:7b"`
+	ContextLength int    `yaml:"context_length" json:"context_length" desc:"Context length in tokens, 0 for the Ollama default" min:"0"`
+	NumParallel   int    `yaml:"num_parallel" json:"num_parallel" desc:"Requests served in parallel per model, 0 for the Ollama default" min:"0"`
+	KeepAlive     string `yaml:"keep_alive" json:"keep_alive" desc:"How long a model stays loaded after a request, e.g. 5m or -1 for ever"`
+}
+
+// DefaultVLLMConfig returns default vLLM configuration
+func DefaultVLLMConfig() VLLMConfig {
+	return VLLMConfig{
+		ContainerSettings: ContainerSettings{
+			Image:         DefaultVLLMImage,
+			ContainerName: DefaultVLLMContainerName,
+			Port:          DefaultVLLMPort,
+			GPUDevices:    []string{"0"},
+		},
+		ShmSize:              "16g",
+		ModelPath:            "/root/data/model",
+		HuggingFaceCache:     "~/.cache/huggingface",
+		TPSize:               1,
+		GPUMemoryUtilization: 0.9,
+		DType:                "auto",
+		KVCacheDtype:         "auto",
+	}
+}
+
+// DefaultLlamaCppConfig returns default llama.cpp configuration, serving on
+// the CPU
+func DefaultLlamaCppConfig() LlamaCppConfig {
+	return LlamaCppConfig{
+		ContainerSettings: ContainerSettings{
+			ContainerName: DefaultLlamaCppContainerName,
+			Port:          DefaultLlamaCppPort,
+		},
+		Mode:      "cpu",
+		ModelPath: "/root/data/gguf",
+		ModelFile: "model.gguf",
+		GPULayers: -1,
+		Parallel:  1,
+	}
+}
+
+// DefaultOllamaConfig returns default Ollama configuration
+func DefaultOllamaConfig() OllamaConfig {
+	return OllamaConfig{
+		ContainerSettings: ContainerSettings{
+			Image:         DefaultOllamaImage,
+			ContainerName: DefaultOllamaContainerName,
+			Port:          DefaultOllamaPort,
+		},
+		ModelsDir: "~/.ollama",
+	}
+}
+
+// ImageRef returns the configured image, or the llama.cpp server image of
+// the mode
+func (l LlamaCppConfig) ImageRef() string {
+	if l.Image != "" {
+		return l.Image
+	}
+	if l.Mode == "gpu" {
+		return DefaultLlamaCppGPUImage
+	}
+	return DefaultLlamaCppCPUImage
+}
+
+// InferenceContainer is an inference container of the selected backend
+type InferenceContainer struct {
+	// Path is the config path of the container settings
+	Path string
+
+	// Model is the name the container serves its model under
+	Model string
+
+	ContainerSettings
+}
+
+// InferenceContainers returns the containers of the selected backend: one
+// per model for SGLang, one otherwise. Their images are resolved to the
+// backend default when unset.
+func (c *Config) InferenceContainers() []InferenceContainer {
+	switch c.Inference.Backend {
+	case BackendVLLM:
+		return []InferenceContainer{{Path: BackendVLLM, Model: c.DefaultModel, ContainerSettings: c.VLLM.ContainerSettings}}
+	case BackendLlamaCpp:
+		settings := c.LlamaCpp.ContainerSettings
+		settings.Image = c.LlamaCpp.ImageRef()
+		return []InferenceContainer{{Path: BackendLlamaCpp, Model: c.DefaultModel, ContainerSettings: settings}}
+	case BackendOllama:
+		return []InferenceContainer{{Path: BackendOllama, Model: c.Ollama.Model, ContainerSettings: c.Ollama.ContainerSettings}}
+	}
+
+	var containers []InferenceContainer
+	for i, m := range c.InferenceModels() {
+		containers = append(containers, InferenceContainer{Path: c.modelPath(i), Model: m.Name, ContainerSettings: m.ContainerSettings})
+	}
+	return containers
+}
+
+// DefaultLLMBaseURL returns the base URL of the inference container that
+// serves default_model, or the first one
+func (c *Config) DefaultLLMBaseURL() string {
+	containers := c.InferenceContainers()
+	for _, container := range containers {
+		if container.Model == c.DefaultModel {
+			return inferenceBaseURL(container.Port)
+		}
+	}
+	return inferenceBaseURL(containers[0].Port)
+}
+
+// LLMURL returns the API the backend sends requests to: llm_base_url, or
+// the URL of the selected inference backend when it is empty
+func (c *Config) LLMURL() string {
+	if c.LLMBaseURL != "" {
+		return c.LLMBaseURL
+	}
+	return c.DefaultLLMBaseURL()
+}
+
+// InferenceModelPath returns the host directory the selected backend loads
+// models from
+func (c *Config) InferenceModelPath() string {
+	switch c.Inference.Backend {
+	case BackendVLLM:
+		return c.VLLM.ModelPath
+	case BackendLlamaCpp:
+		return c.LlamaCpp.ModelPath
+	case BackendOllama:
+		return c.Ollama.ModelsDir
+	}
+	return c.SGLang.ModelPath
+}
+
+// SetInferenceImage sets the image of the selected backend, including every
+// model of the SGLang models list
+func (c *Config) SetInferenceImage(image string) {
+	switch c.Inference.Backend {
+	case BackendVLLM:
+		c.VLLM.Image = image
+	case BackendLlamaCpp:
+		c.LlamaCpp.Image = image
+	case BackendOllama:
+		c.Ollama.Image = image
+	default:
+		c.SGLang.Image = image
+		for i := range c.Models {
+			c.Models[i].Image = image
+		}
+	}
+}
+
+// backendProblems checks the settings of the selected backend other than
+// SGLang, whose settings are checked by engineProblems
+func (c *Config) backendProblems() []Problem {
+	var problems []Problem
+	add := func(severity Severity, path, format string, args ...any) {
+		problems = append(problems, Problem{Path: path, Message: fmt.Sprintf(format, args...), Severity: severity})
+	}
+
+	backend := c.Inference.Backend
+	if backend != BackendSGLang && len(c.Models) > 0 {
+		add(SeverityError, "models", "a models list needs inference.backend sglang, got %q", backend)
+	}
+
+	for _, container := range c.InferenceContainers() {
+		if backend == BackendSGLang {
+			break
+		}
+		if err := registry.ValidateReference(container.Image); err != nil {
+			add(SeverityError, container.Path+".image", "%v", err)
+		}
+	}
+
+	if host := c.Inference.ProbeHost; host != "" && net.ParseIP(host) == nil && !hostnamePattern.MatchString(host) {
+		add(SeverityError, "inference.probe_host", "must be an IP address or host name, got %q", host)
+	}
+
+	switch backend {
+	case BackendVLLM:
+		v := c.VLLM
+		severity := enabledSeverity(v.Enabled)
+		if len(v.GPUDevices) == 0 {
+			add(severity, "vllm.gpu_devices", "vLLM needs at least one GPU, use the llamacpp or ollama backend to serve on the CPU")
+		} else if v.TPSize > 0 && len(v.GPUDevices)%v.TPSize != 0 {
+			add(severity, "vllm.gpu_devices", "tp_size %d does not divide the %d GPU devices listed", v.TPSize, len(v.GPUDevices))
+		}
+		if v.ShmSize != "" {
+			if _, err := ParseMemory(v.ShmSize); err != nil {
+				add(severity, "vllm.shm_size", "%v", err)
+			}
+		}
+		if v.GPUMemoryUtilization <= 0 || v.GPUMemoryUtilization > 1 {
+			add(severity, "vllm.gpu_memory_utilization", "must be greater than 0 and at most 1, got %g", v.GPUMemoryUtilization)
+		}
+
+	case BackendLlamaCpp:
+		l := c.LlamaCpp
+		severity := enabledSeverity(l.Enabled)
+		if l.Mode == "gpu" && len(l.GPUDevices) == 0 {
+			add(severity, "llamacpp.gpu_devices", "gpu mode needs at least one GPU device")
+		}
+		if l.Mode == "cpu" && len(l.GPUDevices) > 0 {
+			add(SeverityWarning, "llamacpp.gpu_devices", "ignored in cpu mode, set mode to gpu to use the GPUs")
+		}
+		if l.ModelFile != "" && (filepath.Base(l.ModelFile) != l.ModelFile || !strings.HasSuffix(strings.ToLower(l.ModelFile), ".gguf")) {
+			add(severity, "llamacpp.model_file", "must be the name of a .gguf file in model_path, got %q", l.ModelFile)
+		}
+
+	case BackendOllama:
+		o := c.Ollama
+		if len(o.ExtraArgs) > 0 {
+			add(SeverityWarning, "ollama.extra_args", "ignored, ollama serve takes no arguments; use extra_env for OLLAMA_* settings")
+		}
+		if o.Model == "" {
+			add(SeverityWarning, "ollama.model", "no model is pulled, pull one with 'docker exec %s ollama pull <model>'", o.ContainerName)
+		} else if o.Model != c.DefaultModel {
+			add(SeverityWarning, "default_model", "the backend requests %q, but Ollama serves %q", c.DefaultModel, o.Model)
+		}
+	}
+	return problems
+}
+
+// enabledSeverity returns the severity of inference engine problems, which
+// only block the config when the engine runs
+func enabledSeverity(enabled bool) Severity {
+	if enabled {
+		return SeverityError
+	}
+	return SeverityWarning
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLLMURL_FollowsBackend(t *testing.T) {
+	tests := []struct {
+		backend string
+		want    string
+	}{
+		{BackendSGLang, "http://host.docker.internal:30000/v1"},
+		{BackendVLLM, "http://host.docker.internal:8000/v1"},
+		{BackendLlamaCpp, "http://host.docker.internal:8081/v1"},
+		{BackendOllama, "http://host.docker.internal:11434/v1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.backend, func(t *testing.T) {
+			cfg := NewDefaultConfig(NewPaths(t.TempDir(), t.TempDir()))
+			cfg.Inference.Backend = tt.backend
+			if got := cfg.LLMURL(); got != tt.want {
+				t.Errorf("LLMURL() = %s, want %s", got, tt.want)
+			}
+
+			data, err := RenderDockerCompose(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(data), yamlQuote("LLM_BASE_URL="+tt.want)) {
+				t.Errorf("Expected the backend URL in the backend environment:\n%s", data)
+			}
+
+			cfg.LLMBaseURL = "http://llm.internal/v1"
+			if got := cfg.LLMURL(); got != cfg.LLMBaseURL {
+				t.Errorf("Expected llm_base_url to win, got %s", got)
+			}
+		})
+	}
+}
+
+func TestInferenceContainers(t *testing.T) {
+	cfg := NewDefaultConfig(NewPaths(t.TempDir(), t.TempDir()))
+	cfg.Inference.Backend = BackendLlamaCpp
+	cfg.LlamaCpp.Mode = "gpu"
+
+	containers := cfg.InferenceContainers()
+	if len(containers) != 1 || containers[0].Path != "llamacpp" || containers[0].Image != DefaultLlamaCppGPUImage {
+		t.Fatalf("Expected the llamacpp container with the gpu image, got %+v", containers)
+	}
+	if refs := cfg.ImageRefs(); !strings.Contains(strings.Join(refs, " "), DefaultLlamaCppGPUImage) {
+		t.Errorf("Expected the llama.cpp image among the image refs, got %v", refs)
+	}
+
+	cfg.SetInferenceImage("mirror.local/llama.cpp:server-cuda")
+	if got := cfg.InferenceContainers()[0].Image; got != "mirror.local/llama.cpp:server-cuda" {
+		t.Errorf("Expected SetInferenceImage to set the llamacpp image, got %s", got)
+	}
+}
+
+func TestBackendProblems(t *testing.T) {
+	tests := []struct {
+		name     string
+		change   func(c *Config)
+		path     string
+		severity Severity
+	}{
+		{"vllm tp size", func(c *Config) { c.Inference.Backend = BackendVLLM; c.VLLM.Enabled = true; c.VLLM.TPSize = 2 }, "vllm.gpu_devices", SeverityError},
+		{"vllm no gpus", func(c *Config) { c.Inference.Backend = BackendVLLM; c.VLLM.GPUDevices = nil }, "vllm.gpu_devices", SeverityWarning},
+		{"vllm memory", func(c *Config) {
+			c.Inference.Backend = BackendVLLM
+			c.VLLM.Enabled = true
+			c.VLLM.GPUMemoryUtilization = 0
+		}, "vllm.gpu_memory_utilization", SeverityError},
+		{"llamacpp gpu mode", func(c *Config) {
+			c.Inference.Backend = BackendLlamaCpp
+			c.LlamaCpp.Enabled = true
+			c.LlamaCpp.Mode = "gpu"
+		}, "llamacpp.gpu_devices", SeverityError},
+		{"llamacpp cpu gpus", func(c *Config) { c.Inference.Backend = BackendLlamaCpp; c.LlamaCpp.GPUDevices = []string{"0"} }, "llamacpp.gpu_devices", SeverityWarning},
+		{"llamacpp model file", func(c *Config) {
+			c.Inference.Backend = BackendLlamaCpp
+			c.LlamaCpp.Enabled = true
+			c.LlamaCpp.ModelFile = "model.bin"
+		}, "llamacpp.model_file", SeverityError},
+		{"ollama image", func(c *Config) { c.Inference.Backend = BackendOllama; c.Ollama.Image = "Ollama/Ollama" }, "ollama.image", SeverityError},
+		{"ollama model", func(c *Config) { c.Inference.Backend = BackendOllama; c.Ollama.Model = "qwen2.5:7b" }, "default_model", SeverityWarning},
+		{"ollama extra args", func(c *Config) { c.Inference.Backend = BackendOllama; c.Ollama.ExtraArgs = []string{"--verbose"} }, "ollama.extra_args", SeverityWarning},
+		{"probe host", func(c *Config) { c.Inference.ProbeHost = "http://gpu-1:30000" }, "inference.probe_host", SeverityError},
+		{"models list", func(c *Config) {
+			c.Inference.Backend = BackendVLLM
+			c.Models = []ModelConfig{{Name: DefaultModel, SGLangConfig: c.SGLang}}
+		}, "models", SeverityError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewDefaultConfig(NewPaths(t.TempDir(), t.TempDir()))
+			tt.change(cfg)
+			var found *Problem
+			for _, p := range cfg.backendProblems() {
+				if p.Path == tt.path {
+					found = &p
+				}
+			}
+			if found == nil {
+				t.Fatalf("Expected a problem at %s", tt.path)
+			}
+			if found.Severity != tt.severity {
+				t.Errorf("Expected %s severity %s, got %s: %s", tt.path, tt.severity, found.Severity, found.Message)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_OtherBackendSkipsSGLang(t *testing.T) {
+	cfg := NewDefaultConfig(NewPaths(t.TempDir(), t.TempDir()))
+	cfg.Inference.Backend = BackendLlamaCpp
+	cfg.LlamaCpp.Enabled = true
+	cfg.SGLang.TPSize = 2
+
+	problems, err := ValidateConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range problems {
+		if strings.HasPrefix(p.Path, "sglang.") || strings.HasPrefix(p.Path, "llamacpp.") {
+			t.Errorf("Unexpected problem: %s", p)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ComposeOverrideFileName is the user-maintained compose file in the config
+// directory. It is linked next to the generated docker-compose.yml, where
+// every compose call passes it as a second -f.
+const ComposeOverrideFileName = "docker-compose.override.yml"
+
+// BuiltinServices are the services of the generated docker-compose.yml, in
+// file order
+var BuiltinServices = []string{"postgres", "backend", "frontend", "silo-proxy-agent", "deep-research"}
+
+// ServiceConfig holds user additions to a built-in compose service
+type ServiceConfig struct {
+	ExtraEnv     map[string]string `yaml:"extra_env,omitempty" desc:"Environment variables added to the service"`
+	ExtraVolumes []string          `yaml:"extra_volumes,omitempty" desc:"Volumes mounted in addition to the built-in ones, in compose short syntax"`
+	Labels       map[string]string `yaml:"labels,omitempty" desc:"Container labels"`
+	Networks     []string          `yaml:"networks,omitempty" desc:"External networks the service joins besides the default network"`
+
+	Publish     *bool  `yaml:"publish,omitempty" desc:"Publish the service port on the host, defaults to true except for postgres"`
+	BindAddress string `yaml:"bind_address,omitempty" desc:"Host address the port is published on, e.g. 127.0.0.1; all interfaces when empty"`
+
+	Resources ResourceConfig `yaml:"resources,omitempty" desc:"CPU, memory, process and ulimit limits"`
+	Restart   string         `yaml:"restart,omitempty" desc:"Restart policy, defaults to the built-in policy of the service" enum:"no,always,on-failure,unless-stopped"`
+}
+
+// yamlQuote returns s as a double-quoted YAML scalar
+func yamlQuote(s string) string {
+	quoted, _ := json.Marshal(s)
+	return string(quoted)
+}
+
+// sortedPairs returns key=value for every map entry, sorted and quoted
+func sortedPairs(m map[string]string) []string {
+	pairs := make([]string, 0, len(m))
+	for k, v := range m {
+		pairs = append(pairs, yamlQuote(k+"="+v))
+	}
+	sort.Strings(pairs)
+	return pairs
+}
+
+// ExtraEnv returns the user environment variables of a service as quoted
+// KEY=value list items
+func (c *Config) ExtraEnv(service string) []string {
+	return sortedPairs(c.ServiceSettings[service].ExtraEnv)
+}
+
+// ExtraVolumes returns the user volumes of a service as quoted list items
+func (c *Config) ExtraVolumes(service string) []string {
+	var volumes []string
+	for _, v := range c.ServiceSettings[service].ExtraVolumes {
+		volumes = append(volumes, yamlQuote(v))
+	}
+	return volumes
+}
+
+// ServiceExtras holds the user labels, networks and resource limits of a
+// service, rendered for docker-compose.yml
+type ServiceExtras struct {
+	Labels []string
+
+	// Resources are the indented deploy, pids_limit and ulimits lines
+	Resources []string
+
+	// Networks is the default network followed by the user networks, or
+	// empty to leave the service on the default network only
+	Networks []string
+}
+
+// ServiceExtras returns the user labels, networks and resource limits of a
+// service
+func (c *Config) ServiceExtras(service string) ServiceExtras {
+	settings := c.ServiceSettings[service]
+	extras := ServiceExtras{
+		Labels:    sortedPairs(settings.Labels),
+		Resources: settings.Resources.composeLines(),
+	}
+	if len(settings.Networks) > 0 {
+		extras.Networks = append(extras.Networks, yamlQuote("default"))
+		for _, n := range settings.Networks {
+			extras.Networks = append(extras.Networks, yamlQuote(n))
+		}
+	}
+	return extras
+}
+
+// ExternalNetworks returns every user network, sorted, to be declared as
+// external in docker-compose.yml
+func (c *Config) ExternalNetworks() []string {
+	seen := make(map[string]bool)
+	var networks []string
+	for _, s := range c.ServiceSettings {
+		for _, n := range s.Networks {
+			if !seen[n] {
+				seen[n] = true
+				networks = append(networks, n)
+			}
+		}
+	}
+	sort.Strings(networks)
+	return networks
+}
+
+// ExtraServiceNames returns the names of the user services, sorted
+func (c *Config) ExtraServiceNames() []string {
+	names := make([]string, 0, len(c.ExtraServices))
+	for name := range c.ExtraServices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ExtraServicesYAML returns the user services as YAML indented to sit under
+// the services key of docker-compose.yml
+func (c *Config) ExtraServicesYAML() (string, error) {
+	if len(c.ExtraServices) == 0 {
+		return "", nil
+	}
+	var node yaml.Node
+	if err := node.Encode(c.ExtraServices); err != nil {
+		return "", fmt.Errorf("failed to encode extra_services: %w", err)
+	}
+	data, err := encodeNode(&node)
+	if err != nil {
+		return "", err
+	}
+
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = "  " + line
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// composeProblems checks the service additions against the built-in
+// services, and the variable names of every extra_env
+func (c *Config) composeProblems() []Problem {
+	builtin := make(map[string]bool)
+	for _, s := range BuiltinServices {
+		builtin[s] = true
+	}
+
+	var problems []Problem
+	names := make([]string, 0, len(c.ServiceSettings))
+	for name := range c.ServiceSettings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !builtin[name] {
+			problems = append(problems, Problem{Path: "services." + name, Message: fmt.Sprintf("unknown service, expected one of %s (use extra_services to add services)", strings.Join(BuiltinServices, ", "))})
+		}
+		problems = append(problems, envNameProblems("services."+name+".extra_env", c.ServiceSettings[name].ExtraEnv)...)
+	}
+	problems = append(problems, envNameProblems("sglang.extra_env", c.SGLang.ExtraEnv)...)
+	problems = append(problems, envNameProblems("vllm.extra_env", c.VLLM.ExtraEnv)...)
+	problems = append(problems, envNameProblems("llamacpp.extra_env", c.LlamaCpp.ExtraEnv)...)
+	problems = append(problems, envNameProblems("ollama.extra_env", c.Ollama.ExtraEnv)...)
+	for i, m := range c.Models {
+		problems = append(problems, envNameProblems(c.modelPath(i)+".extra_env", m.ExtraEnv)...)
+	}
+	for _, name := range c.ExtraServiceNames() {
+		path := "extra_services." + name
+		if builtin[name] {
+			problems = append(problems, Problem{Path: path, Message: "conflicts with a built-in service, use services." + name + " to extend it"})
+			continue
+		}
+		def, ok := c.ExtraServices[name].(map[string]any)
+		if !ok {
+			problems = append(problems, Problem{Path: path, Message: "must be a mapping with a compose service definition"})
+			continue
+		}
+		if def["image"] == nil && def["build"] == nil {
+			problems = append(problems, Problem{Path: path, Message: "needs an image or build"})
+		}
+	}
+	return problems
+}
+
+// envNameProblems reports the keys of env that cannot be environment
+// variable names
+func envNameProblems(path string, env map[string]string) []Problem {
+	var problems []Problem
+	for key := range env {
+		if key == "" || strings.ContainsAny(key, "= \t\n") {
+			problems = append(problems, Problem{Path: path, Message: fmt.Sprintf("invalid variable name %q", key)})
+		}
+	}
+	return problems
+}
+
+// linkComposeOverride links the user's override file next to the generated
+// compose file so compose calls pick it up, and removes a stale link when the
+// override file is gone
+func linkComposeOverride(config *Config, composeFile string) error {
+	link := filepath.Join(filepath.Dir(composeFile), ComposeOverrideFileName)
+	if config.ComposeOverrideFile == "" || config.ComposeOverrideFile == link {
+		return nil
+	}
+
+	if _, err := os.Stat(config.ComposeOverrideFile); err != nil {
+		if info, err := os.Lstat(link); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return os.Remove(link)
+		}
+		return nil
+	}
+
+	if info, err := os.Lstat(link); err == nil {
+		if info.Mode()&os.ModeSymlink == 0 {
+			// A file placed there by hand is used as is
+			return nil
+		}
+		if target, err := os.Readlink(link); err == nil && target == config.ComposeOverrideFile {
+			return nil
+		}
+		if err := os.Remove(link); err != nil {
+			return fmt.Errorf("failed to replace %s: %w", link, err)
+		}
+	}
+	if err := os.Symlink(config.ComposeOverrideFile, link); err != nil {
+		return fmt.Errorf("failed to link compose override file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestGenerateDockerCompose_Extensions(t *testing.T) {
+	paths := NewPaths(t.TempDir(), t.TempDir())
+	writeConfig(t, paths, `services:
+  backend:
+    extra_env:
+      HTTP_PROXY: "http://proxy:3128"
+    extra_volumes: ["/srv/certs:/certs:ro"]
+    labels:
+      traefik.enable: "true"
+    networks: ["proxy"]
+  frontend:
+    extra_volumes: ["/srv/static:/static"]
+extra_services:
+  sidecar:
+    image: busybox
+    command: ["sleep", "infinity"]
+`)
+	cfg, err := LoadOrDefault(paths.ConfigFile, paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(paths.DataDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := GenerateDockerCompose(cfg, paths.ComposeFile); err != nil {
+		t.Fatalf("GenerateDockerCompose failed: %v", err)
+	}
+
+	data, err := os.ReadFile(paths.ComposeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var compose struct {
+		Services map[string]struct {
+			Image       string   `yaml:"image"`
+			Environment []string `yaml:"environment"`
+			Volumes     []string `yaml:"volumes"`
+			Labels      []string `yaml:"labels"`
+			Networks    []string `yaml:"networks"`
+		} `yaml:"services"`
+		Networks map[string]struct {
+			External bool `yaml:"external"`
+		} `yaml:"networks"`
+	}
+	if err := yaml.Unmarshal(data, &compose); err != nil {
+		t.Fatalf("Generated compose file is not valid YAML: %v\n%s", err, data)
+	}
+
+	backend := compose.Services["backend"]
+	if !strings.Contains(strings.Join(backend.Environment, "\n"), "HTTP_PROXY=http://proxy:3128") {
+		t.Errorf("Expected extra env in backend, got %v", backend.Environment)
+	}
+	if len(backend.Volumes) != 2 || backend.Volumes[1] != "/srv/certs:/certs:ro" {
+		t.Errorf("Expected extra volume after the built-in one, got %v", backend.Volumes)
+	}
+	if len(backend.Labels) != 1 || backend.Labels[0] != "traefik.enable=true" {
+		t.Errorf("Expected backend label, got %v", backend.Labels)
+	}
+	if strings.Join(backend.Networks, ",") != "default,proxy" {
+		t.Errorf("Expected backend on default and proxy networks, got %v", backend.Networks)
+	}
+	if !compose.Networks["proxy"].External {
+		t.Errorf("Expected proxy to be declared external, got %v", compose.Networks)
+	}
+	if v := compose.Services["frontend"].Volumes; len(v) != 1 || v[0] != "/srv/static:/static" {
+		t.Errorf("Expected frontend volume, got %v", v)
+	}
+	if compose.Services["sidecar"].Image != "busybox" {
+		t.Errorf("Expected sidecar service, got %+v", compose.Services["sidecar"])
+	}
+	if len(compose.Services["postgres"].Networks) != 0 {
+		t.Errorf("Expected postgres to stay on the default network, got %v", compose.Services["postgres"].Networks)
+	}
+}
+
+func TestComposeProblems(t *testing.T) {
+	cfg := NewDefaultConfig(NewPaths(t.TempDir(), t.TempDir()))
+	cfg.ServiceSettings = map[string]ServiceConfig{
+		"backnd":  {},
+		"backend": {ExtraEnv: map[string]string{"BAD NAME": "x"}},
+	}
+	cfg.ExtraServices = map[string]any{
+		"frontend": map[string]any{"image": "nginx"},
+		"noimage":  map[string]any{"command": "true"},
+		"scalar":   "busybox",
+		"ok":       map[string]any{"build": "./ok"},
+	}
+
+	problems := map[string]bool{}
+	for _, p := range cfg.composeProblems() {
+		problems[p.Path] = true
+	}
+	for _, path := range []string{"services.backnd", "services.backend.extra_env", "extra_services.frontend", "extra_services.noimage", "extra_services.scalar"} {
+		if !problems[path] {
+			t.Errorf("Expected a problem at %s, got %v", path, problems)
+		}
+	}
+	if problems["extra_services.ok"] {
+		t.Error("Expected a service with build to be accepted")
+	}
+}
+
+func TestLinkComposeOverride(t *testing.T) {
+	paths := NewPaths(t.TempDir(), t.TempDir())
+	cfg := NewDefaultConfig(paths)
+	link := filepath.Join(paths.DataDir, ComposeOverrideFileName)
+	if err := os.MkdirAll(paths.DataDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := linkComposeOverride(cfg, paths.ComposeFile); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(link); !os.IsNotExist(err) {
+		t.Error("Expected no link without an override file")
+	}
+
+	writeConfig(t, paths, "")
+	if err := os.WriteFile(paths.ComposeOverrideFile, []byte("services: {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := linkComposeOverride(cfg, paths.ComposeFile); err != nil {
+		t.Fatal(err)
+	}
+	if target, err := os.Readlink(link); err != nil || target != paths.ComposeOverrideFile {
+		t.Errorf("Expected link to %s, got %q (%v)", paths.ComposeOverrideFile, target, err)
+	}
+
+	if err := os.Remove(paths.ComposeOverrideFile); err != nil {
+		t.Fatal(err)
+	}
+	if err := linkComposeOverride(cfg, paths.ComposeFile); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(link); !os.IsNotExist(err) {
+		t.Error("Expected the stale link to be removed")
+	}
+}
@@ -0,0 +1,447 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Document is a config file opened for editing. Edits go through the YAML
+// node tree, so comments and key order of the file are kept.
+type Document struct {
+	path string
+	doc  *yaml.Node
+}
+
+// OpenDocument opens the config file at path for editing, applying pending
+// schema migrations first. A missing file opens as a document holding only
+// the current schema_version, so it is not migrated once written.
+func OpenDocument(path string) (*Document, error) {
+	if _, err := Migrate(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		doc, err := ParseDocument(path, nil)
+		if err != nil {
+			return nil, err
+		}
+		setScalar(doc.doc.Content[0], "schema_version", strconv.Itoa(SchemaVersion), "!!int")
+		return doc, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	return ParseDocument(path, data)
+}
+
+// ParseDocument parses data as the contents of the config file at path
+func ParseDocument(path string, data []byte) (*Document, error) {
+	doc, err := parseDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	return &Document{path: path, doc: doc}, nil
+}
+
+// Get returns the value at a dotted key such as sglang.tp_size, or false if
+// the file does not set it
+func (d *Document) Get(key string) (*yaml.Node, bool) {
+	parts, err := resolveKey(key)
+	if err != nil {
+		return nil, false
+	}
+
+	node := d.doc.Content[0]
+	for _, part := range parts {
+		if node = mappingValue(node, part); node == nil {
+			return nil, false
+		}
+	}
+	return node, true
+}
+
+// Set sets the value at a dotted key, creating parent sections as needed.
+// value is parsed as YAML, so lists can be given in flow style ("[0, 1]").
+func (d *Document) Set(key, value string) error {
+	if _, err := resolveKey(key); err != nil {
+		return err
+	}
+
+	var parsed yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &parsed); err != nil {
+		return fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	if len(parsed.Content) > 0 {
+		node = parsed.Content[0]
+		blockStyle(node)
+	}
+	return d.setNode(key, node)
+}
+
+// blockStyle switches the collections in a parsed value to block style, so
+// values given as JSON read like the rest of the file
+func blockStyle(node *yaml.Node) {
+	switch node.Kind {
+	case yaml.MappingNode, yaml.SequenceNode:
+		node.Style &^= yaml.FlowStyle
+		for _, child := range node.Content {
+			blockStyle(child)
+		}
+	case yaml.ScalarNode:
+		// The tag is kept, so strings are still quoted where needed
+		node.Style &^= yaml.DoubleQuotedStyle | yaml.SingleQuotedStyle
+	}
+}
+
+// setNode sets the value at a dotted key to node
+func (d *Document) setNode(key string, node *yaml.Node) error {
+	parts, err := resolveKey(key)
+	if err != nil {
+		return err
+	}
+
+	parent := d.doc.Content[0]
+	for _, part := range parts[:len(parts)-1] {
+		if parent, err = childMapping(parent, part); err != nil {
+			return err
+		}
+	}
+
+	last := parts[len(parts)-1]
+	if i := mappingIndex(parent, last); i >= 0 {
+		// Keep the comments attached to the old value
+		old := parent.Content[i+1]
+		node.HeadComment, node.LineComment, node.FootComment = old.HeadComment, old.LineComment, old.FootComment
+		parent.Content[i+1] = node
+		return nil
+	}
+	parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: last}, node)
+	return nil
+}
+
+// Unset removes the value at a dotted key, so its default applies again. It
+// reports whether the key was set.
+func (d *Document) Unset(key string) (bool, error) {
+	parts, err := resolveKey(key)
+	if err != nil {
+		return false, err
+	}
+
+	parent := d.doc.Content[0]
+	for _, part := range parts[:len(parts)-1] {
+		if parent = mappingValue(parent, part); parent == nil {
+			return false, nil
+		}
+	}
+	return removeKey(parent, parts[len(parts)-1]) != nil, nil
+}
+
+// Bytes returns the document as YAML
+func (d *Document) Bytes() ([]byte, error) {
+	return encodeNode(d.doc)
+}
+
+// Config returns the config the document describes, with defaults applied
+// for absent keys
+func (d *Document) Config(paths *Paths) (*Config, error) {
+	data, err := d.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	return loadData(data, paths)
+}
+
+// Validate returns the schema and validation problems of the document. The
+// error is set when the document cannot be loaded at all.
+func (d *Document) Validate(paths *Paths) ([]Problem, error) {
+	data, err := d.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	c, err := checkDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	problems := append(c.unknown, c.problems...)
+	if len(c.problems) > 0 {
+		return problems, nil
+	}
+
+	cfg, err := loadData(data, paths)
+	if err != nil {
+		return nil, err
+	}
+	more, err := ValidateConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return append(problems, more...), nil
+}
+
+// Save validates the document and writes it atomically. Warnings, and errors
+// that were already present in before, the previous version of the document,
+// do not block saving, so an invalid file can be fixed one key at a time.
+func (d *Document) Save(paths *Paths, before *Document) error {
+	problems, err := d.Validate(paths)
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]bool)
+	if before != nil {
+		old, _ := before.Validate(paths)
+		for _, p := range old {
+			existing[p.Path+": "+p.Message] = true
+		}
+	}
+
+	var introduced []Problem
+	for _, p := range problems {
+		if !p.IsWarning() && !existing[p.Path+": "+p.Message] {
+			introduced = append(introduced, p)
+		}
+	}
+	if len(introduced) > 0 {
+		return &ValidationError{Problems: introduced}
+	}
+
+	data, err := d.Bytes()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(d.path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := writeFileAtomic(d.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// resolveKey splits a dotted key into the keys of the nested mappings it
+// names, checking it against the schema. Map keys may contain dots, as in
+// registry.mirrors.docker.io.
+func resolveKey(key string) ([]string, error) {
+	if key == "" {
+		return nil, fmt.Errorf("empty key")
+	}
+	parts, ok := splitKey(ConfigSchema(), strings.Split(key, "."))
+	if !ok {
+		return nil, fmt.Errorf("unknown config key %q", key)
+	}
+	return parts, nil
+}
+
+func splitKey(s *Schema, parts []string) ([]string, bool) {
+	if len(parts) == 0 {
+		return nil, true
+	}
+
+	if s.values != nil {
+		// Take the longest map key that leaves a valid path into the value,
+		// or the whole remainder if it names the map entry itself
+		for i := len(parts) - 1; i >= 1; i-- {
+			if rest, ok := splitKey(s.values, parts[i:]); ok {
+				return append([]string{strings.Join(parts[:i], ".")}, rest...), true
+			}
+		}
+		return []string{strings.Join(parts, ".")}, true
+	}
+
+	prop, ok := s.Properties[parts[0]]
+	if !ok {
+		return nil, false
+	}
+	rest, ok := splitKey(prop, parts[1:])
+	if !ok {
+		return nil, false
+	}
+	return append([]string{parts[0]}, rest...), true
+}
+
+// ConfigYAML returns cfg as it would be written to config.yml
+func ConfigYAML(cfg *Config) ([]byte, error) {
+	var node yaml.Node
+	if err := node.Encode(cfg); err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return encodeNode(&node)
+}
+
+// FormatValue returns a YAML node as text: scalars as their value, other
+// nodes as YAML
+func FormatValue(node *yaml.Node) (string, error) {
+	if node.Kind == yaml.ScalarNode {
+		return node.Value, nil
+	}
+	data, err := encodeNode(node)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
+
+// EffectiveValue returns the value of a dotted key in cfg, with derived
+// values resolved as in EffectiveYAML
+func EffectiveValue(cfg *Config, key string) (*yaml.Node, error) {
+	root, err := effectiveDocument(cfg)
+	if err != nil {
+		return nil, err
+	}
+	doc := &Document{doc: root}
+	if _, err := resolveKey(key); err != nil {
+		return nil, err
+	}
+	if node, ok := doc.Get(key); ok {
+		return node, nil
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: ""}, nil
+}
+
+// Change is a setting that differs between two configs
+type Change struct {
+	Key string
+	Old string
+	New string
+}
+
+// Diff returns the settings that differ between two configs, sorted by key.
+// Settings absent from one side have an empty value.
+func Diff(old, new *Config) ([]Change, error) {
+	oldValues, err := flatten(old)
+	if err != nil {
+		return nil, err
+	}
+	newValues, err := flatten(new)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]bool)
+	for k := range oldValues {
+		keys[k] = true
+	}
+	for k := range newValues {
+		keys[k] = true
+	}
+
+	var changes []Change
+	for k := range keys {
+		if oldValues[k] != newValues[k] {
+			changes = append(changes, Change{Key: k, Old: oldValues[k], New: newValues[k]})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes, nil
+}
+
+// flatten maps every scalar and list setting of cfg to its dotted key
+func flatten(cfg *Config) (map[string]string, error) {
+	var node yaml.Node
+	if err := node.Encode(cfg); err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	values := make(map[string]string)
+	var walk func(n *yaml.Node, prefix string) error
+	walk = func(n *yaml.Node, prefix string) error {
+		if n.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				key := n.Content[i].Value
+				if prefix != "" {
+					key = prefix + "." + key
+				}
+				if err := walk(n.Content[i+1], key); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		n.Style |= yaml.FlowStyle
+		value, err := FormatValue(n)
+		if err != nil {
+			return err
+		}
+		values[prefix] = value
+		return nil
+	}
+	if err := walk(&node, ""); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// AffectedServices returns the docker-compose services whose definition
+// differs between the two configs, including services enabled or disabled,
+// in docker-compose.yml order followed by the extra services
+func AffectedServices(old, new *Config) ([]string, error) {
+	oldServices, err := composeServices(old)
+	if err != nil {
+		return nil, err
+	}
+	newServices, err := composeServices(new)
+	if err != nil {
+		return nil, err
+	}
+
+	names := append([]string{}, BuiltinServices...)
+	seen := make(map[string]bool)
+	for _, cfg := range []*Config{old, new} {
+		for _, name := range cfg.ExtraServiceNames() {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	var affected []string
+	for _, name := range names {
+		if !reflect.DeepEqual(oldServices[name], newServices[name]) {
+			affected = append(affected, name)
+		}
+	}
+	return affected, nil
+}
+
+// composeServices renders docker-compose.yml for cfg and returns its
+// service definitions
+func composeServices(cfg *Config) (map[string]any, error) {
+	data, err := RenderDockerCompose(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var compose struct {
+		Services map[string]any `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(data, &compose); err != nil {
+		return nil, fmt.Errorf("failed to parse docker-compose template output: %w", err)
+	}
+	return compose.Services, nil
+}
+
+// encodeNode encodes a YAML node with the two space indent of config.yml
+func encodeNode(node *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,169 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestResolveKey(t *testing.T) {
+	tests := map[string]string{
+		"port":                       "port",
+		"sglang.tp_size":             "sglang|tp_size",
+		"registry.mirrors.docker.io": "registry|mirrors|docker.io",
+		"registry.credentials.reg.local.username": "registry|credentials|reg.local|username",
+	}
+	for key, want := range tests {
+		parts, err := resolveKey(key)
+		if err != nil {
+			t.Errorf("resolveKey(%q) failed: %v", key, err)
+			continue
+		}
+		if got := strings.Join(parts, "|"); got != want {
+			t.Errorf("resolveKey(%q) = %s, want %s", key, got, want)
+		}
+	}
+
+	for _, key := range []string{"", "bogus", "sglang.bogus", "port.sub"} {
+		if _, err := resolveKey(key); err == nil {
+			t.Errorf("Expected resolveKey(%q) to fail", key)
+		}
+	}
+}
+
+func TestDocument_SetUnset(t *testing.T) {
+	paths := NewPaths(t.TempDir(), t.TempDir())
+	data := `# Frontend port
+port: 3000 # custom
+sglang:
+  tp_size: 1
+`
+	before, err := ParseDocument(paths.ConfigFile, []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, _ := ParseDocument(paths.ConfigFile, []byte(data))
+
+	if err := doc.Set("port", "8081"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := doc.Set("sglang.gpu_devices", `["0", "1"]`); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := doc.Set("registry.mirrors.docker.io", "mirror.local"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if removed, err := doc.Unset("sglang.tp_size"); err != nil || !removed {
+		t.Fatalf("Expected sglang.tp_size to be removed, got %v, %v", removed, err)
+	}
+
+	if err := doc.Save(paths, before); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	saved, err := os.ReadFile(paths.ConfigFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# Frontend port\nport: 8081 # custom", "docker.io: mirror.local"} {
+		if !strings.Contains(string(saved), want) {
+			t.Errorf("Expected saved file to contain %q:\n%s", want, saved)
+		}
+	}
+
+	cfg, err := doc.Config(paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 8081 || cfg.SGLang.TPSize != DefaultSGLangConfig().TPSize || len(cfg.SGLang.GPUDevices) != 2 {
+		t.Errorf("Unexpected config after edits: port=%d tp_size=%d gpu_devices=%v", cfg.Port, cfg.SGLang.TPSize, cfg.SGLang.GPUDevices)
+	}
+}
+
+func TestOpenDocument_NewFile(t *testing.T) {
+	paths := NewPaths(t.TempDir(), t.TempDir())
+	doc, err := OpenDocument(paths.ConfigFile)
+	if err != nil {
+		t.Fatalf("OpenDocument failed: %v", err)
+	}
+	if err := doc.Set("port", "8081"); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.Save(paths, nil); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	if version, steps, err := PendingMigrations(paths.ConfigFile); err != nil || version != SchemaVersion || len(steps) != 0 {
+		t.Errorf("Expected a current schema_version, got %d with %d pending migrations, %v", version, len(steps), err)
+	}
+}
+
+func TestDocument_SaveRejectsNewProblems(t *testing.T) {
+	paths := NewPaths(t.TempDir(), t.TempDir())
+	before, _ := ParseDocument(paths.ConfigFile, []byte("port: 70000\n"))
+
+	// Fixing one key of an invalid file is allowed
+	doc, _ := ParseDocument(paths.ConfigFile, []byte("port: 70000\n"))
+	if err := doc.Set("backend_port", "8081"); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.Save(paths, before); err != nil {
+		t.Errorf("Expected existing problem not to block saving, got %v", err)
+	}
+
+	if err := doc.Set("sglang.tp_size", "0"); err != nil {
+		t.Fatal(err)
+	}
+	err := doc.Save(paths, before)
+	var verr *ValidationError
+	if !errors.As(err, &verr) || len(verr.Problems) != 1 || verr.Problems[0].Path != "sglang.tp_size" {
+		t.Errorf("Expected sglang.tp_size problem, got %v", err)
+	}
+}
+
+func TestAffectedServices(t *testing.T) {
+	paths := NewPaths(t.TempDir(), t.TempDir())
+	old := NewDefaultConfig(paths)
+
+	changed := *old
+	changed.LLMBaseURL = "http://other:8000/v1"
+	changed.EnableProxyAgent = true
+
+	services, err := AffectedServices(old, &changed)
+	if err != nil {
+		t.Fatalf("AffectedServices failed: %v", err)
+	}
+	if strings.Join(services, ",") != "backend,silo-proxy-agent,deep-research" {
+		t.Errorf("Expected backend, silo-proxy-agent and deep-research, got %v", services)
+	}
+
+	if services, _ := AffectedServices(old, old); len(services) != 0 {
+		t.Errorf("Expected no affected services, got %v", services)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	paths := NewPaths(t.TempDir(), t.TempDir())
+	defaults := NewDefaultConfig(paths)
+
+	cfg := *defaults
+	cfg.Port = 8081
+	cfg.SGLang.TPSize = 2
+
+	changes, err := Diff(defaults, &cfg)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	expected := []Change{
+		{Key: "port", Old: "80", New: "8081"},
+		{Key: "sglang.tp_size", Old: "1", New: "2"},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected[i], changes[i])
+		}
+	}
+}
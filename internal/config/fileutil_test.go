@@ -0,0 +1,25 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+func TestExpandPath(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CACHE_ROOT", "/cache")
+
+	for path, want := range map[string]string{
+		"~":                    home,
+		"~/.cache/huggingface": home + "/.cache/huggingface",
+		"$CACHE_ROOT/hf":       "/cache/hf",
+		"/data/~model":         "/data/~model",
+	} {
+		if got := ExpandPath(path); got != want {
+			t.Errorf("ExpandPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
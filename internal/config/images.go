@@ -0,0 +1,75 @@
+package config
+
+// Images of the docker-compose services
+const (
+	PostgresImage      = "pgvector/pgvector:pg17"
+	BackendRepository  = "eternis/silo-box-backend"
+	FrontendRepository = "eternis/silo-box-frontend"
+	ProxyAgentImage    = "eternis/silo-proxy-agent"
+)
+
+// Services returns the docker-compose services enabled by the config, in
+// the order they appear in docker-compose.yml
+func (c *Config) Services() []string {
+	services := []string{"postgres", "backend", "frontend"}
+	if c.EnableProxyAgent {
+		services = append(services, "silo-proxy-agent")
+	}
+	if c.EnableDeepResearch {
+		services = append(services, "deep-research")
+	}
+	return services
+}
+
+// ImageRef returns the image reference of a docker-compose service as
+// configured, before pinning and registry rewrites
+func (c *Config) ImageRef(service string) string {
+	switch service {
+	case "postgres":
+		return PostgresImage
+	case "backend":
+		return BackendRepository + ":" + c.ImageTag
+	case "frontend":
+		return FrontendRepository + ":" + c.ImageTag
+	case "silo-proxy-agent":
+		return ProxyAgentImage
+	case "deep-research":
+		return c.DeepResearchImage
+	}
+	return ""
+}
+
+// ServiceImage returns the image a docker-compose service runs
+func (c *Config) ServiceImage(service string) string {
+	return c.Image(c.ImageRef(service))
+}
+
+// Image returns the reference to run for ref: pinned to its recorded
+// digest if any, and moved to the configured registry mirror
+func (c *Config) Image(ref string) string {
+	if digest, ok := c.ImageDigests[ref]; ok {
+		ref = digest
+	}
+	return c.Registry.Rewrite(ref)
+}
+
+// ImageRefs returns the references of every image the config runs: the
+// enabled docker-compose services and the inference engines
+func (c *Config) ImageRefs() []string {
+	var refs []string
+	seen := make(map[string]bool)
+	for _, service := range c.Services() {
+		ref := c.ImageRef(service)
+		if !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+	for _, container := range c.InferenceContainers() {
+		if container.Image != "" && !seen[container.Image] {
+			seen[container.Image] = true
+			refs = append(refs, container.Image)
+		}
+	}
+	return refs
+}
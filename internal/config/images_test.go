@@ -0,0 +1,101 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestServiceImage(t *testing.T) {
+	paths := NewPaths(t.TempDir(), t.TempDir())
+	cfg := NewDefaultConfig(paths)
+	cfg.ImageDigests = map[string]string{
+		PostgresImage: "pgvector/pgvector@sha256:abc",
+	}
+
+	if got := cfg.ServiceImage("postgres"); got != "pgvector/pgvector@sha256:abc" {
+		t.Errorf("Expected postgres to use pinned image, got %s", got)
+	}
+	if got := cfg.ServiceImage("backend"); got != BackendRepository+":"+cfg.ImageTag {
+		t.Errorf("Expected unpinned backend image, got %s", got)
+	}
+
+	cfg.Registry.Mirrors = map[string]string{
+		"docker.io": "mirror.local:5000/hub",
+		"ghcr.io":   "mirror.local:5000/ghcr",
+	}
+	tests := map[string]string{
+		"postgres":      "mirror.local:5000/hub/pgvector/pgvector@sha256:abc",
+		"backend":       "mirror.local:5000/hub/eternis/silo-box-backend:" + cfg.ImageTag,
+		"deep-research": "mirror.local:5000/ghcr/eternisai/deep_research:sha-2e9f2ef",
+	}
+	for service, want := range tests {
+		if got := cfg.ServiceImage(service); got != want {
+			t.Errorf("ServiceImage(%q) = %q, want %q", service, got, want)
+		}
+	}
+
+	composeFile := filepath.Join(t.TempDir(), "docker-compose.yml")
+	if err := GenerateDockerCompose(cfg, composeFile); err != nil {
+		t.Fatalf("GenerateDockerCompose failed: %v", err)
+	}
+	data, err := os.ReadFile(composeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "image: "+yamlQuote(tests["postgres"])) {
+		t.Errorf("Expected rendered compose to use %s:\n%s", tests["postgres"], data)
+	}
+}
+
+func TestImageRefs(t *testing.T) {
+	paths := NewPaths(t.TempDir(), t.TempDir())
+	cfg := NewDefaultConfig(paths)
+	cfg.ImageDigests = map[string]string{
+		PostgresImage: "pgvector/pgvector@sha256:abc",
+	}
+	cfg.Registry.Mirrors = map[string]string{"docker.io": "mirror.local"}
+
+	refs := cfg.ImageRefs()
+	if refs[0] != PostgresImage {
+		t.Errorf("Expected ImageRefs to return unpinned references, got %v", refs)
+	}
+	if refs[len(refs)-1] != cfg.SGLang.Image {
+		t.Errorf("Expected inference image last, got %v", refs)
+	}
+	for _, ref := range refs {
+		if ref == ProxyAgentImage {
+			t.Errorf("Expected disabled proxy agent to be left out, got %v", refs)
+		}
+	}
+}
+
+func TestLoadOrDefault_ImageDigests(t *testing.T) {
+	paths := NewPaths(t.TempDir(), t.TempDir())
+	if err := os.MkdirAll(filepath.Dir(paths.ConfigFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := Save(paths.ConfigFile, NewDefaultConfig(paths)); err != nil {
+		t.Fatal(err)
+	}
+
+	state := &State{}
+	state.PinImage("pgvector/pgvector:pg17", "pgvector/pgvector@sha256:abc")
+	data, _ := json.Marshal(state)
+	if err := os.WriteFile(paths.StateFile, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadOrDefault(paths.ConfigFile, paths)
+	if err != nil {
+		t.Fatalf("LoadOrDefault failed: %v", err)
+	}
+	if got := cfg.Image("pgvector/pgvector:pg17"); got != "pgvector/pgvector@sha256:abc" {
+		t.Errorf("Expected pinned image, got %s", got)
+	}
+	if got := cfg.Image("eternis/silo-proxy-agent"); got != "eternis/silo-proxy-agent" {
+		t.Errorf("Expected unpinned image to be unchanged, got %s", got)
+	}
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ListInstances returns the names of all instances configured under the base
+// config directory. The default instance is always listed first.
+func ListInstances(configDir string) ([]string, error) {
+	if configDir == "" {
+		configDir = DefaultConfigDir()
+	}
+
+	instances := []string{DefaultInstance}
+
+	entries, err := os.ReadDir(filepath.Join(configDir, InstancesDirName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return instances, nil
+		}
+		return nil, fmt.Errorf("failed to read instances directory: %w", err)
+	}
+
+	var named []string
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == DefaultInstance {
+			continue
+		}
+		if ValidateInstanceName(entry.Name()) != nil {
+			continue
+		}
+		named = append(named, entry.Name())
+	}
+	sort.Strings(named)
+
+	return append(instances, named...), nil
+}
+
+// HostPorts returns the host ports published by the config's services and
+// enabled inference engines.
+func HostPorts(cfg *Config) []int {
+	var ports []int
+	for _, p := range cfg.PublishedPorts() {
+		ports = append(ports, p.HostPort)
+	}
+	for _, container := range cfg.InferenceContainers() {
+		if container.Enabled {
+			ports = append(ports, container.Port)
+		}
+	}
+	return ports
+}
+
+// ReservedPorts returns the host ports used by every instance other than the
+// one at paths, mapped to the instance that uses them.
+func ReservedPorts(paths *Paths) (map[int]string, error) {
+	instances, err := ListInstances(paths.RootConfigDir)
+	if err != nil {
+		return nil, err
+	}
+
+	reserved := make(map[int]string)
+	for _, name := range instances {
+		if name == paths.Instance {
+			continue
+		}
+
+		other := NewInstancePaths(name, paths.RootConfigDir, paths.RootDataDir)
+		if _, err := os.Stat(other.ConfigFile); os.IsNotExist(err) {
+			continue
+		}
+
+		cfg, err := LoadOrDefault(other.ConfigFile, other)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load config of instance %s: %w", name, err)
+		}
+
+		for _, port := range HostPorts(cfg) {
+			reserved[port] = name
+		}
+	}
+
+	return reserved, nil
+}
+
+// AllocatePorts moves every host port of cfg that collides with a reserved
+// port to the next free one. Only the ports cfg publishes move: those of
+// the published services and the enabled inference engines. An empty LLM
+// base URL follows the inference port, see LLMURL. Deep research only moves
+// its host port; the port it listens on in the compose network stays the
+// same.
+func AllocatePorts(cfg *Config, reserved map[int]string) {
+	taken := make(map[int]bool, len(reserved))
+	for port := range reserved {
+		taken[port] = true
+	}
+
+	type hostPort struct {
+		key  string
+		port int
+	}
+	var ports []hostPort
+	for _, p := range cfg.PublishedPorts() {
+		ports = append(ports, hostPort{p.Key, p.HostPort})
+	}
+	for _, container := range cfg.InferenceContainers() {
+		if container.Enabled {
+			ports = append(ports, hostPort{container.Path + ".port", container.Port})
+		}
+	}
+
+	for _, p := range ports {
+		port := p.port
+		for taken[port] {
+			port++
+		}
+		taken[port] = true
+		if field := cfg.hostPortField(p.key); field != nil && port != p.port {
+			*field = port
+		}
+	}
+}
+
+// hostPortField returns the field of the host port at key, the Key of a
+// PublishedPort or the port of an InferenceContainer path, or nil if there
+// is none
+func (c *Config) hostPortField(key string) *int {
+	switch key {
+	case "port":
+		return &c.Port
+	case "backend_port":
+		return &c.BackendPort
+	case "postgres_port":
+		return &c.PostgresPort
+	case "deep_research_host_port":
+		return &c.DeepResearchHostPort
+	case BackendSGLang + ".port":
+		return &c.SGLang.Port
+	case BackendVLLM + ".port":
+		return &c.VLLM.Port
+	case BackendLlamaCpp + ".port":
+		return &c.LlamaCpp.Port
+	case BackendOllama + ".port":
+		return &c.Ollama.Port
+	}
+	var i int
+	if _, err := fmt.Sscanf(key, "models[%d].port", &i); err != nil || i < 0 || i >= len(c.Models) {
+		return nil
+	}
+	return &c.Models[i].Port
+}
+
+// inferenceBaseURL returns the LLM base URL of an inference engine published
+// on the given host port.
+func inferenceBaseURL(port int) string {
+	return fmt.Sprintf("http://host.docker.internal:%d/v1", port)
+}
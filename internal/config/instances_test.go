@@ -0,0 +1,144 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNewInstancePaths(t *testing.T) {
+	tmpDir := t.TempDir()
+	configDir := filepath.Join(tmpDir, "config")
+	dataDir := filepath.Join(tmpDir, "data")
+
+	def := NewInstancePaths(DefaultInstance, configDir, dataDir)
+	if def.ConfigFile != filepath.Join(configDir, ConfigFileName) {
+		t.Errorf("Expected default instance config in base dir, got %s", def.ConfigFile)
+	}
+	if def.SocketFile != filepath.Join(dataDir, "silod.sock") {
+		t.Errorf("Expected default instance socket in base dir, got %s", def.SocketFile)
+	}
+
+	staging := NewInstancePaths("staging", configDir, dataDir)
+	if staging.ConfigFile != filepath.Join(configDir, InstancesDirName, "staging", ConfigFileName) {
+		t.Errorf("Unexpected staging config file: %s", staging.ConfigFile)
+	}
+	if staging.SocketFile == def.SocketFile {
+		t.Error("Expected instances to have separate sockets")
+	}
+	if staging.RootConfigDir != configDir || staging.RootDataDir != dataDir {
+		t.Errorf("Expected root dirs to be the base dirs, got %s and %s", staging.RootConfigDir, staging.RootDataDir)
+	}
+}
+
+func TestValidateInstanceName(t *testing.T) {
+	for _, name := range []string{"default", "staging", "team-a", "team_2"} {
+		if err := ValidateInstanceName(name); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "Staging", "-a", "a/b", "a b"} {
+		if err := ValidateInstanceName(name); err == nil {
+			t.Errorf("Expected %q to be invalid", name)
+		}
+	}
+}
+
+func TestListInstances(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, name := range []string{"prod", "staging", "Invalid"} {
+		if err := os.MkdirAll(filepath.Join(tmpDir, InstancesDirName, name), 0755); err != nil {
+			t.Fatalf("Failed to create instance dir: %v", err)
+		}
+	}
+
+	instances, err := ListInstances(tmpDir)
+	if err != nil {
+		t.Fatalf("ListInstances failed: %v", err)
+	}
+
+	expected := []string{DefaultInstance, "prod", "staging"}
+	if !reflect.DeepEqual(instances, expected) {
+		t.Errorf("Expected %v, got %v", expected, instances)
+	}
+}
+
+func TestNewDefaultConfig_NamedInstance(t *testing.T) {
+	paths := NewInstancePaths("staging", t.TempDir(), t.TempDir())
+	cfg := NewDefaultConfig(paths)
+
+	if cfg.SGLang.ContainerName != DefaultInferenceContainerName+"-staging" {
+		t.Errorf("Unexpected inference container name: %s", cfg.SGLang.ContainerName)
+	}
+	if cfg.ProjectName() != "silo-staging" {
+		t.Errorf("Unexpected project name: %s", cfg.ProjectName())
+	}
+
+	def := NewDefaultConfig(NewPaths(t.TempDir(), t.TempDir()))
+	if def.ProjectName() != "" {
+		t.Errorf("Expected empty project name for default instance, got %s", def.ProjectName())
+	}
+}
+
+func TestAllocatePorts(t *testing.T) {
+	configDir := t.TempDir()
+	dataDir := t.TempDir()
+
+	// Install the default instance with default ports
+	defPaths := NewPaths(configDir, dataDir)
+	if err := os.MkdirAll(defPaths.ConfigDir, 0755); err != nil {
+		t.Fatalf("Failed to create config dir: %v", err)
+	}
+	defCfg := NewDefaultConfig(defPaths)
+	defCfg.SGLang.Enabled = true
+	if err := Save(defPaths.ConfigFile, defCfg); err != nil {
+		t.Fatalf("Failed to save default config: %v", err)
+	}
+
+	paths := NewInstancePaths("staging", configDir, dataDir)
+	if err := os.MkdirAll(paths.ConfigDir, 0755); err != nil {
+		t.Fatalf("Failed to create instance dir: %v", err)
+	}
+
+	reserved, err := ReservedPorts(paths)
+	if err != nil {
+		t.Fatalf("ReservedPorts failed: %v", err)
+	}
+	if reserved[DefaultPort] != DefaultInstance {
+		t.Errorf("Expected port %d to be reserved by the default instance", DefaultPort)
+	}
+
+	if _, ok := reserved[DefaultVLLMPort]; ok {
+		t.Errorf("Expected the port of the unused vLLM backend not to be reserved")
+	}
+
+	cfg := NewDefaultConfig(paths)
+	cfg.SGLang.Enabled = true
+	AllocatePorts(cfg, reserved)
+
+	seen := make(map[int]bool)
+	for _, port := range HostPorts(cfg) {
+		if _, ok := reserved[port]; ok {
+			t.Errorf("Port %d collides with another instance", port)
+		}
+		if seen[port] {
+			t.Errorf("Port %d allocated twice", port)
+		}
+		seen[port] = true
+	}
+
+	if cfg.LLMURL() != inferenceBaseURL(cfg.SGLang.Port) {
+		t.Errorf("Expected LLM base URL to follow inference port, got %s", cfg.LLMURL())
+	}
+
+	// Ports the instance does not publish stay put
+	if cfg.PostgresPort != DefaultPostgresPort || cfg.VLLM.Port != DefaultVLLMPort {
+		t.Errorf("Expected unpublished ports to stay, got postgres %d, vllm %d", cfg.PostgresPort, cfg.VLLM.Port)
+	}
+
+	// Deep research keeps listening on its port inside the compose network
+	if cfg.DeepResearchPort != DefaultDeepResearchPort || cfg.DeepResearchHostPort == DefaultDeepResearchPort {
+		t.Errorf("Expected only the deep research host port to move, got %d:%d", cfg.DeepResearchHostPort, cfg.DeepResearchPort)
+	}
+}
@@ -0,0 +1,199 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DropInDirName is the directory next to config.yml holding config fragments.
+// Each *.yml file in it is merged over config.yml in lexical order, so site
+// settings can be shipped separately from the base file.
+const DropInDirName = "config.d"
+
+// DropInFiles returns the config fragments in dir in the order they are
+// applied. Hidden files, such as editor backups, are skipped.
+func DropInFiles(dir string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.yml"))
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, m := range matches {
+		if !strings.HasPrefix(filepath.Base(m), ".") {
+			files = append(files, m)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// sources records which layer each value of a loaded config came from and
+// what config.yml held, so Save writes only changes to config.yml
+type sources struct {
+	// file maps the dotted keys set by config.yml or a drop-in to its name
+	file      map[string]string
+	overrides []Override
+
+	// base is config.yml as loaded and loaded the flattened values of the
+	// config right after loading
+	base   []byte
+	loaded map[string]string
+
+	// migrate is set when config.yml was migrated in memory only
+	migrate bool
+}
+
+// loadData decodes config file contents over the defaults of paths, then
+// merges the config.d drop-ins and applies the environment and flag overrides
+func loadData(data []byte, paths *Paths) (*Config, error) {
+	doc, err := ParseDocument(paths.ConfigFile, data)
+	if err != nil {
+		return nil, err
+	}
+	src := &sources{file: make(map[string]string), base: data}
+	leafKeys(doc.doc.Content[0], "", string(SourceFile), src.file)
+
+	files, err := DropInFiles(paths.ConfigDropInDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		fragment, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config fragment: %w", err)
+		}
+		layer, err := parseDocument(fragment)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		root := layer.Content[0]
+		removeNulls(root)
+		removeKey(root, "schema_version")
+		leafKeys(root, "", filepath.Join(DropInDirName, filepath.Base(file)), src.file)
+		mergeNodes(doc.doc.Content[0], root)
+	}
+
+	if src.overrides = ActiveOverrides(); len(src.overrides) > 0 {
+		if err := doc.applyOverrides(src.overrides); err != nil {
+			return nil, fmt.Errorf("invalid config override %w", err)
+		}
+	}
+
+	layered, err := doc.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := mergeConfig(layered, NewDefaultConfig(paths))
+	if err != nil {
+		return nil, err
+	}
+	if src.loaded, err = flatten(cfg); err != nil {
+		return nil, err
+	}
+	cfg.sources = src
+
+	if state, err := LoadState(paths.StateFile); err == nil {
+		cfg.ImageDigests = state.Images
+	}
+	return cfg, nil
+}
+
+// mergeNodes deep-merges the mapping src into dst: nested mappings are merged
+// key by key, other values in src replace those in dst
+func mergeNodes(dst, src *yaml.Node) {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		j := mappingIndex(dst, key.Value)
+		if j < 0 {
+			dst.Content = append(dst.Content, key, value)
+			continue
+		}
+		if existing := dst.Content[j+1]; existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode {
+			mergeNodes(existing, value)
+			continue
+		}
+		dst.Content[j+1] = value
+	}
+}
+
+// FileConfig returns c as config.yml would hold it after Save: values that
+// come from config.d drop-ins or overrides and were not changed since loading
+// are replaced by the defaults of paths
+func FileConfig(c *Config, paths *Paths) (*Config, error) {
+	if c.sources == nil {
+		return c, nil
+	}
+	data, err := c.layeredYAML()
+	if err != nil {
+		return nil, err
+	}
+	return mergeConfig(data, NewDefaultConfig(paths))
+}
+
+// layeredYAML returns the config.yml contents to save for a config loaded
+// with its layers. Starting from config.yml as loaded, it sets the values
+// changed since loading and the values still at their default, and removes
+// map entries that were deleted. Values that come from drop-ins or overrides
+// and were not changed are left out, so they are never flattened into
+// config.yml.
+func (c *Config) layeredYAML() ([]byte, error) {
+	current, err := flatten(c)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := ParseDocument("", c.sources.base)
+	if err != nil {
+		return nil, err
+	}
+	effective, err := ConfigYAML(c)
+	if err != nil {
+		return nil, err
+	}
+	values, err := ParseDocument("", effective)
+	if err != nil {
+		return nil, err
+	}
+
+	// Walk the keys in struct order so added keys follow the usual layout
+	var keys []string
+	var walk func(node *yaml.Node, prefix string)
+	walk = func(node *yaml.Node, prefix string) {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			if node.Content[i+1].Kind == yaml.MappingNode {
+				walk(node.Content[i+1], key)
+			} else {
+				keys = append(keys, key)
+			}
+		}
+	}
+	walk(values.doc.Content[0], "")
+
+	for _, key := range keys {
+		changed := current[key] != c.sources.loaded[key]
+		if !changed && c.ValueSource(key) != string(SourceDefault) {
+			continue
+		}
+		if node, ok := values.Get(key); ok {
+			if err := doc.setNode(key, node); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for key := range c.sources.loaded {
+		if _, ok := current[key]; !ok {
+			if _, err := doc.Unset(key); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return doc.Bytes()
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeDropIn(t *testing.T, paths *Paths, name, data string) {
+	t.Helper()
+	if err := os.MkdirAll(paths.ConfigDropInDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(paths.ConfigDropInDir, name), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadOrDefault_DropIns(t *testing.T) {
+	paths := NewPaths(t.TempDir(), t.TempDir())
+	writeConfig(t, paths, "port: 8081\nsglang:\n  tp_size: 4\n  dp_size: 1\n")
+	writeDropIn(t, paths, "20-site.yml", "sglang:\n  tp_size: 2\nregistry:\n  mirrors:\n    ghcr.io: mirror.local/ghcr\n")
+	writeDropIn(t, paths, "10-gpus.yml", "sglang:\n  tp_size: 8\n  gpu_devices: [\"4\", \"5\"]\nregistry:\n  mirrors:\n    docker.io: mirror.local/hub\n")
+	writeDropIn(t, paths, ".30-backup.yml", "port: 1\n")
+	writeDropIn(t, paths, "README", "not yaml: [")
+
+	cfg, err := LoadOrDefault(paths.ConfigFile, paths)
+	if err != nil {
+		t.Fatalf("LoadOrDefault failed: %v", err)
+	}
+	if cfg.Port != 8081 {
+		t.Errorf("Expected port from config.yml, got %d", cfg.Port)
+	}
+	if cfg.SGLang.TPSize != 2 {
+		t.Errorf("Expected the last drop-in to win, got tp_size %d", cfg.SGLang.TPSize)
+	}
+	if cfg.SGLang.DPSize != 1 || strings.Join(cfg.SGLang.GPUDevices, ",") != "4,5" {
+		t.Errorf("Expected sglang to be deep-merged, got dp_size %d gpu_devices %v", cfg.SGLang.DPSize, cfg.SGLang.GPUDevices)
+	}
+	if len(cfg.Registry.Mirrors) != 2 {
+		t.Errorf("Expected mirrors from both drop-ins, got %v", cfg.Registry.Mirrors)
+	}
+
+	sources := map[string]string{
+		"port":                       "file",
+		"sglang.dp_size":             "file",
+		"sglang.tp_size":             "config.d/20-site.yml",
+		"sglang.gpu_devices":         "config.d/10-gpus.yml",
+		"registry.mirrors.docker.io": "config.d/10-gpus.yml",
+	}
+	for key, want := range sources {
+		if got := cfg.ValueSource(key); got != want {
+			t.Errorf("ValueSource(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestSave_DoesNotFlattenDropIns(t *testing.T) {
+	paths := NewPaths(t.TempDir(), t.TempDir())
+	writeConfig(t, paths, "# base settings\nport: 8081\nsglang:\n  tp_size: 4\n")
+	writeDropIn(t, paths, "10-site.yml", "sglang:\n  gpu_devices: [\"4\"]\nregistry:\n  mirrors:\n    docker.io: mirror.local/hub\n")
+
+	cfg, err := LoadOrDefault(paths.ConfigFile, paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.ImageTag = "0.2.0"
+	if err := Save(paths.ConfigFile, cfg); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	data, err := os.ReadFile(paths.ConfigFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, unwanted := range []string{"mirror.local", `- "4"`} {
+		if strings.Contains(string(data), unwanted) {
+			t.Errorf("Expected drop-in value %q to stay out of config.yml:\n%s", unwanted, data)
+		}
+	}
+	for _, wanted := range []string{"# base settings", "image_tag: 0.2.0", "tp_size: 4"} {
+		if !strings.Contains(string(data), wanted) {
+			t.Errorf("Expected config.yml to contain %q:\n%s", wanted, data)
+		}
+	}
+
+	reloaded, err := LoadOrDefault(paths.ConfigFile, paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(reloaded.SGLang.GPUDevices, ",") != "4" || reloaded.ImageTag != "0.2.0" {
+		t.Errorf("Expected the saved config to load the same, got %v %s", reloaded.SGLang.GPUDevices, reloaded.ImageTag)
+	}
+}
+
+func TestFileConfig(t *testing.T) {
+	paths := NewPaths(t.TempDir(), t.TempDir())
+	writeDropIn(t, paths, "10-site.yml", "registry:\n  mirrors:\n    docker.io: mirror.local/hub\n")
+	t.Setenv("SILO_SGLANG_TP_SIZE", "2")
+
+	cfg, err := LoadOrDefault(paths.ConfigFile, paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Port = 8081
+
+	file, err := FileConfig(cfg, paths)
+	if err != nil {
+		t.Fatalf("FileConfig failed: %v", err)
+	}
+	if file.Port != 8081 {
+		t.Errorf("Expected the changed port, got %d", file.Port)
+	}
+	if len(file.Registry.Mirrors) != 0 || file.SGLang.TPSize != 1 {
+		t.Errorf("Expected drop-in and override values to be left out, got mirrors %v tp_size %d", file.Registry.Mirrors, file.SGLang.TPSize)
+	}
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAcquireLock(t *testing.T) {
+	paths := NewPaths(t.TempDir(), t.TempDir())
+
+	lock, err := AcquireLock(paths, "upgrade")
+	if err != nil {
+		t.Fatalf("AcquireLock failed: %v", err)
+	}
+
+	_, err = AcquireLock(paths, "inference up")
+	var locked *LockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("Expected *LockedError while the lock is held, got %v", err)
+	}
+	if locked.Info.PID != os.Getpid() || locked.Info.Operation != "upgrade" {
+		t.Errorf("Expected holder to be this process running upgrade, got %+v", locked.Info)
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+
+	lock, err = AcquireLock(paths, "down")
+	if err != nil {
+		t.Fatalf("Expected lock to be free after release, got %v", err)
+	}
+	lock.Release()
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+
+	if err := writeFileAtomic(path, []byte("one"), 0600); err != nil {
+		t.Fatalf("writeFileAtomic failed: %v", err)
+	}
+	if err := writeFileAtomic(path, []byte("two"), 0600); err != nil {
+		t.Fatalf("writeFileAtomic failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "two" {
+		t.Errorf("Expected file to hold the last write, got %q, %v", data, err)
+	}
+	info, _ := os.Stat(path)
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600, got %v", info.Mode().Perm())
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("Expected no temporary files to remain, got %d entries", len(entries))
+	}
+}
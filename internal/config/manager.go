@@ -6,16 +6,20 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"strings"
 
+	"github.com/eternisai/silo/internal/registry"
 	"gopkg.in/yaml.v3"
 )
 
 const (
-	DefaultVersion    = "0.1.8"
-	DefaultImageTag   = "0.1.9"
-	DefaultPort       = 80
-	DefaultLLMBaseURL = "http://host.docker.internal:30000/v1"
-	DefaultModel      = "glm47-awq"
+	DefaultVersion      = "0.1.8"
+	DefaultImageTag     = "0.1.9"
+	DefaultPort         = 80
+	DefaultBackendPort  = 8080
+	DefaultPostgresPort = 5432
+	DefaultModel        = "glm47-awq"
+	DefaultModelsDir    = "/root/data/models"
 
 	// Service toggles
 	DefaultEnableProxyAgent   = false
@@ -25,57 +29,60 @@ const (
 	DefaultProxyServerURL = "ballast.proxy.rlwy.net:16587"
 
 	// Deep research defaults
-	DefaultDeepResearchImage    = "ghcr.io/eternisai/deep_research:sha-2e9f2ef"
-	DefaultDeepResearchPort     = 3031
-	DefaultSearchProvider       = "perplexity"
-	DefaultPerplexityAPIKey     = ""
+	DefaultDeepResearchImage = "ghcr.io/eternisai/deep_research:sha-2e9f2ef"
+	DefaultDeepResearchPort  = 3031
+	DefaultSearchProvider    = "perplexity"
+
+	// DefaultInferenceContainerName is the SGLang container name of the
+	// default instance; named instances append their name to it.
+	DefaultInferenceContainerName = "silo-inference"
+	DefaultInferencePort          = 30000
 )
 
 // SGLangConfig holds configuration for the SGLang inference engine
 type SGLangConfig struct {
-	// Container settings
-	Enabled         bool     `yaml:"enabled" json:"enabled"`
-	Image           string   `yaml:"image" json:"image"`
-	ContainerName   string   `yaml:"container_name" json:"container_name"`
-	Port            int      `yaml:"port" json:"port"`
-	GPUDevices      []string `yaml:"gpu_devices" json:"gpu_devices"`
-	ShmSize         string   `yaml:"shm_size" json:"shm_size"`
-	ModelPath       string   `yaml:"model_path" json:"model_path"`
-	HuggingFaceCache string  `yaml:"huggingface_cache" json:"huggingface_cache"`
+	ContainerSettings `yaml:",inline"`
+
+	ShmSize          string `yaml:"shm_size" json:"shm_size" desc:"Shared memory size, e.g. 64g"`
+	ModelPath        string `yaml:"model_path" json:"model_path" desc:"Host directory with the model files" nonempty:"true"`
+	HuggingFaceCache string `yaml:"huggingface_cache" json:"huggingface_cache" desc:"Host directory of the Hugging Face cache"`
 
 	// Parallelism
-	DPSize int `yaml:"dp_size" json:"dp_size"`
-	TPSize int `yaml:"tp_size" json:"tp_size"`
+	DPSize int `yaml:"dp_size" json:"dp_size" desc:"Data parallel size" min:"1"`
+	TPSize int `yaml:"tp_size" json:"tp_size" desc:"Tensor parallel size" min:"1"`
 
 	// Request limits
-	MaxRunningRequests int `yaml:"max_running_requests" json:"max_running_requests"`
-	MaxTotalTokens     int `yaml:"max_total_tokens" json:"max_total_tokens"`
-	ContextLength      int `yaml:"context_length" json:"context_length"`
+	MaxRunningRequests int `yaml:"max_running_requests" json:"max_running_requests" desc:"Maximum number of concurrent requests" min:"1"`
+	MaxTotalTokens     int `yaml:"max_total_tokens" json:"max_total_tokens" desc:"Maximum tokens in the KV cache pool" min:"1"`
+	ContextLength      int `yaml:"context_length" json:"context_length" desc:"Model context length in tokens" min:"1"`
 
 	// Memory settings
-	MemFractionStatic   float64 `yaml:"mem_fraction_static" json:"mem_fraction_static"`
-	ChunkedPrefillSize  int     `yaml:"chunked_prefill_size" json:"chunked_prefill_size"`
+	MemFractionStatic  float64 `yaml:"mem_fraction_static" json:"mem_fraction_static" desc:"Fraction of GPU memory for weights and KV cache" min:"0" max:"1"`
+	ChunkedPrefillSize int     `yaml:"chunked_prefill_size" json:"chunked_prefill_size" desc:"Prefill chunk size in tokens, -1 disables chunking" min:"-1"`
 
 	// Scheduling and caching
-	SchedulePolicy    string `yaml:"schedule_policy" json:"schedule_policy"`
-	KVCacheDtype      string `yaml:"kv_cache_dtype" json:"kv_cache_dtype"`
-	AttentionBackend  string `yaml:"attention_backend" json:"attention_backend"`
-	DisableRadixCache bool   `yaml:"disable_radix_cache" json:"disable_radix_cache"`
+	SchedulePolicy    string `yaml:"schedule_policy" json:"schedule_policy" desc:"Request scheduling policy" enum:"lpm,random,fcfs,dfs-weight,lof,priority"`
+	KVCacheDtype      string `yaml:"kv_cache_dtype" json:"kv_cache_dtype" desc:"KV cache data type" enum:"auto,fp8_e5m2,fp8_e4m3,bf16,bfloat16"`
+	AttentionBackend  string `yaml:"attention_backend" json:"attention_backend" desc:"Attention kernel backend, e.g. flashinfer or triton"`
+	DisableRadixCache bool   `yaml:"disable_radix_cache" json:"disable_radix_cache" desc:"Disable prefix caching"`
 
 	// Model settings
-	ReasoningParser string `yaml:"reasoning_parser" json:"reasoning_parser"`
-	TrustRemoteCode bool   `yaml:"trust_remote_code" json:"trust_remote_code"`
-	LogLevel        string `yaml:"log_level" json:"log_level"`
+	ReasoningParser string `yaml:"reasoning_parser" json:"reasoning_parser" desc:"Parser for model reasoning output"`
+	ToolCallParser  string `yaml:"tool_call_parser" json:"tool_call_parser" desc:"Parser for model tool calls"`
+	TrustRemoteCode bool   `yaml:"trust_remote_code" json:"trust_remote_code" desc:"Allow custom model code from the model repository"`
+	LogLevel        string `yaml:"log_level" json:"log_level" desc:"SGLang log level" enum:"debug,info,warning,error,critical"`
 }
 
 // DefaultSGLangConfig returns default SGLang configuration
 func DefaultSGLangConfig() SGLangConfig {
 	return SGLangConfig{
-		Enabled:            false,
-		Image:              "lmsysorg/sglang:latest",
-		ContainerName:      "silo-inference",
-		Port:               30000,
-		GPUDevices:         []string{"0", "1", "2"},
+		ContainerSettings: ContainerSettings{
+			Enabled:       false,
+			Image:         "lmsysorg/sglang:latest",
+			ContainerName: DefaultInferenceContainerName,
+			Port:          DefaultInferencePort,
+			GPUDevices:    []string{"0", "1", "2"},
+		},
 		ShmSize:            "64g",
 		ModelPath:          "/root/data/AWQ",
 		HuggingFaceCache:   "~/.cache/huggingface",
@@ -91,57 +98,132 @@ func DefaultSGLangConfig() SGLangConfig {
 		AttentionBackend:   "flashinfer",
 		DisableRadixCache:  true,
 		ReasoningParser:    "glm45",
+		ToolCallParser:     "glm",
 		TrustRemoteCode:    true,
 		LogLevel:           "info",
 	}
 }
 
 type Config struct {
-	Version      string `yaml:"version"`
-	ImageTag     string `yaml:"image_tag"`
-	Port         int    `yaml:"port"`
-	LLMBaseURL   string `yaml:"llm_base_url"`
-	DefaultModel string `yaml:"default_model"`
+	// SchemaVersion is the config file layout version, see Migrate
+	SchemaVersion int `yaml:"schema_version" desc:"Config file layout version" min:"0"`
+
+	Version      string `yaml:"version" desc:"Silo release the config was written for"`
+	ImageTag     string `yaml:"image_tag" desc:"Backend and frontend image tag" nonempty:"true"`
+	Port         int    `yaml:"port" desc:"Frontend host port" min:"1" max:"65535"`
+	BackendPort  int    `yaml:"backend_port" desc:"Backend host port" min:"1" max:"65535"`
+	PostgresPort int    `yaml:"postgres_port" desc:"PostgreSQL host port, when services.postgres.publish is set" min:"1" max:"65535"`
+	LLMBaseURL   string `yaml:"llm_base_url" desc:"OpenAI compatible API the backend sends requests to, the inference backend when empty"`
+	DefaultModel string `yaml:"default_model" desc:"Model name requested from the LLM API" nonempty:"true"`
 	ConfigFile   string `yaml:"-"`
 	DataDir      string `yaml:"-"`
 	SocketFile   string `yaml:"-"`
+	Instance     string `yaml:"-"`
+
+	// SecretsFile holds the generated credentials and EnvDir the env files
+	// rendered from them for docker-compose.yml, see EnsureSecrets
+	SecretsFile string `yaml:"-"`
+	EnvDir      string `yaml:"-"`
+
+	// ComposeOverrideFile is the user's docker-compose.override.yml
+	ComposeOverrideFile string `yaml:"-"`
+
+	// sources records where loaded values came from, see ValueSource
+	sources *sources
+
+	// ImageDigests maps image references to the digest references they
+	// were pinned to at install or upgrade, as recorded in state.json
+	ImageDigests map[string]string `yaml:"-"`
 
 	// Service toggles
-	EnableProxyAgent   bool `yaml:"enable_proxy_agent"`
-	EnableDeepResearch bool `yaml:"enable_deep_research"`
+	EnableProxyAgent   bool `yaml:"enable_proxy_agent" desc:"Run the proxy agent for remote access"`
+	EnableDeepResearch bool `yaml:"enable_deep_research" desc:"Run the deep research service"`
 
 	// Proxy agent configuration
-	ProxyServerURL string `yaml:"proxy_server_url"`
+	ProxyServerURL string `yaml:"proxy_server_url" desc:"Proxy server the agent connects to"`
 
 	// Deep research configuration
-	DeepResearchImage string `yaml:"deep_research_image"`
-	DeepResearchPort  int    `yaml:"deep_research_port"`
-	SearchProvider    string `yaml:"search_provider"`
-	PerplexityAPIKey  string `yaml:"perplexity_api_key"`
-
-	// SGLang inference engine (managed separately from docker-compose)
-	SGLang SGLangConfig `yaml:"sglang"`
+	DeepResearchImage    string `yaml:"deep_research_image" desc:"Deep research Docker image" nonempty:"true"`
+	DeepResearchPort     int    `yaml:"deep_research_port" desc:"Port the deep research service listens on" min:"1" max:"65535"`
+	DeepResearchHostPort int    `yaml:"deep_research_host_port,omitempty" desc:"Deep research host port, deep_research_port when 0" min:"0" max:"65535"`
+	SearchProvider       string `yaml:"search_provider" desc:"Web search provider used by deep research"`
+
+	// Inference engines (managed separately from docker-compose); only the
+	// one selected by inference.backend runs
+	Inference InferenceConfig `yaml:"inference" desc:"Inference backend selection"`
+	SGLang    SGLangConfig    `yaml:"sglang" desc:"SGLang inference engine, managed separately from docker-compose"`
+	VLLM      VLLMConfig      `yaml:"vllm" desc:"vLLM inference engine, used when inference.backend is vllm"`
+	LlamaCpp  LlamaCppConfig  `yaml:"llamacpp" desc:"llama.cpp server for GGUF models on the CPU or GPUs, used when inference.backend is llamacpp"`
+	Ollama    OllamaConfig    `yaml:"ollama" desc:"Ollama inference engine, used when inference.backend is ollama"`
+
+	// Models downloaded by 'silo model pull'
+	ModelsDir string `yaml:"models_dir" desc:"Host directory 'silo model' manages, one subdirectory per model" nonempty:"true"`
+
+	// Models served side by side, each by its own inference container
+	Models []ModelConfig `yaml:"models,omitempty" desc:"Models served by their own inference containers, replacing the sglang container; entries inherit the sglang keys they leave out"`
+
+	// Registry mirrors, credentials and TLS settings for all images
+	Registry registry.Config `yaml:"registry" desc:"Registry mirrors, credentials and TLS settings for all images"`
+
+	// User additions to the generated docker-compose.yml
+	ServiceSettings map[string]ServiceConfig `yaml:"services" desc:"Environment, volumes, labels, networks, resource limits and restart policies of the built-in compose services"`
+	ExtraServices   map[string]any           `yaml:"extra_services" desc:"Additional compose services, in docker-compose syntax"`
 }
 
 type State struct {
-	Version              string `json:"version"`
-	InstalledAt          string `json:"installed_at"`
-	LastUpdated          string `json:"last_updated"`
-	InferenceWasRunning  bool   `json:"inference_was_running"`
+	Version             string `json:"version"`
+	InstalledAt         string `json:"installed_at"`
+	LastUpdated         string `json:"last_updated"`
+	InferenceWasRunning bool   `json:"inference_was_running"`
+
+	// Bundle is set when the installation was last installed or upgraded
+	// from an offline bundle
+	Bundle *BundleInfo `json:"bundle,omitempty"`
+
+	// Images maps each image reference to the digest reference it was
+	// resolved to, e.g. pgvector/pgvector:pg17 to pgvector/pgvector@sha256:...
+	Images map[string]string `json:"images,omitempty"`
 }
 
+// PinImage records the digest reference an image reference resolved to
+func (s *State) PinImage(ref, digest string) {
+	if s.Images == nil {
+		s.Images = make(map[string]string)
+	}
+	s.Images[ref] = digest
+}
+
+// BundleInfo describes the offline bundle an installation came from
+type BundleInfo struct {
+	CreatedAt  string `json:"created_at"`
+	ImportedAt string `json:"imported_at"`
+	CLIVersion string `json:"cli_version"`
+	ImageTag   string `json:"image_tag"`
+}
 
+// Offline reports whether the installation came from an offline bundle, in
+// which case registry and release checks are skipped.
+func (s *State) Offline() bool {
+	return s != nil && s.Bundle != nil
+}
 
 func NewDefaultConfig(paths *Paths) *Config {
-	return &Config{
-		Version:      DefaultVersion,
-		ImageTag:     DefaultImageTag,
-		Port:         DefaultPort,
-		LLMBaseURL:   DefaultLLMBaseURL,
-		DefaultModel: DefaultModel,
-		ConfigFile:   paths.ConfigFile,
-		DataDir:      paths.AppDataDir,
-		SocketFile:   paths.SocketFile,
+	cfg := &Config{
+		SchemaVersion: SchemaVersion,
+		Version:       DefaultVersion,
+		ImageTag:      DefaultImageTag,
+		Port:          DefaultPort,
+		BackendPort:   DefaultBackendPort,
+		PostgresPort:  DefaultPostgresPort,
+		DefaultModel:  DefaultModel,
+		ConfigFile:    paths.ConfigFile,
+		DataDir:       paths.AppDataDir,
+		SocketFile:    paths.SocketFile,
+		Instance:      paths.Instance,
+		SecretsFile:   paths.SecretsFile,
+		EnvDir:        paths.EnvDir,
+
+		ComposeOverrideFile: paths.ComposeOverrideFile,
 
 		// Service toggles
 		EnableProxyAgent:   DefaultEnableProxyAgent,
@@ -154,11 +236,36 @@ func NewDefaultConfig(paths *Paths) *Config {
 		DeepResearchImage: DefaultDeepResearchImage,
 		DeepResearchPort:  DefaultDeepResearchPort,
 		SearchProvider:    DefaultSearchProvider,
-		PerplexityAPIKey:  DefaultPerplexityAPIKey,
 
-		// SGLang inference engine
-		SGLang: DefaultSGLangConfig(),
+		// Inference engines
+		ModelsDir: DefaultModelsDir,
+		Inference: InferenceConfig{Backend: BackendSGLang},
+		SGLang:    DefaultSGLangConfig(),
+		VLLM:      DefaultVLLMConfig(),
+		LlamaCpp:  DefaultLlamaCppConfig(),
+		Ollama:    DefaultOllamaConfig(),
+	}
+
+	// Named instances get their own inference containers so they can run
+	// side by side with the default instance.
+	if paths.Instance != "" && paths.Instance != DefaultInstance {
+		cfg.SGLang.ContainerName = DefaultInferenceContainerName + "-" + paths.Instance
+		cfg.VLLM.ContainerName = DefaultVLLMContainerName + "-" + paths.Instance
+		cfg.LlamaCpp.ContainerName = DefaultLlamaCppContainerName + "-" + paths.Instance
+		cfg.Ollama.ContainerName = DefaultOllamaContainerName + "-" + paths.Instance
+	}
+
+	return cfg
+}
+
+// ProjectName returns the docker compose project name of the config's
+// instance. It is empty for the default instance, which keeps the project
+// name compose derives from the data directory.
+func (c *Config) ProjectName() string {
+	if c.Instance == "" || c.Instance == DefaultInstance {
+		return ""
 	}
+	return "silo-" + c.Instance
 }
 
 func Load(path string) (*Config, error) {
@@ -176,92 +283,102 @@ func Load(path string) (*Config, error) {
 }
 
 // LoadOrDefault loads config from file and fills missing fields with defaults.
-// If the file doesn't exist, returns a new config with all defaults.
-// If the file exists, preserves existing values and only fills in missing/zero fields.
+// If the file doesn't exist, the defaults are used.
+// If the file exists, pending schema migrations are applied to it in memory
+// first, then every key present in the file is kept, including explicit zero
+// values such as false, and only absent keys are filled in. SILO_*
+// environment variables and --set flags override the file, see
+// ActiveOverrides. Loading never writes; the migrations are persisted by
+// Migrate, or by Save before it writes the file.
 func LoadOrDefault(path string, paths *Paths) (*Config, error) {
-	existing, err := Load(path)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return loadData(nil, paths)
+	}
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return NewDefaultConfig(paths), nil
-		}
-		return nil, err
+		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	defaults := NewDefaultConfig(paths)
-	merged := mergeConfigs(existing, defaults)
-	merged.ConfigFile = paths.ConfigFile
-	merged.DataDir = paths.AppDataDir
-	merged.SocketFile = paths.SocketFile
+	migrated, result, err := migrateData(data, make(Secrets))
+	if err != nil {
+		return nil, err
+	}
 
-	return merged, nil
+	cfg, err := loadData(migrated, paths)
+	if err != nil {
+		return nil, err
+	}
+	cfg.sources.migrate = len(result.Applied) > 0
+	return cfg, nil
 }
 
-// mergeConfigs merges two configs, preferring non-zero values from existing config
-// and filling in zero values with defaults. Uses reflection to handle all fields.
-func mergeConfigs(existing, defaults *Config) *Config {
-	result := &Config{}
+// mergeConfig decodes the YAML document data over a copy of defaults. Only
+// keys present in the document are decoded, so absent keys keep their default
+// while explicit zero values are preserved. Keys set to null count as absent.
+func mergeConfig(data []byte, defaults *Config) (*Config, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
 
-	existingVal := reflect.ValueOf(existing).Elem()
-	defaultsVal := reflect.ValueOf(defaults).Elem()
-	resultVal := reflect.ValueOf(result).Elem()
+	merged := *defaults
+	if len(doc.Content) == 0 {
+		return &merged, nil
+	}
 
-	mergeStructFields(existingVal, defaultsVal, resultVal)
+	root := doc.Content[0]
+	removeNulls(root)
+	if err := root.Decode(&merged); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if err := decodeModels(root, &merged); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
 
-	return result
+	return &merged, nil
 }
 
-// mergeStructFields recursively merges struct fields
-func mergeStructFields(existing, defaults, result reflect.Value) {
-	for i := 0; i < existing.NumField(); i++ {
-		existingField := existing.Field(i)
-		defaultField := defaults.Field(i)
-		resultField := result.Field(i)
-
-		if !resultField.CanSet() {
-			continue
-		}
+// removeNulls drops mapping entries whose value is null, recursively
+func removeNulls(node *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
 
-		// For nested structs, merge fields recursively
-		if existingField.Kind() == reflect.Struct {
-			mergeStructFields(existingField, defaultField, resultField)
+	content := node.Content[:0]
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		value := node.Content[i+1]
+		if value.Kind == yaml.ScalarNode && value.Tag == "!!null" {
 			continue
 		}
-
-		if isZeroValue(existingField) {
-			resultField.Set(defaultField)
-		} else {
-			resultField.Set(existingField)
-		}
-	}
-}
-
-// isZeroValue checks if a reflect.Value is the zero value for its type
-func isZeroValue(v reflect.Value) bool {
-	switch v.Kind() {
-	case reflect.String:
-		return v.String() == ""
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return v.Int() == 0
-	case reflect.Bool:
-		return !v.Bool()
-	case reflect.Float32, reflect.Float64:
-		return v.Float() == 0
-	case reflect.Struct:
-		return v.IsZero()
-	case reflect.Slice, reflect.Map:
-		return v.IsNil() || v.Len() == 0
-	default:
-		return v.IsZero()
+		removeNulls(value)
+		content = append(content, node.Content[i], value)
 	}
+	node.Content = content
 }
 
+// Save writes config to path. For a loaded config, config.yml is rewritten
+// with only the values changed since loading and the defaults it lacks;
+// values from config.d drop-ins and environment or flag overrides stay out of
+// it unless they were changed.
 func Save(path string, config *Config) error {
 	data, err := yaml.Marshal(config)
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
+	if config.sources != nil {
+		if data, err = config.layeredYAML(); err != nil {
+			return err
+		}
+		// Keep the backup and the secrets the migrations move out of the file
+		if config.sources.migrate {
+			if _, err := Migrate(path); err != nil {
+				return err
+			}
+			config.sources.migrate = false
+		}
+	}
 
-	if err := os.WriteFile(path, data, 0644); err != nil {
+	if err := writeFileAtomic(path, data, 0644); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
@@ -301,29 +418,54 @@ func UpdateDeepResearchImage(cfg *Config, configPath string) (bool, error) {
 	return true, fmt.Errorf("updated from %s", oldImage)
 }
 
+// Validate checks config against the schema and the rules that span several
+// fields. It returns a *ValidationError listing every error; warnings are
+// left to ValidateConfig.
 func Validate(config *Config) error {
-	if config.ImageTag == "" {
-		return fmt.Errorf("image_tag cannot be empty")
+	problems, err := ValidateConfig(config)
+	if err != nil {
+		return err
+	}
+	if errs, _ := SplitProblems(problems); len(errs) > 0 {
+		return &ValidationError{Problems: errs}
 	}
-	if config.Port < 1 || config.Port > 65535 {
-		return fmt.Errorf("port must be between 1 and 65535")
+	return nil
+}
+
+// ValidateConfig returns every problem of config. Problems have no line and
+// column; use ValidateDocument to locate them in a file.
+func ValidateConfig(config *Config) ([]Problem, error) {
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
 	}
-	if config.LLMBaseURL == "" {
-		return fmt.Errorf("llm_base_url cannot be empty")
+	problems, err := ValidateDocument(data)
+	if err != nil {
+		return nil, err
 	}
-	if config.DefaultModel == "" {
-		return fmt.Errorf("default_model cannot be empty")
+	for i := range problems {
+		problems[i].Line, problems[i].Column = 0, 0
 	}
 
 	// Proxy agent validation (only when enabled)
-	if config.EnableProxyAgent {
-		if config.ProxyServerURL == "" {
-			return fmt.Errorf("proxy_server_url cannot be empty when proxy agent is enabled")
-		}
+	if config.EnableProxyAgent && config.ProxyServerURL == "" {
+		problems = append(problems, Problem{Path: "proxy_server_url", Message: "cannot be empty when proxy agent is enabled"})
 	}
 
+	problems = append(problems, config.semanticProblems()...)
+	problems = append(problems, config.modelProblems()...)
+	problems = append(problems, config.backendProblems()...)
+	problems = append(problems, config.composeProblems()...)
+	problems = append(problems, config.portProblems()...)
+	problems = append(problems, config.resourceProblems(DetectHost())...)
 
-	return nil
+	if err := config.Registry.Validate(); err != nil {
+		for _, msg := range strings.Split(err.Error(), "\n") {
+			problems = append(problems, Problem{Path: "registry", Message: msg})
+		}
+	}
+
+	return problems, nil
 }
 
 func LoadState(path string) (*State, error) {
@@ -346,46 +488,26 @@ func SaveState(path string, state *State) error {
 		return fmt.Errorf("failed to marshal state: %w", err)
 	}
 
-	if err := os.WriteFile(path, data, 0644); err != nil {
+	if err := writeFileAtomic(path, data, 0644); err != nil {
 		return fmt.Errorf("failed to write state file: %w", err)
 	}
 
 	return nil
 }
 
-// FindUnknownFields returns a list of field names in the config file that are not recognized.
-func FindUnknownFields(path string) ([]string, error) {
+// FindUnknownFields returns the keys in the config file that are not part of
+// the schema, at every nesting level, with their line and column
+func FindUnknownFields(path string) ([]Problem, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	// Parse YAML into a map to get all keys
-	var rawConfig map[string]interface{}
-	if err := yaml.Unmarshal(data, &rawConfig); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
-	}
-
-	// Get known fields from Config struct tags
-	knownFields := make(map[string]bool)
-	t := reflect.TypeOf(Config{})
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-		tag := field.Tag.Get("yaml")
-		if tag != "" && tag != "-" {
-			knownFields[tag] = true
-		}
-	}
-
-	// Find unknown fields
-	var unknown []string
-	for key := range rawConfig {
-		if !knownFields[key] {
-			unknown = append(unknown, key)
-		}
+	c, err := checkDocument(data)
+	if err != nil {
+		return nil, err
 	}
-
-	return unknown, nil
+	return c.unknown, nil
 }
 
 // FindMissingFields returns a list of field names that are missing from the config file
@@ -407,11 +529,12 @@ func FindMissingFields(path string) ([]string, error) {
 	var missing []string
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
-		tag := field.Tag.Get("yaml")
-		if tag != "" && tag != "-" {
-			if _, exists := rawConfig[tag]; !exists {
-				missing = append(missing, tag)
-			}
+		name, opts, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" || strings.Contains(opts, "omitempty") {
+			continue // optional keys are never missing
+		}
+		if _, exists := rawConfig[name]; !exists {
+			missing = append(missing, name)
 		}
 	}
 
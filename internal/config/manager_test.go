@@ -121,40 +121,53 @@ enable_deep_research: true
 	}
 }
 
-func TestMergeConfigs(t *testing.T) {
-	existing := &Config{
-		Version:  "0.1.1",
-		ImageTag: "0.1.1",
-		Port:     8080,
-		// Missing: LLMBaseURL, DefaultModel, etc.
-	}
+func TestMergeConfig(t *testing.T) {
+	paths := NewPaths(t.TempDir(), t.TempDir())
+	defaults := NewDefaultConfig(paths)
 
-	defaults := &Config{
-		Version:          DefaultVersion,
-		ImageTag:         DefaultImageTag,
-		Port:             DefaultPort,
-		LLMBaseURL:       DefaultLLMBaseURL,
-		DefaultModel:     DefaultModel,
-		EnableProxyAgent: DefaultEnableProxyAgent,
+	data := `version: "0.1.1"
+port: 8080
+llm_base_url:
+enable_deep_research: false
+sglang:
+  disable_radix_cache: false
+  gpu_devices: ["3"]
+`
+	merged, err := mergeConfig([]byte(data), defaults)
+	if err != nil {
+		t.Fatalf("mergeConfig failed: %v", err)
 	}
 
-	merged := mergeConfigs(existing, defaults)
-
-	// Existing non-zero values should be preserved
+	// Present values are preserved, including explicit zero values
 	if merged.Version != "0.1.1" {
 		t.Errorf("Expected merged.Version=0.1.1, got %s", merged.Version)
 	}
 	if merged.Port != 8080 {
 		t.Errorf("Expected merged.Port=8080, got %d", merged.Port)
 	}
+	if merged.EnableDeepResearch {
+		t.Error("Expected explicit enable_deep_research=false to be preserved")
+	}
+	if merged.SGLang.DisableRadixCache {
+		t.Error("Expected explicit sglang.disable_radix_cache=false to be preserved")
+	}
+	if len(merged.SGLang.GPUDevices) != 1 || merged.SGLang.GPUDevices[0] != "3" {
+		t.Errorf("Expected gpu_devices to be replaced, got %v", merged.SGLang.GPUDevices)
+	}
 
-	// Zero values should be filled from defaults
-	if merged.LLMBaseURL != DefaultLLMBaseURL {
-		t.Errorf("Expected merged.LLMBaseURL=%s, got %s", DefaultLLMBaseURL, merged.LLMBaseURL)
+	// Absent and null keys are filled from defaults
+	if merged.LLMBaseURL != "" {
+		t.Errorf("Expected merged.LLMBaseURL to be empty, got %s", merged.LLMBaseURL)
 	}
 	if merged.DefaultModel != DefaultModel {
 		t.Errorf("Expected merged.DefaultModel=%s, got %s", DefaultModel, merged.DefaultModel)
 	}
+	if merged.SGLang.Port != DefaultInferencePort {
+		t.Errorf("Expected merged.SGLang.Port=%d, got %d", DefaultInferencePort, merged.SGLang.Port)
+	}
+	if len(defaults.SGLang.GPUDevices) != 3 {
+		t.Errorf("Expected defaults to be left unchanged, got %v", defaults.SGLang.GPUDevices)
+	}
 }
 
 func TestFindMissingFields(t *testing.T) {
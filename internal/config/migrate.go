@@ -0,0 +1,334 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaVersion is the config file layout written by this version of silo.
+// Config files without schema_version are version 0.
+const SchemaVersion = 3
+
+// Migration is a numbered step that rewrites a config file from the previous
+// schema version to Version. Settings that move out of the config file into
+// the secrets file are added to secrets.
+type Migration struct {
+	Version     int
+	Description string
+	Apply       func(root *yaml.Node, secrets Secrets) error
+}
+
+// migrations are applied in order to files below their Version
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "Move llama.cpp inference settings under sglang",
+		Apply:       migrateLegacyInference,
+	},
+	{
+		Version:     2,
+		Description: "Move perplexity_api_key to secrets.env",
+		Apply:       migrateSecrets,
+	},
+	{
+		Version:     3,
+		Description: "Derive llm_base_url from the inference backend",
+		Apply:       migrateLLMBaseURL,
+	},
+}
+
+// MigrationResult describes the migrations applied to a config file
+type MigrationResult struct {
+	From    int
+	To      int
+	Applied []Migration
+	Backup  string
+}
+
+// PendingMigrations returns the schema version of the config file at path and
+// the migrations that would be applied to it
+func PendingMigrations(path string) (int, []Migration, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	doc, err := parseDocument(data)
+	if err != nil {
+		return 0, nil, err
+	}
+	version, err := schemaVersion(doc.Content[0])
+	if err != nil {
+		return 0, nil, err
+	}
+	return version, pending(version), nil
+}
+
+// Migrate applies pending migrations to the config file at path. The file is
+// copied to a backup next to it, readable by the owner only since it may hold
+// secrets, before it is rewritten. Secrets moved out of the file are saved to
+// the secrets file next to it first. It returns a result with no applied
+// migrations when the file is current.
+//
+// Migrate writes files, so callers hold the instance lock; loading a config
+// only migrates it in memory.
+func Migrate(path string) (*MigrationResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	secretsFile := filepath.Join(filepath.Dir(path), SecretsFileName)
+	secrets, err := LoadSecrets(secretsFile)
+	if err != nil {
+		return nil, err
+	}
+	moved := maps.Clone(secrets)
+
+	migrated, result, err := migrateData(data, moved)
+	if err != nil || len(result.Applied) == 0 {
+		return result, err
+	}
+
+	result.Backup = fmt.Sprintf("%s.v%d-%s.bak", path, result.From, time.Now().Format("20060102-150405"))
+	if err := os.WriteFile(result.Backup, data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to back up config file: %w", err)
+	}
+	if !maps.Equal(secrets, moved) {
+		if err := SaveSecrets(secretsFile, moved); err != nil {
+			return nil, err
+		}
+	}
+	if err := writeFileAtomic(path, migrated, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write migrated config file: %w", err)
+	}
+
+	return result, nil
+}
+
+// migrateData applies the pending migrations to the contents of a config
+// file, adding the settings that move out of it to secrets. Current contents
+// are returned unchanged.
+func migrateData(data []byte, secrets Secrets) ([]byte, *MigrationResult, error) {
+	doc, err := parseDocument(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	root := doc.Content[0]
+
+	from, err := schemaVersion(root)
+	if err != nil {
+		return nil, nil, err
+	}
+	result := &MigrationResult{From: from, To: from}
+
+	steps := pending(from)
+	if len(steps) == 0 {
+		return data, result, nil
+	}
+
+	for _, m := range steps {
+		if err := m.Apply(root, secrets); err != nil {
+			return nil, nil, fmt.Errorf("config migration %d (%s) failed: %w", m.Version, m.Description, err)
+		}
+		setScalar(root, "schema_version", strconv.Itoa(m.Version), "!!int")
+		result.To = m.Version
+		result.Applied = append(result.Applied, m)
+	}
+
+	migrated, err := encodeNode(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return migrated, result, nil
+}
+
+// pending returns the migrations above version
+func pending(version int) []Migration {
+	var steps []Migration
+	for _, m := range migrations {
+		if m.Version > version {
+			steps = append(steps, m)
+		}
+	}
+	return steps
+}
+
+// parseDocument parses a config file into a document node whose root is a
+// mapping, treating an empty file as an empty mapping
+func parseDocument(data []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{
+			Kind:    yaml.DocumentNode,
+			Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}},
+		}
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config file must be a YAML mapping")
+	}
+	if len(doc.Content[0].Content) == 0 {
+		// An empty file encodes as {}, keep keys added later in block style
+		doc.Content[0].Style &^= yaml.FlowStyle
+	}
+	return &doc, nil
+}
+
+// schemaVersion reads schema_version from the root mapping
+func schemaVersion(root *yaml.Node) (int, error) {
+	node := mappingValue(root, "schema_version")
+	if node == nil || node.Tag == "!!null" {
+		return 0, nil
+	}
+
+	version, err := strconv.Atoi(node.Value)
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("invalid schema_version %q", node.Value)
+	}
+	if version > SchemaVersion {
+		return 0, fmt.Errorf("config schema version %d is newer than this silo supports (%d), upgrade silo", version, SchemaVersion)
+	}
+	return version, nil
+}
+
+// mappingValue returns the value of key in a mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if i := mappingIndex(node, key); i >= 0 {
+		return node.Content[i+1]
+	}
+	return nil
+}
+
+func mappingIndex(node *yaml.Node, key string) int {
+	if node.Kind != yaml.MappingNode {
+		return -1
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// setScalar sets key in a mapping node to a scalar value, adding it if absent
+func setScalar(node *yaml.Node, key, value, tag string) {
+	if v := mappingValue(node, key); v != nil {
+		v.Kind = yaml.ScalarNode
+		v.Tag = tag
+		v.Value = value
+		v.Content = nil
+		return
+	}
+	node.Content = append(node.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value},
+	)
+}
+
+// removeKey deletes key from a mapping node and returns its value, or nil
+func removeKey(node *yaml.Node, key string) *yaml.Node {
+	i := mappingIndex(node, key)
+	if i < 0 {
+		return nil
+	}
+	value := node.Content[i+1]
+	node.Content = append(node.Content[:i], node.Content[i+2:]...)
+	return value
+}
+
+// childMapping returns the mapping under key, creating it if absent
+func childMapping(node *yaml.Node, key string) (*yaml.Node, error) {
+	if v := mappingValue(node, key); v != nil {
+		if v.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("%s must be a mapping", key)
+		}
+		return v, nil
+	}
+	child := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, child)
+	return child, nil
+}
+
+// moveKey moves key from src to dst under a new name. A value already set in
+// dst wins over the moved one.
+func moveKey(src *yaml.Node, key string, dst *yaml.Node, name string) {
+	value := removeKey(src, key)
+	if value == nil || mappingValue(dst, name) != nil {
+		return
+	}
+	dst.Content = append(dst.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}, value)
+}
+
+// migrateLegacyInference moves the settings of the llama.cpp inference engine
+// to their sglang equivalents and drops the ones SGLang has no use for
+func migrateLegacyInference(root *yaml.Node, _ Secrets) error {
+	legacy := []string{"enable_inference_engine", "inference_gpu_devices", "inference_context_size", "inference_model_file", "inference_gpu_layers"}
+	found := false
+	for _, key := range legacy {
+		if mappingIndex(root, key) >= 0 {
+			found = true
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	sglang, err := childMapping(root, "sglang")
+	if err != nil {
+		return err
+	}
+	moveKey(root, "enable_inference_engine", sglang, "enabled")
+	moveKey(root, "inference_gpu_devices", sglang, "gpu_devices")
+	moveKey(root, "inference_context_size", sglang, "context_length")
+	removeKey(root, "inference_model_file")
+	removeKey(root, "inference_gpu_layers")
+	return nil
+}
+
+// migrateSecrets moves the Perplexity API key to the secrets file. A key
+// already in the secrets file wins over the one in config.yml.
+func migrateSecrets(root *yaml.Node, secrets Secrets) error {
+	value := removeKey(root, "perplexity_api_key")
+	if value == nil || value.Value == "" || secrets[SecretPerplexityAPIKey] != "" {
+		return nil
+	}
+	if err := ValidateSecretValue(value.Value); err != nil {
+		return fmt.Errorf("perplexity_api_key: %w", err)
+	}
+	secrets[SecretPerplexityAPIKey] = value.Value
+	return nil
+}
+
+// migrateLLMBaseURL clears llm_base_url when it points at the SGLang engine
+// of the config, so it follows the inference backend from now on. URLs of
+// other servers are kept.
+func migrateLLMBaseURL(root *yaml.Node, _ Secrets) error {
+	value := mappingValue(root, "llm_base_url")
+	if value == nil {
+		return nil
+	}
+
+	port := DefaultInferencePort
+	if sglang := mappingValue(root, "sglang"); sglang != nil {
+		if node := mappingValue(sglang, "port"); node != nil {
+			if p, err := strconv.Atoi(node.Value); err == nil {
+				port = p
+			}
+		}
+	}
+	if value.Value == inferenceBaseURL(port) || value.Value == inferenceBaseURL(DefaultInferencePort) {
+		removeKey(root, "llm_base_url")
+	}
+	return nil
+}
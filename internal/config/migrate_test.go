@@ -0,0 +1,180 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrate_LegacyInference(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	legacy := `image_tag: "0.1.2"
+# GPU inference
+enable_inference_engine: false
+inference_model_file: GLM-4.7-Q4_K_M.gguf
+inference_gpu_layers: 999
+inference_context_size: 8192
+inference_gpu_devices: ["0", "1"]
+`
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	from, steps, err := PendingMigrations(path)
+	if err != nil {
+		t.Fatalf("PendingMigrations failed: %v", err)
+	}
+	if from != 0 || len(steps) != SchemaVersion {
+		t.Fatalf("Expected %d pending migrations from version 0, got %d from %d", SchemaVersion, len(steps), from)
+	}
+
+	result, err := Migrate(path)
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if result.From != 0 || result.To != SchemaVersion || len(result.Applied) != len(steps) {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	backup, err := os.ReadFile(result.Backup)
+	if err != nil {
+		t.Fatalf("Expected backup at %s: %v", result.Backup, err)
+	}
+	if string(backup) != legacy {
+		t.Errorf("Expected backup to hold the original file, got:\n%s", backup)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.SchemaVersion != SchemaVersion {
+		t.Errorf("Expected schema_version=%d, got %d", SchemaVersion, cfg.SchemaVersion)
+	}
+	if cfg.SGLang.ContextLength != 8192 {
+		t.Errorf("Expected context length to move to sglang, got %d", cfg.SGLang.ContextLength)
+	}
+	if strings.Join(cfg.SGLang.GPUDevices, ",") != "0,1" {
+		t.Errorf("Expected gpu devices to move to sglang, got %v", cfg.SGLang.GPUDevices)
+	}
+
+	unknown, err := FindUnknownFields(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unknown) > 0 {
+		t.Errorf("Expected no legacy fields after migration, got %v", unknown)
+	}
+
+	// A current file is left alone
+	result, err = Migrate(path)
+	if err != nil {
+		t.Fatalf("Second Migrate failed: %v", err)
+	}
+	if len(result.Applied) != 0 || result.Backup != "" {
+		t.Errorf("Expected no migrations on a current file, got %+v", result)
+	}
+}
+
+func TestMigrate_LLMBaseURL(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"old default", "llm_base_url: http://host.docker.internal:30000/v1\n", ""},
+		{"inference port", "llm_base_url: http://host.docker.internal:30100/v1\nsglang:\n  port: 30100\n", ""},
+		{"other server", "llm_base_url: http://llm.internal:8000/v1\n", "http://llm.internal:8000/v1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yml")
+			if err := os.WriteFile(path, []byte("schema_version: 2\n"+tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Migrate(path); err != nil {
+				t.Fatalf("Migrate failed: %v", err)
+			}
+			cfg, err := Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.LLMBaseURL != tt.want {
+				t.Errorf("Expected llm_base_url %q, got %q", tt.want, cfg.LLMBaseURL)
+			}
+		})
+	}
+}
+
+func TestMigrate_NewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte("schema_version: 99\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Migrate(path); err == nil {
+		t.Error("Expected error for a schema version newer than supported")
+	}
+}
+
+func TestLoadOrDefault_ExplicitFalse(t *testing.T) {
+	paths := NewPaths(t.TempDir(), t.TempDir())
+	if err := os.MkdirAll(paths.ConfigDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	data := "enable_deep_research: false\nsglang:\n  disable_radix_cache: false\n"
+	if err := os.WriteFile(paths.ConfigFile, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadOrDefault(paths.ConfigFile, paths)
+	if err != nil {
+		t.Fatalf("LoadOrDefault failed: %v", err)
+	}
+	if err := Save(paths.ConfigFile, cfg); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = LoadOrDefault(paths.ConfigFile, paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.EnableDeepResearch || cfg.SGLang.DisableRadixCache {
+		t.Error("Expected explicit false values to survive load and save")
+	}
+}
+
+func TestLoadOrDefault_MigratesInMemory(t *testing.T) {
+	paths := NewPaths(t.TempDir(), t.TempDir())
+	legacy := "schema_version: 1\nport: 8081\nperplexity_api_key: pplx-abc\n"
+	writeConfig(t, paths, legacy)
+
+	cfg, err := LoadOrDefault(paths.ConfigFile, paths)
+	if err != nil {
+		t.Fatalf("LoadOrDefault failed: %v", err)
+	}
+	if cfg.SchemaVersion != SchemaVersion || cfg.Port != 8081 {
+		t.Errorf("Expected the migrated config, got schema_version %d port %d", cfg.SchemaVersion, cfg.Port)
+	}
+
+	entries, _ := os.ReadDir(paths.ConfigDir)
+	if data, _ := os.ReadFile(paths.ConfigFile); string(data) != legacy || len(entries) != 1 {
+		t.Fatalf("Expected loading to leave the config dir alone, got %d files and:\n%s", len(entries), data)
+	}
+
+	// Saving persists the migrations with their backup and secrets
+	if err := Save(paths.ConfigFile, cfg); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if _, steps, err := PendingMigrations(paths.ConfigFile); err != nil || len(steps) != 0 {
+		t.Errorf("Expected no pending migrations after Save, got %v, %v", steps, err)
+	}
+	secrets, err := LoadSecrets(filepath.Join(paths.ConfigDir, SecretsFileName))
+	if err != nil || secrets[SecretPerplexityAPIKey] != "pplx-abc" {
+		t.Errorf("Expected the API key in secrets.env, got %v, %v", secrets, err)
+	}
+	if backups, _ := filepath.Glob(paths.ConfigFile + ".v1-*.bak"); len(backups) != 1 {
+		t.Errorf("Expected one backup, got %v", backups)
+	}
+}
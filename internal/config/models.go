@@ -0,0 +1,149 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ModelConfig is a model served by its own inference container. Keys an
+// entry leaves out take their value from the sglang section, except the
+// container name, which defaults to the sglang container name followed by
+// the model name.
+type ModelConfig struct {
+	Name string `yaml:"name" json:"name" desc:"Model name, used by 'silo inference' commands and as the model id the backend requests" nonempty:"true"`
+
+	SGLangConfig `yaml:",inline"`
+}
+
+// modelNamePattern matches the model names that can be used in container
+// names and as API model ids
+var modelNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ValidateModelName checks that name can be used in container names, as an
+// API model id and as a directory name
+func ValidateModelName(name string) error {
+	if !modelNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid model name %q, use letters, digits, '.', '_' and '-'", name)
+	}
+	return nil
+}
+
+// InferenceModels returns the models to serve: the models list, or the
+// sglang section named after default_model when the list is empty
+func (c *Config) InferenceModels() []ModelConfig {
+	if len(c.Models) > 0 {
+		return c.Models
+	}
+	return []ModelConfig{{Name: c.DefaultModel, SGLangConfig: c.SGLang}}
+}
+
+// modelPath returns the config path of the settings of the i-th model
+// returned by InferenceModels
+func (c *Config) modelPath(i int) string {
+	if len(c.Models) == 0 {
+		return "sglang"
+	}
+	return fmt.Sprintf("models[%d]", i)
+}
+
+// clone returns a copy of c that shares no maps with it, so decoding over
+// the copy leaves c unchanged
+func (c SGLangConfig) clone() SGLangConfig {
+	c.ExtraEnv = maps.Clone(c.ExtraEnv)
+	c.Resources.Ulimits = maps.Clone(c.Resources.Ulimits)
+	return c
+}
+
+// decodeModels decodes the entries of the models list in root over the
+// sglang section of cfg, so every entry inherits the keys it leaves out
+func decodeModels(root *yaml.Node, cfg *Config) error {
+	i := mappingIndex(root, "models")
+	if i < 0 || root.Content[i+1].Kind != yaml.SequenceNode {
+		return nil
+	}
+
+	items := root.Content[i+1].Content
+	cfg.Models = make([]ModelConfig, len(items))
+	for j, item := range items {
+		var entry struct {
+			Name string `yaml:"name"`
+		}
+		if err := item.Decode(&entry); err != nil {
+			return fmt.Errorf("failed to parse models[%d]: %w", j, err)
+		}
+		model := ModelConfig{Name: entry.Name, SGLangConfig: cfg.SGLang.clone()}
+		model.ContainerName = cfg.SGLang.ContainerName + "-" + entry.Name
+		if err := item.Decode(&model); err != nil {
+			return fmt.Errorf("failed to parse models[%d]: %w", j, err)
+		}
+		cfg.Models[j] = model
+	}
+	return nil
+}
+
+// ModelRoutes returns the JSON map from each enabled model to the base URL
+// of its inference container, for the backend to route requests by model.
+// It is empty when no models are listed.
+func (c *Config) ModelRoutes() string {
+	if len(c.Models) == 0 {
+		return ""
+	}
+	routes := make(map[string]string)
+	for _, m := range c.Models {
+		if m.Enabled {
+			routes[m.Name] = inferenceBaseURL(m.Port)
+		}
+	}
+	data, _ := json.Marshal(routes)
+	return string(data)
+}
+
+// modelProblems checks the names of the models and that the enabled ones
+// neither share a container nor a GPU. Ports are checked by portProblems.
+func (c *Config) modelProblems() []Problem {
+	if len(c.Models) == 0 {
+		return nil
+	}
+
+	var problems []Problem
+	names := make(map[string]string)
+	containers := make(map[string]string)
+	gpus := make(map[string]string)
+	for i, m := range c.Models {
+		path := c.modelPath(i)
+		if m.Name != "" {
+			if err := ValidateModelName(m.Name); err != nil {
+				problems = append(problems, Problem{Path: path + ".name", Message: err.Error()})
+			}
+		}
+		if other, ok := names[m.Name]; ok {
+			problems = append(problems, Problem{Path: path + ".name", Message: fmt.Sprintf("model %q is also defined by %s", m.Name, other)})
+		}
+		names[m.Name] = path
+		if other, ok := containers[m.ContainerName]; ok {
+			problems = append(problems, Problem{Path: path + ".container_name", Message: fmt.Sprintf("container %q is also used by %s", m.ContainerName, other)})
+		}
+		containers[m.ContainerName] = path
+
+		if !m.Enabled {
+			continue
+		}
+		for _, gpu := range m.GPUDevices {
+			if other, ok := gpus[gpu]; ok {
+				problems = append(problems, Problem{Path: path + ".gpu_devices", Message: fmt.Sprintf("GPU %s is also used by %s", gpu, other)})
+			}
+			gpus[gpu] = path
+		}
+	}
+
+	if _, ok := names[c.DefaultModel]; !ok {
+		problems = append(problems, Problem{Path: "default_model", Message: fmt.Sprintf("must name one of the models, %s, got %q", strings.Join(slices.Sorted(maps.Keys(names)), ", "), c.DefaultModel)})
+	}
+	return problems
+}
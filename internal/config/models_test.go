@@ -0,0 +1,135 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+const modelsConfig = `default_model: chat
+sglang:
+  enabled: true
+  extra_env:
+    NCCL_DEBUG: "INFO"
+models:
+  - name: chat
+    gpu_devices: ["0", "1"]
+    dp_size: 2
+  - name: fast
+    port: 30001
+    gpu_devices: ["2"]
+    dp_size: 1
+    model_path: /data/small
+    extra_env:
+      HF_HUB_OFFLINE: "1"
+`
+
+func loadModels(t *testing.T, data string) *Config {
+	t.Helper()
+	paths := NewPaths(t.TempDir(), t.TempDir())
+	if err := os.WriteFile(paths.ConfigFile, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadOrDefault(paths.ConfigFile, paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestLoadOrDefault_ModelsInheritSGLang(t *testing.T) {
+	cfg := loadModels(t, modelsConfig)
+
+	models := cfg.InferenceModels()
+	if len(models) != 2 {
+		t.Fatalf("Expected 2 models, got %d", len(models))
+	}
+	chat, fast := models[0], models[1]
+
+	if chat.ContainerName != "silo-inference-chat" || fast.ContainerName != "silo-inference-fast" {
+		t.Errorf("Expected container names derived from the model names, got %q and %q", chat.ContainerName, fast.ContainerName)
+	}
+	if chat.Port != DefaultInferencePort || fast.Port != 30001 {
+		t.Errorf("Expected ports 30000 and 30001, got %d and %d", chat.Port, fast.Port)
+	}
+	if !chat.Enabled || chat.ModelPath != DefaultSGLangConfig().ModelPath || fast.ModelPath != "/data/small" {
+		t.Errorf("Expected unset keys to come from sglang, got %+v", chat.SGLangConfig)
+	}
+	if fast.ExtraEnv["NCCL_DEBUG"] != "INFO" || fast.ExtraEnv["HF_HUB_OFFLINE"] != "1" {
+		t.Errorf("Expected extra_env merged over sglang.extra_env, got %v", fast.ExtraEnv)
+	}
+	if _, ok := cfg.SGLang.ExtraEnv["HF_HUB_OFFLINE"]; ok {
+		t.Error("Expected the sglang section to be unchanged by the models")
+	}
+
+	problems, err := ValidateConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs, _ := SplitProblems(problems); len(errs) > 0 {
+		t.Errorf("Expected a valid config, got %v", errs)
+	}
+}
+
+func TestInferenceModels_SGLangSection(t *testing.T) {
+	cfg := NewDefaultConfig(NewPaths(t.TempDir(), t.TempDir()))
+	models := cfg.InferenceModels()
+	if len(models) != 1 || models[0].Name != DefaultModel || models[0].ContainerName != DefaultInferenceContainerName {
+		t.Errorf("Expected the sglang section as the only model, got %+v", models)
+	}
+	if cfg.ModelRoutes() != "" {
+		t.Errorf("Expected no routes without a models list, got %s", cfg.ModelRoutes())
+	}
+}
+
+func TestModelRoutes(t *testing.T) {
+	cfg := loadModels(t, modelsConfig+"  - name: off\n    enabled: false\n    port: 30002\n    gpu_devices: []\n")
+	want := `{"chat":"http://host.docker.internal:30000/v1","fast":"http://host.docker.internal:30001/v1"}`
+	if got := cfg.ModelRoutes(); got != want {
+		t.Errorf("ModelRoutes() = %s, want %s", got, want)
+	}
+
+	data, err := RenderDockerCompose(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), yamlQuote("LLM_MODEL_ROUTES="+want)) {
+		t.Errorf("Expected the routes in the backend environment:\n%s", data)
+	}
+}
+
+func TestModelProblems(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		path   string
+	}{
+		{"shared gpu", strings.Replace(modelsConfig, `gpu_devices: ["2"]`, `gpu_devices: ["1"]`, 1), "models[1].gpu_devices"},
+		{"shared port", strings.Replace(modelsConfig, "port: 30001", "port: 30000", 1), "models[1].port"},
+		{"duplicate name", strings.Replace(modelsConfig, "name: fast", "name: chat", 1), "models[1].name"},
+		{"invalid name", strings.Replace(modelsConfig, "name: fast", "name: fast model", 1), "models[1].name"},
+		{"default model", strings.Replace(modelsConfig, "default_model: chat", "default_model: glm", 1), "default_model"},
+		{"engine settings", strings.Replace(modelsConfig, "dp_size: 1", "dp_size: 2", 1), "models[1].gpu_devices"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems, err := ValidateConfig(loadModels(t, tt.config))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, p := range problems {
+				if p.Path == tt.path && !p.IsWarning() {
+					return
+				}
+			}
+			t.Errorf("Expected an error at %s, got %v", tt.path, problems)
+		})
+	}
+}
+
+func TestModelProblems_DisabledModelsMayShareGPUs(t *testing.T) {
+	config := strings.Replace(modelsConfig, `gpu_devices: ["2"]`, `gpu_devices: ["1"]`+"\n    enabled: false", 1)
+	for _, p := range loadModels(t, config).modelProblems() {
+		t.Errorf("Unexpected problem: %s", p)
+	}
+}
@@ -0,0 +1,275 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix starts the environment variables that override config keys. The
+// rest of the name is the dotted key in upper case with dots replaced by
+// underscores, e.g. SILO_SGLANG_TP_SIZE for sglang.tp_size.
+const EnvPrefix = "SILO_"
+
+// reservedEnv are SILO_ variables that select instances and directories
+// rather than config values
+var reservedEnv = map[string]bool{
+	"SILO_INSTANCE":            true,
+	"SILO_CONFIG_DIR":          true,
+	"SILO_DATA_DIR":            true,
+	"SILO_DAEMON_BIND_ADDRESS": true,
+}
+
+// Source is the layer an effective config value comes from. Each layer
+// overrides the ones before it: defaults, config.yml, config.d drop-ins,
+// SILO_* environment variables, then --set flags.
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+// Override sets a config key from outside config.yml
+type Override struct {
+	Key    string
+	Value  string
+	Source Source
+
+	// Name is the environment variable or flag the override came from
+	Name string
+}
+
+// Origin describes where the override came from, e.g. "env SILO_PORT"
+func (o Override) Origin() string {
+	return string(o.Source) + " " + o.Name
+}
+
+// flagOverrides are the --set flags of the running command
+var flagOverrides []Override
+
+// SetFlagOverrides sets the overrides given on the command line. They apply
+// to every config loaded by the process afterwards.
+func SetFlagOverrides(overrides []Override) {
+	flagOverrides = overrides
+}
+
+// ParseSetFlags parses key=value pairs given with --set
+func ParseSetFlags(values []string) ([]Override, error) {
+	var overrides []Override
+	for _, v := range values {
+		key, value, ok := strings.Cut(v, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --set %q, expected key=value", v)
+		}
+		if _, err := resolveKey(key); err != nil {
+			return nil, fmt.Errorf("invalid --set %q: %w", v, err)
+		}
+		overrides = append(overrides, Override{Key: key, Value: value, Source: SourceFlag, Name: "--set"})
+	}
+	return overrides, nil
+}
+
+// EnvName returns the environment variable that overrides a config key
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// EnvKeys maps the environment variable of every overridable setting to its
+// config key. Lists and maps are overridden as a whole.
+func EnvKeys() map[string]string {
+	keys := make(map[string]string)
+	var walk func(s *Schema, prefix string)
+	walk = func(s *Schema, prefix string) {
+		for name, prop := range s.Properties {
+			key := name
+			if prefix != "" {
+				key = prefix + "." + name
+			}
+			if prop.Type == "object" && prop.values == nil {
+				walk(prop, key)
+				continue
+			}
+			keys[EnvName(key)] = key
+		}
+	}
+	walk(ConfigSchema(), "")
+	delete(keys, EnvName("schema_version"))
+	return keys
+}
+
+// EnvOverrides returns the overrides set in environ, a list of NAME=value
+// pairs as returned by os.Environ, sorted by key. SILO_ variables that name
+// no setting are returned in unknown.
+func EnvOverrides(environ []string) (overrides []Override, unknown []string) {
+	keys := EnvKeys()
+	for _, kv := range environ {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(name, EnvPrefix) || reservedEnv[name] {
+			continue
+		}
+		key, ok := keys[name]
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		overrides = append(overrides, Override{Key: key, Value: value, Source: SourceEnv, Name: name})
+	}
+	sort.Slice(overrides, func(i, j int) bool { return overrides[i].Key < overrides[j].Key })
+	sort.Strings(unknown)
+	return overrides, unknown
+}
+
+// ActiveOverrides returns the environment and flag overrides that apply to
+// configs loaded by this process, in the order they are applied
+func ActiveOverrides() []Override {
+	env, _ := EnvOverrides(os.Environ())
+	return append(env, flagOverrides...)
+}
+
+// applyOverrides sets each override in the document, checking its value
+// against the schema
+func (d *Document) applyOverrides(overrides []Override) error {
+	for _, o := range overrides {
+		if err := d.Set(o.Key, o.Value); err != nil {
+			return fmt.Errorf("%s: %w", o.Name, err)
+		}
+		if node, _ := d.Get(o.Key); node != nil {
+			c := &schemaCheck{}
+			c.check(schemaAt(o.Key), node, o.Key)
+			if len(c.problems) > 0 {
+				return fmt.Errorf("%s: %s: %s", o.Name, c.problems[0].Path, c.problems[0].Message)
+			}
+		}
+	}
+	return nil
+}
+
+// schemaAt returns the schema of a dotted config key
+func schemaAt(key string) *Schema {
+	s := ConfigSchema()
+	parts, _ := resolveKey(key)
+	for _, part := range parts {
+		if s.values != nil {
+			s = s.values
+		} else if prop, ok := s.Properties[part]; ok {
+			s = prop
+		}
+	}
+	return s
+}
+
+// leafKeys records origin for the dotted keys of the scalars and lists in a
+// mapping
+func leafKeys(node *yaml.Node, prefix, origin string, keys map[string]string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i].Value
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if value := node.Content[i+1]; value.Kind == yaml.MappingNode {
+			leafKeys(value, key, origin, keys)
+		} else {
+			keys[key] = origin
+		}
+	}
+}
+
+// Overrides returns the environment and flag overrides applied to the config
+func (c *Config) Overrides() []Override {
+	if c.sources == nil {
+		return nil
+	}
+	return c.sources.overrides
+}
+
+// ValueSource describes where the value of a dotted key came from: "default",
+// "file" for config.yml, the drop-in file such as "config.d/10-gpus.yml", or
+// the origin of an override such as "env SILO_PORT"
+func (c *Config) ValueSource(key string) string {
+	if c.sources == nil {
+		return string(SourceDefault)
+	}
+	for i := len(c.sources.overrides) - 1; i >= 0; i-- {
+		o := c.sources.overrides[i]
+		if key == o.Key || strings.HasPrefix(key, o.Key+".") {
+			return o.Origin()
+		}
+	}
+	for k := key; ; {
+		if origin, ok := c.sources.file[k]; ok {
+			return origin
+		}
+		i := strings.LastIndex(k, ".")
+		if i < 0 {
+			return string(SourceDefault)
+		}
+		k = k[:i]
+	}
+}
+
+// EffectiveYAML returns cfg as YAML with derived values resolved: an empty
+// llm_base_url shows the URL of the inference backend it follows
+func EffectiveYAML(cfg *Config) ([]byte, error) {
+	doc, err := effectiveDocument(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return encodeNode(doc)
+}
+
+// effectiveDocument returns cfg as a YAML document with derived values
+// resolved, see EffectiveYAML
+func effectiveDocument(cfg *Config) (*yaml.Node, error) {
+	data, err := ConfigYAML(cfg)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	if value := mappingValue(doc.Content[0], "llm_base_url"); value != nil && cfg.LLMBaseURL == "" {
+		value.Value = cfg.LLMURL()
+		value.Style = 0
+	}
+	return doc, nil
+}
+
+// AnnotatedYAML returns cfg as YAML with derived values resolved and a
+// comment after every value naming its source
+func AnnotatedYAML(cfg *Config) ([]byte, error) {
+	doc, err := effectiveDocument(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var annotate func(node *yaml.Node, prefix string)
+	annotate = func(node *yaml.Node, prefix string) {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			path := key.Value
+			if prefix != "" {
+				path = prefix + "." + key.Value
+			}
+			if value.Kind == yaml.MappingNode && len(value.Content) > 0 {
+				annotate(value, path)
+				continue
+			}
+			if value.Kind == yaml.SequenceNode {
+				value.Style |= yaml.FlowStyle
+			}
+			value.LineComment = cfg.ValueSource(path)
+			if path == "llm_base_url" && cfg.LLMBaseURL == "" {
+				value.LineComment += ", derived from the inference backend"
+			}
+		}
+	}
+	annotate(doc.Content[0], "")
+	return encodeNode(doc)
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestEnvKeys(t *testing.T) {
+	keys := EnvKeys()
+	tests := map[string]string{
+		"SILO_PORT":               "port",
+		"SILO_SGLANG_TP_SIZE":     "sglang.tp_size",
+		"SILO_SGLANG_GPU_DEVICES": "sglang.gpu_devices",
+		"SILO_REGISTRY_MIRRORS":   "registry.mirrors",
+	}
+	for name, want := range tests {
+		if got := keys[name]; got != want {
+			t.Errorf("EnvKeys()[%q] = %q, want %q", name, got, want)
+		}
+	}
+	if _, ok := keys["SILO_SCHEMA_VERSION"]; ok {
+		t.Error("Expected schema_version not to be overridable")
+	}
+
+	overrides, unknown := EnvOverrides([]string{"SILO_PORT=81", "SILO_INSTANCE=lab", "SILO_PORTT=1", "HOME=/root"})
+	if len(overrides) != 1 || overrides[0].Key != "port" || overrides[0].Origin() != "env SILO_PORT" {
+		t.Errorf("Unexpected overrides: %+v", overrides)
+	}
+	if len(unknown) != 1 || unknown[0] != "SILO_PORTT" {
+		t.Errorf("Expected SILO_PORTT to be unknown, got %v", unknown)
+	}
+}
+
+func writeConfig(t *testing.T, paths *Paths, data string) {
+	t.Helper()
+	if err := os.MkdirAll(paths.ConfigDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(paths.ConfigFile, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func setFlagOverrides(t *testing.T, values ...string) {
+	t.Helper()
+	overrides, err := ParseSetFlags(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetFlagOverrides(overrides)
+	t.Cleanup(func() { SetFlagOverrides(nil) })
+}
+
+func TestLoadOrDefault_Precedence(t *testing.T) {
+	paths := NewPaths(t.TempDir(), t.TempDir())
+	writeConfig(t, paths, "port: 8081\nbackend_port: 8082\nsglang:\n  tp_size: 4\n")
+
+	t.Setenv("SILO_PORT", "9001")
+	t.Setenv("SILO_SGLANG_TP_SIZE", "2")
+	t.Setenv("SILO_SGLANG_GPU_DEVICES", `["0", "1"]`)
+	setFlagOverrides(t, "port=9002")
+
+	cfg, err := LoadOrDefault(paths.ConfigFile, paths)
+	if err != nil {
+		t.Fatalf("LoadOrDefault failed: %v", err)
+	}
+	if cfg.Port != 9002 || cfg.BackendPort != 8082 || cfg.SGLang.TPSize != 2 {
+		t.Errorf("Expected flag > env > file, got port=%d backend_port=%d tp_size=%d", cfg.Port, cfg.BackendPort, cfg.SGLang.TPSize)
+	}
+	if strings.Join(cfg.SGLang.GPUDevices, ",") != "0,1" {
+		t.Errorf("Expected list override, got %v", cfg.SGLang.GPUDevices)
+	}
+
+	sources := map[string]string{
+		"port":               "flag --set",
+		"sglang.tp_size":     "env SILO_SGLANG_TP_SIZE",
+		"backend_port":       "file",
+		"postgres_port":      "default",
+		"sglang.gpu_devices": "env SILO_SGLANG_GPU_DEVICES",
+	}
+	for key, want := range sources {
+		if got := cfg.ValueSource(key); got != want {
+			t.Errorf("ValueSource(%q) = %q, want %q", key, got, want)
+		}
+	}
+
+	annotated, err := AnnotatedYAML(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(annotated), "tp_size: 2 # env SILO_SGLANG_TP_SIZE") {
+		t.Errorf("Expected annotated source, got:\n%s", annotated)
+	}
+	if want := "llm_base_url: " + cfg.LLMURL() + " # default, derived from the inference backend"; !strings.Contains(string(annotated), want) {
+		t.Errorf("Expected %q, got:\n%s", want, annotated)
+	}
+}
+
+func TestLoadOrDefault_InvalidOverride(t *testing.T) {
+	paths := NewPaths(t.TempDir(), t.TempDir())
+	t.Setenv("SILO_SGLANG_TP_SIZE", "two")
+
+	_, err := LoadOrDefault(paths.ConfigFile, paths)
+	if err == nil || !strings.Contains(err.Error(), "SILO_SGLANG_TP_SIZE") {
+		t.Errorf("Expected error naming the variable, got %v", err)
+	}
+
+	if _, err := ParseSetFlags([]string{"sglang.tp_sizes=2"}); err == nil {
+		t.Error("Expected unknown key in --set to be rejected")
+	}
+}
+
+func TestSave_KeepsOverridesOutOfFile(t *testing.T) {
+	paths := NewPaths(t.TempDir(), t.TempDir())
+	writeConfig(t, paths, "port: 8081\n")
+	t.Setenv("SILO_PORT", "9001")
+	t.Setenv("SILO_BACKEND_PORT", "9002")
+	t.Setenv("SILO_IMAGE_TAG", "0.0.1")
+
+	cfg, err := LoadOrDefault(paths.ConfigFile, paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.ImageTag = "0.2.0"
+	if err := Save(paths.ConfigFile, cfg); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	saved, err := Load(paths.ConfigFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Port != 8081 {
+		t.Errorf("Expected file value 8081 to be kept, got %d", saved.Port)
+	}
+	if saved.BackendPort == 9002 {
+		t.Error("Expected overridden backend port not to be saved")
+	}
+	if saved.PostgresPort != DefaultPostgresPort {
+		t.Errorf("Expected missing defaults to be saved, got postgres_port %d", saved.PostgresPort)
+	}
+	if saved.ImageTag != "0.2.0" {
+		t.Errorf("Expected changed image tag to be saved, got %s", saved.ImageTag)
+	}
+}
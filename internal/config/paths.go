@@ -1,24 +1,47 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 )
 
 const (
 	ConfigFileName  = "config.yml"
 	ComposeFileName = "docker-compose.yml"
 	StateFileName   = "state.json"
+
+	// DefaultInstance is the instance used when no --instance flag or
+	// SILO_INSTANCE variable is given. It lives directly in the base
+	// directories so installs that predate instances keep working.
+	DefaultInstance = "default"
+
+	// InstancesDirName is the subdirectory of the base config and data
+	// directories holding named instances.
+	InstancesDirName = "instances"
 )
 
+// instanceNamePattern restricts instance names to characters that are valid
+// in compose project names and container names.
+var instanceNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
 type Paths struct {
-	ConfigDir   string
-	DataDir     string
-	ConfigFile  string
-	ComposeFile string
-	StateFile   string
-	SocketFile  string
-	AppDataDir  string
+	Instance            string
+	RootConfigDir       string
+	RootDataDir         string
+	ConfigDir           string
+	DataDir             string
+	ConfigFile          string
+	ConfigDropInDir     string
+	ComposeFile         string
+	ComposeOverrideFile string
+	StateFile           string
+	SecretsFile         string
+	EnvDir              string
+	SocketFile          string
+	LockFile            string
+	AppDataDir          string
 }
 
 func DefaultConfigDir() string {
@@ -37,7 +60,41 @@ func DefaultDataDir() string {
 	return filepath.Join(home, ".local", "share", "silo")
 }
 
+// BaseConfigDir returns the base config directory shared by all instances:
+// SILO_CONFIG_DIR, or the default when it is unset
+func BaseConfigDir() string {
+	if dir := os.Getenv("SILO_CONFIG_DIR"); dir != "" {
+		return dir
+	}
+	return DefaultConfigDir()
+}
+
+// BaseDataDir returns the base data directory shared by all instances:
+// SILO_DATA_DIR, or the default when it is unset
+func BaseDataDir() string {
+	if dir := os.Getenv("SILO_DATA_DIR"); dir != "" {
+		return dir
+	}
+	return DefaultDataDir()
+}
+
+// ValidateInstanceName checks that name can be used as an instance name.
+func ValidateInstanceName(name string) error {
+	if !instanceNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid instance name %q: use lowercase letters, digits, '-' and '_'", name)
+	}
+	return nil
+}
+
+// NewPaths returns the paths of the default instance.
 func NewPaths(configDir, dataDir string) *Paths {
+	return NewInstancePaths(DefaultInstance, configDir, dataDir)
+}
+
+// NewInstancePaths returns the paths of the named instance. configDir and
+// dataDir are the base directories shared by all instances; empty values
+// select the defaults.
+func NewInstancePaths(instance, configDir, dataDir string) *Paths {
 	if configDir == "" {
 		configDir = DefaultConfigDir()
 	}
@@ -46,13 +103,32 @@ func NewPaths(configDir, dataDir string) *Paths {
 		dataDir = DefaultDataDir()
 	}
 
+	if instance == "" {
+		instance = DefaultInstance
+	}
+
+	instanceConfigDir := configDir
+	instanceDataDir := dataDir
+	if instance != DefaultInstance {
+		instanceConfigDir = filepath.Join(configDir, InstancesDirName, instance)
+		instanceDataDir = filepath.Join(dataDir, InstancesDirName, instance)
+	}
+
 	return &Paths{
-		ConfigDir:   configDir,
-		DataDir:     dataDir,
-		ConfigFile:  filepath.Join(configDir, ConfigFileName),
-		ComposeFile: filepath.Join(dataDir, ComposeFileName),
-		StateFile:   filepath.Join(dataDir, StateFileName),
-		SocketFile:  filepath.Join(dataDir, "silod.sock"),
-		AppDataDir:  filepath.Join(dataDir, "data"),
+		Instance:            instance,
+		RootConfigDir:       configDir,
+		RootDataDir:         dataDir,
+		ConfigDir:           instanceConfigDir,
+		DataDir:             instanceDataDir,
+		ConfigFile:          filepath.Join(instanceConfigDir, ConfigFileName),
+		ConfigDropInDir:     filepath.Join(instanceConfigDir, DropInDirName),
+		ComposeFile:         filepath.Join(instanceDataDir, ComposeFileName),
+		ComposeOverrideFile: filepath.Join(instanceConfigDir, ComposeOverrideFileName),
+		StateFile:           filepath.Join(instanceDataDir, StateFileName),
+		SecretsFile:         filepath.Join(instanceConfigDir, SecretsFileName),
+		EnvDir:              filepath.Join(instanceDataDir, EnvDirName),
+		SocketFile:          filepath.Join(instanceDataDir, "silod.sock"),
+		LockFile:            filepath.Join(instanceDataDir, LockFileName),
+		AppDataDir:          filepath.Join(instanceDataDir, "data"),
 	}
 }
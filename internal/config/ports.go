@@ -0,0 +1,181 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PublishedPort is a container port of a compose service published on the
+// host
+type PublishedPort struct {
+	Service       string
+	BindAddress   string
+	HostPort      int
+	ContainerPort int
+
+	// Key is the config key of the host port
+	Key string
+}
+
+// Address returns the host address the port is published on, e.g.
+// 127.0.0.1:8080 or :8080 for every interface
+func (p PublishedPort) Address() string {
+	return net.JoinHostPort(p.BindAddress, strconv.Itoa(p.HostPort))
+}
+
+// Mapping returns the port in compose short syntax, bind:host:container
+func (p PublishedPort) Mapping() string {
+	mapping := fmt.Sprintf("%d:%d", p.HostPort, p.ContainerPort)
+	if p.BindAddress == "" {
+		return mapping
+	}
+	if strings.Contains(p.BindAddress, ":") {
+		return "[" + p.BindAddress + "]:" + mapping
+	}
+	return p.BindAddress + ":" + mapping
+}
+
+// unpublishedByDefault are the services only reachable from the other
+// containers unless services.<name>.publish is set
+var unpublishedByDefault = map[string]bool{
+	"postgres": true,
+}
+
+// servicePorts returns the port of every enabled service that has one,
+// whether published or not, in docker-compose.yml order
+func (c *Config) servicePorts() []PublishedPort {
+	ports := []PublishedPort{
+		{Service: "postgres", HostPort: c.PostgresPort, ContainerPort: 5432, Key: "postgres_port"},
+		{Service: "backend", HostPort: c.BackendPort, ContainerPort: 8080, Key: "backend_port"},
+		{Service: "frontend", HostPort: c.Port, ContainerPort: 3000, Key: "port"},
+	}
+	if c.EnableDeepResearch {
+		ports = append(ports, PublishedPort{Service: "deep-research", HostPort: c.DeepResearchHostPortOrDefault(), ContainerPort: c.DeepResearchPort, Key: "deep_research_host_port"})
+	}
+	for i := range ports {
+		ports[i].BindAddress = c.ServiceSettings[ports[i].Service].BindAddress
+	}
+	return ports
+}
+
+// DeepResearchHostPortOrDefault returns the host port deep research is
+// published on
+func (c *Config) DeepResearchHostPortOrDefault() int {
+	if c.DeepResearchHostPort != 0 {
+		return c.DeepResearchHostPort
+	}
+	return c.DeepResearchPort
+}
+
+// Published reports whether the port of a service is published on the host
+func (c *Config) Published(service string) bool {
+	if publish := c.ServiceSettings[service].Publish; publish != nil {
+		return *publish
+	}
+	return !unpublishedByDefault[service]
+}
+
+// PublishedPorts returns the ports of the enabled services that are
+// published on the host, in docker-compose.yml order
+func (c *Config) PublishedPorts() []PublishedPort {
+	var published []PublishedPort
+	for _, p := range c.servicePorts() {
+		if c.Published(p.Service) {
+			published = append(published, p)
+		}
+	}
+	return published
+}
+
+// PortMapping returns the quoted compose port of a service, or "" when the
+// service is not published
+func (c *Config) PortMapping(service string) string {
+	for _, p := range c.PublishedPorts() {
+		if p.Service == service {
+			return yamlQuote(p.Mapping())
+		}
+	}
+	return ""
+}
+
+// NewlyPublishedPorts returns the published ports of config that the
+// docker-compose.yml at composeFile does not already publish; the running
+// containers hold the others
+func NewlyPublishedPorts(config *Config, composeFile string) ([]PublishedPort, error) {
+	data, err := os.ReadFile(composeFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read docker-compose file: %w", err)
+	}
+	var compose struct {
+		Services map[string]struct {
+			Ports []string `yaml:"ports"`
+		} `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(data, &compose); err != nil {
+		return nil, fmt.Errorf("failed to parse docker-compose file: %w", err)
+	}
+
+	current := map[string]bool{}
+	for _, service := range compose.Services {
+		for _, mapping := range service.Ports {
+			// bind:host:container, without the container port
+			address := mapping[:max(strings.LastIndex(mapping, ":"), 0)]
+			if !strings.Contains(address, ":") {
+				address = ":" + address
+			}
+			current[address] = true
+		}
+	}
+
+	var ports []PublishedPort
+	for _, p := range config.PublishedPorts() {
+		if !current[p.Address()] {
+			ports = append(ports, p)
+		}
+	}
+	return ports, nil
+}
+
+// overlaps reports whether two bind addresses can conflict on the same port
+func overlaps(a, b string) bool {
+	wildcard := func(addr string) bool {
+		return addr == "" || addr == "0.0.0.0" || addr == "::"
+	}
+	return a == b || wildcard(a) || wildcard(b)
+}
+
+// portProblems checks the publish settings and that no two published ports,
+// including the inference ports, collide
+func (c *Config) portProblems() []Problem {
+	var problems []Problem
+	for _, name := range BuiltinServices {
+		settings := c.ServiceSettings[name]
+		if settings.BindAddress != "" && net.ParseIP(settings.BindAddress) == nil {
+			problems = append(problems, Problem{Path: "services." + name + ".bind_address", Message: fmt.Sprintf("must be an IP address, got %q", settings.BindAddress)})
+		}
+		if name == "silo-proxy-agent" && (settings.Publish != nil || settings.BindAddress != "") {
+			problems = append(problems, Problem{Path: "services." + name, Message: "the proxy agent publishes no ports"})
+		}
+	}
+
+	published := c.PublishedPorts()
+	for _, container := range c.InferenceContainers() {
+		if container.Enabled {
+			published = append(published, PublishedPort{Service: "inference model " + container.Model, HostPort: container.Port, Key: container.Path + ".port"})
+		}
+	}
+	for i, p := range published {
+		for _, other := range published[:i] {
+			if p.HostPort == other.HostPort && overlaps(p.BindAddress, other.BindAddress) {
+				problems = append(problems, Problem{Path: p.Key, Message: fmt.Sprintf("port %d is also published by %s (%s)", p.HostPort, other.Service, other.Key)})
+			}
+		}
+	}
+	return problems
+}
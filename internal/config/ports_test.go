@@ -0,0 +1,141 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestPublishedPorts(t *testing.T) {
+	cfg := NewDefaultConfig(NewPaths(t.TempDir(), t.TempDir()))
+	var services []string
+	for _, p := range cfg.PublishedPorts() {
+		services = append(services, p.Service)
+	}
+	if strings.Join(services, ",") != "backend,frontend,deep-research" {
+		t.Errorf("Expected postgres to be unpublished by default, got %v", services)
+	}
+
+	publish := true
+	cfg.ServiceSettings = map[string]ServiceConfig{
+		"postgres": {Publish: &publish, BindAddress: "127.0.0.1"},
+		"backend":  {BindAddress: "::1"},
+	}
+	tests := map[string]string{
+		"postgres": `"127.0.0.1:5432:5432"`,
+		"backend":  `"[::1]:8080:8080"`,
+		"frontend": `"80:3000"`,
+	}
+	for service, want := range tests {
+		if got := cfg.PortMapping(service); got != want {
+			t.Errorf("PortMapping(%q) = %s, want %s", service, got, want)
+		}
+	}
+
+	cfg.EnableDeepResearch = false
+	if got := cfg.PortMapping("deep-research"); got != "" {
+		t.Errorf("Expected no port for disabled deep research, got %s", got)
+	}
+}
+
+func TestPortProblems(t *testing.T) {
+	cfg := NewDefaultConfig(NewPaths(t.TempDir(), t.TempDir()))
+	cfg.BackendPort = cfg.Port
+	cfg.SGLang.Enabled = true
+	cfg.SGLang.Port = cfg.DeepResearchPort
+	cfg.ServiceSettings = map[string]ServiceConfig{
+		"frontend":         {BindAddress: "localhost"},
+		"silo-proxy-agent": {BindAddress: "127.0.0.1"},
+	}
+
+	problems := map[string]string{}
+	for _, p := range cfg.portProblems() {
+		problems[p.Path] = p.Message
+	}
+	for _, path := range []string{"port", "sglang.port", "services.frontend.bind_address", "services.silo-proxy-agent"} {
+		if _, ok := problems[path]; !ok {
+			t.Errorf("Expected a problem at %s, got %v", path, problems)
+		}
+	}
+
+	// Unpublished postgres and distinct bind addresses do not collide
+	cfg = NewDefaultConfig(NewPaths(t.TempDir(), t.TempDir()))
+	cfg.PostgresPort = cfg.BackendPort
+	cfg.Port = cfg.DeepResearchPort
+	cfg.ServiceSettings = map[string]ServiceConfig{
+		"frontend":      {BindAddress: "127.0.0.1"},
+		"deep-research": {BindAddress: "127.0.0.2"},
+	}
+	if problems := cfg.portProblems(); len(problems) != 0 {
+		t.Errorf("Expected no collisions, got %v", problems)
+	}
+}
+
+func TestGenerateDockerCompose_Ports(t *testing.T) {
+	paths := NewPaths(t.TempDir(), t.TempDir())
+	cfg := NewDefaultConfig(paths)
+	cfg.ServiceSettings = map[string]ServiceConfig{"backend": {BindAddress: "127.0.0.1"}}
+	if err := os.MkdirAll(paths.DataDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := GenerateDockerCompose(cfg, paths.ComposeFile); err != nil {
+		t.Fatalf("GenerateDockerCompose failed: %v", err)
+	}
+
+	data, err := os.ReadFile(paths.ComposeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var compose struct {
+		Services map[string]struct {
+			Ports []string `yaml:"ports"`
+		} `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(data, &compose); err != nil {
+		t.Fatalf("Generated compose file is not valid YAML: %v\n%s", err, data)
+	}
+	if ports := compose.Services["postgres"].Ports; len(ports) != 0 {
+		t.Errorf("Expected postgres not to be published, got %v", ports)
+	}
+	if ports := compose.Services["backend"].Ports; len(ports) != 1 || ports[0] != "127.0.0.1:8080:8080" {
+		t.Errorf("Expected backend on 127.0.0.1, got %v", ports)
+	}
+}
+
+func TestNewlyPublishedPorts(t *testing.T) {
+	paths := NewPaths(t.TempDir(), t.TempDir())
+	cfg := NewDefaultConfig(paths)
+	cfg.ServiceSettings = map[string]ServiceConfig{"backend": {BindAddress: "::1"}}
+
+	ports, err := NewlyPublishedPorts(cfg, paths.ComposeFile)
+	if err != nil {
+		t.Fatalf("NewlyPublishedPorts failed: %v", err)
+	}
+	if len(ports) != len(cfg.PublishedPorts()) {
+		t.Errorf("Expected every port to be new without a compose file, got %v", ports)
+	}
+
+	if err := os.MkdirAll(paths.DataDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := GenerateDockerCompose(cfg, paths.ComposeFile); err != nil {
+		t.Fatalf("GenerateDockerCompose failed: %v", err)
+	}
+	publish := true
+	cfg.Port = 8081
+	cfg.ServiceSettings["postgres"] = ServiceConfig{Publish: &publish}
+
+	ports, err = NewlyPublishedPorts(cfg, paths.ComposeFile)
+	if err != nil {
+		t.Fatalf("NewlyPublishedPorts failed: %v", err)
+	}
+	var keys []string
+	for _, p := range ports {
+		keys = append(keys, p.Key)
+	}
+	if strings.Join(keys, ",") != "postgres_port,port" {
+		t.Errorf("Expected only the changed ports to be new, got %v", keys)
+	}
+}
@@ -0,0 +1,309 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/eternisai/silo/internal/units"
+)
+
+// ResourceConfig holds the resource limits and reservations of a container.
+// Zero values leave the Docker defaults in place.
+type ResourceConfig struct {
+	CPUs              float64           `yaml:"cpus,omitempty" json:"cpus,omitempty" desc:"CPU limit in cores, e.g. 2.5" min:"0"`
+	Memory            string            `yaml:"memory,omitempty" json:"memory,omitempty" desc:"Memory limit, e.g. 8g"`
+	CPUsReservation   float64           `yaml:"cpus_reservation,omitempty" json:"cpus_reservation,omitempty" desc:"CPU cores reserved for the container" min:"0"`
+	MemoryReservation string            `yaml:"memory_reservation,omitempty" json:"memory_reservation,omitempty" desc:"Memory reserved for the container, e.g. 2g"`
+	PidsLimit         int               `yaml:"pids_limit,omitempty" json:"pids_limit,omitempty" desc:"Maximum number of processes, -1 for unlimited" min:"-1"`
+	Ulimits           map[string]string `yaml:"ulimits,omitempty" json:"ulimits,omitempty" desc:"Ulimits as soft:hard or a single value for both, e.g. nofile: 65536:65536"`
+}
+
+// ulimitNames are the ulimits Docker accepts
+var ulimitNames = map[string]bool{
+	"core": true, "cpu": true, "data": true, "fsize": true, "locks": true,
+	"memlock": true, "msgqueue": true, "nice": true, "nofile": true, "nproc": true,
+	"rss": true, "rtprio": true, "rttime": true, "sigpending": true, "stack": true,
+}
+
+// ParseMemory parses a memory size such as 512m or 8g into bytes. Units are
+// b, k, m, g and t, case-insensitive and optionally followed by b.
+func ParseMemory(s string) (int64, error) {
+	value := strings.ToLower(strings.TrimSpace(s))
+	value = strings.TrimSuffix(value, "b")
+
+	multiplier := int64(1)
+	if n := len(value); n > 0 {
+		switch value[n-1] {
+		case 'k':
+			multiplier = 1 << 10
+		case 'm':
+			multiplier = 1 << 20
+		case 'g':
+			multiplier = 1 << 30
+		case 't':
+			multiplier = 1 << 40
+		}
+		if multiplier > 1 {
+			value = value[:n-1]
+		}
+	}
+
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q, expected a positive number with an optional unit, e.g. 512m or 8g", s)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// parseUlimit parses a soft:hard or single ulimit value; -1 is unlimited
+func parseUlimit(value string) (soft, hard int64, err error) {
+	softValue, hardValue, ok := strings.Cut(value, ":")
+	if !ok {
+		hardValue = softValue
+	}
+	if soft, err = strconv.ParseInt(softValue, 10, 64); err == nil {
+		hard, err = strconv.ParseInt(hardValue, 10, 64)
+	}
+	if err != nil || soft < -1 || hard < -1 {
+		return 0, 0, fmt.Errorf("invalid ulimit %q, expected soft:hard or a single number", value)
+	}
+	if hard != -1 && (soft == -1 || soft > hard) {
+		return 0, 0, fmt.Errorf("invalid ulimit %q, the soft limit exceeds the hard limit", value)
+	}
+	return soft, hard, nil
+}
+
+// sortedUlimits returns the ulimit names of r, sorted
+func (r ResourceConfig) sortedUlimits() []string {
+	names := make([]string, 0, len(r.Ulimits))
+	for name := range r.Ulimits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// formatCPUs formats a CPU count for compose and docker run
+func formatCPUs(cpus float64) string {
+	return strconv.FormatFloat(cpus, 'f', -1, 64)
+}
+
+// DockerRunArgs returns the docker run flags for the limits
+func (r ResourceConfig) DockerRunArgs() []string {
+	var args []string
+	if r.CPUs > 0 {
+		args = append(args, "--cpus", formatCPUs(r.CPUs))
+	}
+	if r.Memory != "" {
+		args = append(args, "--memory", r.Memory)
+	}
+	if r.MemoryReservation != "" {
+		args = append(args, "--memory-reservation", r.MemoryReservation)
+	}
+	if r.PidsLimit != 0 {
+		args = append(args, "--pids-limit", strconv.Itoa(r.PidsLimit))
+	}
+	for _, name := range r.sortedUlimits() {
+		soft, hard, err := parseUlimit(r.Ulimits[name])
+		if err != nil {
+			continue
+		}
+		args = append(args, "--ulimit", fmt.Sprintf("%s=%d:%d", name, soft, hard))
+	}
+	return args
+}
+
+// composeLines returns the compose keys for the limits, indented to sit in
+// a service definition
+func (r ResourceConfig) composeLines() []string {
+	var limits, reservations, lines []string
+	if r.CPUs > 0 {
+		limits = append(limits, "cpus: "+yamlQuote(formatCPUs(r.CPUs)))
+	}
+	if r.Memory != "" {
+		limits = append(limits, "memory: "+yamlQuote(r.Memory))
+	}
+	if r.CPUsReservation > 0 {
+		reservations = append(reservations, "cpus: "+yamlQuote(formatCPUs(r.CPUsReservation)))
+	}
+	if r.MemoryReservation != "" {
+		reservations = append(reservations, "memory: "+yamlQuote(r.MemoryReservation))
+	}
+
+	if len(limits) > 0 || len(reservations) > 0 {
+		lines = append(lines, "deploy:", "  resources:")
+		if len(limits) > 0 {
+			lines = append(lines, "    limits:")
+			for _, l := range limits {
+				lines = append(lines, "      "+l)
+			}
+		}
+		if len(reservations) > 0 {
+			lines = append(lines, "    reservations:")
+			for _, l := range reservations {
+				lines = append(lines, "      "+l)
+			}
+		}
+	}
+	if r.PidsLimit != 0 {
+		lines = append(lines, fmt.Sprintf("pids_limit: %d", r.PidsLimit))
+	}
+	if len(r.Ulimits) > 0 {
+		lines = append(lines, "ulimits:")
+		for _, name := range r.sortedUlimits() {
+			soft, hard, err := parseUlimit(r.Ulimits[name])
+			if err != nil {
+				continue
+			}
+			lines = append(lines, "  "+name+":", fmt.Sprintf("    soft: %d", soft), fmt.Sprintf("    hard: %d", hard))
+		}
+	}
+
+	for i, l := range lines {
+		lines[i] = "    " + l
+	}
+	return lines
+}
+
+// Restart returns the restart policy of a built-in service, or def when the
+// user did not set one
+func (c *Config) Restart(service, def string) string {
+	if policy := c.ServiceSettings[service].Restart; policy != "" {
+		return yamlQuote(policy)
+	}
+	return yamlQuote(def)
+}
+
+// Host describes the CPU and memory of the machine the containers run on
+type Host struct {
+	CPUs   int
+	Memory int64
+}
+
+// DetectHost returns the CPU count and total memory of this machine. Memory
+// is zero when /proc/meminfo is not available.
+func DetectHost() Host {
+	host := Host{CPUs: runtime.NumCPU()}
+
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return host
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			if kb, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+				host.Memory = kb << 10
+			}
+			break
+		}
+	}
+	return host
+}
+
+// containerResources returns the resource settings of every enabled
+// container, including the inference models, by config path
+func (c *Config) containerResources() map[string]ResourceConfig {
+	enabled := map[string]bool{
+		"postgres":         true,
+		"backend":          true,
+		"frontend":         true,
+		"silo-proxy-agent": c.EnableProxyAgent,
+		"deep-research":    c.EnableDeepResearch,
+	}
+	resources := make(map[string]ResourceConfig)
+	for name, settings := range c.ServiceSettings {
+		if enabled[name] {
+			resources["services."+name+".resources"] = settings.Resources
+		}
+	}
+	for _, container := range c.InferenceContainers() {
+		if container.Enabled {
+			resources[container.Path+".resources"] = container.Resources
+		}
+	}
+	return resources
+}
+
+// resourceProblems checks the resource settings of every container, and
+// the limits and the sum of the reservations against host. The host checks
+// are warnings: the config may be validated on another machine than the one
+// it runs on, e.g. before a bundle export.
+func (c *Config) resourceProblems(host Host) []Problem {
+	var problems []Problem
+	var reservedCPUs float64
+	var reservedMemory int64
+
+	resources := c.containerResources()
+	paths := make([]string, 0, len(resources))
+	for path := range resources {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		r := resources[path]
+		add := func(key, format string, args ...any) {
+			problems = append(problems, Problem{Path: path + "." + key, Message: fmt.Sprintf(format, args...)})
+		}
+		warn := func(key, format string, args ...any) {
+			problems = append(problems, Problem{Path: path + "." + key, Message: fmt.Sprintf(format, args...), Severity: SeverityWarning})
+		}
+
+		var memory, memoryReservation int64
+		if r.Memory != "" {
+			var err error
+			if memory, err = ParseMemory(r.Memory); err != nil {
+				add("memory", "%v", err)
+			} else if host.Memory > 0 && memory > host.Memory {
+				warn("memory", "%s exceeds the host memory of %s", r.Memory, units.FormatBytes(host.Memory))
+			}
+		}
+		if r.MemoryReservation != "" {
+			var err error
+			if memoryReservation, err = ParseMemory(r.MemoryReservation); err != nil {
+				add("memory_reservation", "%v", err)
+			} else if memory > 0 && memoryReservation > memory {
+				add("memory_reservation", "%s exceeds the memory limit of %s", r.MemoryReservation, r.Memory)
+			}
+			reservedMemory += memoryReservation
+		}
+
+		if host.CPUs > 0 && r.CPUs > float64(host.CPUs) {
+			warn("cpus", "%s exceeds the host CPU count of %d", formatCPUs(r.CPUs), host.CPUs)
+		}
+		if r.CPUs > 0 && r.CPUsReservation > r.CPUs {
+			add("cpus_reservation", "%s exceeds the CPU limit of %s", formatCPUs(r.CPUsReservation), formatCPUs(r.CPUs))
+		}
+		if !strings.HasPrefix(path, "services.") && r.CPUsReservation > 0 {
+			add("cpus_reservation", "not supported by docker run, use cpus to limit the inference container")
+		}
+		reservedCPUs += r.CPUsReservation
+
+		for _, name := range r.sortedUlimits() {
+			if !ulimitNames[name] {
+				add("ulimits."+name, "unknown ulimit")
+				continue
+			}
+			if _, _, err := parseUlimit(r.Ulimits[name]); err != nil {
+				add("ulimits."+name, "%v", err)
+			}
+		}
+	}
+
+	if host.CPUs > 0 && reservedCPUs > float64(host.CPUs) {
+		problems = append(problems, Problem{Path: "services", Message: fmt.Sprintf("CPU reservations add up to %s, more than the host CPU count of %d", formatCPUs(reservedCPUs), host.CPUs), Severity: SeverityWarning})
+	}
+	if host.Memory > 0 && reservedMemory > host.Memory {
+		problems = append(problems, Problem{Path: "services", Message: fmt.Sprintf("memory reservations add up to %s, more than the host memory of %s", units.FormatBytes(reservedMemory), units.FormatBytes(host.Memory)), Severity: SeverityWarning})
+	}
+	return problems
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseMemory(t *testing.T) {
+	tests := map[string]int64{
+		"512m":  512 << 20,
+		"8g":    8 << 30,
+		"8GB":   8 << 30,
+		"1.5g":  3 << 29,
+		"1024":  1024,
+		"100kb": 100 << 10,
+	}
+	for input, want := range tests {
+		got, err := ParseMemory(input)
+		if err != nil || got != want {
+			t.Errorf("ParseMemory(%q) = %d, %v, want %d", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"", "g", "-1g", "8x", "eight"} {
+		if _, err := ParseMemory(input); err == nil {
+			t.Errorf("Expected ParseMemory(%q) to fail", input)
+		}
+	}
+}
+
+func TestResourceProblems(t *testing.T) {
+	cfg := NewDefaultConfig(NewPaths(t.TempDir(), t.TempDir()))
+	cfg.SGLang.Enabled = true
+	cfg.SGLang.Resources = ResourceConfig{CPUsReservation: 1}
+	cfg.ServiceSettings = map[string]ServiceConfig{
+		"postgres": {Resources: ResourceConfig{MemoryReservation: "6g", CPUsReservation: 3}},
+		"backend": {Resources: ResourceConfig{
+			CPUs:              8,
+			Memory:            "2g",
+			MemoryReservation: "3g",
+			Ulimits:           map[string]string{"nofiles": "1", "nproc": "10:5"},
+		}},
+		"deep-research": {Resources: ResourceConfig{Memory: "32g", CPUsReservation: 2}},
+	}
+
+	problems := map[string]Severity{}
+	for _, p := range cfg.resourceProblems(Host{CPUs: 4, Memory: 8 << 30}) {
+		if _, seen := problems[p.Path]; !seen || p.Severity == "" {
+			problems[p.Path] = p.Severity
+		}
+	}
+	// Only the checks against the host are warnings
+	for path, severity := range map[string]Severity{
+		"services.backend.resources.cpus":               SeverityWarning,
+		"services.backend.resources.memory_reservation": "",
+		"services.backend.resources.ulimits.nofiles":    "",
+		"services.backend.resources.ulimits.nproc":      "",
+		"services.deep-research.resources.memory":       SeverityWarning,
+		"sglang.resources.cpus_reservation":             "",
+		"services":                                      SeverityWarning,
+	} {
+		got, ok := problems[path]
+		if !ok {
+			t.Errorf("Expected a problem at %s, got %v", path, problems)
+		} else if got != severity {
+			t.Errorf("Expected severity %q at %s, got %q", severity, path, got)
+		}
+	}
+
+	cfg.EnableDeepResearch = false
+	cfg.SGLang.Enabled = false
+	cfg.ServiceSettings["backend"] = ServiceConfig{}
+	cfg.ServiceSettings["postgres"] = ServiceConfig{Resources: ResourceConfig{MemoryReservation: "6g", CPUsReservation: 3}}
+	if problems := cfg.resourceProblems(Host{CPUs: 4, Memory: 8 << 30}); len(problems) != 0 {
+		t.Errorf("Expected disabled containers to be ignored, got %v", problems)
+	}
+}
+
+func TestGenerateDockerCompose_Resources(t *testing.T) {
+	paths := NewPaths(t.TempDir(), t.TempDir())
+	cfg := NewDefaultConfig(paths)
+	cfg.ServiceSettings = map[string]ServiceConfig{
+		"deep-research": {
+			Resources: ResourceConfig{
+				CPUs:              2,
+				Memory:            "4g",
+				MemoryReservation: "1g",
+				PidsLimit:         512,
+				Ulimits:           map[string]string{"nofile": "1024:4096", "memlock": "-1"},
+			},
+			Restart: "on-failure",
+		},
+		"postgres": {Restart: "no", Resources: ResourceConfig{CPUsReservation: 0.5}},
+	}
+	if err := os.MkdirAll(paths.DataDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := GenerateDockerCompose(cfg, paths.ComposeFile); err != nil {
+		t.Fatalf("GenerateDockerCompose failed: %v", err)
+	}
+
+	data, err := os.ReadFile(paths.ComposeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type resources struct {
+		CPUs   string `yaml:"cpus"`
+		Memory string `yaml:"memory"`
+	}
+	var compose struct {
+		Services map[string]struct {
+			Restart string `yaml:"restart"`
+			Deploy  struct {
+				Resources struct {
+					Limits       resources `yaml:"limits"`
+					Reservations resources `yaml:"reservations"`
+				} `yaml:"resources"`
+			} `yaml:"deploy"`
+			PidsLimit int `yaml:"pids_limit"`
+			Ulimits   map[string]struct {
+				Soft int `yaml:"soft"`
+				Hard int `yaml:"hard"`
+			} `yaml:"ulimits"`
+		} `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(data, &compose); err != nil {
+		t.Fatalf("Generated compose file is not valid YAML: %v\n%s", err, data)
+	}
+
+	dr := compose.Services["deep-research"]
+	if dr.Deploy.Resources.Limits != (resources{"2", "4g"}) || dr.Deploy.Resources.Reservations != (resources{"", "1g"}) {
+		t.Errorf("Unexpected deep-research resources: %+v", dr.Deploy.Resources)
+	}
+	if dr.PidsLimit != 512 || dr.Ulimits["nofile"].Hard != 4096 || dr.Ulimits["memlock"].Soft != -1 {
+		t.Errorf("Unexpected deep-research pids limit or ulimits: %d %+v", dr.PidsLimit, dr.Ulimits)
+	}
+	if dr.Restart != "on-failure" {
+		t.Errorf("Expected deep-research restart on-failure, got %q", dr.Restart)
+	}
+
+	pg := compose.Services["postgres"]
+	if pg.Restart != "no" || pg.Deploy.Resources.Reservations.CPUs != "0.5" {
+		t.Errorf("Unexpected postgres settings: restart %q, %+v", pg.Restart, pg.Deploy.Resources)
+	}
+	if compose.Services["backend"].Restart != "unless-stopped" {
+		t.Errorf("Expected the built-in restart policy for backend, got %q", compose.Services["backend"].Restart)
+	}
+	if strings.Contains(string(data), "deploy:\n    restart") {
+		t.Errorf("Unexpected empty deploy section:\n%s", data)
+	}
+}
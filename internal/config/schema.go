@@ -0,0 +1,333 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaID is the $id of the generated JSON Schema
+const SchemaID = "https://github.com/EternisAI/silo/config.schema.json"
+
+// Schema is a JSON Schema for a config value. It is generated from the
+// yaml, desc, enum, min, max and nonempty struct tags of Config.
+type Schema struct {
+	SchemaURI            string             `json:"$schema,omitempty"`
+	ID                   string             `json:"$id,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            int                `json:"minLength,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties any                `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+
+	// values is the schema of map values, nil for structs
+	values *Schema
+}
+
+// ConfigSchema returns the JSON Schema of config.yml
+func ConfigSchema() *Schema {
+	s := schemaFor(reflect.TypeOf(Config{}))
+	s.SchemaURI = "https://json-schema.org/draft/2020-12/schema"
+	s.ID = SchemaID
+	s.Title = "Silo config.yml"
+	return s
+}
+
+// JSON returns the schema as indented JSON
+func (s *Schema) JSON() ([]byte, error) {
+	return json.MarshalIndent(s, "", "  ")
+}
+
+// schemaFor builds the schema of a Go type
+func schemaFor(t reflect.Type) *Schema {
+	switch t.Kind() {
+	case reflect.Struct:
+		s := &Schema{Type: "object", Properties: make(map[string]*Schema), AdditionalProperties: false}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.Anonymous && strings.Contains(field.Tag.Get("yaml"), ",inline") {
+				maps.Copy(s.Properties, schemaFor(field.Type).Properties)
+				continue
+			}
+			name := yamlName(field)
+			if name == "" {
+				continue
+			}
+			s.Properties[name] = fieldSchema(field)
+		}
+		return s
+	case reflect.Pointer:
+		return schemaFor(t.Elem())
+	case reflect.Map:
+		values := schemaFor(t.Elem())
+		return &Schema{Type: "object", AdditionalProperties: values, values: values}
+	case reflect.Slice:
+		return &Schema{Type: "array", Items: schemaFor(t.Elem())}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Interface:
+		// Any value, e.g. compose service definitions
+		return &Schema{}
+	default:
+		return &Schema{Type: "string"}
+	}
+}
+
+// fieldSchema builds the schema of a struct field, applying its tags
+func fieldSchema(field reflect.StructField) *Schema {
+	s := schemaFor(field.Type)
+	s.Description = field.Tag.Get("desc")
+	if enum := field.Tag.Get("enum"); enum != "" {
+		s.Enum = strings.Split(enum, ",")
+	}
+	if v, err := strconv.ParseFloat(field.Tag.Get("min"), 64); err == nil {
+		s.Minimum = &v
+	}
+	if v, err := strconv.ParseFloat(field.Tag.Get("max"), 64); err == nil {
+		s.Maximum = &v
+	}
+	if field.Tag.Get("nonempty") == "true" {
+		s.MinLength = 1
+	}
+	return s
+}
+
+// yamlName returns the key of a struct field in config.yml, or "" if the
+// field is not part of the file
+func yamlName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "-" || !field.IsExported() {
+		return ""
+	}
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+// Severity tells whether a problem blocks using the config
+type Severity string
+
+const (
+	// SeverityError problems make the config unusable and block saving
+	SeverityError Severity = "error"
+
+	// SeverityWarning problems are reported but do not block anything
+	SeverityWarning Severity = "warning"
+)
+
+// Problem is a config value that violates the schema or a validation rule.
+// Line and Column are 0 when the value has no position in a file. Problems
+// without a severity are errors.
+type Problem struct {
+	Path     string   `json:"path"`
+	Line     int      `json:"line,omitempty"`
+	Column   int      `json:"column,omitempty"`
+	Message  string   `json:"message"`
+	Severity Severity `json:"severity,omitempty"`
+}
+
+// IsWarning reports whether the problem is only a warning
+func (p Problem) IsWarning() bool {
+	return p.Severity == SeverityWarning
+}
+
+// SplitProblems separates errors from warnings, keeping their order
+func SplitProblems(problems []Problem) (errors, warnings []Problem) {
+	for _, p := range problems {
+		if p.IsWarning() {
+			warnings = append(warnings, p)
+		} else {
+			errors = append(errors, p)
+		}
+	}
+	return errors, warnings
+}
+
+func (p Problem) String() string {
+	if p.Line > 0 {
+		return fmt.Sprintf("line %d, column %d: %s: %s", p.Line, p.Column, p.Path, p.Message)
+	}
+	return fmt.Sprintf("%s: %s", p.Path, p.Message)
+}
+
+// ValidationError holds every problem found by Validate
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		msgs[i] = p.String()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// schemaCheck walks a YAML node against a schema
+type schemaCheck struct {
+	problems []Problem
+	unknown  []Problem
+}
+
+func (c *schemaCheck) add(path string, node *yaml.Node, format string, args ...any) {
+	c.problems = append(c.problems, Problem{
+		Path:    path,
+		Line:    node.Line,
+		Column:  node.Column,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (c *schemaCheck) check(s *Schema, node *yaml.Node, path string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" || s.Type == "" {
+		return
+	}
+
+	switch s.Type {
+	case "object":
+		if node.Kind != yaml.MappingNode {
+			c.add(path, node, "must be a mapping")
+			return
+		}
+		c.checkMapping(s, node, path)
+
+	case "array":
+		if node.Kind != yaml.SequenceNode {
+			c.add(path, node, "must be a list")
+			return
+		}
+		for i, item := range node.Content {
+			c.check(s.Items, item, fmt.Sprintf("%s[%d]", path, i))
+		}
+
+	default:
+		if node.Kind != yaml.ScalarNode {
+			c.add(path, node, "must be a %s", s.Type)
+			return
+		}
+		c.checkScalar(s, node, path)
+	}
+}
+
+func (c *schemaCheck) checkMapping(s *Schema, node *yaml.Node, path string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		child := key.Value
+		if path != "" {
+			child = path + "." + key.Value
+		}
+
+		if s.values != nil {
+			c.check(s.values, value, child)
+			continue
+		}
+
+		prop, ok := s.Properties[key.Value]
+		if !ok {
+			c.unknown = append(c.unknown, Problem{Path: child, Line: key.Line, Column: key.Column, Message: "unknown field"})
+			continue
+		}
+		c.check(prop, value, child)
+	}
+}
+
+func (c *schemaCheck) checkScalar(s *Schema, node *yaml.Node, path string) {
+	switch s.Type {
+	case "boolean":
+		if node.Tag != "!!bool" {
+			c.add(path, node, "must be true or false, got %q", node.Value)
+			return
+		}
+	case "integer":
+		if node.Tag != "!!int" {
+			c.add(path, node, "must be an integer, got %q", node.Value)
+			return
+		}
+	case "number":
+		if node.Tag != "!!int" && node.Tag != "!!float" {
+			c.add(path, node, "must be a number, got %q", node.Value)
+			return
+		}
+	}
+
+	if s.Type == "integer" || s.Type == "number" {
+		var value float64
+		if err := node.Decode(&value); err != nil || math.IsNaN(value) {
+			c.add(path, node, "must be a number, got %q", node.Value)
+			return
+		}
+		if s.Minimum != nil && value < *s.Minimum {
+			c.add(path, node, "must be at least %g, got %s", *s.Minimum, node.Value)
+		}
+		if s.Maximum != nil && value > *s.Maximum {
+			c.add(path, node, "must be at most %g, got %s", *s.Maximum, node.Value)
+		}
+		return
+	}
+
+	if s.MinLength > 0 && node.Value == "" {
+		c.add(path, node, "cannot be empty")
+	}
+	if len(s.Enum) > 0 {
+		for _, v := range s.Enum {
+			if node.Value == v {
+				return
+			}
+		}
+		c.add(path, node, "must be one of %s, got %q", strings.Join(s.Enum, ", "), node.Value)
+	}
+}
+
+// checkDocument checks the YAML document data against the config schema
+func checkDocument(data []byte) (*schemaCheck, error) {
+	doc, err := parseDocument(data)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &schemaCheck{}
+	c.check(ConfigSchema(), doc.Content[0], "")
+	sortProblems(c.problems)
+	sortProblems(c.unknown)
+	return c, nil
+}
+
+// ValidateDocument checks the config file contents data against the schema
+// and returns every violation with its line and column. Unknown keys are
+// reported by FindUnknownFields instead.
+func ValidateDocument(data []byte) ([]Problem, error) {
+	c, err := checkDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	return c.problems, nil
+}
+
+func sortProblems(problems []Problem) {
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Line != problems[j].Line {
+			return problems[i].Line < problems[j].Line
+		}
+		return problems[i].Column < problems[j].Column
+	})
+}
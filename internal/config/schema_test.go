@@ -0,0 +1,133 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigSchema(t *testing.T) {
+	data, err := ConfigSchema().JSON()
+	if err != nil {
+		t.Fatalf("JSON failed: %v", err)
+	}
+
+	var schema map[string]any
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("Schema is not valid JSON: %v", err)
+	}
+	if schema["additionalProperties"] != false {
+		t.Error("Expected top level to reject unknown keys")
+	}
+
+	props := schema["properties"].(map[string]any)
+	port := props["port"].(map[string]any)
+	if port["type"] != "integer" || port["minimum"] != 1.0 || port["maximum"] != 65535.0 {
+		t.Errorf("Unexpected port schema: %v", port)
+	}
+	if _, ok := props["config_file"]; ok {
+		t.Error("Expected fields not stored in config.yml to be left out")
+	}
+
+	sglang := props["sglang"].(map[string]any)["properties"].(map[string]any)
+	if enum := sglang["log_level"].(map[string]any)["enum"]; enum == nil {
+		t.Error("Expected sglang.log_level to have an enum")
+	}
+	mirrors := props["registry"].(map[string]any)["properties"].(map[string]any)["mirrors"].(map[string]any)
+	if mirrors["additionalProperties"].(map[string]any)["type"] != "string" {
+		t.Errorf("Expected registry.mirrors to map to strings, got %v", mirrors)
+	}
+}
+
+func TestValidateDocument(t *testing.T) {
+	data := `port: 0
+enable_deep_research: "yes"
+sglang:
+  mem_fraction_static: 1.5
+  kv_cache_dtype: fp16
+  gpu_devices: "0"
+registry:
+  mirrors:
+    docker.io: [a]
+`
+	problems, err := ValidateDocument([]byte(data))
+	if err != nil {
+		t.Fatalf("ValidateDocument failed: %v", err)
+	}
+
+	expected := []Problem{
+		{Path: "port", Line: 1, Column: 7},
+		{Path: "enable_deep_research", Line: 2, Column: 23},
+		{Path: "sglang.mem_fraction_static", Line: 4, Column: 24},
+		{Path: "sglang.kv_cache_dtype", Line: 5, Column: 19},
+		{Path: "sglang.gpu_devices", Line: 6, Column: 16},
+		{Path: "registry.mirrors.docker.io", Line: 9, Column: 16},
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("Expected %d problems, got %d: %v", len(expected), len(problems), problems)
+	}
+	for i, want := range expected {
+		got := problems[i]
+		if got.Path != want.Path || got.Line != want.Line || got.Column != want.Column {
+			t.Errorf("Problem %d: expected %s at %d:%d, got %s", i, want.Path, want.Line, want.Column, got)
+		}
+	}
+}
+
+func TestFindUnknownFields_Nested(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	data := `port: 80
+typo_port: 81
+sglang:
+  prot: 30000
+registry:
+  credentials:
+    registry.local:
+      username: silo
+      token: abc
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	unknown, err := FindUnknownFields(path)
+	if err != nil {
+		t.Fatalf("FindUnknownFields failed: %v", err)
+	}
+
+	expected := []string{"typo_port", "sglang.prot", "registry.credentials.registry.local.token"}
+	if len(unknown) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, unknown)
+	}
+	for i, path := range expected {
+		if unknown[i].Path != path {
+			t.Errorf("Expected unknown field %s, got %s", path, unknown[i].Path)
+		}
+	}
+	if unknown[1].Line != 4 || unknown[1].Column != 3 {
+		t.Errorf("Expected sglang.prot at 4:3, got %d:%d", unknown[1].Line, unknown[1].Column)
+	}
+}
+
+func TestValidate_AllProblems(t *testing.T) {
+	cfg := NewDefaultConfig(NewPaths(t.TempDir(), t.TempDir()))
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("Expected default config to be valid, got %v", err)
+	}
+
+	cfg.Port = 0
+	cfg.DefaultModel = ""
+	cfg.EnableProxyAgent = true
+	cfg.ProxyServerURL = ""
+
+	err := Validate(cfg)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Expected *ValidationError, got %v", err)
+	}
+	if len(verr.Problems) != 3 {
+		t.Errorf("Expected 3 problems, got %v", verr.Problems)
+	}
+}
@@ -0,0 +1,271 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// SecretsFileName holds the credentials of an instance, next to
+	// config.yml and readable by the owner only
+	SecretsFileName = "secrets.env"
+
+	// EnvDirName is the directory in the instance data directory holding
+	// the env files docker-compose.yml passes to services
+	EnvDirName = "env"
+
+	SecretPostgresPassword   = "postgres_password"
+	SecretDeepResearchAPIKey = "deep_research_api_key"
+	SecretPerplexityAPIKey   = "perplexity_api_key"
+
+	// LegacyPostgresPassword is the password installs used before secrets
+	// were generated. Their database keeps it until it is rotated.
+	LegacyPostgresPassword = "silobox"
+
+	postgresUser     = "silobox"
+	postgresDatabase = "silobox"
+)
+
+// SecretSpec describes a secret silo manages
+type SecretSpec struct {
+	Name        string
+	Description string
+
+	// Generated secrets are created at install and can be rotated; the
+	// others are provided by the user
+	Generated bool
+
+	// Services are the compose services that receive the secret
+	Services []string
+}
+
+// SecretSpecs lists the secrets of an instance
+var SecretSpecs = []SecretSpec{
+	{
+		Name:        SecretPostgresPassword,
+		Description: "Password of the PostgreSQL silobox user",
+		Generated:   true,
+		Services:    []string{"postgres", "backend"},
+	},
+	{
+		Name:        SecretDeepResearchAPIKey,
+		Description: "API key deep research sends to the LLM API",
+		Generated:   true,
+		Services:    []string{"deep-research"},
+	},
+	{
+		Name:        SecretPerplexityAPIKey,
+		Description: "Perplexity API key for deep research",
+		Services:    []string{"deep-research"},
+	},
+}
+
+// LookupSecret returns the spec of the named secret
+func LookupSecret(name string) (SecretSpec, error) {
+	for _, spec := range SecretSpecs {
+		if spec.Name == name {
+			return spec, nil
+		}
+	}
+	names := make([]string, len(SecretSpecs))
+	for i, spec := range SecretSpecs {
+		names[i] = spec.Name
+	}
+	return SecretSpec{}, fmt.Errorf("unknown secret %q (known: %s)", name, strings.Join(names, ", "))
+}
+
+// Secrets maps secret names to their values
+type Secrets map[string]string
+
+// LoadSecrets reads the secrets file at path. A missing file yields no
+// secrets and no error.
+func LoadSecrets(path string) (Secrets, error) {
+	secrets := make(Secrets)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return secrets, nil
+		}
+		return nil, fmt.Errorf("failed to read secrets file: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(text, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected NAME=value", path, line)
+		}
+		secrets[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+	return secrets, nil
+}
+
+// SaveSecrets writes secrets to path atomically with mode 0600
+func SaveSecrets(path string, secrets Secrets) error {
+	var buf bytes.Buffer
+	buf.WriteString("# Silo secrets, managed with 'silo secrets'. Keep this file private.\n")
+	for _, name := range secrets.names() {
+		fmt.Fprintf(&buf, "%s=%s\n", strings.ToUpper(name), secrets[name])
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := writeFileAtomic(path, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write secrets file: %w", err)
+	}
+	return nil
+}
+
+// names returns the known secrets first, in SecretSpecs order, then any
+// others sorted
+func (s Secrets) names() []string {
+	var names, others []string
+	known := make(map[string]bool)
+	for _, spec := range SecretSpecs {
+		known[spec.Name] = true
+		if _, ok := s[spec.Name]; ok {
+			names = append(names, spec.Name)
+		}
+	}
+	for name := range s {
+		if !known[name] {
+			others = append(others, name)
+		}
+	}
+	sort.Strings(others)
+	return append(names, others...)
+}
+
+// GenerateSecret returns a random 256 bit value as hex, safe to use in URLs
+// and env files
+func GenerateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// ValidateSecretValue checks that value can be written to an env file
+func ValidateSecretValue(value string) error {
+	if value == "" {
+		return fmt.Errorf("secret cannot be empty")
+	}
+	if strings.ContainsAny(value, " \t\r\n'\"#\\") {
+		return fmt.Errorf("secret cannot contain whitespace, quotes, '#' or '\\'")
+	}
+	return nil
+}
+
+// EnsureSecrets loads the secrets of cfg and generates the missing ones,
+// saving the file if anything was added. An existing database keeps the
+// legacy postgres password, since the password is only applied when the
+// database is created; rotate it to replace it.
+func EnsureSecrets(cfg *Config) (Secrets, error) {
+	secrets, err := LoadSecrets(cfg.SecretsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := false
+	for _, spec := range SecretSpecs {
+		if !spec.Generated || secrets[spec.Name] != "" {
+			continue
+		}
+		value, err := GenerateSecret()
+		if err != nil {
+			return nil, err
+		}
+		if spec.Name == SecretPostgresPassword && DatabaseExists(cfg) {
+			value = LegacyPostgresPassword
+		}
+		secrets[spec.Name] = value
+		changed = true
+	}
+
+	if changed {
+		if err := SaveSecrets(cfg.SecretsFile, secrets); err != nil {
+			return nil, err
+		}
+	}
+	return secrets, nil
+}
+
+// DatabaseExists reports whether postgres has initialized its data directory
+func DatabaseExists(cfg *Config) bool {
+	_, err := os.Stat(filepath.Join(cfg.DataDir, "postgres", "PG_VERSION"))
+	return err == nil
+}
+
+// ServiceEnv returns the environment each compose service receives from its
+// env file
+func ServiceEnv(secrets Secrets) map[string][]string {
+	password := secrets[SecretPostgresPassword]
+	// The password is escaped, since it may contain '@', '/', ':' or '%'
+	databaseURL := &url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(postgresUser, password),
+		Host:     "postgres:5432",
+		Path:     "/" + postgresDatabase,
+		RawQuery: "sslmode=disable",
+	}
+	return map[string][]string{
+		"postgres": {
+			"POSTGRES_PASSWORD=" + password,
+		},
+		"backend": {
+			"DATABASE_URL=" + databaseURL.String(),
+		},
+		"deep-research": {
+			"API_KEY=" + secrets[SecretDeepResearchAPIKey],
+			"PERPLEXITY_API_KEY=" + secrets[SecretPerplexityAPIKey],
+		},
+	}
+}
+
+// WriteEnvFiles writes the env file of every service that receives secrets,
+// with mode 0600
+func WriteEnvFiles(cfg *Config, secrets Secrets) error {
+	if err := os.MkdirAll(cfg.EnvDir, 0700); err != nil {
+		return fmt.Errorf("failed to create env directory: %w", err)
+	}
+
+	for service, env := range ServiceEnv(secrets) {
+		data := strings.Join(env, "\n") + "\n"
+		if err := writeFileAtomic(cfg.EnvFile(service), []byte(data), 0600); err != nil {
+			return fmt.Errorf("failed to write env file for %s: %w", service, err)
+		}
+	}
+	return nil
+}
+
+// EnvFile returns the path of the env file of a compose service
+func (c *Config) EnvFile(service string) string {
+	return filepath.Join(c.EnvDir, service+".env")
+}
+
+// PostgresPasswordSQL returns the statement that sets the password of the
+// postgres user
+func PostgresPasswordSQL(password string) string {
+	return fmt.Sprintf("ALTER USER %s WITH PASSWORD '%s';\n", postgresUser, password)
+}
+
+// PostgresPSQLCommand returns the psql command, run in the postgres
+// container, that reads statements from stdin as the silo database user
+func PostgresPSQLCommand() []string {
+	return []string{"psql", "-U", postgresUser, "-d", postgresDatabase, "-v", "ON_ERROR_STOP=1", "-q"}
+}
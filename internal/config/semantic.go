@@ -0,0 +1,116 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/eternisai/silo/internal/registry"
+)
+
+// AttentionBackends are the SGLang attention backends known to this
+// release. Others are reported as warnings, since SGLang adds backends
+// between releases.
+var AttentionBackends = []string{
+	"aiter", "ascend", "cutlass_mla", "dual_chunk_flash_attn", "fa3", "fa4",
+	"flashinfer", "flashmla", "flex_attention", "intel_amx", "torch_native",
+	"triton", "trtllm_mha", "trtllm_mla", "wave",
+}
+
+// SearchProviders maps the search providers deep research supports to the
+// secret holding their API key, or "" when they need none
+var SearchProviders = map[string]string{
+	"perplexity": SecretPerplexityAPIKey,
+}
+
+// semanticProblems checks values that are well-typed but meaningless, and
+// settings that only make sense together
+func (c *Config) semanticProblems() []Problem {
+	var problems []Problem
+	add := func(severity Severity, path, format string, args ...any) {
+		problems = append(problems, Problem{Path: path, Message: fmt.Sprintf(format, args...), Severity: severity})
+	}
+
+	// An empty llm_base_url is derived from the inference backend
+	if c.LLMBaseURL != "" {
+		if u, err := url.Parse(c.LLMBaseURL); err != nil {
+			add(SeverityError, "llm_base_url", "invalid URL: %v", err)
+		} else if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+			add(SeverityError, "llm_base_url", "must be an http or https URL with a host, got %q", c.LLMBaseURL)
+		}
+	}
+
+	images := map[string]string{
+		"image_tag":           c.ImageRef("backend"),
+		"deep_research_image": c.DeepResearchImage,
+	}
+	for _, path := range []string{"image_tag", "deep_research_image"} {
+		if images[path] == "" {
+			continue // reported by the schema
+		}
+		if err := registry.ValidateReference(images[path]); err != nil {
+			add(SeverityError, path, "%v", err)
+		}
+	}
+
+	// With a models list the sglang section only supplies defaults, so
+	// its settings are checked as part of every model. The settings of
+	// the other backends are checked by backendProblems.
+	if c.Inference.Backend == BackendSGLang {
+		for i, m := range c.InferenceModels() {
+			problems = append(problems, engineProblems(c.modelPath(i), m.SGLangConfig)...)
+		}
+	}
+
+	if c.EnableDeepResearch {
+		secret, known := SearchProviders[c.SearchProvider]
+		switch {
+		case !known:
+			add(SeverityError, "search_provider", "must be one of %s, got %q", strings.Join(searchProviderNames(), ", "), c.SearchProvider)
+		case secret != "":
+			// An unreadable secrets file is reported by silo check
+			if secrets, err := LoadSecrets(c.SecretsFile); err == nil && secrets[secret] == "" {
+				add(SeverityWarning, "search_provider", "%s needs an API key, set it with 'silo secrets set %s'", c.SearchProvider, secret)
+			}
+		}
+	}
+	return problems
+}
+
+// engineProblems checks the settings of an inference container at path
+func engineProblems(path string, sg SGLangConfig) []Problem {
+	var problems []Problem
+	add := func(severity Severity, key, format string, args ...any) {
+		problems = append(problems, Problem{Path: path + "." + key, Message: fmt.Sprintf(format, args...), Severity: severity})
+	}
+
+	if sg.Image != "" {
+		if err := registry.ValidateReference(sg.Image); err != nil {
+			add(SeverityError, "image", "%v", err)
+		}
+	}
+
+	severity := enabledSeverity(sg.Enabled)
+	if devices := len(sg.GPUDevices); sg.DPSize*sg.TPSize != devices {
+		add(severity, "gpu_devices", "dp_size %d * tp_size %d = %d GPUs, but %d GPU devices are listed", sg.DPSize, sg.TPSize, sg.DPSize*sg.TPSize, devices)
+	}
+	if sg.ShmSize != "" {
+		if _, err := ParseMemory(sg.ShmSize); err != nil {
+			add(severity, "shm_size", "%v", err)
+		}
+	}
+	if sg.MemFractionStatic <= 0 || sg.MemFractionStatic > 1 {
+		add(severity, "mem_fraction_static", "must be greater than 0 and at most 1, got %g", sg.MemFractionStatic)
+	}
+	if backend := sg.AttentionBackend; backend != "" && !slices.Contains(AttentionBackends, backend) {
+		add(SeverityWarning, "attention_backend", "unknown attention backend %q, known backends are %s", backend, strings.Join(AttentionBackends, ", "))
+	}
+	return problems
+}
+
+// searchProviderNames returns the supported search providers, sorted
+func searchProviderNames() []string {
+	return slices.Sorted(maps.Keys(SearchProviders))
+}
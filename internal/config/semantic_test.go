@@ -0,0 +1,69 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestSemanticProblems(t *testing.T) {
+	tests := []struct {
+		name     string
+		change   func(c *Config)
+		path     string
+		severity Severity
+	}{
+		{"llm url scheme", func(c *Config) { c.LLMBaseURL = "localhost:30000/v1" }, "llm_base_url", SeverityError},
+		{"llm url host", func(c *Config) { c.LLMBaseURL = "http:///v1" }, "llm_base_url", SeverityError},
+		{"image tag", func(c *Config) { c.ImageTag = "latest version" }, "image_tag", SeverityError},
+		{"deep research image", func(c *Config) { c.DeepResearchImage = "GHCR.io/Eternis/DR" }, "deep_research_image", SeverityError},
+		{"sglang image", func(c *Config) { c.SGLang.Image = "lmsysorg/sglang@sha256:xyz" }, "sglang.image", SeverityError},
+		{"gpu count", func(c *Config) { c.SGLang.Enabled = true; c.SGLang.TPSize = 2 }, "sglang.gpu_devices", SeverityError},
+		{"gpu count disabled", func(c *Config) { c.SGLang.TPSize = 2 }, "sglang.gpu_devices", SeverityWarning},
+		{"shm size", func(c *Config) { c.SGLang.Enabled = true; c.SGLang.ShmSize = "lots" }, "sglang.shm_size", SeverityError},
+		{"mem fraction", func(c *Config) { c.SGLang.Enabled = true; c.SGLang.MemFractionStatic = 0 }, "sglang.mem_fraction_static", SeverityError},
+		{"attention backend", func(c *Config) { c.SGLang.AttentionBackend = "flash" }, "sglang.attention_backend", SeverityWarning},
+		{"search provider", func(c *Config) { c.SearchProvider = "bing" }, "search_provider", SeverityError},
+		{"search provider key", func(c *Config) {}, "search_provider", SeverityWarning},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewDefaultConfig(NewPaths(t.TempDir(), t.TempDir()))
+			tt.change(cfg)
+			var found *Problem
+			for _, p := range cfg.semanticProblems() {
+				if p.Path == tt.path {
+					found = &p
+				}
+			}
+			if found == nil {
+				t.Fatalf("Expected a problem at %s", tt.path)
+			}
+			if found.Severity != tt.severity {
+				t.Errorf("Expected %s severity %s, got %s: %s", tt.path, tt.severity, found.Severity, found.Message)
+			}
+		})
+	}
+}
+
+func TestValidate_IgnoresWarnings(t *testing.T) {
+	paths := NewPaths(t.TempDir(), t.TempDir())
+	cfg := NewDefaultConfig(paths)
+	if err := SaveSecrets(paths.SecretsFile, Secrets{SecretPerplexityAPIKey: "pplx-123"}); err != nil {
+		t.Fatal(err)
+	}
+	problems, err := ValidateConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 0 {
+		t.Errorf("Expected the default config with an API key to be clean, got %v", problems)
+	}
+
+	cfg.SGLang.AttentionBackend = "flash"
+	if err := Validate(cfg); err != nil {
+		t.Errorf("Expected warnings not to fail validation, got %v", err)
+	}
+	problems, _ = ValidateConfig(cfg)
+	if errs, warnings := SplitProblems(problems); len(errs) != 0 || len(warnings) != 1 {
+		t.Errorf("Expected one warning, got errors %v and warnings %v", errs, warnings)
+	}
+}
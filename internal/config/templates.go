@@ -1,46 +1,156 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
 	"text/template"
+	"time"
 
 	"github.com/eternisai/silo/internal/assets"
 )
 
+const (
+	// ComposeHistoryDirName holds the last generated versions of
+	// docker-compose.yml, next to it
+	ComposeHistoryDirName = "compose-history"
+
+	// ComposeHistoryLimit is the number of versions kept
+	ComposeHistoryLimit = 10
+)
+
+// GenerateDockerCompose writes docker-compose.yml for config to output,
+// along with the env files that pass secrets to its services. Missing
+// secrets are generated first. The file is replaced atomically and each new
+// version is archived, see ComposeHistory. The user's override file, if any,
+// is linked next to output.
 func GenerateDockerCompose(config *Config, output string) error {
-	tmpl, err := template.New("docker-compose").Parse(assets.DockerComposeTemplate)
+	secrets, err := EnsureSecrets(config)
+	if err != nil {
+		return err
+	}
+	if err := WriteEnvFiles(config, secrets); err != nil {
+		return err
+	}
+
+	data, err := RenderDockerCompose(config)
+	if err != nil {
+		return err
+	}
+
+	current, err := os.ReadFile(output)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read docker-compose file: %w", err)
+	}
+	if !bytes.Equal(current, data) {
+		if err := writeFileAtomic(output, data, 0644); err != nil {
+			return fmt.Errorf("failed to create docker-compose file: %w", err)
+		}
+		if err := archiveCompose(output, data); err != nil {
+			return err
+		}
+	}
+
+	if err := linkComposeOverride(config, output); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// composeFuncs are the template functions that escape values for
+// docker-compose.yml
+var composeFuncs = template.FuncMap{
+	"quote": yamlQuote,
+	"env": func(name string, value any) string {
+		return yamlQuote(fmt.Sprintf("%s=%v", name, value))
+	},
+}
+
+// RenderDockerCompose returns the docker-compose.yml contents for config.
+// Every value is quoted, so it cannot change the structure of the file.
+func RenderDockerCompose(config *Config) ([]byte, error) {
+	tmpl, err := template.New("docker-compose").Funcs(composeFuncs).Parse(assets.DockerComposeTemplate)
 	if err != nil {
-		return fmt.Errorf("failed to parse docker-compose template: %w", err)
+		return nil, fmt.Errorf("failed to parse docker-compose template: %w", err)
 	}
 
-	f, err := os.Create(output)
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, config); err != nil {
+		return nil, fmt.Errorf("failed to execute docker-compose template: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// ComposeDiff returns the changes GenerateDockerCompose would make to the
+// compose file at path as a unified diff, or "" when there are none
+func ComposeDiff(config *Config, path string) (string, error) {
+	data, err := RenderDockerCompose(config)
 	if err != nil {
-		return fmt.Errorf("failed to create docker-compose file: %w", err)
+		return "", err
+	}
+	current, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("failed to read docker-compose file: %w", err)
+	}
+	return UnifiedDiff(path, path+" (new)", current, data), nil
+}
+
+// archiveCompose copies a newly generated compose file into the history
+// directory next to it and removes all but the newest ComposeHistoryLimit
+// versions
+func archiveCompose(composeFile string, data []byte) error {
+	dir := filepath.Join(filepath.Dir(composeFile), ComposeHistoryDirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create compose history directory: %w", err)
 	}
-	defer f.Close()
 
-	if err := tmpl.Execute(f, config); err != nil {
-		return fmt.Errorf("failed to execute docker-compose template: %w", err)
+	name := fmt.Sprintf("docker-compose.%s.yml", time.Now().Format("20060102-150405.000"))
+	if err := writeFileAtomic(filepath.Join(dir, name), data, 0644); err != nil {
+		return fmt.Errorf("failed to archive docker-compose file: %w", err)
 	}
 
+	versions, err := ComposeHistory(composeFile)
+	if err != nil {
+		return err
+	}
+	for len(versions) > ComposeHistoryLimit {
+		if err := os.Remove(versions[0]); err != nil {
+			return fmt.Errorf("failed to prune compose history: %w", err)
+		}
+		versions = versions[1:]
+	}
 	return nil
 }
 
+// ComposeHistory returns the archived versions of a compose file, oldest
+// first
+func ComposeHistory(composeFile string) ([]string, error) {
+	versions, err := filepath.Glob(filepath.Join(filepath.Dir(composeFile), ComposeHistoryDirName, "docker-compose.*.yml"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(versions)
+	return versions, nil
+}
+
 func GenerateConfig(config *Config, output string) error {
 	tmpl, err := template.New("config").Parse(assets.ConfigTemplate)
 	if err != nil {
 		return fmt.Errorf("failed to parse config template: %w", err)
 	}
 
-	f, err := os.Create(output)
-	if err != nil {
-		return fmt.Errorf("failed to create config file: %w", err)
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, config); err != nil {
+		return fmt.Errorf("failed to execute config template: %w", err)
 	}
-	defer f.Close()
 
-	if err := tmpl.Execute(f, config); err != nil {
-		return fmt.Errorf("failed to execute config template: %w", err)
+	if err := writeFileAtomic(output, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to create config file: %w", err)
 	}
 
 	return nil
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestRenderDockerCompose_EscapesValues(t *testing.T) {
+	cfg := NewDefaultConfig(NewPaths(t.TempDir(), t.TempDir()))
+	cfg.DefaultModel = `glm: "4.7" # awq`
+	cfg.LLMBaseURL = "http://llm:30000/v1?key=a:b"
+	cfg.SearchProvider = "tavily\nBAD: 1"
+	cfg.DataDir = "/srv/silo data: #1"
+
+	data, err := RenderDockerCompose(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var compose struct {
+		Services map[string]struct {
+			Environment []string `yaml:"environment"`
+			Volumes     []string `yaml:"volumes"`
+		} `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(data, &compose); err != nil {
+		t.Fatalf("Rendered compose file is not valid YAML: %v\n%s", err, data)
+	}
+
+	env := strings.Join(compose.Services["deep-research"].Environment, "\n")
+	for _, want := range []string{"MODEL_NAME=" + cfg.DefaultModel, "BASE_URL=" + cfg.LLMBaseURL, "SEARCH_PROVIDER=" + cfg.SearchProvider} {
+		if !strings.Contains(env, want) {
+			t.Errorf("Expected %q to round-trip, got:\n%s", want, env)
+		}
+	}
+	if v := compose.Services["postgres"].Volumes; len(v) != 1 || v[0] != cfg.DataDir+"/postgres:/var/lib/postgresql/data" {
+		t.Errorf("Expected data dir to round-trip, got %v", v)
+	}
+}
+
+func TestGenerateDockerCompose_History(t *testing.T) {
+	paths := NewPaths(t.TempDir(), t.TempDir())
+	cfg := NewDefaultConfig(paths)
+	if err := os.MkdirAll(paths.DataDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for port := 1; port <= ComposeHistoryLimit+2; port++ {
+		cfg.Port = 8000 + port
+		if err := GenerateDockerCompose(cfg, paths.ComposeFile); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Unchanged output is not archived again
+	if err := GenerateDockerCompose(cfg, paths.ComposeFile); err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := ComposeHistory(paths.ComposeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != ComposeHistoryLimit {
+		t.Fatalf("Expected %d versions, got %d", ComposeHistoryLimit, len(versions))
+	}
+	latest, _ := os.ReadFile(versions[len(versions)-1])
+	current, _ := os.ReadFile(paths.ComposeFile)
+	if string(latest) != string(current) {
+		t.Error("Expected the newest version to match the current file")
+	}
+	oldest, _ := os.ReadFile(versions[0])
+	if !strings.Contains(string(oldest), `"8003:3000"`) {
+		t.Errorf("Expected the oldest kept version to be the third one, got:\n%s", oldest)
+	}
+
+	cfg.Port = 9000
+	diff, err := ComposeDiff(cfg, paths.ComposeFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(diff, `-      - "8012:3000"`) || !strings.Contains(diff, `+      - "9000:3000"`) {
+		t.Errorf("Unexpected diff:\n%s", diff)
+	}
+}
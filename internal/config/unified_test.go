@@ -0,0 +1,43 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	old := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+	tests := []struct {
+		name string
+		new  string
+		want string
+	}{
+		{"equal", old, ""},
+		{
+			"change",
+			strings.Replace(old, "f\n", "F\n", 1),
+			"--- old\n+++ new\n@@ -3,7 +3,7 @@\n c\n d\n e\n-f\n+F\n g\n h\n i\n",
+		},
+		{
+			"two hunks",
+			strings.Replace(strings.Replace(old, "a\n", "", 1), "m\n", "m\nn\n", 1),
+			"--- old\n+++ new\n@@ -1,4 +1,3 @@\n-a\n b\n c\n d\n@@ -11,3 +10,4 @@\n k\n l\n m\n+n\n",
+		},
+		{
+			"from empty",
+			"",
+			"--- old\n+++ new\n@@ -1,13 +0,0 @@\n-a\n-b\n-c\n-d\n-e\n-f\n-g\n-h\n-i\n-j\n-k\n-l\n-m\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnifiedDiff("old", "new", []byte(old), []byte(tt.new)); got != tt.want {
+				t.Errorf("UnifiedDiff() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+
+	if got := UnifiedDiff("old", "new", nil, []byte("x\n")); got != "--- old\n+++ new\n@@ -0,0 +1 @@\n+x\n" {
+		t.Errorf("Unexpected diff from an empty file:\n%s", got)
+	}
+}
@@ -21,7 +21,7 @@ type LogEntry struct {
 type UpRequest struct {
 	ImageTag         string `json:"image_tag,omitempty"`
 	Port             int    `json:"port,omitempty"`
-	EnableProxyAgent bool   `json:"enable_proxy_agent,omitempty"`
+	EnableProxyAgent *bool  `json:"enable_proxy_agent,omitempty"`
 }
 
 // RestartRequest represents request body for /api/v1/restart
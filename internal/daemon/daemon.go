@@ -4,13 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"sync"
 	"time"
 
 	"github.com/eternisai/silo/internal/config"
 	"github.com/eternisai/silo/internal/docker"
-	"github.com/eternisai/silo/internal/inference"
 	"github.com/eternisai/silo/internal/version"
 	"github.com/eternisai/silo/pkg/logger"
 )
@@ -22,7 +22,9 @@ type Daemon struct {
 	paths  *config.Paths
 	server *Server
 	logger *logger.Logger
-	opLock sync.Mutex // Prevents concurrent operations
+	jobs   *JobStore
+	opLock sync.Mutex   // Prevents concurrent operations
+	mu     sync.RWMutex // Guards config and state, reloaded by background jobs
 	wg     sync.WaitGroup
 }
 
@@ -44,40 +46,41 @@ func DefaultConfig() *Config {
 	}
 }
 
-// New creates a new daemon instance
-func New() (*Daemon, error) {
+// New creates a daemon for the named Silo instance
+func New(instance string) (*Daemon, error) {
 	log := logger.New(false)
 
-	// Determine config directory
-	configDir := os.Getenv("SILO_CONFIG_DIR")
-	if configDir == "" {
-		configDir = config.DefaultConfigDir()
+	if err := config.ValidateInstanceName(instance); err != nil {
+		return nil, err
 	}
 
-	// Determine data directory
-	dataDir := os.Getenv("SILO_DATA_DIR")
-	if dataDir == "" {
-		dataDir = config.DefaultDataDir()
-	}
-
-	paths := config.NewPaths(configDir, dataDir)
+	paths := config.NewInstancePaths(instance, config.BaseConfigDir(), config.BaseDataDir())
 
 	// Create config directory if it doesn't exist
 	if err := os.MkdirAll(paths.ConfigDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	// Load configuration with defaults for missing fields
+	// Load configuration with defaults for missing fields under the instance
+	// lock, then save it so new fields and schema migrations are persisted.
+	// While the CLI is changing the instance, the config is only loaded.
+	lock, lockErr := config.AcquireLock(paths, "daemon start")
 	cfg, err := config.LoadOrDefault(paths.ConfigFile, paths)
 	if err != nil {
+		if lockErr == nil {
+			lock.Release()
+		}
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
-
-	// Save merged config to ensure any new fields are persisted
-	if err := config.Save(paths.ConfigFile, cfg); err != nil {
-		log.Warn("Failed to save merged config: %v", err)
+	if lockErr != nil {
+		log.Warn("Skipping merged config save: %v", lockErr)
 	} else {
-		log.Debug("Config loaded and updated at %s", paths.ConfigFile)
+		if err := config.Save(paths.ConfigFile, cfg); err != nil {
+			log.Warn("Failed to save merged config: %v", err)
+		} else {
+			log.Debug("Config loaded and updated at %s", paths.ConfigFile)
+		}
+		lock.Release()
 	}
 
 	// Load state (use default empty state if not found)
@@ -99,6 +102,7 @@ func New() (*Daemon, error) {
 		state:  state,
 		paths:  paths,
 		logger: log,
+		jobs:   NewJobStore(),
 	}
 
 	// Create API server if enabled
@@ -116,7 +120,7 @@ func New() (*Daemon, error) {
 
 // Start begins daemon operations
 func (d *Daemon) Start(ctx context.Context) error {
-	d.logger.Info("Starting daemon services...")
+	d.logger.Info("Starting daemon services for instance %s...", d.paths.Instance)
 
 	// Error channel for critical failures
 	errChan := make(chan error, 1)
@@ -165,6 +169,50 @@ func (d *Daemon) Stop() error {
 	return nil
 }
 
+// currentConfig returns the loaded config. A reload replaces it rather than
+// changing it, so callers may keep using the returned config.
+func (d *Daemon) currentConfig() *config.Config {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.config
+}
+
+// currentState returns a copy of the loaded state
+func (d *Daemon) currentState() *config.State {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	state := *d.state
+	state.Images = maps.Clone(d.state.Images)
+	return &state
+}
+
+// updateState applies update to the loaded state and saves it
+func (d *Daemon) updateState(update func(*config.State)) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	update(d.state)
+	return config.SaveState(d.paths.StateFile, d.state)
+}
+
+// reload replaces the loaded config and state with the ones on disk, e.g.
+// after an install or upgrade wrote them
+func (d *Daemon) reload() {
+	cfg, err := config.LoadOrDefault(d.paths.ConfigFile, d.paths)
+	if err != nil {
+		d.logger.Warn("Failed to reload config: %v", err)
+	}
+	state, stateErr := config.LoadState(d.paths.StateFile)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err == nil {
+		d.config = cfg
+	}
+	if stateErr == nil {
+		d.state = state
+	}
+}
+
 // GetStatus returns current daemon status
 func (d *Daemon) GetStatus() (*Status, error) {
 	ctx := context.Background()
@@ -173,23 +221,29 @@ func (d *Daemon) GetStatus() (*Status, error) {
 		return nil, fmt.Errorf("failed to get container status: %w", err)
 	}
 
-	// Get version info
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	cfg := d.currentConfig()
+	var cliVer *version.VersionInfo
+	var imageVers []version.ImageVersionInfo
 
-	cliVer, err := version.Check(ctx, d.config.Version)
-	if err != nil {
-		d.logger.Warn("Failed to check CLI version: %v", err)
-	}
+	// Get version info, unless installed from an offline bundle
+	if state, _ := config.LoadState(d.paths.StateFile); !state.Offline() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
 
-	imageVers, err := version.CheckImageVersions(ctx, d.config.ImageTag)
-	if err != nil {
-		d.logger.Warn("Failed to check image versions: %v", err)
+		cliVer, err = version.Check(ctx, cfg.Version)
+		if err != nil {
+			d.logger.Warn("Failed to check CLI version: %v", err)
+		}
+
+		imageVers, err = version.CheckImageVersions(ctx, cfg.Registry, cfg.ImageTag)
+		if err != nil {
+			d.logger.Warn("Failed to check image versions: %v", err)
+		}
 	}
 
 	return &Status{
-		State:         d.state,
-		Config:        d.config,
+		State:         d.currentState(),
+		Config:        cfg,
 		Containers:    containers,
 		CLIVersion:    cliVer,
 		ImageVersions: imageVers,
@@ -204,8 +258,3 @@ type Status struct {
 	CLIVersion    *version.VersionInfo
 	ImageVersions []version.ImageVersionInfo
 }
-
-// getInferenceEngine returns an inference engine manager
-func (d *Daemon) getInferenceEngine() *inference.Engine {
-	return inference.New(d.config, d.logger)
-}
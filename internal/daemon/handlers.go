@@ -3,14 +3,18 @@ package daemon
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/eternisai/silo/internal/config"
 	"github.com/eternisai/silo/internal/docker"
+	"github.com/eternisai/silo/internal/gpu"
+	"github.com/eternisai/silo/internal/inference"
 	"github.com/eternisai/silo/internal/installer"
 	"github.com/eternisai/silo/internal/updater"
 	"github.com/eternisai/silo/internal/version"
@@ -25,6 +29,7 @@ const (
 	UpgradeTimeout = 10 * time.Minute
 	LogsTimeout    = 30 * time.Second
 	VersionTimeout = 10 * time.Second
+	GPUsTimeout    = 30 * time.Second
 	MaxLogLines    = 10000
 )
 
@@ -35,9 +40,18 @@ func (s *Server) handleUp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Acquire operation lock
-	s.daemon.opLock.Lock()
-	defer s.daemon.opLock.Unlock()
+	// Acquire the operation lock and the instance lock shared with the CLI.
+	// An install keeps them until its job finishes.
+	release, ok := s.lockOperation(w, "up")
+	if !ok {
+		return
+	}
+	async := false
+	defer func() {
+		if !async {
+			release()
+		}
+	}()
 
 	// Parse request
 	var req UpRequest
@@ -49,6 +63,12 @@ func (s *Server) handleUp(w http.ResponseWriter, r *http.Request) {
 	// Create API logger
 	apiLog := NewAPILogger()
 
+	if err := s.migrateConfig(apiLog); err != nil {
+		s.respondWithLogs(w, http.StatusInternalServerError, false, "",
+			fmt.Sprintf("Failed to migrate config: %v", err), "", apiLog.GetLogs())
+		return
+	}
+
 	// Check if already installed
 	_, err := os.Stat(s.daemon.paths.ComposeFile)
 	if err == nil {
@@ -72,8 +92,15 @@ func (s *Server) handleUp(w http.ResponseWriter, r *http.Request) {
 	// Not installed, run installation
 	apiLog.Info("Installing Silo...")
 
-	// Build config for installation
-	cfg := config.NewDefaultConfig(s.daemon.paths)
+	// Build config for installation from config.yml and config.d, if
+	// written beforehand
+	cfg, err := config.LoadOrDefault(s.daemon.paths.ConfigFile, s.daemon.paths)
+	if err != nil {
+		apiLog.Error("Failed to load config: %v", err)
+		s.respondWithLogs(w, http.StatusInternalServerError, false, "",
+			fmt.Sprintf("Failed to load config: %v", err), "", apiLog.GetLogs())
+		return
+	}
 
 	// Apply request parameters
 	if req.ImageTag != "" {
@@ -82,7 +109,16 @@ func (s *Server) handleUp(w http.ResponseWriter, r *http.Request) {
 	if req.Port > 0 {
 		cfg.Port = req.Port
 	}
-	cfg.EnableProxyAgent = req.EnableProxyAgent
+	if req.EnableProxyAgent != nil {
+		cfg.EnableProxyAgent = *req.EnableProxyAgent
+	}
+
+	// Keep host ports clear of the other instances on this host
+	if reserved, err := config.ReservedPorts(s.daemon.paths); err != nil {
+		apiLog.Warn("Failed to read ports of other instances: %v", err)
+	} else {
+		config.AllocatePorts(cfg, reserved)
+	}
 
 	// Validate config
 	if err := config.Validate(cfg); err != nil {
@@ -92,22 +128,36 @@ func (s *Server) handleUp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Run installer (using daemon logger for actual installation)
-	ctx, cancel := context.WithTimeout(context.Background(), UpTimeout)
-	defer cancel()
+	// Install in the background; clients poll the job for pull progress
+	// and the outcome
+	job := s.daemon.jobs.Start("install")
+	apiLog.Info("Started job %s", job.ID)
 
-	// Use a quiet logger to avoid outputting to terminal
-	quietLog := logger.New(true)
-	inst := installer.New(cfg, s.daemon.paths, quietLog)
-	if err := inst.Install(ctx); err != nil {
-		apiLog.Error("Installation failed: %v", err)
-		s.respondWithLogs(w, http.StatusInternalServerError, false, "",
-			fmt.Sprintf("Installation failed: %v", err), "", apiLog.GetLogs())
-		return
-	}
+	async = true
+	go func() {
+		defer release()
+
+		// Run installer (using daemon logger for actual installation)
+		ctx, cancel := context.WithTimeout(context.Background(), UpTimeout)
+		defer cancel()
 
-	apiLog.Success("Installation completed successfully")
-	s.respondWithLogs(w, http.StatusOK, true, "Silo installed and started successfully", "", "", apiLog.GetLogs())
+		// Use a quiet logger to avoid outputting to terminal
+		quietLog := logger.New(true)
+		inst := installer.New(cfg, s.daemon.paths, quietLog)
+		inst.SetPullProgress(s.daemon.jobs.PullProgress(job.ID))
+		err := inst.Install(ctx)
+
+		// A failed install may have written config and state as well
+		s.daemon.reload()
+		s.daemon.jobs.Finish(job.ID, err)
+		if err != nil {
+			s.daemon.logger.Error("Installation failed: %v", err)
+			return
+		}
+		s.daemon.logger.Success("Installation completed successfully")
+	}()
+
+	s.respondWithJob(w, http.StatusAccepted, true, "Installation started", "", job.ID, apiLog.GetLogs())
 }
 
 // handleDown handles POST /api/v1/down - stop containers
@@ -121,6 +171,13 @@ func (s *Server) handleDown(w http.ResponseWriter, r *http.Request) {
 	s.daemon.opLock.Lock()
 	defer s.daemon.opLock.Unlock()
 
+	// Acquire the instance lock shared with the CLI
+	lock, ok := s.lockInstance(w, "down")
+	if !ok {
+		return
+	}
+	defer lock.Release()
+
 	apiLog := NewAPILogger()
 	apiLog.Info("Stopping containers...")
 
@@ -149,6 +206,13 @@ func (s *Server) handleRestart(w http.ResponseWriter, r *http.Request) {
 	s.daemon.opLock.Lock()
 	defer s.daemon.opLock.Unlock()
 
+	// Acquire the instance lock shared with the CLI
+	lock, ok := s.lockInstance(w, "restart")
+	if !ok {
+		return
+	}
+	defer lock.Release()
+
 	// Parse request
 	var req RestartRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -184,13 +248,28 @@ func (s *Server) handleUpgrade(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Acquire operation lock
-	s.daemon.opLock.Lock()
-	defer s.daemon.opLock.Unlock()
+	// Acquire the operation lock and the instance lock shared with the CLI.
+	// They are kept until the upgrade job finishes.
+	release, ok := s.lockOperation(w, "upgrade")
+	if !ok {
+		return
+	}
+	async := false
+	defer func() {
+		if !async {
+			release()
+		}
+	}()
 
 	apiLog := NewAPILogger()
 	apiLog.Info("Starting upgrade process...")
 
+	if err := s.migrateConfig(apiLog); err != nil {
+		s.respondWithLogs(w, http.StatusInternalServerError, false, "",
+			fmt.Sprintf("Failed to migrate config: %v", err), "", apiLog.GetLogs())
+		return
+	}
+
 	// Load current config with defaults for missing fields
 	cfg, err := config.LoadOrDefault(s.daemon.paths.ConfigFile, s.daemon.paths)
 	if err != nil {
@@ -200,28 +279,73 @@ func (s *Server) handleUpgrade(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Run updater (using daemon logger for actual update)
-	ctx, cancel := context.WithTimeout(context.Background(), UpgradeTimeout)
-	defer cancel()
+	// Upgrade in the background; clients poll the job for pull progress
+	// and the outcome
+	job := s.daemon.jobs.Start("upgrade")
+	apiLog.Info("Started job %s", job.ID)
 
-	quietLog := logger.New(true)
-	upd := updater.New(cfg, s.daemon.paths, quietLog)
-	if err := upd.Update(ctx); err != nil {
-		apiLog.Error("Upgrade failed: %v", err)
-		s.respondWithLogs(w, http.StatusInternalServerError, false, "",
-			fmt.Sprintf("Upgrade failed: %v", err), "", apiLog.GetLogs())
+	async = true
+	go func() {
+		defer release()
+
+		// Run updater (using daemon logger for actual update)
+		ctx, cancel := context.WithTimeout(context.Background(), UpgradeTimeout)
+		defer cancel()
+
+		quietLog := logger.New(true)
+		upd := updater.New(cfg, s.daemon.paths, quietLog)
+		upd.SetPullProgress(s.daemon.jobs.PullProgress(job.ID))
+		err := upd.Update(ctx)
+
+		// Reload with defaults for any new fields; a failed upgrade may
+		// have migrated the config or pinned images as well
+		s.daemon.reload()
+		s.daemon.jobs.Finish(job.ID, err)
+		if err != nil {
+			s.daemon.logger.Error("Upgrade failed: %v", err)
+			return
+		}
+		s.daemon.logger.Success("Upgrade completed successfully")
+	}()
+
+	s.respondWithJob(w, http.StatusAccepted, true, "Upgrade started", "", job.ID, apiLog.GetLogs())
+}
+
+// handleJobs handles GET /api/v1/jobs - list running and recent jobs
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed", "")
 		return
 	}
 
-	// Reload daemon config after upgrade with defaults for any new fields
-	if newCfg, err := config.LoadOrDefault(s.daemon.paths.ConfigFile, s.daemon.paths); err == nil {
-		s.daemon.config = newCfg
-	} else {
-		s.daemon.logger.Warn("Failed to reload config after upgrade: %v", err)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Message: "Jobs retrieved",
+		Data:    s.daemon.jobs.List(),
+	})
+}
+
+// handleJob handles GET /api/v1/jobs/{id} - get job state and progress
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed", "")
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/api/v1/jobs/")
+	job, ok := s.daemon.jobs.Get(id)
+	if !ok {
+		s.respondError(w, http.StatusNotFound, "Job not found", id)
+		return
 	}
 
-	apiLog.Success("Upgrade completed successfully")
-	s.respondWithLogs(w, http.StatusOK, true, "Upgrade completed successfully", "", "", apiLog.GetLogs())
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Message: "Job retrieved",
+		Data:    job,
+	})
 }
 
 // handleLogs handles GET /api/v1/logs - get container logs
@@ -287,6 +411,19 @@ func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Installations from an offline bundle have no registry access
+	if state, _ := config.LoadState(s.daemon.paths.StateFile); state.Offline() {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(APIResponse{
+			Success: true,
+			Message: "Offline installation, version checks skipped",
+			Data: map[string]interface{}{
+				"bundle": state.Bundle,
+			},
+		})
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(r.Context(), VersionTimeout)
 	defer cancel()
 
@@ -299,7 +436,7 @@ func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check image versions
-	imageVers, err := version.CheckImageVersions(ctx, cfg.ImageTag)
+	imageVers, err := version.CheckImageVersions(ctx, cfg.Registry, cfg.ImageTag)
 	if err != nil {
 		s.respondError(w, http.StatusInternalServerError,
 			"Failed to check image versions", err.Error())
@@ -365,6 +502,20 @@ func (s *Server) handleCheck(w http.ResponseWriter, r *http.Request) {
 	s.respondWithLogs(w, http.StatusOK, true, "Configuration is valid", "", "", apiLog.GetLogs())
 }
 
+// inferenceEngines returns the inference engine of the model named by the
+// model query parameter, or defaults when the request names none
+func (s *Server) inferenceEngines(r *http.Request, defaults []*inference.Engine) ([]*inference.Engine, error) {
+	model := r.URL.Query().Get("model")
+	if model == "" {
+		return defaults, nil
+	}
+	engine, err := inference.ForModel(s.daemon.currentConfig(), model, s.daemon.logger)
+	if err != nil {
+		return nil, err
+	}
+	return []*inference.Engine{engine}, nil
+}
+
 // handleInferenceUp handles POST /api/v1/inference/up - start inference engine
 func (s *Server) handleInferenceUp(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -372,34 +523,148 @@ func (s *Server) handleInferenceUp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// With wait=true the response is sent once the engines are ready
+	wait := r.URL.Query().Get("wait") == "true"
+	readyTimeout := inference.DefaultReadyTimeout
+	if v := r.URL.Query().Get("timeout"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			s.respondError(w, http.StatusBadRequest, "Invalid timeout", fmt.Sprintf("timeout must be a positive duration such as 10m, got %q", v))
+			return
+		}
+		readyTimeout = d
+	}
+
 	// Acquire operation lock
 	s.daemon.opLock.Lock()
 	defer s.daemon.opLock.Unlock()
 
+	// Acquire the instance lock shared with the CLI
+	lock, ok := s.lockInstance(w, "inference up")
+	if !ok {
+		return
+	}
+	defer lock.Release()
+
 	apiLog := NewAPILogger()
 	apiLog.Info("Starting inference engine...")
 
-	ctx, cancel := context.WithTimeout(context.Background(), UpTimeout)
+	timeout := UpTimeout
+	if wait {
+		timeout += readyTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	engine := s.daemon.getInferenceEngine()
-	if err := engine.Up(ctx); err != nil {
-		apiLog.Error("Failed to start inference engine: %v", err)
-		s.respondWithLogs(w, http.StatusInternalServerError, false, "",
-			fmt.Sprintf("Failed to start inference engine: %v", err), "", apiLog.GetLogs())
+	cfg := s.daemon.currentConfig()
+	engines, err := s.inferenceEngines(r, inference.DefaultEngines(cfg, s.daemon.logger))
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Unknown model", err.Error())
 		return
 	}
+	if wait {
+		// Report the startup phases in the response logs
+		for _, engine := range engines {
+			model := engine.Model()
+			engine.SetProgress(func(p inference.Progress) {
+				if p.Phase != inference.PhaseFailed {
+					apiLog.Info("%s: %s", model, p)
+				}
+			})
+		}
+	}
+	// Fail before docker run when a GPU is missing or lacks memory
+	if errorCount := s.preflightGPUs(ctx, engines, apiLog); errorCount > 0 {
+		s.respondWithLogs(w, http.StatusPreconditionFailed, false, "",
+			fmt.Sprintf("GPU check found %d problem(s)", errorCount), "", apiLog.GetLogs())
+		return
+	}
+	for _, engine := range engines {
+		if err := engine.Up(ctx); err != nil {
+			apiLog.Error("Failed to start inference engine %s: %v", engine.Model(), err)
+			s.respondWithLogs(w, http.StatusInternalServerError, false, "",
+				fmt.Sprintf("Failed to start inference engine %s: %v", engine.Model(), err), "", apiLog.GetLogs())
+			return
+		}
+
+		// Update state to track that inference was running, pinning the
+		// image docker run pulled if it was not pinned yet
+		var digest string
+		ref := engine.ImageRef()
+		if s.daemon.currentState().Images[ref] == "" {
+			digest, _ = docker.ResolveDigest(ctx, ref, cfg.Registry.Rewrite(ref))
+		}
+		err := s.daemon.updateState(func(state *config.State) {
+			state.InferenceWasRunning = true
+			if digest != "" {
+				state.PinImage(ref, digest)
+			}
+		})
+		if err != nil {
+			s.daemon.logger.Warn("Failed to save state: %v", err)
+		}
+	}
 
-	// Update state to track that inference was running
-	s.daemon.state.InferenceWasRunning = true
-	if err := config.SaveState(s.daemon.paths.StateFile, s.daemon.state); err != nil {
-		s.daemon.logger.Warn("Failed to save state: %v", err)
+	if wait {
+		for _, engine := range engines {
+			apiLog.Info("Waiting for inference engine %s to become ready...", engine.Model())
+			if err := engine.WaitForHealthy(ctx, readyTimeout); err != nil {
+				apiLog.Error("%v", err)
+				var startup *inference.StartupError
+				if errors.As(err, &startup) {
+					for _, d := range startup.Diagnostics {
+						apiLog.Error("%s", d.Message)
+						apiLog.Info("%s", d.Hint)
+					}
+				}
+				s.respondWithLogs(w, http.StatusGatewayTimeout, false, "",
+					fmt.Sprintf("Inference engine %s is not ready: %v", engine.Model(), err), "", apiLog.GetLogs())
+				return
+			}
+		}
+		apiLog.Success("Inference engine is ready")
+		s.respondWithLogs(w, http.StatusOK, true, "Inference engine is ready", "", "", apiLog.GetLogs())
+		return
 	}
 
 	apiLog.Success("Inference engine started successfully")
 	s.respondWithLogs(w, http.StatusOK, true, "Inference engine started successfully", "", "", apiLog.GetLogs())
 }
 
+// preflightGPUs logs the GPU problems of the engines that are not running
+// yet and returns the number of errors
+func (s *Server) preflightGPUs(ctx context.Context, engines []*inference.Engine, apiLog *APILogger) int {
+	errorCount := 0
+	for _, p := range inference.PreflightGPUs(ctx, engines) {
+		if p.IsWarning() {
+			apiLog.Warn("%s", p)
+		} else {
+			apiLog.Error("%s", p)
+			errorCount++
+		}
+	}
+	return errorCount
+}
+
+// handleSystemGPUs handles GET /api/v1/system/gpus - list the GPUs of the
+// host, the driver and CUDA versions and the NVIDIA container toolkit
+func (s *Server) handleSystemGPUs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed", "")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), GPUsTimeout)
+	defer cancel()
+
+	inv := gpu.Detect(ctx)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(APIResponse{
+		Success: true,
+		Data:    inv,
+	})
+}
+
 // handleInferenceDown handles POST /api/v1/inference/down - stop inference engine
 func (s *Server) handleInferenceDown(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -411,23 +676,45 @@ func (s *Server) handleInferenceDown(w http.ResponseWriter, r *http.Request) {
 	s.daemon.opLock.Lock()
 	defer s.daemon.opLock.Unlock()
 
+	// Acquire the instance lock shared with the CLI
+	lock, ok := s.lockInstance(w, "inference down")
+	if !ok {
+		return
+	}
+	defer lock.Release()
+
 	apiLog := NewAPILogger()
 	apiLog.Info("Stopping inference engine...")
 
 	ctx, cancel := context.WithTimeout(context.Background(), DownTimeout)
 	defer cancel()
 
-	engine := s.daemon.getInferenceEngine()
-	if err := engine.Down(ctx); err != nil {
-		apiLog.Error("Failed to stop inference engine: %v", err)
-		s.respondWithLogs(w, http.StatusInternalServerError, false, "",
-			fmt.Sprintf("Failed to stop inference engine: %v", err), "", apiLog.GetLogs())
+	all := inference.Engines(s.daemon.currentConfig(), s.daemon.logger)
+	engines, err := s.inferenceEngines(r, all)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Unknown model", err.Error())
 		return
 	}
+	for _, engine := range engines {
+		if err := engine.Down(ctx); err != nil {
+			apiLog.Error("Failed to stop inference engine %s: %v", engine.Model(), err)
+			s.respondWithLogs(w, http.StatusInternalServerError, false, "",
+				fmt.Sprintf("Failed to stop inference engine %s: %v", engine.Model(), err), "", apiLog.GetLogs())
+			return
+		}
+	}
 
-	// Update state to track that inference was stopped
-	s.daemon.state.InferenceWasRunning = false
-	if err := config.SaveState(s.daemon.paths.StateFile, s.daemon.state); err != nil {
+	// Update state to track whether any model is still running
+	wasRunning := false
+	for _, engine := range all {
+		if running, _ := engine.IsRunning(ctx); running {
+			wasRunning = true
+		}
+	}
+	err = s.daemon.updateState(func(state *config.State) {
+		state.InferenceWasRunning = wasRunning
+	})
+	if err != nil {
 		s.daemon.logger.Warn("Failed to save state: %v", err)
 	}
 
@@ -445,7 +732,12 @@ func (s *Server) handleInferenceStatus(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 	defer cancel()
 
-	engine := s.daemon.getInferenceEngine()
+	engines, err := s.inferenceEngines(r, []*inference.Engine{inference.New(s.daemon.currentConfig(), s.daemon.logger)})
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Unknown model", err.Error())
+		return
+	}
+	engine := engines[0]
 	info, err := engine.Status(ctx)
 	if err != nil {
 		s.respondError(w, http.StatusInternalServerError,
@@ -453,11 +745,16 @@ func (s *Server) handleInferenceStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Try health check
-	healthy := false
+	// Probe readiness: healthy servers may still serve another model
+	healthy, ready := false, false
+	probeError := ""
 	if info.Running {
-		if err := engine.HealthCheck(ctx); err == nil {
-			healthy = true
+		err := engine.Ready(ctx)
+		var mismatch *inference.ModelMismatchError
+		ready = err == nil
+		healthy = ready || errors.As(err, &mismatch)
+		if err != nil {
+			probeError = err.Error()
 		}
 	}
 
@@ -468,6 +765,17 @@ func (s *Server) handleInferenceStatus(w http.ResponseWriter, r *http.Request) {
 		"image":   info.Image,
 		"running": info.Running,
 		"healthy": healthy,
+		"ready":   ready,
+	}
+	if probeError != "" {
+		data["probe_error"] = probeError
+	}
+
+	// The logs tell how far a starting engine got, or why it failed
+	if !ready && info.State != "not found" {
+		if progress, err := engine.Progress(ctx); err == nil {
+			data["progress"] = progress
+		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -501,7 +809,12 @@ func (s *Server) handleInferenceLogs(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), LogsTimeout)
 	defer cancel()
 
-	engine := s.daemon.getInferenceEngine()
+	engines, err := s.inferenceEngines(r, []*inference.Engine{inference.New(s.daemon.currentConfig(), s.daemon.logger)})
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Unknown model", err.Error())
+		return
+	}
+	engine := engines[0]
 	logs, err := engine.LogsBuffer(ctx, lines)
 	if err != nil {
 		s.respondError(w, http.StatusInternalServerError,
@@ -528,6 +841,54 @@ func (s *Server) respondError(w http.ResponseWriter, status int, error, details
 	})
 }
 
+// lockInstance takes the instance lock shared with the CLI for a mutating
+// operation. It responds with 409 Conflict when another process holds it.
+func (s *Server) lockInstance(w http.ResponseWriter, operation string) (*config.Lock, bool) {
+	lock, err := config.AcquireLock(s.daemon.paths, operation)
+	if err != nil {
+		status := http.StatusInternalServerError
+		var locked *config.LockedError
+		if errors.As(err, &locked) {
+			status = http.StatusConflict
+		}
+		s.respondError(w, status, "Operation in progress", err.Error())
+		return nil, false
+	}
+	return lock, true
+}
+
+// lockOperation takes the daemon operation lock and the instance lock shared
+// with the CLI, and returns the function that releases both
+func (s *Server) lockOperation(w http.ResponseWriter, operation string) (func(), bool) {
+	s.daemon.opLock.Lock()
+	lock, ok := s.lockInstance(w, operation)
+	if !ok {
+		s.daemon.opLock.Unlock()
+		return nil, false
+	}
+	return func() {
+		lock.Release()
+		s.daemon.opLock.Unlock()
+	}, true
+}
+
+// migrateConfig persists pending schema migrations of config.yml. Callers
+// hold the instance lock.
+func (s *Server) migrateConfig(apiLog *APILogger) error {
+	result, err := config.Migrate(s.daemon.paths.ConfigFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		apiLog.Error("Failed to migrate config: %v", err)
+		return err
+	}
+	if len(result.Applied) > 0 {
+		apiLog.Success("Migrated config from schema version %d to %d (backup: %s)", result.From, result.To, result.Backup)
+	}
+	return nil
+}
+
 // respondWithLogs sends a response with log entries
 func (s *Server) respondWithLogs(w http.ResponseWriter, status int, success bool, message, error, details string, logs []LogEntry) {
 	w.Header().Set("Content-Type", "application/json")
@@ -541,6 +902,24 @@ func (s *Server) respondWithLogs(w http.ResponseWriter, status int, success bool
 	})
 }
 
+// respondWithJob sends a response with log entries and the final job state
+func (s *Server) respondWithJob(w http.ResponseWriter, status int, success bool, message, error, jobID string, logs []LogEntry) {
+	var data interface{}
+	if job, ok := s.daemon.jobs.Get(jobID); ok {
+		data = job
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(APIResponse{
+		Success: success,
+		Message: message,
+		Data:    data,
+		Error:   error,
+		Logs:    logs,
+	})
+}
+
 // fileExists checks if a file exists
 func fileExists(path string) bool {
 	_, err := os.Stat(path)
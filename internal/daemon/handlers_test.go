@@ -85,6 +85,36 @@ func TestHandleHealthEndpoint(t *testing.T) {
 	}
 }
 
+func TestHandleSystemGPUs(t *testing.T) {
+	s := &Server{socketPath: ""}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/system/gpus", nil)
+	w := httptest.NewRecorder()
+
+	s.handleSystemGPUs(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	// Hosts without nvidia-smi get an inventory that says why
+	var resp struct {
+		Success bool `json:"success"`
+		Data    struct {
+			GPUs  []json.RawMessage `json:"gpus"`
+			Error string            `json:"error"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !resp.Success {
+		t.Error("Expected success to be true")
+	}
+	if resp.Data.GPUs == nil && resp.Data.Error == "" {
+		t.Errorf("Expected GPUs or the reason there are none, got %+v", resp.Data)
+	}
+}
+
 func TestMethodNotAllowed(t *testing.T) {
 	s := &Server{socketPath: ""}
 
@@ -144,6 +174,13 @@ func TestMethodNotAllowed(t *testing.T) {
 			path:     "/api/v1/check",
 			wantCode: http.StatusMethodNotAllowed,
 		},
+		{
+			name:     "GPUS with POST",
+			handler:  s.handleSystemGPUs,
+			method:   http.MethodPost,
+			path:     "/api/v1/system/gpus",
+			wantCode: http.StatusMethodNotAllowed,
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,144 @@
+package daemon
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/eternisai/silo/internal/docker"
+)
+
+// MaxJobs is the number of finished jobs kept for inspection
+const MaxJobs = 50
+
+// Job states
+const (
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// Job tracks a long-running operation such as an install or upgrade
+type Job struct {
+	ID         string                      `json:"id"`
+	Operation  string                      `json:"operation"`
+	State      string                      `json:"state"`
+	StartedAt  string                      `json:"started_at"`
+	FinishedAt string                      `json:"finished_at,omitempty"`
+	Error      string                      `json:"error,omitempty"`
+	Pulls      map[string]docker.PullEvent `json:"pulls,omitempty"`
+}
+
+// JobStore keeps the running and recently finished jobs
+type JobStore struct {
+	mu    sync.Mutex
+	jobs  map[string]*Job
+	order []string
+	next  atomic.Uint64
+}
+
+// NewJobStore creates an empty job store
+func NewJobStore() *JobStore {
+	return &JobStore{
+		jobs: make(map[string]*Job),
+	}
+}
+
+// Start registers a new running job for the operation
+func (s *JobStore) Start(operation string) *Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job := &Job{
+		ID:        fmt.Sprintf("%s-%d", operation, s.next.Add(1)),
+		Operation: operation,
+		State:     JobRunning,
+		StartedAt: time.Now().Format(time.RFC3339),
+	}
+	s.jobs[job.ID] = job
+	s.order = append(s.order, job.ID)
+
+	// Drop the oldest finished jobs beyond the limit
+	for len(s.order) > MaxJobs {
+		oldest := s.jobs[s.order[0]]
+		if oldest != nil && oldest.State == JobRunning {
+			break
+		}
+		delete(s.jobs, s.order[0])
+		s.order = s.order[1:]
+	}
+
+	return job
+}
+
+// PullProgress returns a pull progress handler that records events on the job
+func (s *JobStore) PullProgress(id string) docker.PullProgressFunc {
+	return func(e docker.PullEvent) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		job, ok := s.jobs[id]
+		if !ok {
+			return
+		}
+		if job.Pulls == nil {
+			job.Pulls = make(map[string]docker.PullEvent)
+		}
+		job.Pulls[e.Service] = e
+	}
+}
+
+// Finish marks the job as succeeded, or failed when err is not nil
+func (s *JobStore) Finish(id string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return
+	}
+	job.FinishedAt = time.Now().Format(time.RFC3339)
+	if err != nil {
+		job.State = JobFailed
+		job.Error = err.Error()
+	} else {
+		job.State = JobSucceeded
+	}
+}
+
+// Get returns a copy of the job with the given ID
+func (s *JobStore) Get(id string) (Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return copyJob(job), true
+}
+
+// List returns copies of all jobs, oldest first
+func (s *JobStore) List() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := make([]Job, 0, len(s.order))
+	for _, id := range s.order {
+		jobs = append(jobs, copyJob(s.jobs[id]))
+	}
+	return jobs
+}
+
+// copyJob returns a copy of job that is safe to use without the store lock
+func copyJob(job *Job) Job {
+	c := *job
+	if job.Pulls != nil {
+		c.Pulls = make(map[string]docker.PullEvent, len(job.Pulls))
+		for k, v := range job.Pulls {
+			c.Pulls[k] = v
+		}
+	}
+	return c
+}
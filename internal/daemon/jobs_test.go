@@ -0,0 +1,187 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eternisai/silo/internal/config"
+	"github.com/eternisai/silo/internal/docker"
+	"github.com/eternisai/silo/pkg/logger"
+)
+
+func TestJobStore(t *testing.T) {
+	store := NewJobStore()
+
+	job := store.Start("upgrade")
+	if job.State != JobRunning {
+		t.Errorf("Expected new job to be running, got %s", job.State)
+	}
+
+	progress := store.PullProgress(job.ID)
+	progress(docker.PullEvent{Service: "backend", ImageCurrent: 10, ImageTotal: 100})
+	progress(docker.PullEvent{Service: "backend", ImageCurrent: 50, ImageTotal: 100})
+
+	got, ok := store.Get(job.ID)
+	if !ok {
+		t.Fatal("Expected job to be found")
+	}
+	if got.Pulls["backend"].ImageCurrent != 50 {
+		t.Errorf("Expected latest pull progress, got %+v", got.Pulls["backend"])
+	}
+
+	store.Finish(job.ID, errors.New("pull failed"))
+	got, _ = store.Get(job.ID)
+	if got.State != JobFailed || got.Error != "pull failed" {
+		t.Errorf("Expected failed job, got %+v", got)
+	}
+
+	if len(store.List()) != 1 {
+		t.Errorf("Expected 1 job, got %d", len(store.List()))
+	}
+}
+
+func TestJobStoreLimit(t *testing.T) {
+	store := NewJobStore()
+	for i := 0; i < MaxJobs+10; i++ {
+		job := store.Start("install")
+		store.Finish(job.ID, nil)
+	}
+
+	if n := len(store.List()); n != MaxJobs {
+		t.Errorf("Expected %d jobs to be kept, got %d", MaxJobs, n)
+	}
+}
+
+func TestHandleJob(t *testing.T) {
+	s := &Server{daemon: &Daemon{jobs: NewJobStore()}}
+	job := s.daemon.jobs.Start("install")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/jobs/"+job.ID, nil)
+	w := httptest.NewRecorder()
+	s.handleJob(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var resp APIResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	data, _ := resp.Data.(map[string]interface{})
+	if data["id"] != job.ID {
+		t.Errorf("Expected job %s, got %v", job.ID, data["id"])
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/jobs/missing", nil)
+	w = httptest.NewRecorder()
+	s.handleJob(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestHandleUpgrade_RunsAsJob(t *testing.T) {
+	paths := config.NewPaths(t.TempDir(), t.TempDir())
+	s := &Server{daemon: &Daemon{paths: paths, jobs: NewJobStore(), logger: logger.New(true)}}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/upgrade", nil)
+	w := httptest.NewRecorder()
+	s.handleUpgrade(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body)
+	}
+	var resp struct {
+		Data Job `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	// Without an installation the job fails, then releases the locks
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, ok := s.daemon.jobs.Get(resp.Data.ID)
+		if !ok {
+			t.Fatalf("Job %q not found", resp.Data.ID)
+		}
+		if job.State != JobRunning {
+			if job.State != JobFailed || !strings.Contains(job.Error, "not installed") {
+				t.Errorf("Expected the job to fail as not installed, got %+v", job)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Job did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	s.daemon.opLock.Lock()
+	defer s.daemon.opLock.Unlock()
+	lock, err := config.AcquireLock(paths, "test")
+	if err != nil {
+		t.Fatalf("Expected the instance lock to be released: %v", err)
+	}
+	lock.Release()
+}
+
+func TestHandleUpgrade_ConcurrentRequests(t *testing.T) {
+	paths := config.NewPaths(t.TempDir(), t.TempDir())
+	if err := os.MkdirAll(paths.ConfigDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.SaveState(paths.StateFile, &config.State{Version: "1.0.0"}); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{daemon: &Daemon{
+		config: config.NewDefaultConfig(paths),
+		state:  &config.State{},
+		paths:  paths,
+		jobs:   NewJobStore(),
+		logger: logger.New(true),
+	}}
+
+	w := httptest.NewRecorder()
+	s.handleUpgrade(w, httptest.NewRequest(http.MethodPost, "/api/v1/upgrade", nil))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body)
+	}
+	var resp struct {
+		Data Job `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	// The job reloads config and state while requests read them; run with
+	// -race to catch unsynchronised access
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.handleInferenceStatus(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/inference/status", nil))
+		_ = s.daemon.currentState()
+
+		job, ok := s.daemon.jobs.Get(resp.Data.ID)
+		if !ok {
+			t.Fatalf("Job %q not found", resp.Data.ID)
+		}
+		if job.State != JobRunning {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Job did not finish")
+		}
+	}
+
+	if got := s.daemon.currentState().Version; got != "1.0.0" {
+		t.Errorf("Expected the job to reload the state, got version %q", got)
+	}
+}
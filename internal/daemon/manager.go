@@ -0,0 +1,115 @@
+package daemon
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/eternisai/silo/internal/config"
+	"github.com/eternisai/silo/pkg/logger"
+)
+
+// InstanceScanInterval is how often the manager looks for new instances
+const InstanceScanInterval = 30 * time.Second
+
+// Manager runs one daemon per Silo instance on the host
+type Manager struct {
+	instances []string // Fixed instance list, or nil to manage all instances
+	daemons   map[string]*Daemon
+	logger    *logger.Logger
+	mu        sync.Mutex
+	wg        sync.WaitGroup
+}
+
+// NewManager creates a manager for the given instances. With no instances it
+// manages every instance under the base config directory, including ones
+// created while it runs.
+func NewManager(instances ...string) *Manager {
+	return &Manager{
+		instances: instances,
+		daemons:   make(map[string]*Daemon),
+		logger:    logger.New(false),
+	}
+}
+
+// Start starts a daemon for each instance and blocks until ctx is cancelled
+func (m *Manager) Start(ctx context.Context) error {
+	m.startInstances(ctx)
+
+	if m.instances != nil {
+		<-ctx.Done()
+		return nil
+	}
+
+	ticker := time.NewTicker(InstanceScanInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			m.startInstances(ctx)
+		}
+	}
+}
+
+// Stop gracefully stops all daemons
+func (m *Manager) Stop() error {
+	m.mu.Lock()
+	daemons := make([]*Daemon, 0, len(m.daemons))
+	for _, d := range m.daemons {
+		if d != nil {
+			daemons = append(daemons, d)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, d := range daemons {
+		if err := d.Stop(); err != nil {
+			m.logger.Warn("Error stopping daemon for instance %s: %v", d.paths.Instance, err)
+		}
+	}
+
+	m.wg.Wait()
+	return nil
+}
+
+// startInstances starts a daemon for every instance that has none yet
+func (m *Manager) startInstances(ctx context.Context) {
+	instances := m.instances
+	if instances == nil {
+		var err error
+		instances, err = config.ListInstances(config.BaseConfigDir())
+		if err != nil {
+			m.logger.Warn("Failed to list instances: %v", err)
+			return
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, name := range instances {
+		if _, ok := m.daemons[name]; ok {
+			continue
+		}
+
+		d, err := New(name)
+		if err != nil {
+			// Remember the failure so the instance is not retried on every scan
+			m.logger.Error("Failed to create daemon for instance %s: %v", name, err)
+			m.daemons[name] = nil
+			continue
+		}
+		m.daemons[name] = d
+
+		m.wg.Add(1)
+		go func(name string, d *Daemon) {
+			defer m.wg.Done()
+			if err := d.Start(ctx); err != nil {
+				m.logger.Error("Daemon for instance %s failed: %v", name, err)
+			}
+		}(name, d)
+	}
+}
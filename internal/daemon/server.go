@@ -49,6 +49,8 @@ func (s *Server) Start(ctx context.Context) error {
 	mux.HandleFunc("/api/v1/logs", s.handleLogs)
 	mux.HandleFunc("/api/v1/version", s.handleVersion)
 	mux.HandleFunc("/api/v1/check", s.handleCheck)
+	mux.HandleFunc("/api/v1/jobs", s.handleJobs)
+	mux.HandleFunc("/api/v1/jobs/", s.handleJob)
 
 	// Register inference engine API handlers
 	mux.HandleFunc("/api/v1/inference/up", s.handleInferenceUp)
@@ -56,6 +58,9 @@ func (s *Server) Start(ctx context.Context) error {
 	mux.HandleFunc("/api/v1/inference/status", s.handleInferenceStatus)
 	mux.HandleFunc("/api/v1/inference/logs", s.handleInferenceLogs)
 
+	// Register system API handlers
+	mux.HandleFunc("/api/v1/system/gpus", s.handleSystemGPUs)
+
 	s.server = &http.Server{
 		Addr:         fmt.Sprintf("%s:%d", s.bindAddr, s.port),
 		Handler:      s.loggingMiddleware(mux),
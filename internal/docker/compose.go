@@ -1,12 +1,16 @@
 package docker
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 )
 
 type Container struct {
@@ -17,6 +21,39 @@ type Container struct {
 	Service string
 }
 
+// OverrideFileName is a user-maintained compose file that is passed after the
+// generated one to every compose call when it exists next to it
+const OverrideFileName = "docker-compose.override.yml"
+
+// composeFiles returns the -f flags selecting the generated compose file and
+// its override file, if present
+func composeFiles(composePath string) []string {
+	files := []string{"-f", composePath}
+	override := filepath.Join(filepath.Dir(composePath), OverrideFileName)
+	if _, err := os.Stat(override); err == nil {
+		files = append(files, "-f", override)
+	}
+	return files
+}
+
+// Validate checks the compose file and its override with docker compose
+// config, returning the compose error message on failure
+func Validate(ctx context.Context, composePath string) error {
+	composeCmd := GetComposeCommand()
+	args := append(composeCmd[1:], composeFiles(composePath)...)
+	args = append(args, "config", "--quiet")
+	cmd := exec.CommandContext(ctx, composeCmd[0], args...)
+	cmd.Dir = filepath.Dir(composePath)
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if msg := strings.TrimSpace(string(output)); msg != "" {
+			return fmt.Errorf("%s", msg)
+		}
+		return fmt.Errorf("invalid compose configuration: %w", err)
+	}
+	return nil
+}
+
 type LogOptions struct {
 	Follow bool
 	Lines  int
@@ -24,7 +61,8 @@ type LogOptions struct {
 
 func Up(ctx context.Context, composePath string) error {
 	composeCmd := GetComposeCommand()
-	args := append(composeCmd[1:], "-f", composePath, "up", "-d")
+	args := append(composeCmd[1:], composeFiles(composePath)...)
+	args = append(args, "up", "-d")
 	cmd := exec.CommandContext(ctx, composeCmd[0], args...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -38,7 +76,8 @@ func Up(ctx context.Context, composePath string) error {
 
 func Down(ctx context.Context, composePath string, removeVolumes bool) error {
 	composeCmd := GetComposeCommand()
-	args := append(composeCmd[1:], "-f", composePath, "down")
+	args := append(composeCmd[1:], composeFiles(composePath)...)
+	args = append(args, "down")
 	if removeVolumes {
 		args = append(args, "-v")
 	}
@@ -56,28 +95,53 @@ func Down(ctx context.Context, composePath string, removeVolumes bool) error {
 
 // PullResult contains the result of pulling a service image
 type PullResult struct {
-	Service string
-	Error   error
+	Service  string
+	Error    error
+	Bytes    int64
+	Duration time.Duration
 }
 
 // Pull pulls images for the given services, or default services if none specified.
 // Returns results for each service attempted.
 func Pull(ctx context.Context, composePath string, services ...string) []PullResult {
+	return PullWithProgress(ctx, composePath, nil, services...)
+}
+
+// PullWithProgress pulls images like Pull and reports structured progress to
+// onEvent. With a nil onEvent, or when compose cannot emit JSON progress, the
+// compose output is passed through to the terminal instead.
+func PullWithProgress(ctx context.Context, composePath string, onEvent PullProgressFunc, services ...string) []PullResult {
 	if len(services) == 0 {
 		services = []string{"backend", "frontend"}
 	}
 
 	var results []PullResult
 	for _, service := range services {
-		err := pullService(ctx, composePath, service)
-		results = append(results, PullResult{Service: service, Error: err})
+		start := time.Now()
+		var pulled int64
+		var err error
+		if onEvent != nil && supportsJSONProgress() {
+			err = pullServiceWithProgress(ctx, composePath, service, func(e PullEvent) {
+				pulled = e.ImageTotal
+				onEvent(e)
+			})
+		} else {
+			err = pullService(ctx, composePath, service)
+		}
+		results = append(results, PullResult{
+			Service:  service,
+			Error:    err,
+			Bytes:    pulled,
+			Duration: time.Since(start),
+		})
 	}
 	return results
 }
 
 func pullService(ctx context.Context, composePath string, service string) error {
 	composeCmd := GetComposeCommand()
-	args := append(composeCmd[1:], "-f", composePath, "pull", service)
+	args := append(composeCmd[1:], composeFiles(composePath)...)
+	args = append(args, "pull", service)
 
 	cmd := exec.CommandContext(ctx, composeCmd[0], args...)
 	cmd.Stdout = os.Stdout
@@ -90,9 +154,63 @@ func pullService(ctx context.Context, composePath string, service string) error
 	return nil
 }
 
+func pullServiceWithProgress(ctx context.Context, composePath string, service string, onEvent PullProgressFunc) error {
+	composeCmd := GetComposeCommand()
+	args := append(composeCmd[1:], "--progress", "json")
+	args = append(args, composeFiles(composePath)...)
+	args = append(args, "pull", service)
+
+	cmd := exec.CommandContext(ctx, composeCmd[0], args...)
+	cmd.Dir = filepath.Dir(composePath)
+
+	// Compose writes progress to stderr; keep stdout for anything else
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("failed to pull %s: %w", service, err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to pull %s: %w", service, err)
+	}
+	failure := trackPull(stderr, service, onEvent, &output)
+
+	if err := cmd.Wait(); err != nil {
+		if failure != "" {
+			return fmt.Errorf("failed to pull %s: %s", service, failure)
+		}
+		if msg := strings.TrimSpace(output.String()); msg != "" {
+			return fmt.Errorf("failed to pull %s: %w: %s", service, err, msg)
+		}
+		return fmt.Errorf("failed to pull %s: %w", service, err)
+	}
+	return nil
+}
+
+var (
+	jsonProgressOnce      sync.Once
+	jsonProgressSupported bool
+)
+
+// supportsJSONProgress reports whether the installed compose accepts
+// --progress json.
+func supportsJSONProgress() bool {
+	jsonProgressOnce.Do(func() {
+		composeCmd := GetComposeCommand()
+		if len(composeCmd) < 2 {
+			return
+		}
+		args := append(composeCmd[1:], "--progress", "json", "version")
+		jsonProgressSupported = exec.Command(composeCmd[0], args...).Run() == nil
+	})
+	return jsonProgressSupported
+}
+
 func Ps(ctx context.Context, composePath string) ([]Container, error) {
 	composeCmd := GetComposeCommand()
-	args := append(composeCmd[1:], "-f", composePath, "ps", "-q")
+	args := append(composeCmd[1:], composeFiles(composePath)...)
+	args = append(args, "ps", "-q")
 	cmd := exec.CommandContext(ctx, composeCmd[0], args...)
 	cmd.Dir = filepath.Dir(composePath)
 
@@ -140,7 +258,8 @@ func Ps(ctx context.Context, composePath string) ([]Container, error) {
 
 func Logs(ctx context.Context, composePath string, service string, opts LogOptions) error {
 	composeCmd := GetComposeCommand()
-	args := append(composeCmd[1:], "-f", composePath, "logs")
+	args := append(composeCmd[1:], composeFiles(composePath)...)
+	args = append(args, "logs")
 
 	if opts.Follow {
 		args = append(args, "-f")
@@ -168,10 +287,31 @@ func Logs(ctx context.Context, composePath string, service string, opts LogOptio
 
 func Exec(ctx context.Context, composePath string, service string, command []string) error {
 	composeCmd := GetComposeCommand()
-	args := append(composeCmd[1:], "-f", composePath, "exec", "-T", service)
+	args := append(composeCmd[1:], composeFiles(composePath)...)
+	args = append(args, "exec", "-T", service)
+	args = append(args, command...)
+
+	cmd := exec.CommandContext(ctx, composeCmd[0], args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Dir = filepath.Dir(composePath)
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to execute command in container: %w", err)
+	}
+	return nil
+}
+
+// ExecInput runs command in a running service container with stdin as its
+// input, keeping the input off the command line
+func ExecInput(ctx context.Context, composePath string, service string, command []string, stdin io.Reader) error {
+	composeCmd := GetComposeCommand()
+	args := append(composeCmd[1:], composeFiles(composePath)...)
+	args = append(args, "exec", "-T", service)
 	args = append(args, command...)
 
 	cmd := exec.CommandContext(ctx, composeCmd[0], args...)
+	cmd.Stdin = stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	cmd.Dir = filepath.Dir(composePath)
@@ -184,7 +324,8 @@ func Exec(ctx context.Context, composePath string, service string, command []str
 
 func Restart(ctx context.Context, composePath string, service string) error {
 	composeCmd := GetComposeCommand()
-	args := append(composeCmd[1:], "-f", composePath, "restart")
+	args := append(composeCmd[1:], composeFiles(composePath)...)
+	args = append(args, "restart")
 	if service != "" {
 		args = append(args, service)
 	}
@@ -0,0 +1,156 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ImageInfo describes a local image
+type ImageInfo struct {
+	ID          string   `json:"id"`
+	RepoDigests []string `json:"repo_digests,omitempty"`
+	Os          string   `json:"os,omitempty"`
+	Arch        string   `json:"architecture,omitempty"`
+}
+
+// InspectImage returns information about a local image
+func InspectImage(ctx context.Context, image string) (*ImageInfo, error) {
+	cmd := exec.CommandContext(ctx, "docker", "image", "inspect", "--format", "{{json .}}", image)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("image %s not found locally: %w", image, err)
+	}
+
+	var info ImageInfo
+	if err := json.Unmarshal(output, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse image inspect output: %w", err)
+	}
+	return &info, nil
+}
+
+// ResolveDigest returns the digest reference of ref, e.g.
+// pgvector/pgvector@sha256:... for pgvector/pgvector:pg17. The image is
+// inspected under local, the name it was pulled as, which differs from ref
+// when it was pulled from a registry mirror. References that already carry
+// a digest are returned unchanged.
+func ResolveDigest(ctx context.Context, ref, local string) (string, error) {
+	if strings.Contains(ref, "@") {
+		return ref, nil
+	}
+
+	info, err := InspectImage(ctx, local)
+	if err != nil {
+		return "", err
+	}
+
+	repo := Repository(local)
+	for _, repoDigest := range info.RepoDigests {
+		if Repository(repoDigest) == repo {
+			return Repository(ref) + "@" + Digest(repoDigest), nil
+		}
+	}
+	return "", fmt.Errorf("image %s has no registry digest", local)
+}
+
+// ResolveDigests resolves each reference to its digest reference, looking
+// images up under the name local returns for them. References that cannot
+// be resolved, such as images missing locally, are left out.
+func ResolveDigests(ctx context.Context, refs []string, local func(string) string) (map[string]string, map[string]error) {
+	digests := make(map[string]string)
+	failed := make(map[string]error)
+	for _, ref := range refs {
+		digest, err := ResolveDigest(ctx, ref, local(ref))
+		if err != nil {
+			failed[ref] = err
+			continue
+		}
+		digests[ref] = digest
+	}
+	return digests, failed
+}
+
+// Digest returns the digest of a digest reference, e.g. sha256:...
+func Digest(ref string) string {
+	if i := strings.Index(ref, "@"); i >= 0 {
+		return ref[i+1:]
+	}
+	return ""
+}
+
+// HasDigest reports whether the image was pulled with the digest of ref,
+// from any registry
+func (i *ImageInfo) HasDigest(ref string) bool {
+	digest := Digest(ref)
+	for _, repoDigest := range i.RepoDigests {
+		if digest != "" && Digest(repoDigest) == digest {
+			return true
+		}
+	}
+	return false
+}
+
+// Repository returns the repository of an image reference, without tag or
+// digest
+func Repository(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image
+}
+
+// ContainerImage returns information about the image a container runs
+func ContainerImage(ctx context.Context, container string) (*ImageInfo, error) {
+	cmd := exec.CommandContext(ctx, "docker", "inspect", "--format", "{{.Image}}", container)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container %s: %w", container, err)
+	}
+	return InspectImage(ctx, strings.TrimSpace(string(output)))
+}
+
+// TagImage adds the name target to the local image source
+func TagImage(ctx context.Context, source, target string) error {
+	cmd := exec.CommandContext(ctx, "docker", "tag", source, target)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to tag %s as %s: %w: %s", source, target, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// PullImage pulls a single image outside of compose
+func PullImage(ctx context.Context, image string) error {
+	cmd := exec.CommandContext(ctx, "docker", "pull", image)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to pull %s: %w", image, err)
+	}
+	return nil
+}
+
+// SaveImage writes an image to a tar archive at output
+func SaveImage(ctx context.Context, image, output string) error {
+	cmd := exec.CommandContext(ctx, "docker", "save", "-o", output, image)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to save %s: %w: %s", image, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// LoadImage loads images from a tar archive created by SaveImage
+func LoadImage(ctx context.Context, input string) error {
+	cmd := exec.CommandContext(ctx, "docker", "load", "-i", input)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to load %s: %w: %s", filepath.Base(input), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
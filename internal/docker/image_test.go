@@ -0,0 +1,19 @@
+package docker
+
+import "testing"
+
+func TestRepository(t *testing.T) {
+	tests := map[string]string{
+		"pgvector/pgvector:pg17":                      "pgvector/pgvector",
+		"eternis/silo-proxy-agent":                    "eternis/silo-proxy-agent",
+		"lmsysorg/sglang@sha256:abc":                  "lmsysorg/sglang",
+		"ghcr.io/eternisai/deep_research:sha-2e9f2ef": "ghcr.io/eternisai/deep_research",
+		"localhost:5000/silo/backend":                 "localhost:5000/silo/backend",
+		"localhost:5000/silo/backend:1.0@sha256:abc":  "localhost:5000/silo/backend",
+	}
+	for image, want := range tests {
+		if got := Repository(image); got != want {
+			t.Errorf("Repository(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
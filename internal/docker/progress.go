@@ -0,0 +1,180 @@
+package docker
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+)
+
+// PullEvent is a progress update for one image pull. It carries the state of
+// the layer that changed together with totals for the whole image.
+type PullEvent struct {
+	Service string `json:"service"`
+	Layer   string `json:"layer,omitempty"`
+	Status  string `json:"status"`
+	Current int64  `json:"current,omitempty"`
+	Total   int64  `json:"total,omitempty"`
+
+	// Image-wide totals over all layers seen so far
+	ImageCurrent int64  `json:"image_current"`
+	ImageTotal   int64  `json:"image_total"`
+	Layers       int    `json:"layers"`
+	LayersDone   int    `json:"layers_done"`
+	ETASeconds   int    `json:"eta_seconds,omitempty"`
+	Done         bool   `json:"done,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// Percent returns the image download progress in percent, or -1 when the
+// image size is not known yet.
+func (e PullEvent) Percent() int {
+	if e.ImageTotal <= 0 {
+		return -1
+	}
+	return int(e.ImageCurrent * 100 / e.ImageTotal)
+}
+
+// PullProgressFunc receives pull progress events
+type PullProgressFunc func(PullEvent)
+
+// composeMessage is one line of `docker compose --progress json` output
+type composeMessage struct {
+	ID       string `json:"id"`
+	ParentID string `json:"parent_id"`
+	Text     string `json:"text"`
+	Status   string `json:"status"`
+	Current  int64  `json:"current"`
+	Total    int64  `json:"total"`
+}
+
+// layerState tracks the download of a single layer
+type layerState struct {
+	current int64
+	total   int64
+	done    bool
+}
+
+// pullTracker aggregates compose progress messages for one service
+type pullTracker struct {
+	service string
+	started time.Time
+	now     func() time.Time
+	layers  map[string]*layerState
+	order   []string
+	failed  string
+	done    bool
+}
+
+func newPullTracker(service string) *pullTracker {
+	return &pullTracker{
+		service: service,
+		started: time.Now(),
+		now:     time.Now,
+		layers:  make(map[string]*layerState),
+	}
+}
+
+// layerDoneStatuses are layer states after which all bytes are on disk
+var layerDoneStatuses = map[string]bool{
+	"Download complete":  true,
+	"Verifying Checksum": true,
+	"Extracting":         true,
+	"Pull complete":      true,
+	"Already exists":     true,
+}
+
+// update applies a compose message and returns the resulting event
+func (t *pullTracker) update(msg composeMessage) PullEvent {
+	event := PullEvent{Service: t.service, Status: msg.Text}
+
+	if msg.ParentID == "" {
+		// Image-level message, e.g. "Pulling", "Pulled" or "Error"
+		switch msg.Text {
+		case "Pulled":
+			t.done = true
+			for _, l := range t.layers {
+				l.done = true
+				l.current = l.total
+			}
+		case "Error":
+			t.failed = msg.Status
+		}
+	} else {
+		l, ok := t.layers[msg.ID]
+		if !ok {
+			l = &layerState{}
+			t.layers[msg.ID] = l
+			t.order = append(t.order, msg.ID)
+		}
+		if msg.Total > 0 {
+			l.total = msg.Total
+		}
+		if msg.Text == "Downloading" && msg.Current > 0 {
+			l.current = msg.Current
+		}
+		if layerDoneStatuses[msg.Text] {
+			l.done = true
+			l.current = l.total
+		}
+
+		event.Layer = msg.ID
+		event.Current = l.current
+		event.Total = l.total
+	}
+
+	t.fill(&event)
+	return event
+}
+
+// fill sets the image-wide totals and ETA on event
+func (t *pullTracker) fill(event *PullEvent) {
+	for _, id := range t.order {
+		l := t.layers[id]
+		event.ImageCurrent += l.current
+		event.ImageTotal += l.total
+		if l.done {
+			event.LayersDone++
+		}
+	}
+	event.Layers = len(t.order)
+	event.Done = t.done
+	event.Error = t.failed
+
+	elapsed := t.now().Sub(t.started).Seconds()
+	remaining := event.ImageTotal - event.ImageCurrent
+	if !t.done && elapsed > 0 && event.ImageCurrent > 0 && remaining > 0 {
+		rate := float64(event.ImageCurrent) / elapsed
+		event.ETASeconds = int(float64(remaining)/rate + 0.5)
+	}
+}
+
+// trackPull reads `docker compose --progress json pull` output from r and
+// reports an event for every progress message. Lines that are not JSON
+// progress messages are passed to plain, if set. It returns the error
+// message compose reported for the image, if any.
+func trackPull(r io.Reader, service string, onEvent PullProgressFunc, plain io.Writer) string {
+	tracker := newPullTracker(service)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var msg composeMessage
+		if !strings.HasPrefix(line, "{") || json.Unmarshal([]byte(line), &msg) != nil || msg.ID == "" {
+			if plain != nil {
+				_, _ = io.WriteString(plain, line+"\n")
+			}
+			continue
+		}
+
+		onEvent(tracker.update(msg))
+	}
+
+	return tracker.failed
+}
@@ -0,0 +1,82 @@
+package docker
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+const composePullOutput = `{"id":"backend","text":"Pulling","status":"Working"}
+{"id":"a1b2c3","parent_id":"backend","text":"Pulling fs layer","status":"Working"}
+{"id":"d4e5f6","parent_id":"backend","text":"Already exists","status":"Done"}
+{"id":"a1b2c3","parent_id":"backend","text":"Downloading","status":"[==>   ]","current":25,"total":100}
+some plain line
+{"id":"a1b2c3","parent_id":"backend","text":"Downloading","status":"[=====>]","current":50,"total":100}
+{"id":"a1b2c3","parent_id":"backend","text":"Pull complete","status":"Done"}
+{"id":"backend","text":"Pulled","status":"Done"}
+`
+
+func TestTrackPull(t *testing.T) {
+	var events []PullEvent
+	var plain bytes.Buffer
+
+	failure := trackPull(strings.NewReader(composePullOutput), "backend", func(e PullEvent) {
+		events = append(events, e)
+	}, &plain)
+
+	if failure != "" {
+		t.Errorf("Expected no failure, got %q", failure)
+	}
+	if len(events) != 7 {
+		t.Fatalf("Expected 7 events, got %d", len(events))
+	}
+	if plain.String() != "some plain line\n" {
+		t.Errorf("Expected plain line to be passed through, got %q", plain.String())
+	}
+
+	mid := events[4]
+	if mid.Layer != "a1b2c3" || mid.Current != 50 || mid.Total != 100 {
+		t.Errorf("Unexpected layer progress: %+v", mid)
+	}
+	if mid.ImageCurrent != 50 || mid.ImageTotal != 100 {
+		t.Errorf("Unexpected image progress: %d/%d", mid.ImageCurrent, mid.ImageTotal)
+	}
+	if mid.Layers != 2 || mid.LayersDone != 1 {
+		t.Errorf("Expected 1 of 2 layers done, got %d of %d", mid.LayersDone, mid.Layers)
+	}
+	if mid.Percent() != 50 {
+		t.Errorf("Expected 50%%, got %d%%", mid.Percent())
+	}
+
+	last := events[len(events)-1]
+	if !last.Done || last.ImageCurrent != last.ImageTotal {
+		t.Errorf("Expected completed pull, got %+v", last)
+	}
+}
+
+func TestTrackPull_Error(t *testing.T) {
+	output := `{"id":"deep-research","text":"Error","status":"denied: requested access to the resource is denied"}
+`
+	failure := trackPull(strings.NewReader(output), "deep-research", func(PullEvent) {}, nil)
+	if !strings.Contains(failure, "denied") {
+		t.Errorf("Expected denial to be reported, got %q", failure)
+	}
+}
+
+func TestPullTrackerETA(t *testing.T) {
+	tracker := newPullTracker("frontend")
+	now := tracker.started
+	tracker.now = func() time.Time { return now }
+
+	now = now.Add(10 * time.Second)
+	event := tracker.update(composeMessage{ID: "l1", ParentID: "frontend", Text: "Downloading", Current: 100, Total: 400})
+
+	// 100 bytes in 10s leaves 300 bytes at 10 B/s
+	if event.ETASeconds != 30 {
+		t.Errorf("Expected ETA of 30s, got %ds", event.ETASeconds)
+	}
+	if event.Percent() != 25 {
+		t.Errorf("Expected 25%%, got %d%%", event.Percent())
+	}
+}
@@ -0,0 +1,127 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/eternisai/silo/internal/registry"
+)
+
+// CertsDir is where the docker daemon looks up registry CA certificates
+const CertsDir = "/etc/docker/certs.d"
+
+// Login logs docker in to every registry with configured credentials
+func Login(ctx context.Context, cfg registry.Config) error {
+	hosts := make([]string, 0, len(cfg.Credentials))
+	for host := range cfg.Credentials {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	for _, host := range hosts {
+		creds := cfg.Credentials[host]
+		password, err := creds.Secret()
+		if err != nil {
+			return fmt.Errorf("credentials for %s: %w", host, err)
+		}
+
+		cmd := exec.CommandContext(ctx, "docker", "login", "--username", creds.Username, "--password-stdin", host)
+		cmd.Stdin = strings.NewReader(password)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("docker login to %s failed: %w: %s", host, err, strings.TrimSpace(string(out)))
+		}
+	}
+	return nil
+}
+
+// InstallCA copies the configured CA certificate into the docker daemon's
+// certificate directory of every mirror host. It needs write access to
+// CertsDir, which usually means root.
+func InstallCA(cfg registry.Config) error {
+	if cfg.CAFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(cfg.CAFile)
+	if err != nil {
+		return fmt.Errorf("failed to read registry CA file: %w", err)
+	}
+
+	for _, host := range cfg.MirrorHosts() {
+		target := filepath.Join(CertsDir, host, "ca.crt")
+		if existing, err := os.ReadFile(target); err == nil && string(existing) == string(data) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("failed to install CA for %s: %w", host, err)
+		}
+		if err := os.WriteFile(target, data, 0644); err != nil {
+			return fmt.Errorf("failed to install CA for %s: %w", host, err)
+		}
+	}
+	return nil
+}
+
+// RegistryProblems returns the registry settings the docker daemon does not
+// honour yet: insecure registries it was not configured to allow and mirror
+// hosts without the configured CA certificate.
+func RegistryProblems(ctx context.Context, cfg registry.Config) []string {
+	var problems []string
+
+	if len(cfg.Insecure) > 0 {
+		allowed, err := insecureRegistries(ctx)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("could not read docker insecure registries: %v", err))
+		} else {
+			for _, host := range cfg.Insecure {
+				if !allowed[host] {
+					problems = append(problems, fmt.Sprintf(
+						"registry %s is not in the docker daemon's insecure-registries (add it to /etc/docker/daemon.json)", host))
+				}
+			}
+		}
+	}
+
+	if cfg.CAFile != "" {
+		for _, host := range cfg.MirrorHosts() {
+			if _, err := os.Stat(filepath.Join(CertsDir, host, "ca.crt")); err != nil {
+				problems = append(problems, fmt.Sprintf(
+					"docker does not trust the registry CA for %s (copy %s to %s)",
+					host, cfg.CAFile, filepath.Join(CertsDir, host, "ca.crt")))
+			}
+		}
+	}
+
+	return problems
+}
+
+// insecureRegistries returns the registries the docker daemon allows over
+// plain HTTP or without certificate verification
+func insecureRegistries(ctx context.Context) (map[string]bool, error) {
+	cmd := exec.CommandContext(ctx, "docker", "info", "--format", "{{json .RegistryConfig.IndexConfigs}}")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var indexes map[string]struct {
+		Secure bool `json:"Secure"`
+	}
+	if err := json.Unmarshal(output, &indexes); err != nil {
+		return nil, fmt.Errorf("failed to parse docker info: %w", err)
+	}
+
+	allowed := make(map[string]bool)
+	for host, index := range indexes {
+		if !index.Secure {
+			allowed[host] = true
+		}
+	}
+	return allowed, nil
+}
@@ -0,0 +1,193 @@
+// Package gpu takes the inventory of the NVIDIA GPUs of the host from
+// nvidia-smi and checks that docker can pass them to containers.
+package gpu
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DetectTimeout bounds the nvidia-smi and docker commands of Detect
+const DetectTimeout = 15 * time.Second
+
+// queryFields are the nvidia-smi --query-gpu fields ParseQuery reads, in
+// order
+var queryFields = []string{"index", "uuid", "name", "memory.total", "memory.used", "memory.free", "driver_version"}
+
+// nvidiaRuntime is the docker runtime the NVIDIA container toolkit
+// registers, and runtimeHook the OCI hook docker run --gpus calls when the
+// toolkit is installed without registering it
+const (
+	nvidiaRuntime = "nvidia"
+	runtimeHook   = "nvidia-container-runtime-hook"
+)
+
+// Commands are run through these, so tests can replay recorded output
+var (
+	run = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, name, args...)
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			if msg := strings.TrimSpace(stderr.String() + string(out)); msg != "" {
+				return nil, fmt.Errorf("%w: %s", err, msg)
+			}
+			return nil, err
+		}
+		return out, nil
+	}
+	lookPath = exec.LookPath
+)
+
+// GPU is a GPU of the host. Memory is in bytes.
+type GPU struct {
+	Index       int    `json:"index"`
+	UUID        string `json:"uuid"`
+	Name        string `json:"name"`
+	MemoryTotal int64  `json:"memory_total"`
+	MemoryUsed  int64  `json:"memory_used"`
+	MemoryFree  int64  `json:"memory_free"`
+}
+
+// Inventory lists the GPUs of the host and the software that runs them in
+// containers. A host without nvidia-smi or docker has an inventory that
+// records why, rather than failing.
+type Inventory struct {
+	GPUs          []GPU  `json:"gpus"`
+	DriverVersion string `json:"driver_version,omitempty"`
+	CUDAVersion   string `json:"cuda_version,omitempty"`
+
+	// Error is why nvidia-smi could not list the GPUs
+	Error string `json:"error,omitempty"`
+
+	// ContainerToolkit reports whether the NVIDIA container toolkit is
+	// installed, which docker run --gpus needs
+	ContainerToolkit bool     `json:"container_toolkit"`
+	DockerRuntimes   []string `json:"docker_runtimes,omitempty"`
+	DockerError      string   `json:"docker_error,omitempty"`
+}
+
+// Detect takes the inventory of the host
+func Detect(ctx context.Context) *Inventory {
+	ctx, cancel := context.WithTimeout(ctx, DetectTimeout)
+	defer cancel()
+
+	inv := &Inventory{GPUs: []GPU{}}
+	out, err := run(ctx, "nvidia-smi", "--query-gpu="+strings.Join(queryFields, ","), "--format=csv,noheader,nounits")
+	if err == nil {
+		inv.GPUs, inv.DriverVersion, err = ParseQuery(out)
+	}
+	if err != nil {
+		inv.Error = fmt.Sprintf("nvidia-smi: %v", err)
+	} else if out, err := run(ctx, "nvidia-smi"); err == nil {
+		// Only the summary table shows the CUDA version the driver supports
+		inv.CUDAVersion = ParseCUDAVersion(out)
+	}
+
+	out, err = run(ctx, "docker", "info", "--format", "{{json .Runtimes}}")
+	if err == nil {
+		inv.DockerRuntimes, err = ParseRuntimes(out)
+	}
+	if err != nil {
+		inv.DockerError = fmt.Sprintf("docker info: %v", err)
+	}
+	inv.ContainerToolkit = slices.Contains(inv.DockerRuntimes, nvidiaRuntime)
+	if !inv.ContainerToolkit {
+		_, err := lookPath(runtimeHook)
+		inv.ContainerToolkit = err == nil
+	}
+	return inv
+}
+
+// ParseQuery parses the output of nvidia-smi --query-gpu with queryFields
+// and --format=csv,noheader,nounits, returning the GPUs and the driver
+// version
+func ParseQuery(out []byte) ([]GPU, string, error) {
+	r := csv.NewReader(bytes.NewReader(out))
+	r.FieldsPerRecord = len(queryFields)
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse GPU list: %w", err)
+	}
+
+	gpus := []GPU{}
+	driver := ""
+	for _, rec := range records {
+		index, err := strconv.Atoi(rec[0])
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to parse GPU list: invalid index %q", rec[0])
+		}
+		gpu := GPU{Index: index, UUID: rec[1], Name: rec[2]}
+		for i, field := range []*int64{&gpu.MemoryTotal, &gpu.MemoryUsed, &gpu.MemoryFree} {
+			*field = parseMiB(rec[3+i])
+		}
+		gpus = append(gpus, gpu)
+		driver = rec[6]
+	}
+	return gpus, driver, nil
+}
+
+// parseMiB returns a memory amount in MiB as bytes, and 0 for values
+// nvidia-smi does not know, such as [N/A]
+func parseMiB(s string) int64 {
+	mib, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return mib << 20
+}
+
+var cudaVersionPattern = regexp.MustCompile(`CUDA Version:\s*([0-9.]+)`)
+
+// ParseCUDAVersion returns the CUDA version in the nvidia-smi summary, or
+// "" if it shows none
+func ParseCUDAVersion(out []byte) string {
+	if m := cudaVersionPattern.FindSubmatch(out); m != nil {
+		return string(m[1])
+	}
+	return ""
+}
+
+// ParseRuntimes returns the sorted runtime names of docker info --format
+// '{{json .Runtimes}}'
+func ParseRuntimes(out []byte) ([]string, error) {
+	var runtimes map[string]json.RawMessage
+	if err := json.Unmarshal(out, &runtimes); err != nil {
+		return nil, fmt.Errorf("failed to parse docker runtimes: %w", err)
+	}
+	names := make([]string, 0, len(runtimes))
+	for name := range runtimes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// Lookup returns the GPU a gpu_devices entry names, by index or UUID, or
+// nil if the host has none such
+func (inv *Inventory) Lookup(device string) *GPU {
+	device = strings.TrimSpace(device)
+	for i := range inv.GPUs {
+		gpu := &inv.GPUs[i]
+		if strconv.Itoa(gpu.Index) == device || strings.EqualFold(gpu.UUID, device) {
+			return gpu
+		}
+	}
+	return nil
+}
+
+// Available reports whether nvidia-smi listed the GPUs
+func (inv *Inventory) Available() bool {
+	return inv.Error == ""
+}
@@ -0,0 +1,131 @@
+package gpu
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// replay makes commands answer with the testdata files recorded for them,
+// keyed by program name, and fail for programs without one
+func replay(t *testing.T, recorded map[string]string, hook bool) {
+	t.Helper()
+	origRun, origLookPath := run, lookPath
+	t.Cleanup(func() { run, lookPath = origRun, origLookPath })
+
+	run = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		key := name
+		if len(args) > 0 && strings.HasPrefix(args[0], "--query-gpu=") {
+			key += " --query-gpu"
+		}
+		file, ok := recorded[key]
+		if !ok {
+			return nil, errors.New("executable file not found in $PATH")
+		}
+		return os.ReadFile(filepath.Join("testdata", file))
+	}
+	lookPath = func(file string) (string, error) {
+		if hook && file == runtimeHook {
+			return "/usr/bin/" + file, nil
+		}
+		return "", errors.New("not found")
+	}
+}
+
+func TestDetect(t *testing.T) {
+	replay(t, map[string]string{
+		"nvidia-smi --query-gpu": "query_h100.csv",
+		"nvidia-smi":             "nvidia_smi.txt",
+		"docker":                 "docker_runtimes.json",
+	}, false)
+
+	inv := Detect(context.Background())
+	if !inv.Available() || inv.DockerError != "" {
+		t.Fatalf("Unexpected errors: %q, %q", inv.Error, inv.DockerError)
+	}
+	if len(inv.GPUs) != 4 || inv.DriverVersion != "550.54.15" || inv.CUDAVersion != "12.4" {
+		t.Fatalf("Unexpected inventory: %+v", inv)
+	}
+	gpu := inv.GPUs[1]
+	if gpu.Index != 1 || gpu.Name != "NVIDIA H100 80GB HBM3" || gpu.MemoryTotal != 81559<<20 || gpu.MemoryUsed != 71234<<20 || gpu.MemoryFree != 9760<<20 {
+		t.Errorf("Unexpected GPU: %+v", gpu)
+	}
+	if !inv.ContainerToolkit || !slices.Equal(inv.DockerRuntimes, []string{"io.containerd.runc.v2", "nvidia", "runc"}) {
+		t.Errorf("Expected the nvidia runtime, got %v", inv.DockerRuntimes)
+	}
+}
+
+func TestDetect_ToolkitHook(t *testing.T) {
+	for _, hook := range []bool{true, false} {
+		replay(t, map[string]string{
+			"nvidia-smi --query-gpu": "query_h100.csv",
+			"nvidia-smi":             "nvidia_smi.txt",
+			"docker":                 "docker_runtimes_runc.json",
+		}, hook)
+		if inv := Detect(context.Background()); inv.ContainerToolkit != hook {
+			t.Errorf("With the runtime hook installed %v, ContainerToolkit = %v", hook, inv.ContainerToolkit)
+		}
+	}
+}
+
+func TestDetect_NoNvidiaSMI(t *testing.T) {
+	replay(t, map[string]string{"docker": "docker_runtimes_runc.json"}, false)
+
+	inv := Detect(context.Background())
+	if inv.Available() || !strings.Contains(inv.Error, "nvidia-smi") {
+		t.Errorf("Expected the nvidia-smi error, got %q", inv.Error)
+	}
+	if inv.GPUs == nil || len(inv.GPUs) != 0 || inv.ContainerToolkit {
+		t.Errorf("Expected no GPUs, got %+v", inv)
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	out, err := os.ReadFile("testdata/query_na.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gpus, driver, err := ParseQuery(out)
+	if err != nil {
+		t.Fatalf("ParseQuery failed: %v", err)
+	}
+	if len(gpus) != 1 || gpus[0].MemoryTotal != 0 || gpus[0].Name != "NVIDIA GH200 480GB" || driver != "560.35.03" {
+		t.Errorf("Unexpected GPUs %+v, driver %q", gpus, driver)
+	}
+
+	for _, bad := range []string{"0, GPU-1, A10\n", "x, GPU-1, A10, 1, 1, 1, 550\n"} {
+		if _, _, err := ParseQuery([]byte(bad)); err == nil {
+			t.Errorf("Expected %q to fail", bad)
+		}
+	}
+	if gpus, _, err := ParseQuery(nil); err != nil || gpus == nil || len(gpus) != 0 {
+		t.Errorf("Expected no GPUs from empty output, got %v, %v", gpus, err)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	out, err := os.ReadFile("testdata/query_h100.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gpus, _, err := ParseQuery(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inv := &Inventory{GPUs: gpus}
+
+	for device, want := range map[string]int{"2": 2, " 0": 0, "GPU-7a9e2b4c-1d3f-4e5a-8b6c-9d0e1f2a3b4c": 1, "gpu-9B8A7C6D-5e4f-3a2b-1c0d-e9f8a7b6c5d4": 3} {
+		if gpu := inv.Lookup(device); gpu == nil || gpu.Index != want {
+			t.Errorf("Lookup(%q) = %+v, want GPU %d", device, gpu, want)
+		}
+	}
+	for _, device := range []string{"4", "GPU-missing", "all"} {
+		if gpu := inv.Lookup(device); gpu != nil {
+			t.Errorf("Lookup(%q) = %+v, want none", device, gpu)
+		}
+	}
+}
@@ -0,0 +1,391 @@
+package inference
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/eternisai/silo/internal/config"
+	"github.com/eternisai/silo/pkg/logger"
+)
+
+// container runs an inference server in a docker container. Backends embed
+// it for the container lifecycle and supply the docker run arguments.
+type container struct {
+	name       string
+	imageRef   string
+	image      string
+	hostPort   int
+	healthPath string
+	label      string
+	logger     *logger.Logger
+
+	// probeHost is the address the host port is probed at, and
+	// servedModel the model /v1/models must list, empty when the server
+	// answers to any model name
+	probeHost   string
+	servedModel string
+	client      *http.Client
+
+	// backend and section, the config section of the engine, select the
+	// hints of diagnostics; onProgress receives startup progress
+	backend    string
+	section    string
+	onProgress func(Progress)
+
+	// gpus reports whether the engine runs on its gpu_devices, and
+	// memoryKey names the setting of the share of every GPU's memory it
+	// claims at start, memoryFraction, if it claims one
+	gpus           bool
+	memoryKey      string
+	memoryFraction float64
+
+	// runArgs returns the docker run arguments of the backend
+	runArgs func() []string
+
+	settings config.ContainerSettings
+}
+
+// newContainer returns the container for settings. An unset name, image and
+// port fall back to those of defaults.
+func newContainer(cfg *config.Config, settings, defaults config.ContainerSettings, healthPath string, log *logger.Logger) *container {
+	c := &container{
+		name:       settings.ContainerName,
+		imageRef:   settings.Image,
+		hostPort:   settings.Port,
+		healthPath: healthPath,
+		label:      "Inference engine",
+		logger:     log,
+		probeHost:  cfg.Inference.ProbeHost,
+		client:     http.DefaultClient,
+		settings:   settings,
+	}
+	if c.name == "" {
+		c.name = defaults.ContainerName
+	}
+	if c.imageRef == "" {
+		c.imageRef = defaults.Image
+	}
+	if c.hostPort == 0 {
+		c.hostPort = defaults.Port
+	}
+	if c.probeHost == "" {
+		c.probeHost = DefaultProbeHost
+	}
+	c.image = cfg.Image(c.imageRef)
+	return c
+}
+
+// runOptions are the docker run settings that differ between backends
+type runOptions struct {
+	containerPort int
+	shmSize       string
+	ipcHost       bool
+	env           []string
+	volumes       []string
+}
+
+// dockerArgs returns the docker run arguments up to the image. The GPUs
+// are renumbered from 0 inside the container, and extra_env follows the
+// backend's environment.
+func (c *container) dockerArgs(opts runOptions) []string {
+	s := c.settings
+	args := []string{
+		"run", "-d",
+		"--name", c.name,
+		"--restart", c.restartPolicy(),
+	}
+	gpus := c.gpus && len(s.GPUDevices) > 0
+	if gpus {
+		// The quotes keep docker from splitting the device list at commas
+		args = append(args, "--gpus", fmt.Sprintf(`"device=%s"`, strings.Join(s.GPUDevices, ",")))
+	}
+	if opts.shmSize != "" {
+		args = append(args, "--shm-size", opts.shmSize)
+	}
+	if opts.ipcHost {
+		args = append(args, "--ipc=host")
+	}
+	args = append(args, c.resources().DockerRunArgs()...)
+	args = append(args, "-p", fmt.Sprintf("%d:%d", c.hostPort, opts.containerPort))
+
+	if gpus {
+		visible := make([]string, len(s.GPUDevices))
+		for i := range visible {
+			visible[i] = strconv.Itoa(i)
+		}
+		args = append(args, "-e", "CUDA_VISIBLE_DEVICES="+strings.Join(visible, ","))
+	}
+	for _, env := range opts.env {
+		args = append(args, "-e", env)
+	}
+	for _, name := range slices.Sorted(maps.Keys(s.ExtraEnv)) {
+		args = append(args, "-e", name+"="+s.ExtraEnv[name])
+	}
+	for _, volume := range opts.volumes {
+		args = append(args, "-v", volume)
+	}
+	return args
+}
+
+// restartPolicy returns the restart policy from config or default
+func (c *container) restartPolicy() string {
+	if c.settings.Restart != "" {
+		return c.settings.Restart
+	}
+	return DefaultRestartPolicy
+}
+
+// resources returns the configured resource limits with the default
+// ulimits filled in
+func (c *container) resources() config.ResourceConfig {
+	resources := c.settings.Resources
+	ulimits := maps.Clone(defaultUlimits)
+	maps.Copy(ulimits, resources.Ulimits)
+	resources.Ulimits = ulimits
+	return resources
+}
+
+// Up starts the inference engine container
+func (c *container) Up(ctx context.Context) error {
+	// Check if already running
+	running, err := c.IsRunning(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check container status: %w", err)
+	}
+	if running {
+		c.logger.Info("%s is already running", c.label)
+		return nil
+	}
+
+	// Remove existing stopped container if present
+	if exists, _ := c.containerExists(ctx); exists {
+		c.logger.Info("Removing existing stopped container...")
+		if err := c.removeContainer(ctx); err != nil {
+			return fmt.Errorf("failed to remove existing container: %w", err)
+		}
+	}
+
+	c.logger.Info("Starting %s...", strings.ToLower(c.label))
+
+	cmd := exec.CommandContext(ctx, "docker", c.runArgs()...)
+	out := c.output()
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to start inference engine: %w", err)
+	}
+
+	c.logger.Success("%s started", c.label)
+	return nil
+}
+
+// output returns the writer for the output of docker commands: stdout,
+// followed by a tracker reporting progress when a handler is set
+func (c *container) output() io.Writer {
+	if c.onProgress == nil {
+		return os.Stdout
+	}
+	return io.MultiWriter(os.Stdout, &progressWriter{
+		tracker:  NewTracker(c.backend, c.section),
+		reporter: &progressReporter{report: c.onProgress},
+	})
+}
+
+// progressWriter feeds output to a tracker and reports progress changes
+type progressWriter struct {
+	tracker  *Tracker
+	reporter *progressReporter
+	partial  string
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	data := w.partial + string(p)
+	end := strings.LastIndexAny(data, "\r\n")
+	w.partial = data[end+1:]
+	for _, line := range strings.FieldsFunc(data[:end+1], func(r rune) bool { return r == '\n' || r == '\r' }) {
+		if w.tracker.Line(line) {
+			w.reporter.update(w.tracker.Progress())
+		}
+	}
+	return len(p), nil
+}
+
+// progressReporter passes progress on when the phase changes, a failure
+// is found or the percentage advances by progressStep
+type progressReporter struct {
+	report func(Progress)
+	last   *Progress
+}
+
+// progressStep is the smallest percentage change reported
+const progressStep = 10
+
+func (r *progressReporter) update(p Progress) {
+	if r.report == nil {
+		return
+	}
+	if last := r.last; last != nil && p.Phase == last.Phase && len(p.Diagnostics) == len(last.Diagnostics) &&
+		p.Percent < last.Percent+progressStep && (p.Percent != 100 || last.Percent == 100) {
+		return
+	}
+	r.last = &p
+	r.report(p)
+}
+
+// Down stops the inference engine container
+func (c *container) Down(ctx context.Context) error {
+	running, err := c.IsRunning(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check container status: %w", err)
+	}
+	if !running {
+		c.logger.Info("%s is not running", c.label)
+		return nil
+	}
+
+	c.logger.Info("Stopping %s...", strings.ToLower(c.label))
+
+	// Stop container
+	stopCmd := exec.CommandContext(ctx, "docker", "stop", c.name)
+	if err := stopCmd.Run(); err != nil {
+		return fmt.Errorf("failed to stop container: %w", err)
+	}
+
+	// Remove container
+	rmCmd := exec.CommandContext(ctx, "docker", "rm", c.name)
+	if err := rmCmd.Run(); err != nil {
+		return fmt.Errorf("failed to remove container: %w", err)
+	}
+
+	c.logger.Success("%s stopped", c.label)
+	return nil
+}
+
+// Status returns the current status of the inference engine
+func (c *container) Status(ctx context.Context) (*ContainerInfo, error) {
+	cmd := exec.CommandContext(ctx, "docker", "inspect",
+		"--format", "{{.State.Status}}|{{.State.Running}}|{{.Config.Image}}",
+		c.name)
+
+	output, err := cmd.Output()
+	if err != nil {
+		// Container doesn't exist
+		return &ContainerInfo{
+			Name:    c.name,
+			State:   "not found",
+			Running: false,
+		}, nil
+	}
+
+	parts := strings.Split(strings.TrimSpace(string(output)), "|")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("unexpected docker inspect output")
+	}
+
+	return &ContainerInfo{
+		Name:    c.name,
+		State:   parts[0],
+		Status:  parts[0],
+		Image:   parts[2],
+		Running: parts[1] == "true",
+	}, nil
+}
+
+// Logs streams logs from the inference engine container
+func (c *container) Logs(ctx context.Context, follow bool, lines int) error {
+	args := []string{"logs"}
+	if follow {
+		args = append(args, "-f")
+	}
+	if lines > 0 {
+		args = append(args, "--tail", fmt.Sprintf("%d", lines))
+	}
+	args = append(args, c.name)
+
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.Canceled {
+			return nil
+		}
+		return fmt.Errorf("failed to fetch logs: %w", err)
+	}
+	return nil
+}
+
+// IsRunning checks if the inference engine container is running
+func (c *container) IsRunning(ctx context.Context) (bool, error) {
+	info, err := c.Status(ctx)
+	if err != nil {
+		return false, err
+	}
+	return info.Running, nil
+}
+
+// containerExists checks if the container exists (running or stopped)
+func (c *container) containerExists(ctx context.Context) (bool, error) {
+	cmd := exec.CommandContext(ctx, "docker", "inspect", c.name)
+	err := cmd.Run()
+	return err == nil, nil
+}
+
+// removeContainer removes the container
+func (c *container) removeContainer(ctx context.Context) error {
+	cmd := exec.CommandContext(ctx, "docker", "rm", "-f", c.name)
+	return cmd.Run()
+}
+
+// inspectRaw returns raw docker inspect output as JSON
+func (c *container) inspectRaw(ctx context.Context) (map[string]interface{}, error) {
+	cmd := exec.CommandContext(ctx, "docker", "inspect", c.name)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("container not found: %w", err)
+	}
+
+	var result []map[string]interface{}
+	if err := json.Unmarshal(output, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse inspect output: %w", err)
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no container data returned")
+	}
+
+	return result[0], nil
+}
+
+// logsBuffer returns logs as a string buffer (for API responses)
+func (c *container) logsBuffer(ctx context.Context, lines int) (string, error) {
+	args := []string{"logs"}
+	if lines > 0 {
+		args = append(args, "--tail", fmt.Sprintf("%d", lines))
+	}
+	args = append(args, c.name)
+
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("failed to fetch logs: %w", err)
+	}
+
+	// Docker logs go to stderr for container stderr
+	combined := stdout.String() + stderr.String()
+	return combined, nil
+}
@@ -0,0 +1,301 @@
+package inference
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/eternisai/silo/internal/config"
+)
+
+// Startup phases of an inference engine, in the order they happen
+const (
+	PhaseStarting     = "starting"
+	PhasePullingImage = "pulling_image"
+	PhaseDownloading  = "downloading_weights"
+	PhaseLoading      = "loading_weights"
+	PhaseCapturing    = "capturing_cuda_graphs"
+	PhaseReady        = "ready"
+	PhaseFailed       = "failed"
+)
+
+// phaseOrder ranks the phases, the tracked phase never moves back
+var phaseOrder = map[string]int{
+	PhaseStarting:     0,
+	PhasePullingImage: 1,
+	PhaseDownloading:  2,
+	PhaseLoading:      3,
+	PhaseCapturing:    4,
+	PhaseReady:        5,
+	PhaseFailed:       6,
+}
+
+// phaseLabels are the phases as shown to users
+var phaseLabels = map[string]string{
+	PhaseStarting:     "Starting",
+	PhasePullingImage: "Pulling image",
+	PhaseDownloading:  "Downloading weights",
+	PhaseLoading:      "Loading weights",
+	PhaseCapturing:    "Capturing CUDA graphs",
+	PhaseReady:        "Ready",
+	PhaseFailed:       "Failed",
+}
+
+const (
+	// progressLogLines is the number of log lines read to find the progress
+	progressLogLines = 2000
+
+	// maxDiagnosticLine bounds the log line quoted by a diagnostic
+	maxDiagnosticLine = 300
+)
+
+// Progress is the startup progress of an engine, read from its output
+type Progress struct {
+	Phase string `json:"phase"`
+
+	// Percent is the progress of the phase when the engine reports it,
+	// e.g. of the checkpoint shards loaded
+	Percent int `json:"percent,omitempty"`
+
+	Diagnostics []Diagnostic `json:"diagnostics,omitempty"`
+}
+
+// String returns the phase and its percentage, e.g. "Loading weights (45%)"
+func (p Progress) String() string {
+	label := phaseLabels[p.Phase]
+	if p.Percent > 0 && p.Phase != PhaseReady && p.Phase != PhaseFailed {
+		return fmt.Sprintf("%s (%d%%)", label, p.Percent)
+	}
+	return label
+}
+
+// Diagnostic is a known failure found in the engine output, with a hint
+// naming the config keys that fix it
+type Diagnostic struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Hint    string `json:"hint"`
+	Line    string `json:"line"`
+}
+
+// StartupError reports an engine that failed to start for known reasons
+type StartupError struct {
+	Label       string
+	Diagnostics []Diagnostic
+}
+
+func (e *StartupError) Error() string {
+	messages := make([]string, len(e.Diagnostics))
+	for i, d := range e.Diagnostics {
+		messages[i] = d.Message
+	}
+	return fmt.Sprintf("%s failed to start: %s", e.Label, strings.Join(messages, "; "))
+}
+
+// phaseRule moves the tracker to phase on lines matching pattern, reading
+// a percentage from them if percent is set
+type phaseRule struct {
+	phase   string
+	pattern *regexp.Regexp
+	percent bool
+}
+
+var phaseRules = []phaseRule{
+	{PhasePullingImage, regexp.MustCompile(`Unable to find image .* locally|: Pulling from |Pulling fs layer|Downloaded newer image`), false},
+	{PhaseDownloading, regexp.MustCompile(`Fetching \d+ files|Downloading .*\.(safetensors|bin|gguf)|pulling [0-9a-f]{12}`), true},
+	{PhaseLoading, regexp.MustCompile(`Load weight begin|Loading (safetensors|pt) checkpoint shards|Starting to load model|Loading model weights|llama_model_loader: loaded meta data|main: loading model`), true},
+	{PhaseCapturing, regexp.MustCompile(`Capture cuda graph begin|Capturing cudagraphs|Capturing CUDA graph|Capturing batches`), true},
+	{PhaseReady, regexp.MustCompile(`The server is fired up and ready to roll|Application startup complete|starting the main loop|Listening on .*\(version`), false},
+}
+
+var percentPattern = regexp.MustCompile(`(\d{1,3})%`)
+
+// failureRule maps a failure signature to a diagnostic; hint receives the
+// backend and the config section of the engine
+type failureRule struct {
+	code    string
+	pattern *regexp.Regexp
+	message string
+	hint    func(backend, section string) string
+}
+
+var failureRules = []failureRule{
+	{
+		code:    "gpu_unavailable",
+		pattern: regexp.MustCompile(`no CUDA-capable device is detected|CUDA driver version is insufficient|could not select device driver|Found no NVIDIA driver|NVIDIA driver on your system is too old`),
+		message: "The container cannot use the GPUs",
+		hint: func(backend, s string) string {
+			return fmt.Sprintf("Check the NVIDIA driver with nvidia-smi and that the NVIDIA container toolkit is installed, and that %s.gpu_devices names GPUs of this host", s)
+		},
+	},
+	{
+		code:    "kv_cache",
+		pattern: regexp.MustCompile(`larger than the maximum number of tokens that can be stored in KV cache|No available memory for the cache blocks|Not enough memory\. Please try to increase --mem-fraction-static`),
+		message: "Too little GPU memory is left for the KV cache",
+		hint: func(backend, s string) string {
+			if backend == config.BackendVLLM {
+				return fmt.Sprintf("Lower %[1]s.max_model_len, raise %[1]s.gpu_memory_utilization, or spread the model over more GPUs with %[1]s.tp_size", s)
+			}
+			return fmt.Sprintf("Raise %[1]s.mem_fraction_static, lower %[1]s.context_length, or spread the model over more GPUs with %[1]s.tp_size", s)
+		},
+	},
+	{
+		code:    "gpu_oom",
+		pattern: regexp.MustCompile(`CUDA out of memory|OutOfMemoryError|CUDA error: out of memory|cudaErrorMemoryAllocation|cudaMalloc failed: out of memory|failed to allocate CUDA\d* buffer`),
+		message: "The GPUs ran out of memory",
+		hint: func(backend, s string) string {
+			switch backend {
+			case config.BackendVLLM:
+				return fmt.Sprintf("Lower %[1]s.gpu_memory_utilization, %[1]s.max_model_len or %[1]s.max_num_seqs, or spread the model over more GPUs with %[1]s.tp_size", s)
+			case config.BackendLlamaCpp:
+				return fmt.Sprintf("Offload fewer layers with %[1]s.gpu_layers or lower %[1]s.context_size", s)
+			case config.BackendOllama:
+				return fmt.Sprintf("Use a smaller model or quantization in %[1]s.model, or lower %[1]s.context_length", s)
+			}
+			return fmt.Sprintf("Lower %[1]s.mem_fraction_static, %[1]s.context_length or %[1]s.max_running_requests, or spread the model over more GPUs with %[1]s.tp_size", s)
+		},
+	},
+	{
+		code:    "nccl",
+		pattern: regexp.MustCompile(`NCCL error|ncclInternalError|ncclSystemError|ncclUnhandledCudaError|ncclRemoteError`),
+		message: "NCCL failed to communicate between the GPUs",
+		hint: func(backend, s string) string {
+			return fmt.Sprintf("Check that %[1]s.gpu_devices lists distinct GPUs and %[1]s.shm_size is large enough; on hosts without GPU peer-to-peer support set NCCL_P2P_DISABLE: \"1\" in %[1]s.extra_env", s)
+		},
+	},
+	{
+		code:    "model_missing",
+		pattern: regexp.MustCompile(`No such file or directory: '?/(workspace/model|models)|does not appear to have a file named|is not a valid model identifier|Can't load the configuration of|Incorrect path_or_model_id|failed to open GGUF file|invalid magic characters|pull model manifest: file does not exist`),
+		message: "The model files were not found or could not be read",
+		hint: func(backend, s string) string {
+			switch backend {
+			case config.BackendLlamaCpp:
+				return fmt.Sprintf("Check that %[1]s.model_file names a .gguf file in %[1]s.model_path; 'silo model list' shows the pulled models and 'silo model verify' checks them", s)
+			case config.BackendOllama:
+				return fmt.Sprintf("Check that %s.model names a model of the Ollama library, e.g. qwen2.5:7b", s)
+			}
+			return fmt.Sprintf("Check that %s.model_path is the directory with the model's config.json and weights; 'silo model list' shows the pulled models and 'silo model verify' checks them", s)
+		},
+	},
+	{
+		code:    "unsupported_dtype",
+		pattern: regexp.MustCompile(`(?i)bfloat16 is only supported on GPUs with compute capability|bfloat16 is not supported|unsupported dtype|not supported for dtype|fp8e4nv not supported|fp8 .*is not supported`),
+		message: "The GPUs do not support the model's data type",
+		hint: func(backend, s string) string {
+			if backend == config.BackendVLLM {
+				return fmt.Sprintf("Set %[1]s.dtype to half on GPUs without bfloat16 and %[1]s.kv_cache_dtype to auto on GPUs without FP8", s)
+			}
+			return fmt.Sprintf("Set %[1]s.kv_cache_dtype to auto on GPUs without FP8, and add --dtype float16 to %[1]s.extra_args on GPUs without bfloat16", s)
+		},
+	},
+}
+
+// Tracker follows the output of an engine line by line and tracks its
+// startup phase and failures
+type Tracker struct {
+	backend  string
+	section  string
+	progress Progress
+	seen     map[string]bool
+}
+
+// NewTracker returns a tracker for an engine of backend configured in the
+// config section, e.g. sglang or models[1], which hints refer to
+func NewTracker(backend, section string) *Tracker {
+	return &Tracker{
+		backend:  backend,
+		section:  section,
+		progress: Progress{Phase: PhaseStarting},
+		seen:     make(map[string]bool),
+	}
+}
+
+// Line processes one line of output and reports whether the progress
+// changed
+func (t *Tracker) Line(line string) bool {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return false
+	}
+
+	for _, rule := range failureRules {
+		if t.seen[rule.code] || !rule.pattern.MatchString(line) {
+			continue
+		}
+		t.seen[rule.code] = true
+		if len(line) > maxDiagnosticLine {
+			line = line[:maxDiagnosticLine] + "..."
+		}
+		t.progress.Phase = PhaseFailed
+		t.progress.Percent = 0
+		t.progress.Diagnostics = append(t.progress.Diagnostics, Diagnostic{
+			Code:    rule.code,
+			Message: rule.message,
+			Hint:    rule.hint(t.backend, t.section),
+			Line:    line,
+		})
+		return true
+	}
+	if t.progress.Phase == PhaseFailed {
+		return false
+	}
+
+	for _, rule := range phaseRules {
+		if !rule.pattern.MatchString(line) || phaseOrder[rule.phase] < phaseOrder[t.progress.Phase] {
+			continue
+		}
+		percent := 0
+		if m := percentPattern.FindStringSubmatch(line); rule.percent && m != nil {
+			percent, _ = strconv.Atoi(m[1])
+		}
+		if rule.phase == t.progress.Phase && percent == t.progress.Percent {
+			return false
+		}
+		t.progress.Phase = rule.phase
+		t.progress.Percent = percent
+		return true
+	}
+	return false
+}
+
+// Write processes output, which may hold several lines; progress bars
+// redraw with carriage returns, which end a line as well
+func (t *Tracker) Write(output string) {
+	for _, line := range strings.FieldsFunc(output, func(r rune) bool { return r == '\n' || r == '\r' }) {
+		t.Line(line)
+	}
+}
+
+// Progress returns the tracked progress
+func (t *Tracker) Progress() Progress {
+	p := t.progress
+	p.Diagnostics = append([]Diagnostic(nil), p.Diagnostics...)
+	return p
+}
+
+// ParseLogs returns the startup progress recorded in the logs of an engine
+// of backend, configured in the config section
+func ParseLogs(backend, section, logs string) Progress {
+	t := NewTracker(backend, section)
+	t.Write(logs)
+	return t.Progress()
+}
+
+// Progress reads the startup progress and known failures of the engine
+// from its recent logs
+func (e *Engine) Progress(ctx context.Context) (*Progress, error) {
+	logs, err := e.container.logsBuffer(ctx, progressLogLines)
+	if err != nil {
+		return nil, err
+	}
+	p := ParseLogs(e.container.backend, e.container.section, logs)
+	return &p, nil
+}
+
+// SetProgress sets a handler that receives the startup progress while the
+// engine starts and while WaitForHealthy waits for it
+func (e *Engine) SetProgress(fn func(Progress)) {
+	e.container.onProgress = fn
+}
@@ -0,0 +1,114 @@
+package inference
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eternisai/silo/internal/config"
+)
+
+func TestParseLogs_Fixtures(t *testing.T) {
+	tests := []struct {
+		file    string
+		backend string
+		phase   string
+		percent int
+		codes   []string
+		hint    string
+	}{
+		{"sglang_ready.log", config.BackendSGLang, PhaseReady, 0, nil, ""},
+		{"sglang_loading.log", config.BackendSGLang, PhaseLoading, 42, nil, ""},
+		{"sglang_oom.log", config.BackendSGLang, PhaseFailed, 0, []string{"gpu_oom"}, "sglang.mem_fraction_static"},
+		{"sglang_nccl.log", config.BackendSGLang, PhaseFailed, 0, []string{"nccl"}, "sglang.shm_size"},
+		{"sglang_dtype.log", config.BackendSGLang, PhaseFailed, 0, []string{"unsupported_dtype"}, "--dtype float16"},
+		{"vllm_ready.log", config.BackendVLLM, PhaseReady, 0, nil, ""},
+		{"vllm_kv_cache.log", config.BackendVLLM, PhaseFailed, 0, []string{"kv_cache"}, "vllm.max_model_len"},
+		{"vllm_missing_model.log", config.BackendVLLM, PhaseFailed, 0, []string{"model_missing"}, "vllm.model_path"},
+		{"llamacpp_ready.log", config.BackendLlamaCpp, PhaseReady, 0, nil, ""},
+		{"llamacpp_missing_model.log", config.BackendLlamaCpp, PhaseFailed, 0, []string{"model_missing"}, "llamacpp.model_file"},
+		{"llamacpp_oom.log", config.BackendLlamaCpp, PhaseFailed, 0, []string{"gpu_oom"}, "llamacpp.gpu_layers"},
+		{"ollama_pull.log", config.BackendOllama, PhaseDownloading, 64, nil, ""},
+		{"gpu_unavailable.log", config.BackendVLLM, PhaseFailed, 0, []string{"gpu_unavailable"}, "vllm.gpu_devices"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := ParseLogs(tt.backend, tt.backend, string(data))
+			if p.Phase != tt.phase || p.Percent != tt.percent {
+				t.Errorf("Expected %s at %d%%, got %s at %d%%", tt.phase, tt.percent, p.Phase, p.Percent)
+			}
+
+			var codes []string
+			for _, d := range p.Diagnostics {
+				codes = append(codes, d.Code)
+				if d.Message == "" || d.Line == "" {
+					t.Errorf("Expected a message and the log line, got %+v", d)
+				}
+			}
+			if strings.Join(codes, ",") != strings.Join(tt.codes, ",") {
+				t.Errorf("Expected diagnostics %v, got %v", tt.codes, codes)
+			}
+			if tt.hint != "" && !strings.Contains(p.Diagnostics[0].Hint, tt.hint) {
+				t.Errorf("Expected the hint to mention %s, got %q", tt.hint, p.Diagnostics[0].Hint)
+			}
+		})
+	}
+}
+
+func TestTracker_Phases(t *testing.T) {
+	tracker := NewTracker(config.BackendSGLang, "models[1]")
+	if p := tracker.Progress(); p.Phase != PhaseStarting || p.String() != "Starting" {
+		t.Errorf("Expected a new tracker to be starting, got %+v", p)
+	}
+
+	if !tracker.Line("Loading safetensors checkpoint shards:  25% Completed | 3/12") {
+		t.Error("Expected loading to change the progress")
+	}
+	if got := tracker.Progress().String(); got != "Loading weights (25%)" {
+		t.Errorf("Unexpected progress %q", got)
+	}
+	if tracker.Line("Loading safetensors checkpoint shards:  25% Completed | 3/12") {
+		t.Error("Expected the same line not to change the progress")
+	}
+
+	// A later phase is not undone by a line of an earlier one, e.g. from
+	// another tensor parallel rank
+	tracker.Line("Capture cuda graph begin. This can take up to several minutes.")
+	tracker.Line("[TP1] Load weight begin. avail mem=78.68 GB")
+	if p := tracker.Progress(); p.Phase != PhaseCapturing {
+		t.Errorf("Expected the phase to stay at capturing, got %s", p.Phase)
+	}
+
+	tracker.Line("torch.OutOfMemoryError: CUDA out of memory. Tried to allocate 1.17 GiB.")
+	tracker.Line("The server is fired up and ready to roll!")
+	p := tracker.Progress()
+	if p.Phase != PhaseFailed || len(p.Diagnostics) != 1 {
+		t.Fatalf("Expected a failure that later lines do not clear, got %+v", p)
+	}
+	if !strings.Contains(p.Diagnostics[0].Hint, "models[1].mem_fraction_static") {
+		t.Errorf("Expected the hint to name the model's section, got %q", p.Diagnostics[0].Hint)
+	}
+}
+
+func TestProgressWriter(t *testing.T) {
+	var reported []string
+	w := &progressWriter{
+		tracker:  NewTracker(config.BackendOllama, config.BackendOllama),
+		reporter: &progressReporter{report: func(p Progress) { reported = append(reported, p.String()) }},
+	}
+
+	// Lines arrive split across writes, progress bars redraw with \r
+	for _, chunk := range []string{"Unable to find image 'ollama/ollama:latest' lo", "cally\n", "pulling 2bada8a74506:   1%\rpulling 2bada8a74506:   5%\r", "pulling 2bada8a74506:  12%\rpulling 2bada8a74506: 100%\n"} {
+		w.Write([]byte(chunk))
+	}
+
+	want := []string{"Pulling image", "Downloading weights (1%)", "Downloading weights (12%)", "Downloading weights (100%)"}
+	if strings.Join(reported, "|") != strings.Join(want, "|") {
+		t.Errorf("Expected reports %v, got %v", want, reported)
+	}
+}
@@ -0,0 +1,108 @@
+package inference
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/eternisai/silo/internal/config"
+	"github.com/eternisai/silo/internal/gpu"
+)
+
+// GPUDevices returns the gpu_devices the engine runs on, none when it runs
+// on the CPU
+func (e *Engine) GPUDevices() []string {
+	if !e.container.gpus {
+		return nil
+	}
+	return e.container.settings.GPUDevices
+}
+
+// CheckGPUs checks the gpu_devices of the engine against the GPUs of the
+// host: every device must exist and have the share of its memory free that
+// the engine claims at start. A running engine holds that memory itself, so
+// check engines before they start. Engines without GPUs have no problems.
+func (e *Engine) CheckGPUs(inv *gpu.Inventory) []config.Problem {
+	c := e.container
+	devices := e.GPUDevices()
+	if len(devices) == 0 {
+		return nil
+	}
+
+	path := c.section + ".gpu_devices"
+	var problems []config.Problem
+	add := func(severity config.Severity, format string, args ...any) {
+		problems = append(problems, config.Problem{Path: path, Message: fmt.Sprintf(format, args...), Severity: severity})
+	}
+
+	if !inv.Available() {
+		add(config.SeverityWarning, "cannot check GPUs %s, %s", strings.Join(devices, ", "), inv.Error)
+		return problems
+	}
+	if inv.DockerError == "" && !inv.ContainerToolkit {
+		add(config.SeverityWarning, "the NVIDIA container toolkit is not installed, docker cannot pass GPUs to containers")
+	}
+
+	seen := make(map[int]bool)
+	for _, device := range devices {
+		g := inv.Lookup(device)
+		if g == nil {
+			add(config.SeverityError, "GPU %s does not exist, the host has %s", device, hostGPUs(inv))
+			continue
+		}
+		if seen[g.Index] {
+			add(config.SeverityError, "GPU %s is listed more than once", device)
+			continue
+		}
+		seen[g.Index] = true
+
+		if c.memoryFraction <= 0 || g.MemoryTotal == 0 {
+			continue
+		}
+		need := int64(c.memoryFraction * float64(g.MemoryTotal))
+		if g.MemoryFree < need {
+			add(config.SeverityError, "GPU %s (%s) has %s of %s free, but %s.%s %g claims %s; stop what uses the GPU, lower %[5]s.%[6]s, or list another GPU",
+				device, g.Name, gib(g.MemoryFree), gib(g.MemoryTotal), c.section, c.memoryKey, c.memoryFraction, gib(need))
+		}
+	}
+	return problems
+}
+
+// PreflightGPUs checks the GPUs of the engines that are not running yet
+// against the inventory of the host, taken once for all of them
+func PreflightGPUs(ctx context.Context, engines []*Engine) []config.Problem {
+	var stopped []*Engine
+	for _, engine := range engines {
+		if running, _ := engine.IsRunning(ctx); !running && len(engine.GPUDevices()) > 0 {
+			stopped = append(stopped, engine)
+		}
+	}
+	if len(stopped) == 0 {
+		return nil
+	}
+
+	inv := gpu.Detect(ctx)
+	var problems []config.Problem
+	for _, engine := range stopped {
+		problems = append(problems, engine.CheckGPUs(inv)...)
+	}
+	return problems
+}
+
+// hostGPUs describes the GPUs of the host by index
+func hostGPUs(inv *gpu.Inventory) string {
+	if len(inv.GPUs) == 0 {
+		return "no NVIDIA GPUs"
+	}
+	indexes := make([]string, len(inv.GPUs))
+	for i, g := range inv.GPUs {
+		indexes[i] = strconv.Itoa(g.Index)
+	}
+	return "GPUs " + strings.Join(indexes, ", ")
+}
+
+// gib formats bytes in GiB, as the GPU memory sizes are
+func gib(n int64) string {
+	return fmt.Sprintf("%.1f GiB", float64(n)/(1<<30))
+}
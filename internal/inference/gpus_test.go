@@ -0,0 +1,127 @@
+package inference
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/eternisai/silo/internal/config"
+	"github.com/eternisai/silo/internal/gpu"
+)
+
+// h100s returns an inventory of two 80 GiB GPUs, GPU 1 mostly in use
+func h100s() *gpu.Inventory {
+	const total = 80 << 30
+	return &gpu.Inventory{
+		GPUs: []gpu.GPU{
+			{Index: 0, UUID: "GPU-aaaa", Name: "NVIDIA H100", MemoryTotal: total, MemoryFree: total - 1<<30},
+			{Index: 1, UUID: "GPU-bbbb", Name: "NVIDIA H100", MemoryTotal: total, MemoryFree: 10 << 30},
+		},
+		ContainerToolkit: true,
+	}
+}
+
+func TestCheckGPUs(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*config.Config)
+		inv    func(*gpu.Inventory)
+		want   []string
+	}{
+		{
+			name:   "fits",
+			modify: func(c *config.Config) { c.SGLang.GPUDevices = []string{"0"} },
+		},
+		{
+			name:   "missing and busy",
+			modify: func(c *config.Config) { c.SGLang.GPUDevices = []string{"0", "1", "2"} },
+			want: []string{
+				"sglang.gpu_devices: GPU 1 (NVIDIA H100) has 10.0 GiB of 80.0 GiB free, but sglang.mem_fraction_static 0.88 claims 70.4 GiB",
+				"sglang.gpu_devices: GPU 2 does not exist, the host has GPUs 0, 1",
+			},
+		},
+		{
+			name:   "listed twice",
+			modify: func(c *config.Config) { c.SGLang.GPUDevices = []string{"0", "GPU-aaaa"} },
+			want:   []string{"GPU GPU-aaaa is listed more than once"},
+		},
+		{
+			name: "vllm utilization",
+			modify: func(c *config.Config) {
+				c.Inference.Backend = config.BackendVLLM
+				c.VLLM.GPUDevices = []string{"GPU-bbbb"}
+				c.VLLM.GPUMemoryUtilization = 0.1
+			},
+		},
+		{
+			name: "vllm busy",
+			modify: func(c *config.Config) {
+				c.Inference.Backend = config.BackendVLLM
+				c.VLLM.GPUDevices = []string{"1"}
+			},
+			want: []string{"vllm.gpu_memory_utilization 0.9 claims 72.0 GiB"},
+		},
+		{
+			name: "llamacpp on the cpu",
+			modify: func(c *config.Config) {
+				c.Inference.Backend = config.BackendLlamaCpp
+				c.LlamaCpp.GPUDevices = []string{"7"}
+			},
+		},
+		{
+			name: "ollama only needs the GPUs to exist",
+			modify: func(c *config.Config) {
+				c.Inference.Backend = config.BackendOllama
+				c.Ollama.GPUDevices = []string{"1", "3"}
+			},
+			want: []string{"ollama.gpu_devices: GPU 3 does not exist"},
+		},
+		{
+			name:   "no toolkit",
+			modify: func(c *config.Config) { c.SGLang.GPUDevices = []string{"0"} },
+			inv:    func(inv *gpu.Inventory) { inv.ContainerToolkit = false },
+			want:   []string{"NVIDIA container toolkit is not installed"},
+		},
+		{
+			name:   "no nvidia-smi",
+			modify: func(c *config.Config) { c.SGLang.GPUDevices = []string{"0", "9"} },
+			inv:    func(inv *gpu.Inventory) { *inv = gpu.Inventory{Error: "nvidia-smi: not found"} },
+			want:   []string{"cannot check GPUs 0, 9, nvidia-smi: not found"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.NewDefaultConfig(config.NewPaths(t.TempDir(), t.TempDir()))
+			tt.modify(cfg)
+			inv := h100s()
+			if tt.inv != nil {
+				tt.inv(inv)
+			}
+
+			problems := New(cfg, nil).CheckGPUs(inv)
+			if len(problems) != len(tt.want) {
+				t.Fatalf("Expected %d problems, got %v", len(tt.want), problems)
+			}
+			for i, want := range tt.want {
+				if got := problems[i].String(); !strings.Contains(got, want) {
+					t.Errorf("Problem %d = %q, want it to contain %q", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckGPUs_Models(t *testing.T) {
+	cfg := config.NewDefaultConfig(config.NewPaths(t.TempDir(), t.TempDir()))
+	cfg.Models = []config.ModelConfig{
+		{Name: "chat", SGLangConfig: config.SGLangConfig{ContainerSettings: config.ContainerSettings{GPUDevices: []string{"0"}}}},
+		{Name: "fast", SGLangConfig: config.SGLangConfig{ContainerSettings: config.ContainerSettings{GPUDevices: []string{"1"}}, MemFractionStatic: 0.1}},
+	}
+	cfg.DefaultModel = "chat"
+
+	for _, engine := range Engines(cfg, nil) {
+		if problems := engine.CheckGPUs(h100s()); len(problems) != 0 {
+			t.Errorf("Expected %s to fit, got %v", engine.Model(), problems)
+		}
+	}
+}
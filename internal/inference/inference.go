@@ -1,12 +1,8 @@
 package inference
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"os"
-	"os/exec"
 	"strings"
 
 	"github.com/eternisai/silo/internal/config"
@@ -16,12 +12,52 @@ import (
 const (
 	DefaultContainerName = "glm_model"
 	DefaultImage         = "lmsysorg/sglang:latest"
+	DefaultRestartPolicy = "unless-stopped"
+
+	// ContainerPort is the port SGLang listens on inside the container
+	ContainerPort = 30000
+
+	// containerModelPath is where SGLang and vLLM mount the model directory
+	containerModelPath = "/workspace/model"
 )
 
-// Engine manages the inference engine container
+// defaultUlimits are the ulimits of the inference container unless the
+// config sets them
+var defaultUlimits = map[string]string{
+	"memlock": "-1:-1",
+	"nofile":  "1048576:1048576",
+}
+
+// Backend runs the inference server of one inference backend, see
+// config.Backends
+type Backend interface {
+	// Up starts the inference container
+	Up(ctx context.Context) error
+
+	// Down stops and removes the inference container
+	Down(ctx context.Context) error
+
+	// Status returns the state of the inference container
+	Status(ctx context.Context) (*ContainerInfo, error)
+
+	// Logs streams the container logs to stdout
+	Logs(ctx context.Context, follow bool, lines int) error
+
+	// HealthCheck calls the health endpoint of the server
+	HealthCheck(ctx context.Context) error
+
+	// RunArgs returns the docker run arguments that start the container
+	RunArgs() []string
+}
+
+// Engine manages the inference container of one model, run by the backend
+// selected in the config
 type Engine struct {
-	cfg    *config.Config
-	logger *logger.Logger
+	Backend
+
+	model     string
+	enabled   bool
+	container *container
 }
 
 // ContainerInfo holds information about the inference container
@@ -33,296 +69,135 @@ type ContainerInfo struct {
 	Running bool
 }
 
-// New creates a new inference engine manager
+// New creates a manager for the inference engine of the default model, or
+// of the first model when none serves it
 func New(cfg *config.Config, log *logger.Logger) *Engine {
-	return &Engine{
-		cfg:    cfg,
-		logger: log,
-	}
-}
-
-// Up starts the inference engine container
-func (e *Engine) Up(ctx context.Context) error {
-	// Check if already running
-	running, err := e.IsRunning(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to check container status: %w", err)
-	}
-	if running {
-		e.logger.Info("Inference engine is already running")
-		return nil
-	}
-
-	// Remove existing stopped container if present
-	if exists, _ := e.containerExists(ctx); exists {
-		e.logger.Info("Removing existing stopped container...")
-		if err := e.removeContainer(ctx); err != nil {
-			return fmt.Errorf("failed to remove existing container: %w", err)
+	engines := Engines(cfg, log)
+	for _, e := range engines {
+		if e.model == cfg.DefaultModel {
+			return e
 		}
 	}
-
-	e.logger.Info("Starting inference engine...")
-
-	args := e.buildDockerRunArgs()
-	cmd := exec.CommandContext(ctx, "docker", args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to start inference engine: %w", err)
-	}
-
-	e.logger.Success("Inference engine started")
-	return nil
+	return engines[0]
 }
 
-// Down stops the inference engine container
-func (e *Engine) Down(ctx context.Context) error {
-	running, err := e.IsRunning(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to check container status: %w", err)
-	}
-	if !running {
-		e.logger.Info("Inference engine is not running")
-		return nil
+// Engines returns a manager for the inference engine of every model: one
+// per model of the SGLang models list, or the single engine of the other
+// backends
+func Engines(cfg *config.Config, log *logger.Logger) []*Engine {
+	switch cfg.Inference.Backend {
+	case config.BackendVLLM:
+		return []*Engine{newVLLM(cfg, log)}
+	case config.BackendLlamaCpp:
+		return []*Engine{newLlamaCpp(cfg, log)}
+	case config.BackendOllama:
+		return []*Engine{newOllama(cfg, log)}
 	}
 
-	e.logger.Info("Stopping inference engine...")
-
-	containerName := e.getContainerName()
-
-	// Stop container
-	stopCmd := exec.CommandContext(ctx, "docker", "stop", containerName)
-	if err := stopCmd.Run(); err != nil {
-		return fmt.Errorf("failed to stop container: %w", err)
+	var engines []*Engine
+	containers := cfg.InferenceContainers()
+	for i, m := range cfg.InferenceModels() {
+		e := NewModel(cfg, m, log)
+		e.container.section = containers[i].Path
+		engines = append(engines, e)
 	}
-
-	// Remove container
-	rmCmd := exec.CommandContext(ctx, "docker", "rm", containerName)
-	if err := rmCmd.Run(); err != nil {
-		return fmt.Errorf("failed to remove container: %w", err)
-	}
-
-	e.logger.Success("Inference engine stopped")
-	return nil
+	return engines
 }
 
-// Status returns the current status of the inference engine
-func (e *Engine) Status(ctx context.Context) (*ContainerInfo, error) {
-	containerName := e.getContainerName()
-
-	cmd := exec.CommandContext(ctx, "docker", "inspect",
-		"--format", "{{.State.Status}}|{{.State.Running}}|{{.Config.Image}}",
-		containerName)
-
-	output, err := cmd.Output()
-	if err != nil {
-		// Container doesn't exist
-		return &ContainerInfo{
-			Name:    containerName,
-			State:   "not found",
-			Running: false,
-		}, nil
+// DefaultEngines returns the engines started when no model is named: the
+// enabled models of the models list, or the engine of the selected backend
+func DefaultEngines(cfg *config.Config, log *logger.Logger) []*Engine {
+	if len(cfg.Models) == 0 {
+		return []*Engine{New(cfg, log)}
 	}
-
-	parts := strings.Split(strings.TrimSpace(string(output)), "|")
-	if len(parts) != 3 {
-		return nil, fmt.Errorf("unexpected docker inspect output")
+	var engines []*Engine
+	for _, e := range Engines(cfg, log) {
+		if e.Enabled() {
+			engines = append(engines, e)
+		}
 	}
-
-	return &ContainerInfo{
-		Name:    containerName,
-		State:   parts[0],
-		Status:  parts[0],
-		Image:   parts[2],
-		Running: parts[1] == "true",
-	}, nil
+	return engines
 }
 
-// Logs streams logs from the inference engine container
-func (e *Engine) Logs(ctx context.Context, follow bool, lines int) error {
-	containerName := e.getContainerName()
-
-	args := []string{"logs"}
-	if follow {
-		args = append(args, "-f")
-	}
-	if lines > 0 {
-		args = append(args, "--tail", fmt.Sprintf("%d", lines))
-	}
-	args = append(args, containerName)
-
-	cmd := exec.CommandContext(ctx, "docker", args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	if err := cmd.Run(); err != nil {
-		if ctx.Err() == context.Canceled {
-			return nil
+// ForModel returns the manager for the inference engine of the named model
+func ForModel(cfg *config.Config, name string, log *logger.Logger) (*Engine, error) {
+	var names []string
+	for _, e := range Engines(cfg, log) {
+		if e.model == name {
+			return e, nil
 		}
-		return fmt.Errorf("failed to fetch logs: %w", err)
+		names = append(names, e.model)
 	}
-	return nil
+	return nil, fmt.Errorf("unknown model %q, configured models are %s", name, strings.Join(names, ", "))
 }
 
-// IsRunning checks if the inference engine container is running
-func (e *Engine) IsRunning(ctx context.Context) (bool, error) {
-	info, err := e.Status(ctx)
-	if err != nil {
-		return false, err
+// newEngine returns the engine running backend in c, serving model
+func newEngine(backend Backend, c *container, model string) *Engine {
+	return &Engine{
+		Backend:   backend,
+		model:     model,
+		enabled:   c.settings.Enabled,
+		container: c,
 	}
-	return info.Running, nil
 }
 
-// containerExists checks if the container exists (running or stopped)
-func (e *Engine) containerExists(ctx context.Context) (bool, error) {
-	containerName := e.getContainerName()
-	cmd := exec.CommandContext(ctx, "docker", "inspect", containerName)
-	err := cmd.Run()
-	return err == nil, nil
+// Model returns the name of the model the engine serves
+func (e *Engine) Model() string {
+	return e.model
 }
 
-// removeContainer removes the container
-func (e *Engine) removeContainer(ctx context.Context) error {
-	containerName := e.getContainerName()
-	cmd := exec.CommandContext(ctx, "docker", "rm", "-f", containerName)
-	return cmd.Run()
+// Enabled reports whether the engine is enabled in the config
+func (e *Engine) Enabled() bool {
+	return e.enabled
 }
 
-// getContainerName returns the container name from config or default
-func (e *Engine) getContainerName() string {
-	if e.cfg.SGLang.ContainerName != "" {
-		return e.cfg.SGLang.ContainerName
-	}
-	return DefaultContainerName
+// ContainerName returns the container name from config or default
+func (e *Engine) ContainerName() string {
+	return e.container.name
 }
 
-// buildDockerRunArgs builds the docker run command arguments
-func (e *Engine) buildDockerRunArgs() []string {
-	return []string{
-		"run", "-d",
-		"--name", e.getContainerName(),
-		"--restart", "unless-stopped",
-		"--gpus", `"device=0,1,2"`,
-		"--shm-size", "64g",
-		"--ipc=host",
-		"--ulimit", "memlock=-1:-1",
-		"--ulimit", "nofile=1048576:1048576",
-		"-p", "30000:30000",
-		"-e", "CUDA_VISIBLE_DEVICES=0,1,2",
-		"-e", "PYTORCH_ALLOC_CONF=expandable_segments:True",
-		"-v", "/root/data/AWQ:/workspace/model",
-		"-v", os.ExpandEnv("$HOME/.cache/huggingface") + ":/root/.cache/huggingface",
-		"lmsysorg/sglang:latest",
-		"python3", "-m", "sglang.launch_server",
-		"--model-path", "/workspace/model",
-		"--host", "0.0.0.0",
-		"--port", "30000",
-		"--dp-size", "3",
-		"--tp-size", "1",
-		"--max-running-requests", "32",
-		"--max-total-tokens", "262144",
-		"--context-length", "131072",
-		"--mem-fraction-static", "0.88",
-		"--chunked-prefill-size", "-1",
-		"--schedule-policy", "fcfs",
-		"--kv-cache-dtype", "fp8_e4m3",
-		"--attention-backend", "flashinfer",
-		"--disable-radix-cache",
-		"--reasoning-parser", "glm45",
-		"--tool-call-parser", "glm",
-		"--trust-remote-code",
-		"--log-level", "info",
-	}
+// ImageRef returns the inference engine image reference from config or the
+// backend default
+func (e *Engine) ImageRef() string {
+	return e.container.imageRef
 }
 
-// GetDockerRunCommand returns the full docker run command for debugging
-func (e *Engine) GetDockerRunCommand() string {
-	args := e.buildDockerRunArgs()
-	return "docker " + strings.Join(args, " ")
+// Image returns the image to run, pinned to its recorded digest if any
+func (e *Engine) Image() string {
+	return e.container.image
 }
 
-// HealthCheck checks if the inference engine is healthy by calling its API
-func (e *Engine) HealthCheck(ctx context.Context) error {
-	port := e.cfg.SGLang.Port
-	if port == 0 {
-		port = 30000
-	}
-
-	cmd := exec.CommandContext(ctx, "curl", "-sf",
-		fmt.Sprintf("http://localhost:%d/health", port))
-
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("health check failed: %w", err)
-	}
-	return nil
+// IsRunning checks if the inference engine container is running
+func (e *Engine) IsRunning(ctx context.Context) (bool, error) {
+	return e.container.IsRunning(ctx)
 }
 
 // InspectRaw returns raw docker inspect output as JSON
 func (e *Engine) InspectRaw(ctx context.Context) (map[string]interface{}, error) {
-	containerName := e.getContainerName()
-
-	cmd := exec.CommandContext(ctx, "docker", "inspect", containerName)
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("container not found: %w", err)
-	}
-
-	var result []map[string]interface{}
-	if err := json.Unmarshal(output, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse inspect output: %w", err)
-	}
-
-	if len(result) == 0 {
-		return nil, fmt.Errorf("no container data returned")
-	}
-
-	return result[0], nil
-}
-
-// WaitForHealthy waits for the inference engine to become healthy
-func (e *Engine) WaitForHealthy(ctx context.Context) error {
-	e.logger.Info("Waiting for inference engine to become healthy...")
-
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			if err := e.HealthCheck(ctx); err == nil {
-				e.logger.Success("Inference engine is healthy")
-				return nil
-			}
-			// Wait before retrying (context-aware sleep)
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case <-ctx.Done():
-			}
-		}
-	}
+	return e.container.inspectRaw(ctx)
 }
 
 // LogsBuffer returns logs as a string buffer (for API responses)
 func (e *Engine) LogsBuffer(ctx context.Context, lines int) (string, error) {
-	containerName := e.getContainerName()
+	return e.container.logsBuffer(ctx, lines)
+}
 
-	args := []string{"logs"}
-	if lines > 0 {
-		args = append(args, "--tail", fmt.Sprintf("%d", lines))
+// GetDockerRunCommand returns the docker run command as it would be typed in
+// a shell, for display and comparison
+func (e *Engine) GetDockerRunCommand() string {
+	args := e.RunArgs()
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
 	}
-	args = append(args, containerName)
-
-	cmd := exec.CommandContext(ctx, "docker", args...)
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	return "docker " + strings.Join(quoted, " ")
+}
 
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("failed to fetch logs: %w", err)
+// shellQuote single-quotes arg when a shell would otherwise split or
+// expand it
+func shellQuote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\n'\"\\$`*?[]{}()<>|&;#~!") {
+		return arg
 	}
-
-	// Docker logs go to stderr for container stderr
-	combined := stdout.String() + stderr.String()
-	return combined, nil
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
 }
@@ -10,19 +10,90 @@ import (
 )
 
 func TestBuildDockerRunArgs(t *testing.T) {
-	cfg := &config.Config{}
-	log := logger.New(false)
-	engine := New(cfg, log)
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MODEL_ROOT", "/models")
+
+	defaultLaunch := "python3 -m sglang.launch_server --model-path /workspace/model --host 0.0.0.0 --port 30000 --dp-size 3 --tp-size 1 --max-running-requests 32 --max-total-tokens 262144 --context-length 131072 --mem-fraction-static 0.88 --chunked-prefill-size 8192 --schedule-policy fcfs --kv-cache-dtype fp8_e4m3 --attention-backend flashinfer --disable-radix-cache --reasoning-parser glm45 --tool-call-parser glm --trust-remote-code --log-level info"
+
+	tests := []struct {
+		name   string
+		modify func(*config.SGLangConfig)
+		want   string
+	}{
+		{
+			name:   "defaults",
+			modify: func(*config.SGLangConfig) {},
+			want:   `docker run -d --name silo-inference --restart unless-stopped --gpus "device=0,1,2" --shm-size 64g --ipc=host --ulimit memlock=-1:-1 --ulimit nofile=1048576:1048576 -p 30000:30000 -e CUDA_VISIBLE_DEVICES=0,1,2 -e PYTORCH_ALLOC_CONF=expandable_segments:True -v /root/data/AWQ:/workspace/model -v ` + home + `/.cache/huggingface:/root/.cache/huggingface lmsysorg/sglang:latest ` + defaultLaunch,
+		},
+		{
+			name: "custom devices, paths and port",
+			modify: func(sg *config.SGLangConfig) {
+				sg.GPUDevices = []string{"4", "5"}
+				sg.DPSize = 1
+				sg.TPSize = 2
+				sg.Port = 31000
+				sg.ShmSize = "32g"
+				sg.ModelPath = "$MODEL_ROOT/glm"
+				sg.HuggingFaceCache = "/srv/hf"
+				sg.ContainerName = "glm_tp2"
+			},
+			want: `docker run -d --name glm_tp2 --restart unless-stopped --gpus "device=4,5" --shm-size 32g --ipc=host --ulimit memlock=-1:-1 --ulimit nofile=1048576:1048576 -p 31000:30000 -e CUDA_VISIBLE_DEVICES=0,1 -e PYTORCH_ALLOC_CONF=expandable_segments:True -v /models/glm:/workspace/model -v /srv/hf:/root/.cache/huggingface lmsysorg/sglang:latest python3 -m sglang.launch_server --model-path /workspace/model --host 0.0.0.0 --port 30000 --dp-size 1 --tp-size 2 --max-running-requests 32 --max-total-tokens 262144 --context-length 131072 --mem-fraction-static 0.88 --chunked-prefill-size 8192 --schedule-policy fcfs --kv-cache-dtype fp8_e4m3 --attention-backend flashinfer --disable-radix-cache --reasoning-parser glm45 --tool-call-parser glm --trust-remote-code --log-level info`,
+		},
+		{
+			name: "tuning flags",
+			modify: func(sg *config.SGLangConfig) {
+				sg.ChunkedPrefillSize = -1
+				sg.MemFractionStatic = 0.9
+				sg.SchedulePolicy = "lpm"
+				sg.AttentionBackend = "fa3"
+				sg.ToolCallParser = "qwen25"
+				sg.DisableRadixCache = false
+				sg.TrustRemoteCode = false
+			},
+			want: `docker run -d --name silo-inference --restart unless-stopped --gpus "device=0,1,2" --shm-size 64g --ipc=host --ulimit memlock=-1:-1 --ulimit nofile=1048576:1048576 -p 30000:30000 -e CUDA_VISIBLE_DEVICES=0,1,2 -e PYTORCH_ALLOC_CONF=expandable_segments:True -v /root/data/AWQ:/workspace/model -v ` + home + `/.cache/huggingface:/root/.cache/huggingface lmsysorg/sglang:latest python3 -m sglang.launch_server --model-path /workspace/model --host 0.0.0.0 --port 30000 --dp-size 3 --tp-size 1 --max-running-requests 32 --max-total-tokens 262144 --context-length 131072 --mem-fraction-static 0.9 --chunked-prefill-size -1 --schedule-policy lpm --kv-cache-dtype fp8_e4m3 --attention-backend fa3 --reasoning-parser glm45 --tool-call-parser qwen25 --log-level info`,
+		},
+		{
+			name: "extra args and env",
+			modify: func(sg *config.SGLangConfig) {
+				sg.ExtraArgs = []string{"--enable-metrics", "--served-model-name", "glm"}
+				sg.ExtraEnv = map[string]string{"NCCL_DEBUG": "INFO", "HF_HUB_OFFLINE": "1"}
+			},
+			want: `docker run -d --name silo-inference --restart unless-stopped --gpus "device=0,1,2" --shm-size 64g --ipc=host --ulimit memlock=-1:-1 --ulimit nofile=1048576:1048576 -p 30000:30000 -e CUDA_VISIBLE_DEVICES=0,1,2 -e PYTORCH_ALLOC_CONF=expandable_segments:True -e HF_HUB_OFFLINE=1 -e NCCL_DEBUG=INFO -v /root/data/AWQ:/workspace/model -v ` + home + `/.cache/huggingface:/root/.cache/huggingface lmsysorg/sglang:latest ` + defaultLaunch + ` --enable-metrics --served-model-name glm`,
+		},
+		{
+			name: "empty settings are omitted",
+			modify: func(sg *config.SGLangConfig) {
+				*sg = config.SGLangConfig{ModelPath: "/models/glm"}
+			},
+			want: `docker run -d --name glm_model --restart unless-stopped --ipc=host --ulimit memlock=-1:-1 --ulimit nofile=1048576:1048576 -p 30000:30000 -e PYTORCH_ALLOC_CONF=expandable_segments:True -v /models/glm:/workspace/model lmsysorg/sglang:latest python3 -m sglang.launch_server --model-path /workspace/model --host 0.0.0.0 --port 30000`,
+		},
+	}
 
-	args := engine.buildDockerRunArgs()
-	cmd := "docker " + strings.Join(args, " ")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{SGLang: config.DefaultSGLangConfig()}
+			tt.modify(&cfg.SGLang)
 
-	// Expected command (with $HOME expanded)
-	homeDir := os.Getenv("HOME")
-	expected := `docker run -d --name glm_model --restart unless-stopped --gpus "device=0,1,2" --shm-size 64g --ipc=host --ulimit memlock=-1:-1 --ulimit nofile=1048576:1048576 -p 30000:30000 -e CUDA_VISIBLE_DEVICES=0,1,2 -e PYTORCH_ALLOC_CONF=expandable_segments:True -v /root/data/AWQ:/workspace/model -v ` + homeDir + `/.cache/huggingface:/root/.cache/huggingface lmsysorg/sglang:latest python3 -m sglang.launch_server --model-path /workspace/model --host 0.0.0.0 --port 30000 --dp-size 3 --tp-size 1 --max-running-requests 32 --max-total-tokens 262144 --context-length 131072 --mem-fraction-static 0.88 --chunked-prefill-size -1 --schedule-policy fcfs --kv-cache-dtype fp8_e4m3 --attention-backend flashinfer --disable-radix-cache --reasoning-parser glm45 --tool-call-parser glm --trust-remote-code --log-level info`
+			got := "docker " + strings.Join(New(cfg, logger.New(false)).RunArgs(), " ")
+			if got != tt.want {
+				t.Errorf("Docker command mismatch.\n\nGot:\n%s\n\nExpected:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetDockerRunCommand_Quoting(t *testing.T) {
+	cfg := &config.Config{SGLang: config.DefaultSGLangConfig()}
+	cfg.SGLang.ExtraArgs = []string{"--chat-template", "it's a template"}
+	cmd := New(cfg, logger.New(false)).GetDockerRunCommand()
 
-	if cmd != expected {
-		t.Errorf("Docker command mismatch.\n\nGot:\n%s\n\nExpected:\n%s", cmd, expected)
+	for _, want := range []string{`--gpus '"device=0,1,2"'`, `--chat-template 'it'\''s a template'`, "--port 30000 --dp-size 3"} {
+		if !strings.Contains(cmd, want) {
+			t.Errorf("Expected %q in:\n%s", want, cmd)
+		}
 	}
 }
 
@@ -31,7 +102,7 @@ func TestBuildDockerRunArgs_ContainerName(t *testing.T) {
 	log := logger.New(false)
 	engine := New(cfg, log)
 
-	args := engine.buildDockerRunArgs()
+	args := engine.RunArgs()
 
 	// Find --name argument
 	for i, arg := range args {
@@ -46,11 +117,12 @@ func TestBuildDockerRunArgs_ContainerName(t *testing.T) {
 }
 
 func TestBuildDockerRunArgs_SGLangFlags(t *testing.T) {
-	cfg := &config.Config{}
+	cfg := &config.Config{SGLang: config.DefaultSGLangConfig()}
+	cfg.SGLang.ChunkedPrefillSize = -1
 	log := logger.New(false)
 	engine := New(cfg, log)
 
-	args := engine.buildDockerRunArgs()
+	args := engine.RunArgs()
 	cmd := strings.Join(args, " ")
 
 	requiredFlags := []string{
@@ -77,3 +149,158 @@ func TestBuildDockerRunArgs_SGLangFlags(t *testing.T) {
 		}
 	}
 }
+
+func TestBuildDockerRunArgs_PinnedImage(t *testing.T) {
+	cfg := &config.Config{
+		ImageDigests: map[string]string{
+			"lmsysorg/sglang:latest": "lmsysorg/sglang@sha256:abc",
+		},
+	}
+	engine := New(cfg, logger.New(false))
+
+	args := engine.RunArgs()
+	found := false
+	for _, arg := range args {
+		if arg == "lmsysorg/sglang@sha256:abc" {
+			found = true
+		}
+		if arg == "lmsysorg/sglang:latest" {
+			t.Errorf("Expected pinned image instead of tag in args")
+		}
+	}
+	if !found {
+		t.Errorf("Expected pinned image in args: %v", args)
+	}
+}
+
+func TestBuildDockerRunArgs_Resources(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.SGLang.Restart = "on-failure"
+	cfg.SGLang.Resources = config.ResourceConfig{
+		CPUs:              16,
+		Memory:            "128g",
+		MemoryReservation: "64g",
+		PidsLimit:         4096,
+		Ulimits:           map[string]string{"nofile": "65536", "stack": "67108864:67108864"},
+	}
+	cmd := "docker " + strings.Join(New(cfg, logger.New(false)).RunArgs(), " ")
+
+	for _, want := range []string{
+		"--restart on-failure",
+		"--cpus 16 --memory 128g --memory-reservation 64g --pids-limit 4096",
+		"--ulimit memlock=-1:-1 --ulimit nofile=65536:65536 --ulimit stack=67108864:67108864",
+	} {
+		if !strings.Contains(cmd, want) {
+			t.Errorf("Expected %q in:\n%s", want, cmd)
+		}
+	}
+}
+
+func TestEngines_Models(t *testing.T) {
+	cfg := &config.Config{DefaultModel: "fast", SGLang: config.DefaultSGLangConfig()}
+	for _, name := range []string{"chat", "fast"} {
+		model := config.ModelConfig{Name: name, SGLangConfig: config.DefaultSGLangConfig()}
+		model.ContainerName = "silo-inference-" + name
+		cfg.Models = append(cfg.Models, model)
+	}
+	cfg.Models[1].Enabled = true
+	log := logger.New(false)
+
+	if engines := Engines(cfg, log); len(engines) != 2 || engines[0].Model() != "chat" {
+		t.Fatalf("Expected an engine per model, got %d", len(engines))
+	}
+	if engines := DefaultEngines(cfg, log); len(engines) != 1 || engines[0].Model() != "fast" {
+		t.Errorf("Expected only the enabled model to start by default")
+	}
+	if New(cfg, log).Model() != "fast" {
+		t.Errorf("Expected New to manage the default model")
+	}
+
+	engine, err := ForModel(cfg, "chat", log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := strings.Join(engine.RunArgs(), " ")
+	if !strings.Contains(cmd, "--name silo-inference-chat ") || !strings.HasSuffix(cmd, "--log-level info --served-model-name chat") {
+		t.Errorf("Expected the chat container serving the model by name:\n%s", cmd)
+	}
+
+	if _, err := ForModel(cfg, "missing", log); err == nil || !strings.Contains(err.Error(), "chat, fast") {
+		t.Errorf("Expected an unknown model error listing the models, got %v", err)
+	}
+}
+
+func TestRunArgs_Backends(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*config.Config)
+		want   string
+	}{
+		{
+			name: "vllm",
+			modify: func(cfg *config.Config) {
+				cfg.Inference.Backend = config.BackendVLLM
+				cfg.VLLM.HuggingFaceCache = ""
+				cfg.VLLM.MaxModelLen = 32768
+				cfg.VLLM.ToolCallParser = "hermes"
+			},
+			want: `docker run -d --name silo-vllm --restart unless-stopped --gpus "device=0" --shm-size 16g --ipc=host --ulimit memlock=-1:-1 --ulimit nofile=1048576:1048576 -p 8000:8000 -e CUDA_VISIBLE_DEVICES=0 -v /root/data/model:/workspace/model vllm/vllm-openai:latest --model /workspace/model --host 0.0.0.0 --port 8000 --served-model-name glm47-awq --tensor-parallel-size 1 --max-model-len 32768 --gpu-memory-utilization 0.9 --dtype auto --kv-cache-dtype auto --enable-auto-tool-choice --tool-call-parser hermes`,
+		},
+		{
+			name: "llamacpp cpu",
+			modify: func(cfg *config.Config) {
+				cfg.Inference.Backend = config.BackendLlamaCpp
+				cfg.LlamaCpp.GPUDevices = []string{"0"}
+				cfg.LlamaCpp.ContextSize = 8192
+				cfg.LlamaCpp.Threads = 8
+			},
+			want: `docker run -d --name silo-llamacpp --restart unless-stopped --ulimit memlock=-1:-1 --ulimit nofile=1048576:1048576 -p 8081:8080 -v /root/data/gguf:/models:ro ghcr.io/ggml-org/llama.cpp:server --model /models/model.gguf --host 0.0.0.0 --port 8080 --alias glm47-awq --ctx-size 8192 --threads 8 --parallel 1`,
+		},
+		{
+			name: "llamacpp gpu",
+			modify: func(cfg *config.Config) {
+				cfg.Inference.Backend = config.BackendLlamaCpp
+				cfg.LlamaCpp.Mode = "gpu"
+				cfg.LlamaCpp.GPUDevices = []string{"1"}
+			},
+			want: `docker run -d --name silo-llamacpp --restart unless-stopped --gpus "device=1" --ulimit memlock=-1:-1 --ulimit nofile=1048576:1048576 -p 8081:8080 -e CUDA_VISIBLE_DEVICES=0 -v /root/data/gguf:/models:ro ghcr.io/ggml-org/llama.cpp:server-cuda --model /models/model.gguf --host 0.0.0.0 --port 8080 --alias glm47-awq --parallel 1 --n-gpu-layers 999`,
+		},
+		{
+			name: "ollama",
+			modify: func(cfg *config.Config) {
+				cfg.Inference.Backend = config.BackendOllama
+				cfg.Ollama.ModelsDir = "/srv/ollama"
+				cfg.Ollama.Model = "qwen2.5:7b"
+				cfg.Ollama.KeepAlive = "-1"
+				cfg.Ollama.ExtraEnv = map[string]string{"OLLAMA_FLASH_ATTENTION": "1"}
+			},
+			want: `docker run -d --name silo-ollama --restart unless-stopped --ulimit memlock=-1:-1 --ulimit nofile=1048576:1048576 -p 11434:11434 -e OLLAMA_KEEP_ALIVE=-1 -e OLLAMA_FLASH_ATTENTION=1 -v /srv/ollama:/root/.ollama ollama/ollama:latest`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.NewDefaultConfig(config.NewPaths(t.TempDir(), t.TempDir()))
+			tt.modify(cfg)
+
+			got := "docker " + strings.Join(New(cfg, logger.New(false)).RunArgs(), " ")
+			if got != tt.want {
+				t.Errorf("Docker command mismatch.\n\nGot:\n%s\n\nExpected:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEngines_Backend(t *testing.T) {
+	cfg := config.NewDefaultConfig(config.NewPaths(t.TempDir(), t.TempDir()))
+	cfg.Inference.Backend = config.BackendOllama
+	cfg.Ollama.Model = "qwen2.5:7b"
+
+	engines := Engines(cfg, logger.New(false))
+	if len(engines) != 1 || engines[0].Model() != "qwen2.5:7b" || engines[0].ContainerName() != config.DefaultOllamaContainerName {
+		t.Fatalf("Expected the single ollama engine, got %d", len(engines))
+	}
+	if _, err := ForModel(cfg, "qwen2.5:7b", logger.New(false)); err != nil {
+		t.Errorf("Expected the ollama model to be found: %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package inference
+
+import (
+	"strconv"
+
+	"github.com/eternisai/silo/internal/config"
+	"github.com/eternisai/silo/pkg/logger"
+)
+
+const (
+	// llamaCppContainerPort is the port llama-server listens on inside the
+	// container
+	llamaCppContainerPort = 8080
+
+	// llamaCppModelDir is where the GGUF directory is mounted
+	llamaCppModelDir = "/models"
+
+	// allGPULayers offloads every layer, llama-server has no flag for all
+	allGPULayers = 999
+)
+
+// llamaCppBackend runs a GGUF model with the llama.cpp server, on the CPU
+// or with layers offloaded to GPUs
+type llamaCppBackend struct {
+	*container
+
+	l          config.LlamaCppConfig
+	servedName string
+}
+
+// newLlamaCpp creates a manager for the llama.cpp engine
+func newLlamaCpp(cfg *config.Config, log *logger.Logger) *Engine {
+	defaults := config.ContainerSettings{
+		ContainerName: config.DefaultLlamaCppContainerName,
+		Image:         cfg.LlamaCpp.ImageRef(),
+		Port:          config.DefaultLlamaCppPort,
+	}
+	b := &llamaCppBackend{
+		container:  newContainer(cfg, cfg.LlamaCpp.ContainerSettings, defaults, "/health", log),
+		l:          cfg.LlamaCpp,
+		servedName: cfg.DefaultModel,
+	}
+	b.runArgs = b.RunArgs
+	b.backend, b.section = config.BackendLlamaCpp, config.BackendLlamaCpp
+	b.gpus = b.gpu()
+	b.servedModel = b.servedName
+	return newEngine(b, b.container, cfg.DefaultModel)
+}
+
+// gpu reports whether the server offloads layers to GPUs
+func (b *llamaCppBackend) gpu() bool {
+	return b.l.Mode == "gpu"
+}
+
+// RunArgs builds the docker run command arguments from the llama.cpp
+// settings. In cpu mode the container gets no GPUs. The image's entrypoint
+// is llama-server, so only its arguments follow the image.
+func (b *llamaCppBackend) RunArgs() []string {
+	l := b.l
+
+	opts := runOptions{
+		containerPort: llamaCppContainerPort,
+	}
+	if l.ModelPath != "" {
+		opts.volumes = append(opts.volumes, config.ExpandPath(l.ModelPath)+":"+llamaCppModelDir+":ro")
+	}
+
+	args := b.dockerArgs(opts)
+	args = append(args, b.image,
+		"--model", llamaCppModelDir+"/"+l.ModelFile,
+		"--host", "0.0.0.0",
+		"--port", strconv.Itoa(llamaCppContainerPort),
+	)
+	f := flags{&args}
+
+	f.string("--alias", b.servedName)
+	f.int("--ctx-size", l.ContextSize)
+	f.int("--threads", l.Threads)
+	f.int("--parallel", l.Parallel)
+	if b.gpu() {
+		layers := l.GPULayers
+		if layers < 0 {
+			layers = allGPULayers
+		}
+		args = append(args, "--n-gpu-layers", strconv.Itoa(layers))
+	}
+
+	return append(args, l.ExtraArgs...)
+}
@@ -0,0 +1,117 @@
+package inference
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/eternisai/silo/internal/config"
+	"github.com/eternisai/silo/pkg/logger"
+)
+
+const (
+	// ollamaContainerPort is the port Ollama listens on inside the container
+	ollamaContainerPort = 11434
+
+	// ollamaStartTimeout bounds the wait for the server before pulling
+	ollamaStartTimeout = time.Minute
+)
+
+// ollamaBackend runs models with Ollama, which loads them on demand and
+// uses the GPUs when it is given any
+type ollamaBackend struct {
+	*container
+
+	o config.OllamaConfig
+}
+
+// newOllama creates a manager for the Ollama engine
+func newOllama(cfg *config.Config, log *logger.Logger) *Engine {
+	defaults := config.ContainerSettings{
+		ContainerName: config.DefaultOllamaContainerName,
+		Image:         config.DefaultOllamaImage,
+		Port:          config.DefaultOllamaPort,
+	}
+	b := &ollamaBackend{
+		container: newContainer(cfg, cfg.Ollama.ContainerSettings, defaults, "/api/version", log),
+		o:         cfg.Ollama,
+	}
+	b.runArgs = b.RunArgs
+	b.backend, b.section = config.BackendOllama, config.BackendOllama
+	b.gpus = true
+	b.servedModel = cfg.Ollama.Model
+	return newEngine(b, b.container, cfg.Ollama.Model)
+}
+
+// RunArgs builds the docker run command arguments from the Ollama
+// settings, which are passed as OLLAMA_* variables. The image runs
+// ollama serve, so no command follows the image.
+func (b *ollamaBackend) RunArgs() []string {
+	o := b.o
+
+	opts := runOptions{
+		containerPort: ollamaContainerPort,
+	}
+	if o.ContextLength != 0 {
+		opts.env = append(opts.env, "OLLAMA_CONTEXT_LENGTH="+strconv.Itoa(o.ContextLength))
+	}
+	if o.NumParallel != 0 {
+		opts.env = append(opts.env, "OLLAMA_NUM_PARALLEL="+strconv.Itoa(o.NumParallel))
+	}
+	if o.KeepAlive != "" {
+		opts.env = append(opts.env, "OLLAMA_KEEP_ALIVE="+o.KeepAlive)
+	}
+	if o.ModelsDir != "" {
+		opts.volumes = append(opts.volumes, config.ExpandPath(o.ModelsDir)+":/root/.ollama")
+	}
+
+	return append(b.dockerArgs(opts), b.image)
+}
+
+// Up starts the Ollama container and pulls the configured model, which is
+// quick when the models directory already holds it
+func (b *ollamaBackend) Up(ctx context.Context) error {
+	if err := b.container.Up(ctx); err != nil {
+		return err
+	}
+	if b.o.Model == "" {
+		return nil
+	}
+
+	if err := b.waitForServer(ctx); err != nil {
+		return err
+	}
+
+	b.logger.Info("Pulling model %s...", b.o.Model)
+	cmd := exec.CommandContext(ctx, "docker", "exec", b.name, "ollama", "pull", b.o.Model)
+	out := b.output()
+	cmd.Stdout = out
+	cmd.Stderr = out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to pull model %s: %w", b.o.Model, err)
+	}
+	b.logger.Success("Model %s is available", b.o.Model)
+	return nil
+}
+
+// waitForServer polls the Ollama API until it answers, since pulls go
+// through the server
+func (b *ollamaBackend) waitForServer(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, ollamaStartTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		if err := b.HealthCheck(ctx); err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("ollama did not start within %s", ollamaStartTimeout)
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package inference
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultProbeHost is the address the inference ports are probed at
+	// unless inference.probe_host names another
+	DefaultProbeHost = "127.0.0.1"
+
+	// DefaultReadyTimeout bounds the wait for an engine to load its model
+	DefaultReadyTimeout = 15 * time.Minute
+
+	// probeRequestTimeout bounds a single probe request
+	probeRequestTimeout = 5 * time.Second
+)
+
+// The wait between readiness probes doubles from probeInitialInterval up
+// to probeMaxInterval
+var (
+	probeInitialInterval = time.Second
+	probeMaxInterval     = 10 * time.Second
+)
+
+// ModelMismatchError reports a server that answers but does not serve the
+// model clients request
+type ModelMismatchError struct {
+	Want   string
+	Served []string
+}
+
+func (e *ModelMismatchError) Error() string {
+	if len(e.Served) == 0 {
+		return fmt.Sprintf("the server lists no models, expected %s", e.Want)
+	}
+	return fmt.Sprintf("the server serves %s, expected %s", strings.Join(e.Served, ", "), e.Want)
+}
+
+// URL returns the base URL of the inference server on the host
+func (c *container) URL() string {
+	return "http://" + net.JoinHostPort(c.probeHost, strconv.Itoa(c.hostPort))
+}
+
+// get requests path from the inference server, bounded by
+// probeRequestTimeout. A status other than 2xx is an error.
+func (c *container) get(ctx context.Context, path string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, probeRequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL()+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("%s returned %s", path, resp.Status)
+	}
+	return body, nil
+}
+
+// HealthCheck checks if the inference engine is healthy by calling its
+// health endpoint
+func (c *container) HealthCheck(ctx context.Context) error {
+	if _, err := c.get(ctx, c.healthPath); err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	return nil
+}
+
+// ServedModels returns the model IDs the server lists at /v1/models
+func (c *container) ServedModels(ctx context.Context) ([]string, error) {
+	body, err := c.get(ctx, "/v1/models")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list models: %w", err)
+	}
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse /v1/models: %w", err)
+	}
+	models := make([]string, len(list.Data))
+	for i, m := range list.Data {
+		models[i] = m.ID
+	}
+	return models, nil
+}
+
+// servesModel reports whether served lists want; Ollama lists untagged
+// models with their :latest tag
+func servesModel(served []string, want string) bool {
+	for _, id := range served {
+		if id == want || (!strings.Contains(want, ":") && id == want+":latest") {
+			return true
+		}
+	}
+	return false
+}
+
+// Ready checks that the engine is healthy and serves the model clients
+// request, as listed by /v1/models. Engines that answer to any model name
+// are only health checked.
+func (e *Engine) Ready(ctx context.Context) error {
+	if err := e.HealthCheck(ctx); err != nil {
+		return err
+	}
+	if e.container.servedModel == "" {
+		return nil
+	}
+	served, err := e.container.ServedModels(ctx)
+	if err != nil {
+		return err
+	}
+	if !servesModel(served, e.container.servedModel) {
+		return &ModelMismatchError{Want: e.container.servedModel, Served: served}
+	}
+	return nil
+}
+
+// URL returns the base URL of the inference server, e.g. for requests to
+// its OpenAI compatible API
+func (e *Engine) URL() string {
+	return e.container.URL()
+}
+
+// WaitForHealthy waits up to timeout for the inference engine to become
+// ready, probing with a growing interval and reporting the progress found
+// in its logs. A server that serves the wrong model, or logs a known
+// failure, fails at once, waiting does not fix it.
+func (e *Engine) WaitForHealthy(ctx context.Context, timeout time.Duration) error {
+	label := e.container.label
+	e.container.logger.Info("Waiting for %s to become ready (up to %s)...", strings.ToLower(label), timeout)
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	reporter := &progressReporter{report: e.container.onProgress}
+	interval := probeInitialInterval
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	var lastErr error
+	for {
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) && lastErr != nil {
+				return fmt.Errorf("%s not ready within %s: %w", label, timeout, lastErr)
+			}
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		err := e.Ready(ctx)
+		if err == nil {
+			reporter.update(Progress{Phase: PhaseReady})
+			e.container.logger.Success("%s is ready", label)
+			return nil
+		}
+		var mismatch *ModelMismatchError
+		if errors.As(err, &mismatch) {
+			return fmt.Errorf("%s is up but not ready: %w", label, err)
+		}
+		// A probe cut short by the deadline says less than the one before
+		if lastErr == nil || ctx.Err() == nil {
+			lastErr = err
+		}
+
+		// The logs tell how far the engine got, and known failures end
+		// the wait early
+		if progress, perr := e.Progress(ctx); perr == nil {
+			reporter.update(*progress)
+			if progress.Phase == PhaseFailed {
+				return &StartupError{Label: label, Diagnostics: progress.Diagnostics}
+			}
+		}
+		e.container.logger.Debug("%s not ready yet: %v", label, err)
+
+		timer.Reset(interval)
+		interval = min(interval*2, probeMaxInterval)
+	}
+}
@@ -0,0 +1,193 @@
+package inference
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eternisai/silo/internal/config"
+	"github.com/eternisai/silo/pkg/logger"
+)
+
+// fakeServer is an inference server that becomes healthy after
+// unhealthyFor health checks and lists models at /v1/models
+type fakeServer struct {
+	unhealthyFor int32
+	models       string
+	checks       atomic.Int32
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/health":
+		if f.checks.Add(1) <= f.unhealthyFor {
+			http.Error(w, "loading", http.StatusServiceUnavailable)
+		}
+	case "/v1/models":
+		w.Write([]byte(f.models))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// vllmEngine returns a vLLM engine serving default_model qwen on the port of
+// the test server
+func vllmEngine(t *testing.T, srv *httptest.Server) *Engine {
+	t.Helper()
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, _ := strconv.Atoi(u.Port())
+
+	cfg := config.NewDefaultConfig(config.NewPaths(t.TempDir(), t.TempDir()))
+	cfg.Inference.Backend = config.BackendVLLM
+	cfg.VLLM.Port = port
+	cfg.DefaultModel = "qwen"
+	return New(cfg, logger.NewSilent())
+}
+
+func shortProbeIntervals(t *testing.T) {
+	initial, max := probeInitialInterval, probeMaxInterval
+	probeInitialInterval, probeMaxInterval = time.Millisecond, 5*time.Millisecond
+	t.Cleanup(func() { probeInitialInterval, probeMaxInterval = initial, max })
+}
+
+func TestReady(t *testing.T) {
+	fake := &fakeServer{models: `{"object": "list", "data": [{"id": "qwen"}]}`}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	engine := vllmEngine(t, srv)
+
+	if engine.URL() != srv.URL {
+		t.Errorf("URL() = %s, want %s", engine.URL(), srv.URL)
+	}
+	if err := engine.Ready(context.Background()); err != nil {
+		t.Fatalf("Expected the engine to be ready, got %v", err)
+	}
+
+	fake.models = `{"data": [{"id": "/workspace/model"}]}`
+	err := engine.Ready(context.Background())
+	var mismatch *ModelMismatchError
+	if !errors.As(err, &mismatch) || mismatch.Want != "qwen" {
+		t.Fatalf("Expected a model mismatch, got %v", err)
+	}
+}
+
+func TestReady_DefaultSGLang(t *testing.T) {
+	fake := &fakeServer{models: `{"data": [{"id": "/workspace/model"}]}`}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, _ := strconv.Atoi(u.Port())
+
+	cfg := config.NewDefaultConfig(config.NewPaths(t.TempDir(), t.TempDir()))
+	cfg.SGLang.Port = port
+	cfg.DefaultModel = "glm"
+	engine := New(cfg, logger.NewSilent())
+
+	var mismatch *ModelMismatchError
+	if err := engine.Ready(context.Background()); !errors.As(err, &mismatch) || mismatch.Want != "glm" {
+		t.Fatalf("Expected a model mismatch, got %v", err)
+	}
+	if args := strings.Join(engine.RunArgs(), " "); !strings.HasSuffix(args, "--served-model-name glm") {
+		t.Errorf("Expected the default model to be served by name, got %s", args)
+	}
+
+	fake.models = `{"data": [{"id": "glm"}]}`
+	if err := engine.Ready(context.Background()); err != nil {
+		t.Fatalf("Expected the engine to be ready, got %v", err)
+	}
+}
+
+func TestReady_Unreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	engine := vllmEngine(t, srv)
+	srv.Close()
+
+	if err := engine.Ready(context.Background()); err == nil || !strings.Contains(err.Error(), "health check failed") {
+		t.Fatalf("Expected a failed health check, got %v", err)
+	}
+}
+
+func TestServesModel(t *testing.T) {
+	served := []string{"qwen2.5:latest", "llama3:8b"}
+	for want, ok := range map[string]bool{"qwen2.5": true, "qwen2.5:latest": true, "llama3:8b": true, "llama3": false, "qwen2.5:7b": false} {
+		if servesModel(served, want) != ok {
+			t.Errorf("servesModel(%v, %q) = %v, want %v", served, want, !ok, ok)
+		}
+	}
+}
+
+func TestWaitForHealthy(t *testing.T) {
+	shortProbeIntervals(t)
+
+	fake := &fakeServer{unhealthyFor: 3, models: `{"data": [{"id": "qwen"}]}`}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	if err := vllmEngine(t, srv).WaitForHealthy(context.Background(), 5*time.Second); err != nil {
+		t.Fatalf("WaitForHealthy failed: %v", err)
+	}
+	if got := fake.checks.Load(); got != 4 {
+		t.Errorf("Expected 4 health checks, got %d", got)
+	}
+}
+
+func TestWaitForHealthy_Timeout(t *testing.T) {
+	shortProbeIntervals(t)
+
+	fake := &fakeServer{unhealthyFor: 1 << 30}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	start := time.Now()
+	err := vllmEngine(t, srv).WaitForHealthy(context.Background(), 50*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "not ready within 50ms") || !strings.Contains(err.Error(), "503") {
+		t.Fatalf("Expected a timeout with the last probe error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("WaitForHealthy took %s", elapsed)
+	}
+	if fake.checks.Load() < 3 {
+		t.Errorf("Expected repeated probes with backoff, got %d", fake.checks.Load())
+	}
+}
+
+func TestWaitForHealthy_WrongModel(t *testing.T) {
+	shortProbeIntervals(t)
+
+	fake := &fakeServer{models: `{"data": [{"id": "llama"}]}`}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	err := vllmEngine(t, srv).WaitForHealthy(context.Background(), 5*time.Second)
+	var mismatch *ModelMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("Expected a model mismatch, got %v", err)
+	}
+	if fake.checks.Load() != 1 {
+		t.Errorf("Expected the mismatch to fail without retrying, got %d checks", fake.checks.Load())
+	}
+}
+
+func TestProbeHost(t *testing.T) {
+	cfg := config.NewDefaultConfig(config.NewPaths(t.TempDir(), t.TempDir()))
+	if got := New(cfg, nil).URL(); got != "http://127.0.0.1:30000" {
+		t.Errorf("URL() = %s", got)
+	}
+	cfg.Inference.ProbeHost = "::1"
+	if got := New(cfg, nil).URL(); got != "http://[::1]:30000" {
+		t.Errorf("URL() = %s", got)
+	}
+}
@@ -0,0 +1,127 @@
+package inference
+
+import (
+	"strconv"
+
+	"github.com/eternisai/silo/internal/config"
+	"github.com/eternisai/silo/pkg/logger"
+)
+
+// sglangBackend runs a model with SGLang
+type sglangBackend struct {
+	*container
+
+	sg config.SGLangConfig
+
+	// servedName is the model name the server answers to, empty to keep
+	// the SGLang default when no default_model is set
+	servedName string
+}
+
+// NewModel creates a manager for the SGLang engine of a model
+func NewModel(cfg *config.Config, model config.ModelConfig, log *logger.Logger) *Engine {
+	defaults := config.ContainerSettings{
+		ContainerName: DefaultContainerName,
+		Image:         DefaultImage,
+		Port:          config.DefaultInferencePort,
+	}
+	b := &sglangBackend{
+		container:  newContainer(cfg, model.ContainerSettings, defaults, "/health", log),
+		sg:         model.SGLangConfig,
+		servedName: model.Name,
+	}
+	// The backend requests models by name, default_model for the single
+	// engine of the sglang section
+	b.servedModel = b.servedName
+	if len(cfg.Models) > 0 {
+		b.label = "Inference engine " + model.Name
+	}
+	b.runArgs = b.RunArgs
+	b.backend, b.section = config.BackendSGLang, config.BackendSGLang
+	b.gpus = true
+	b.memoryKey, b.memoryFraction = "mem_fraction_static", model.MemFractionStatic
+	return newEngine(b, b.container, model.Name)
+}
+
+// RunArgs builds the docker run command arguments from the model's engine
+// settings. Zero and empty settings leave the SGLang defaults in place.
+func (b *sglangBackend) RunArgs() []string {
+	sg := b.sg
+
+	opts := runOptions{
+		containerPort: ContainerPort,
+		shmSize:       sg.ShmSize,
+		ipcHost:       true,
+		env:           []string{"PYTORCH_ALLOC_CONF=expandable_segments:True"},
+	}
+	if sg.ModelPath != "" {
+		opts.volumes = append(opts.volumes, config.ExpandPath(sg.ModelPath)+":"+containerModelPath)
+	}
+	if sg.HuggingFaceCache != "" {
+		opts.volumes = append(opts.volumes, config.ExpandPath(sg.HuggingFaceCache)+":/root/.cache/huggingface")
+	}
+
+	args := b.dockerArgs(opts)
+	args = append(args, b.image, "python3", "-m", "sglang.launch_server")
+	return append(args, launchArgs(sg, b.servedName)...)
+}
+
+// launchArgs returns the sglang.launch_server arguments for the settings,
+// serving the model under servedName if set
+func launchArgs(sg config.SGLangConfig, servedName string) []string {
+	args := []string{
+		"--model-path", containerModelPath,
+		"--host", "0.0.0.0",
+		"--port", strconv.Itoa(ContainerPort),
+	}
+	f := flags{&args}
+
+	f.int("--dp-size", sg.DPSize)
+	f.int("--tp-size", sg.TPSize)
+	f.int("--max-running-requests", sg.MaxRunningRequests)
+	f.int("--max-total-tokens", sg.MaxTotalTokens)
+	f.int("--context-length", sg.ContextLength)
+	f.float("--mem-fraction-static", sg.MemFractionStatic)
+	f.int("--chunked-prefill-size", sg.ChunkedPrefillSize)
+	f.string("--schedule-policy", sg.SchedulePolicy)
+	f.string("--kv-cache-dtype", sg.KVCacheDtype)
+	f.string("--attention-backend", sg.AttentionBackend)
+	f.bool("--disable-radix-cache", sg.DisableRadixCache)
+	f.string("--reasoning-parser", sg.ReasoningParser)
+	f.string("--tool-call-parser", sg.ToolCallParser)
+	f.bool("--trust-remote-code", sg.TrustRemoteCode)
+	f.string("--log-level", sg.LogLevel)
+	f.string("--served-model-name", servedName)
+
+	return append(args, sg.ExtraArgs...)
+}
+
+// flags appends server flags to args, leaving out zero and empty values so
+// the server default applies
+type flags struct {
+	args *[]string
+}
+
+func (f flags) int(flag string, value int) {
+	if value != 0 {
+		*f.args = append(*f.args, flag, strconv.Itoa(value))
+	}
+}
+
+func (f flags) float(flag string, value float64) {
+	if value != 0 {
+		*f.args = append(*f.args, flag, strconv.FormatFloat(value, 'f', -1, 64))
+	}
+}
+
+func (f flags) string(flag, value string) {
+	if value != "" {
+		*f.args = append(*f.args, flag, value)
+	}
+}
+
+func (f flags) bool(flag string, value bool) {
+	if value {
+		*f.args = append(*f.args, flag)
+	}
+}
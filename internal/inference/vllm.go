@@ -0,0 +1,82 @@
+package inference
+
+import (
+	"strconv"
+
+	"github.com/eternisai/silo/internal/config"
+	"github.com/eternisai/silo/pkg/logger"
+)
+
+// vllmContainerPort is the port the vLLM OpenAI server listens on inside
+// the container
+const vllmContainerPort = 8000
+
+// vllmBackend runs default_model with vLLM
+type vllmBackend struct {
+	*container
+
+	v          config.VLLMConfig
+	servedName string
+}
+
+// newVLLM creates a manager for the vLLM engine
+func newVLLM(cfg *config.Config, log *logger.Logger) *Engine {
+	defaults := config.ContainerSettings{
+		ContainerName: config.DefaultVLLMContainerName,
+		Image:         config.DefaultVLLMImage,
+		Port:          config.DefaultVLLMPort,
+	}
+	b := &vllmBackend{
+		container:  newContainer(cfg, cfg.VLLM.ContainerSettings, defaults, "/health", log),
+		v:          cfg.VLLM,
+		servedName: cfg.DefaultModel,
+	}
+	b.runArgs = b.RunArgs
+	b.backend, b.section = config.BackendVLLM, config.BackendVLLM
+	b.gpus = true
+	b.memoryKey, b.memoryFraction = "gpu_memory_utilization", cfg.VLLM.GPUMemoryUtilization
+	b.servedModel = b.servedName
+	return newEngine(b, b.container, cfg.DefaultModel)
+}
+
+// RunArgs builds the docker run command arguments from the vLLM settings.
+// The image's entrypoint is the OpenAI compatible server, so only its
+// arguments follow the image.
+func (b *vllmBackend) RunArgs() []string {
+	v := b.v
+
+	opts := runOptions{
+		containerPort: vllmContainerPort,
+		shmSize:       v.ShmSize,
+		ipcHost:       true,
+	}
+	if v.ModelPath != "" {
+		opts.volumes = append(opts.volumes, config.ExpandPath(v.ModelPath)+":"+containerModelPath)
+	}
+	if v.HuggingFaceCache != "" {
+		opts.volumes = append(opts.volumes, config.ExpandPath(v.HuggingFaceCache)+":/root/.cache/huggingface")
+	}
+
+	args := b.dockerArgs(opts)
+	args = append(args, b.image,
+		"--model", containerModelPath,
+		"--host", "0.0.0.0",
+		"--port", strconv.Itoa(vllmContainerPort),
+	)
+	f := flags{&args}
+
+	f.string("--served-model-name", b.servedName)
+	f.int("--tensor-parallel-size", v.TPSize)
+	f.int("--max-model-len", v.MaxModelLen)
+	f.int("--max-num-seqs", v.MaxNumSeqs)
+	f.float("--gpu-memory-utilization", v.GPUMemoryUtilization)
+	f.string("--dtype", v.DType)
+	f.string("--kv-cache-dtype", v.KVCacheDtype)
+	f.string("--reasoning-parser", v.ReasoningParser)
+	if v.ToolCallParser != "" {
+		args = append(args, "--enable-auto-tool-choice", "--tool-call-parser", v.ToolCallParser)
+	}
+	f.bool("--trust-remote-code", v.TrustRemoteCode)
+
+	return append(args, v.ExtraArgs...)
+}
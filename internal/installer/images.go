@@ -0,0 +1,44 @@
+package installer
+
+import (
+	"context"
+	"sort"
+
+	"github.com/eternisai/silo/internal/config"
+	"github.com/eternisai/silo/internal/docker"
+	"github.com/eternisai/silo/internal/registry"
+	"github.com/eternisai/silo/pkg/logger"
+)
+
+// PrepareRegistries logs in to the configured registries and makes the
+// docker daemon trust the registry CA where possible
+func PrepareRegistries(ctx context.Context, reg registry.Config, log *logger.Logger) error {
+	if err := docker.InstallCA(reg); err != nil {
+		log.Warn("%v", err)
+	}
+	for _, problem := range docker.RegistryProblems(ctx, reg) {
+		log.Warn("Registry: %s", problem)
+	}
+	return docker.Login(ctx, reg)
+}
+
+// PinImages resolves every image of cfg to its digest and regenerates
+// composeFile with the digest references. Images that cannot be resolved
+// keep their tag and are reported, since a moving tag can change them later.
+func PinImages(ctx context.Context, cfg *config.Config, composeFile string, log *logger.Logger) error {
+	refs := cfg.ImageRefs()
+	digests, failed := docker.ResolveDigests(ctx, refs, cfg.Registry.Rewrite)
+
+	images := make([]string, 0, len(failed))
+	for image := range failed {
+		images = append(images, image)
+	}
+	sort.Strings(images)
+	for _, image := range images {
+		log.Warn("Not pinning %s, it stays on its tag: %v", image, failed[image])
+	}
+	cfg.ImageDigests = digests
+	log.Info("Pinned %d of %d images by digest", len(digests), len(refs))
+
+	return config.GenerateDockerCompose(cfg, composeFile)
+}
@@ -16,6 +16,12 @@ type Installer struct {
 	config *config.Config
 	paths  *config.Paths
 	logger *logger.Logger
+
+	// pullProgress receives image pull progress; nil passes compose output through
+	pullProgress docker.PullProgressFunc
+
+	// bundle is set for offline installs whose images are already loaded
+	bundle *config.BundleInfo
 }
 
 func New(cfg *config.Config, paths *config.Paths, log *logger.Logger) *Installer {
@@ -26,6 +32,17 @@ func New(cfg *config.Config, paths *config.Paths, log *logger.Logger) *Installer
 	}
 }
 
+// SetBundle marks the install as coming from an offline bundle. Images are
+// expected to be loaded already, so nothing is pulled.
+func (i *Installer) SetBundle(info *config.BundleInfo) {
+	i.bundle = info
+}
+
+// SetPullProgress sets the handler that receives image pull progress
+func (i *Installer) SetPullProgress(fn docker.PullProgressFunc) {
+	i.pullProgress = fn
+}
+
 func (i *Installer) Install(ctx context.Context) error {
 	i.logger.Info("Starting Silo installation...")
 
@@ -41,8 +58,19 @@ func (i *Installer) Install(ctx context.Context) error {
 		return fmt.Errorf("failed to generate configs: %w", err)
 	}
 
-	if err := i.pullImages(ctx); err != nil {
-		return fmt.Errorf("failed to pull images: %w", err)
+	if i.bundle != nil {
+		i.logger.Info("Using images from offline bundle, skipping pull")
+	} else {
+		if err := PrepareRegistries(ctx, i.config.Registry, i.logger); err != nil {
+			return fmt.Errorf("failed to prepare registries: %w", err)
+		}
+		if err := i.pullImages(ctx); err != nil {
+			return fmt.Errorf("failed to pull images: %w", err)
+		}
+	}
+
+	if err := PinImages(ctx, i.config, i.paths.ComposeFile, i.logger); err != nil {
+		return fmt.Errorf("failed to pin images: %w", err)
 	}
 
 	if err := i.startContainers(ctx); err != nil {
@@ -73,8 +101,7 @@ func (i *Installer) runPreflightChecks() error {
 		return err
 	}
 
-	i.logger.Debug("Checking port availability...")
-	if err := CheckPortAvailability(i.config.Port); err != nil {
+	if err := CheckPublishedPorts(i.config.PublishedPorts(), i.logger); err != nil {
 		return err
 	}
 
@@ -110,9 +137,22 @@ func (i *Installer) generateConfigs() error {
 		return err
 	}
 
-	i.logger.Debug("Generating config.yml...")
-	if err := config.GenerateConfig(i.config, i.paths.ConfigFile); err != nil {
-		return err
+	// A config.yml written before the first install keeps its keys and
+	// comments; only the values changed since loading are saved to it
+	if _, err := os.Stat(i.paths.ConfigFile); err == nil {
+		i.logger.Debug("Updating config.yml...")
+		if err := config.Save(i.paths.ConfigFile, i.config); err != nil {
+			return err
+		}
+	} else {
+		i.logger.Debug("Generating config.yml...")
+		cfg, err := config.FileConfig(i.config, i.paths)
+		if err != nil {
+			return err
+		}
+		if err := config.GenerateConfig(cfg, i.paths.ConfigFile); err != nil {
+			return err
+		}
 	}
 
 	i.logger.Success("Configuration files generated")
@@ -123,13 +163,16 @@ func (i *Installer) pullImages(ctx context.Context) error {
 	i.logger.Info("Pulling Docker images...")
 
 	// Determine which services to pull
-	services := []string{"backend", "frontend"}
+	services := []string{"postgres", "backend", "frontend"}
+	if i.config.EnableProxyAgent {
+		services = append(services, "silo-proxy-agent")
+	}
 	if i.config.EnableDeepResearch {
 		services = append(services, "deep-research")
 	}
 
 	// Pull each service, tracking failures
-	results := docker.Pull(ctx, i.paths.ComposeFile, services...)
+	results := docker.PullWithProgress(ctx, i.paths.ComposeFile, i.pullProgress, services...)
 
 	var failed []string
 	for _, r := range results {
@@ -173,6 +216,8 @@ func (i *Installer) saveState() error {
 		Version:     i.config.Version,
 		InstalledAt: time.Now().Format(time.RFC3339),
 		LastUpdated: time.Now().Format(time.RFC3339),
+		Bundle:      i.bundle,
+		Images:      i.config.ImageDigests,
 	}
 
 	if err := config.SaveState(i.paths.StateFile, state); err != nil {
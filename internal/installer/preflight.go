@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"strconv"
 	"syscall"
 
+	"github.com/eternisai/silo/internal/config"
 	"github.com/eternisai/silo/internal/docker"
+	"github.com/eternisai/silo/pkg/logger"
 )
 
 const RequiredDiskSpaceGB = 5
@@ -33,8 +36,10 @@ func CheckDiskSpace(path string, requiredGB int) error {
 	return nil
 }
 
-func CheckPortAvailability(port int) error {
-	addr := fmt.Sprintf(":%d", port)
+// CheckPortAvailability checks that port can be bound on bindAddress, or on
+// every interface when bindAddress is empty
+func CheckPortAvailability(bindAddress string, port int) error {
+	addr := net.JoinHostPort(bindAddress, strconv.Itoa(port))
 	listener, err := net.Listen("tcp", addr)
 	if err != nil {
 		// Check if it's a permission error (common for ports < 1024 on Linux)
@@ -49,8 +54,20 @@ func CheckPortAvailability(port int) error {
 				}
 			}
 		}
-		return fmt.Errorf("port %d is already in use", port)
+		return fmt.Errorf("port %s is already in use", addr)
 	}
 	listener.Close()
 	return nil
 }
+
+// CheckPublishedPorts checks that every port in ports can be bound on the
+// host
+func CheckPublishedPorts(ports []config.PublishedPort, log *logger.Logger) error {
+	for _, p := range ports {
+		log.Debug("Checking port availability of %s (%s)...", p.Service, p.Address())
+		if err := CheckPortAvailability(p.BindAddress, p.HostPort); err != nil {
+			return fmt.Errorf("%s: %w (set %s or services.%s.publish: false)", p.Service, err, p.Key, p.Service)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,291 @@
+package model
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eternisai/silo/pkg/logger"
+)
+
+// fakeHub serves a repository the way a Hugging Face compatible endpoint
+// does, and records the Range and Authorization headers it receives
+type fakeHub struct {
+	files  map[string][]byte
+	lfs    map[string]bool
+	mu     sync.Mutex
+	ranges []string
+	auth   []string
+}
+
+func newFakeHub() *fakeHub {
+	return &fakeHub{
+		files: map[string][]byte{
+			"config.json":         []byte(`{"model_type": "tiny"}`),
+			"tiny-Q4_K_M.gguf":    bytes.Repeat([]byte("q4"), 4096),
+			"tiny-Q8_0.gguf":      bytes.Repeat([]byte("q8"), 8192),
+			"tokenizer/vocab.txt": []byte("a\nb\nc\n"),
+		},
+		lfs: map[string]bool{"tiny-Q4_K_M.gguf": true, "tiny-Q8_0.gguf": true},
+	}
+}
+
+func (h *fakeHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		h.mu.Lock()
+		h.auth = append(h.auth, auth)
+		h.mu.Unlock()
+	}
+
+	if r.URL.Path == "/api/models/org/tiny/tree/main" {
+		var entries []map[string]any
+		for path, data := range h.files {
+			entry := map[string]any{"type": "file", "path": path, "size": len(data)}
+			if h.lfs[path] {
+				entry["lfs"] = map[string]any{"oid": sum(data), "size": len(data)}
+			}
+			entries = append(entries, entry)
+		}
+		entries = append(entries, map[string]any{"type": "directory", "path": "tokenizer"})
+		json.NewEncoder(w).Encode(entries)
+		return
+	}
+
+	path, ok := strings.CutPrefix(r.URL.Path, "/org/tiny/resolve/main/")
+	if !ok {
+		path = strings.TrimPrefix(r.URL.Path, "/files/")
+	}
+	data, ok := h.files[path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if rng := r.Header.Get("Range"); rng != "" {
+		h.mu.Lock()
+		h.ranges = append(h.ranges, rng)
+		h.mu.Unlock()
+	}
+	http.ServeContent(w, r, path, time.Time{}, bytes.NewReader(data))
+}
+
+func sum(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+func newTestPuller(endpoint string) *Puller {
+	p := NewPuller(logger.NewSilent())
+	p.Endpoint = endpoint
+	p.Token = ""
+	p.freeSpace = func(string) (uint64, error) { return 1 << 40, nil }
+	return p
+}
+
+func TestParseSource(t *testing.T) {
+	tests := []struct {
+		source string
+		want   Source
+		name   string
+	}{
+		{"org/tiny", Source{Repo: "org/tiny", Revision: "main"}, "tiny"},
+		{"hf:org/tiny@v1.0", Source{Repo: "org/tiny", Revision: "v1.0"}, "tiny"},
+		{"https://example.com/files/tiny-Q4_K_M.gguf", Source{URL: "https://example.com/files/tiny-Q4_K_M.gguf"}, "tiny-Q4_K_M"},
+	}
+	for _, tt := range tests {
+		got, err := ParseSource(tt.source)
+		if err != nil {
+			t.Errorf("ParseSource(%q) failed: %v", tt.source, err)
+			continue
+		}
+		if got != tt.want || got.DefaultName() != tt.name {
+			t.Errorf("ParseSource(%q) = %+v named %q, want %+v named %q", tt.source, got, got.DefaultName(), tt.want, tt.name)
+		}
+	}
+
+	for _, bad := range []string{"tiny", "org/tiny/extra", "https://example.com/"} {
+		if _, err := ParseSource(bad); err == nil {
+			t.Errorf("Expected ParseSource(%q) to fail", bad)
+		}
+	}
+}
+
+func TestPull_Repository(t *testing.T) {
+	hub := newFakeHub()
+	srv := httptest.NewServer(hub)
+	defer srv.Close()
+
+	store := NewStore(t.TempDir())
+	src, _ := ParseSource("org/tiny")
+	puller := newTestPuller(srv.URL)
+	puller.Include = []string{"*Q4_K_M.gguf", "*.json", "tokenizer/*"}
+
+	manifest, err := puller.Pull(context.Background(), store, src, "tiny")
+	if err != nil {
+		t.Fatalf("Pull failed: %v", err)
+	}
+	if len(manifest.Files) != 3 {
+		t.Fatalf("Expected the 3 included files, got %+v", manifest.Files)
+	}
+	for _, f := range manifest.Files {
+		data, err := os.ReadFile(filepath.Join(store.Path("tiny"), f.Path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, hub.files[f.Path]) || f.SHA256 != sum(data) {
+			t.Errorf("Unexpected contents or checksum of %s", f.Path)
+		}
+	}
+
+	models, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(models) != 1 || models[0].Files != 3 || models[0].Manifest == nil || models[0].Manifest.Source != "hf:org/tiny" {
+		t.Errorf("Expected the pulled model in the list, got %+v", models)
+	}
+	if gguf, _ := store.GGUFFiles("tiny"); len(gguf) != 1 || gguf[0] != "tiny-Q4_K_M.gguf" {
+		t.Errorf("Expected the gguf file, got %v", gguf)
+	}
+
+	if problems, err := store.Verify("tiny"); err != nil || len(problems) != 0 {
+		t.Fatalf("Expected a verified model, got %v %v", problems, err)
+	}
+	if err := os.WriteFile(filepath.Join(store.Path("tiny"), "config.json"), []byte(`{"model_type": "huge"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(filepath.Join(store.Path("tiny"), "tokenizer", "vocab.txt"))
+	problems, err := store.Verify("tiny")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 2 {
+		t.Errorf("Expected the changed and the missing file to be reported, got %v", problems)
+	}
+
+	if err := store.Remove("tiny"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get("tiny"); err == nil {
+		t.Error("Expected the model to be removed")
+	}
+}
+
+func TestPull_Resume(t *testing.T) {
+	hub := newFakeHub()
+	srv := httptest.NewServer(hub)
+	defer srv.Close()
+
+	store := NewStore(t.TempDir())
+	dir := store.Path("tiny")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	data := hub.files["tiny-Q8_0.gguf"]
+	if err := os.WriteFile(filepath.Join(dir, "tiny-Q8_0.gguf"+partSuffix), data[:1000], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	src, _ := ParseSource("org/tiny")
+	puller := newTestPuller(srv.URL)
+	puller.Include = []string{"tiny-Q8_0.gguf"}
+	if _, err := puller.Pull(context.Background(), store, src, "tiny"); err != nil {
+		t.Fatalf("Pull failed: %v", err)
+	}
+
+	if len(hub.ranges) != 1 || hub.ranges[0] != "bytes=1000-" {
+		t.Errorf("Expected the download to resume at byte 1000, got ranges %v", hub.ranges)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "tiny-Q8_0.gguf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("Expected the resumed file to match")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "tiny-Q8_0.gguf"+partSuffix)); !os.IsNotExist(err) {
+		t.Error("Expected the partial file to be gone")
+	}
+}
+
+func TestPull_URLChecksum(t *testing.T) {
+	hub := newFakeHub()
+	srv := httptest.NewServer(hub)
+	defer srv.Close()
+
+	store := NewStore(t.TempDir())
+	src, err := ParseSource(srv.URL + "/files/tiny-Q4_K_M.gguf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	puller := newTestPuller(srv.URL)
+	puller.SHA256 = sum([]byte("something else"))
+	if _, err := puller.Pull(context.Background(), store, src, src.DefaultName()); err == nil || !strings.Contains(err.Error(), "sha256") {
+		t.Fatalf("Expected a checksum error, got %v", err)
+	}
+	if files, _ := modelFiles(store.Path(src.DefaultName())); len(files) != 0 {
+		t.Errorf("Expected the corrupt download to be removed, got %v", files)
+	}
+
+	puller.SHA256 = sum(hub.files["tiny-Q4_K_M.gguf"])
+	manifest, err := puller.Pull(context.Background(), store, src, src.DefaultName())
+	if err != nil {
+		t.Fatalf("Pull failed: %v", err)
+	}
+	if len(manifest.Files) != 1 || manifest.Files[0].SHA256 != puller.SHA256 {
+		t.Errorf("Unexpected manifest %+v", manifest)
+	}
+}
+
+func TestPull_TokenOnlyToEndpoint(t *testing.T) {
+	hub, other := newFakeHub(), newFakeHub()
+	hubSrv, otherSrv := httptest.NewServer(hub), httptest.NewServer(other)
+	defer hubSrv.Close()
+	defer otherSrv.Close()
+
+	puller := newTestPuller(hubSrv.URL)
+	puller.Token = "hf_secret"
+	puller.Include = []string{"config.json"}
+	store := NewStore(t.TempDir())
+
+	for _, ref := range []string{"org/tiny", otherSrv.URL + "/files/tiny-Q4_K_M.gguf"} {
+		src, err := ParseSource(ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := puller.Pull(context.Background(), store, src, src.DefaultName()); err != nil {
+			t.Fatalf("Pull %s failed: %v", ref, err)
+		}
+	}
+
+	if len(hub.auth) == 0 || hub.auth[0] != "Bearer hf_secret" {
+		t.Errorf("Expected the endpoint to receive the token, got %v", hub.auth)
+	}
+	if len(other.auth) != 0 {
+		t.Errorf("Expected no token for other hosts, got %v", other.auth)
+	}
+}
+
+func TestPull_DiskSpace(t *testing.T) {
+	srv := httptest.NewServer(newFakeHub())
+	defer srv.Close()
+
+	store := NewStore(t.TempDir())
+	src, _ := ParseSource("org/tiny")
+	puller := newTestPuller(srv.URL)
+	puller.freeSpace = func(string) (uint64, error) { return 1024, nil }
+
+	if _, err := puller.Pull(context.Background(), store, src, "tiny"); err == nil || !strings.Contains(err.Error(), "insufficient disk space") {
+		t.Fatalf("Expected a disk space error, got %v", err)
+	}
+}
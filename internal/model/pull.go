@@ -0,0 +1,497 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/eternisai/silo/internal/units"
+	"github.com/eternisai/silo/pkg/logger"
+)
+
+const (
+	// DefaultEndpoint is the Hugging Face endpoint used unless HF_ENDPOINT
+	// or the puller names another
+	DefaultEndpoint = "https://huggingface.co"
+
+	// DefaultConcurrency is the number of files downloaded at once
+	DefaultConcurrency = 4
+
+	// DefaultRevision is the repository revision pulled unless one is named
+	DefaultRevision = "main"
+)
+
+// Source is where a model is pulled from: a repository of a Hugging Face
+// compatible endpoint, or a single file at a plain HTTP URL
+type Source struct {
+	Repo     string
+	Revision string
+	URL      string
+}
+
+// ParseSource parses a model source: an http(s) URL, or a repository as
+// org/name or hf:org/name, optionally followed by @revision
+func ParseSource(s string) (Source, error) {
+	if strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://") {
+		u, err := url.Parse(s)
+		if err != nil || u.Host == "" || path.Base(u.Path) == "/" || path.Base(u.Path) == "." {
+			return Source{}, fmt.Errorf("invalid model URL %q, it must name a file", s)
+		}
+		return Source{URL: s}, nil
+	}
+
+	repo, revision, _ := strings.Cut(strings.TrimPrefix(s, "hf:"), "@")
+	if revision == "" {
+		revision = DefaultRevision
+	}
+	parts := strings.Split(repo, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return Source{}, fmt.Errorf("invalid model source %q, expected org/name, hf:org/name or an http(s) URL", s)
+	}
+	return Source{Repo: repo, Revision: revision}, nil
+}
+
+// String returns the source as recorded in the manifest
+func (s Source) String() string {
+	if s.URL != "" {
+		return s.URL
+	}
+	return "hf:" + s.Repo
+}
+
+// DefaultName returns the model name for the source: the repository name,
+// or the file name without its extension
+func (s Source) DefaultName() string {
+	if s.URL != "" {
+		u, _ := url.Parse(s.URL)
+		base := path.Base(u.Path)
+		return strings.TrimSuffix(base, path.Ext(base))
+	}
+	return path.Base(s.Repo)
+}
+
+// remoteFile is a file to download. Size is -1 and SHA256 empty when the
+// server does not tell them.
+type remoteFile struct {
+	Path   string
+	URL    string
+	Size   int64
+	SHA256 string
+}
+
+// Puller downloads models into a store
+type Puller struct {
+	// Endpoint is the Hugging Face compatible endpoint, DefaultEndpoint
+	// when empty
+	Endpoint string
+
+	// Token is sent to Endpoint as a bearer token, e.g. a Hugging Face
+	// access token
+	Token string
+
+	// Concurrency is the number of files downloaded at once
+	Concurrency int
+
+	// Include restricts repository files to those matching one of the
+	// patterns, e.g. *Q4_K_M.gguf; every file is pulled when empty
+	Include []string
+
+	// SHA256 is the expected checksum of a file pulled from a URL
+	SHA256 string
+
+	Client *http.Client
+	Logger *logger.Logger
+
+	// freeSpace replaces the disk space check in tests
+	freeSpace func(dir string) (uint64, error)
+}
+
+// NewPuller returns a puller configured from HF_ENDPOINT and HF_TOKEN
+func NewPuller(log *logger.Logger) *Puller {
+	endpoint := os.Getenv("HF_ENDPOINT")
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+	return &Puller{
+		Endpoint:    endpoint,
+		Token:       os.Getenv("HF_TOKEN"),
+		Concurrency: DefaultConcurrency,
+		Client:      http.DefaultClient,
+		Logger:      log,
+	}
+}
+
+// Pull downloads the model at src into the store under name and writes its
+// manifest. Files already downloaded are kept and partial downloads are
+// resumed. Checksums published by the source are verified; the manifest
+// records the checksum of every file.
+func (p *Puller) Pull(ctx context.Context, store *Store, src Source, name string) (*Manifest, error) {
+	files, err := p.resolve(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files to pull from %s", src)
+	}
+
+	dir := store.Path(name)
+
+	// A pull of the same source again verifies files the source publishes
+	// no checksum for against the previous pull
+	if old, err := ReadManifest(dir); err == nil && old.Source == src.String() {
+		for i, f := range files {
+			for _, prev := range old.Files {
+				if f.SHA256 == "" && prev.Path == f.Path && (f.Size < 0 || prev.Size == f.Size) {
+					files[i].SHA256 = prev.SHA256
+				}
+			}
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create model directory: %w", err)
+	}
+	if err := p.checkSpace(dir, files); err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{
+		Name:     name,
+		Source:   src.String(),
+		Revision: src.Revision,
+		Files:    make([]File, len(files)),
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	concurrency := max(p.Concurrency, 1)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				file, err := p.download(ctx, dir, files[i])
+				if err != nil {
+					once.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					continue
+				}
+				manifest.Files[i] = file
+			}
+		}()
+	}
+	for i := range files {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	manifest.PulledAt = time.Now().UTC().Format(time.RFC3339)
+	if err := writeManifest(dir, manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// resolve lists the files of the source
+func (p *Puller) resolve(ctx context.Context, src Source) ([]remoteFile, error) {
+	if src.URL != "" {
+		u, _ := url.Parse(src.URL)
+		file := remoteFile{Path: path.Base(u.Path), URL: src.URL, Size: -1, SHA256: strings.ToLower(p.SHA256)}
+		if resp, err := p.request(ctx, http.MethodHead, src.URL, nil); err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				file.Size = resp.ContentLength
+			}
+		}
+		return []remoteFile{file}, nil
+	}
+	return p.repoFiles(ctx, src)
+}
+
+// treeEntry is an entry of the Hugging Face tree API. LFS files carry the
+// sha256 of their contents.
+type treeEntry struct {
+	Type string `json:"type"`
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+	LFS  *struct {
+		OID  string `json:"oid"`
+		Size int64  `json:"size"`
+	} `json:"lfs"`
+}
+
+// repoFiles lists the files of a repository revision that match Include
+func (p *Puller) repoFiles(ctx context.Context, src Source) ([]remoteFile, error) {
+	endpoint := p.endpoint()
+	api := fmt.Sprintf("%s/api/models/%s/tree/%s?recursive=true", endpoint, src.Repo, url.PathEscape(src.Revision))
+
+	resp, err := p.request(ctx, http.MethodGet, api, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files of %s: %w", src.Repo, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list files of %s: %s", src.Repo, resp.Status)
+	}
+
+	var entries []treeEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("failed to parse file list of %s: %w", src.Repo, err)
+	}
+
+	var files []remoteFile
+	for _, e := range entries {
+		if e.Type != "file" || !p.included(e.Path) {
+			continue
+		}
+		if !filepath.IsLocal(filepath.FromSlash(e.Path)) {
+			return nil, fmt.Errorf("invalid file path %q in %s", e.Path, src.Repo)
+		}
+		file := remoteFile{
+			Path: e.Path,
+			URL:  fmt.Sprintf("%s/%s/resolve/%s/%s", endpoint, src.Repo, url.PathEscape(src.Revision), escapePath(e.Path)),
+			Size: e.Size,
+		}
+		if e.LFS != nil {
+			file.Size = e.LFS.Size
+			file.SHA256 = e.LFS.OID
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// included reports whether a repository file matches Include, by its path
+// or its base name
+func (p *Puller) included(file string) bool {
+	if len(p.Include) == 0 {
+		return true
+	}
+	for _, pattern := range p.Include {
+		if ok, _ := path.Match(pattern, file); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, path.Base(file)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSpace fails when the files left to download do not fit in dir
+func (p *Puller) checkSpace(dir string, files []remoteFile) error {
+	var needed int64
+	for _, f := range files {
+		if f.Size < 0 {
+			continue
+		}
+		dst := filepath.Join(dir, filepath.FromSlash(f.Path))
+		if info, err := os.Stat(dst); err == nil && info.Size() == f.Size {
+			continue
+		}
+		have := int64(0)
+		if info, err := os.Stat(dst + partSuffix); err == nil {
+			have = info.Size()
+		}
+		needed += max(f.Size-have, 0)
+	}
+
+	free, err := freeSpace(dir)
+	if p.freeSpace != nil {
+		free, err = p.freeSpace(dir)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check disk space: %w", err)
+	}
+	if uint64(needed) > free {
+		return fmt.Errorf("insufficient disk space in %s: %s needed, %s available", dir, units.FormatBytes(needed), units.FormatBytes(int64(free)))
+	}
+	return nil
+}
+
+// download fetches a file into dir, resuming a partial download, and
+// returns it with its checksum. A file already complete is kept.
+func (p *Puller) download(ctx context.Context, dir string, f remoteFile) (File, error) {
+	dst := filepath.Join(dir, filepath.FromSlash(f.Path))
+	part := dst + partSuffix
+
+	if info, err := os.Stat(dst); err == nil && (f.Size < 0 || info.Size() == f.Size) {
+		if file, err := checkFile(dst, f); err == nil {
+			p.Logger.Info("%s is up to date", f.Path)
+			return file, nil
+		}
+		// A corrupt file is downloaded again
+		os.Remove(dst)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return File{}, fmt.Errorf("failed to create directory for %s: %w", f.Path, err)
+	}
+
+	var offset int64
+	if info, err := os.Stat(part); err == nil {
+		offset = info.Size()
+	}
+	if offset > 0 && offset == f.Size {
+		return p.finish(part, dst, f)
+	}
+
+	header := http.Header{}
+	if offset > 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		p.Logger.Info("Resuming %s at %s", f.Path, units.FormatBytes(offset))
+	} else {
+		p.Logger.Info("Downloading %s", f.Path)
+	}
+
+	resp, err := p.request(ctx, http.MethodGet, f.URL, header)
+	if err != nil {
+		return File{}, fmt.Errorf("failed to download %s: %w", f.Path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0 {
+		// The partial file is already complete
+		return p.finish(part, dst, f)
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		flags |= os.O_APPEND
+	case http.StatusOK:
+		// The server ignored the range, start over
+		flags |= os.O_TRUNC
+	default:
+		return File{}, fmt.Errorf("failed to download %s: %s", f.Path, resp.Status)
+	}
+
+	out, err := os.OpenFile(part, flags, 0644)
+	if err != nil {
+		return File{}, fmt.Errorf("failed to write %s: %w", f.Path, err)
+	}
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		out.Close()
+		return File{}, fmt.Errorf("failed to download %s: %w", f.Path, err)
+	}
+	if err := out.Close(); err != nil {
+		return File{}, fmt.Errorf("failed to write %s: %w", f.Path, err)
+	}
+	return p.finish(part, dst, f)
+}
+
+// finish verifies a completed download and moves it into place. A file
+// that fails verification is removed so the next pull starts over.
+func (p *Puller) finish(part, dst string, f remoteFile) (File, error) {
+	file, err := checkFile(part, f)
+	if err != nil {
+		os.Remove(part)
+		return File{}, err
+	}
+	if err := os.Rename(part, dst); err != nil {
+		return File{}, fmt.Errorf("failed to move %s into place: %w", f.Path, err)
+	}
+	p.Logger.Success("Downloaded %s (%s)", f.Path, units.FormatBytes(file.Size))
+	return file, nil
+}
+
+// checkFile hashes the file at path and checks it against the size and
+// checksum the source published
+func checkFile(path string, f remoteFile) (File, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return File{}, err
+	}
+	if f.Size >= 0 && info.Size() != f.Size {
+		return File{}, fmt.Errorf("%s: size %d, expected %d", f.Path, info.Size(), f.Size)
+	}
+	sum, err := fileSHA256(path)
+	if err != nil {
+		return File{}, err
+	}
+	if f.SHA256 != "" && sum != f.SHA256 {
+		return File{}, fmt.Errorf("%s: sha256 %s, expected %s", f.Path, sum, f.SHA256)
+	}
+	return File{Path: f.Path, Size: info.Size(), SHA256: sum}, nil
+}
+
+// endpoint returns the Hugging Face endpoint without a trailing slash
+func (p *Puller) endpoint() string {
+	if endpoint := strings.TrimSuffix(p.Endpoint, "/"); endpoint != "" {
+		return endpoint
+	}
+	return DefaultEndpoint
+}
+
+// request sends a request, with the puller's token if it goes to the
+// Hugging Face endpoint; plain URLs of other hosts never see the token
+func (p *Puller) request(ctx context.Context, method, url string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if p.Token != "" && p.toEndpoint(req.URL) {
+		req.Header.Set("Authorization", "Bearer "+p.Token)
+	}
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
+
+// toEndpoint reports whether u has the scheme and host of the endpoint
+func (p *Puller) toEndpoint(u *url.URL) bool {
+	endpoint, err := url.Parse(p.endpoint())
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Scheme, endpoint.Scheme) && strings.EqualFold(u.Host, endpoint.Host)
+}
+
+// escapePath escapes every segment of a repository file path
+func escapePath(file string) string {
+	segments := strings.Split(file, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// freeSpace returns the bytes available to unprivileged users in dir
+func freeSpace(dir string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, err
+	}
+	return stat.Bavail * uint64(stat.Bsize), nil
+}
@@ -0,0 +1,267 @@
+// Package model manages the models directory: one subdirectory per model,
+// pulled from a Hugging Face compatible endpoint or a plain URL, with a
+// manifest recording the size and sha256 of every file.
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/eternisai/silo/internal/config"
+)
+
+// ManifestName is the manifest file in every pulled model directory
+const ManifestName = ".silo-model.json"
+
+// partSuffix marks files that are still being downloaded
+const partSuffix = ".part"
+
+// Manifest describes a pulled model
+type Manifest struct {
+	Name     string `json:"name"`
+	Source   string `json:"source"`
+	Revision string `json:"revision,omitempty"`
+	PulledAt string `json:"pulled_at"`
+	Files    []File `json:"files"`
+}
+
+// File is a model file with its checksum, Path is relative to the model
+// directory
+type File struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Model is a directory of the models directory. Manifest is nil for models
+// that were not pulled by silo, e.g. copied by hand.
+type Model struct {
+	Name     string
+	Dir      string
+	Size     int64
+	Files    int
+	Manifest *Manifest
+}
+
+// Store is the models directory
+type Store struct {
+	Dir string
+}
+
+// NewStore returns the store of the models directory dir; a leading ~ and
+// environment variables are expanded
+func NewStore(dir string) *Store {
+	return &Store{Dir: config.ExpandPath(dir)}
+}
+
+// Path returns the directory of the named model
+func (s *Store) Path(name string) string {
+	return filepath.Join(s.Dir, name)
+}
+
+// List returns the models in the models directory, sorted by name. A
+// missing directory holds no models.
+func (s *Store) List() ([]Model, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read models directory: %w", err)
+	}
+
+	var models []Model
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		m, err := s.Get(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		models = append(models, *m)
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].Name < models[j].Name })
+	return models, nil
+}
+
+// Get returns the named model
+func (s *Store) Get(name string) (*Model, error) {
+	if err := config.ValidateModelName(name); err != nil {
+		return nil, err
+	}
+	dir := s.Path(name)
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("model %q not found in %s", name, s.Dir)
+	}
+
+	m := &Model{Name: name, Dir: dir}
+	files, err := modelFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		m.Size += f.Size
+	}
+	m.Files = len(files)
+
+	manifest, err := ReadManifest(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	m.Manifest = manifest
+	return m, nil
+}
+
+// Remove deletes the named model and its files
+func (s *Store) Remove(name string) error {
+	m, err := s.Get(name)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(m.Dir); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", m.Dir, err)
+	}
+	return nil
+}
+
+// FileProblem is a model file that does not match the manifest
+type FileProblem struct {
+	Path    string
+	Message string
+}
+
+func (p FileProblem) String() string {
+	return p.Path + ": " + p.Message
+}
+
+// Verify checks the files of the named model against its manifest: every
+// file must exist with the recorded size and sha256
+func (s *Store) Verify(name string) ([]FileProblem, error) {
+	m, err := s.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if m.Manifest == nil {
+		return nil, fmt.Errorf("model %q has no %s, it was not pulled with 'silo model pull'", name, ManifestName)
+	}
+
+	var problems []FileProblem
+	for _, f := range m.Manifest.Files {
+		path := filepath.Join(m.Dir, filepath.FromSlash(f.Path))
+		info, err := os.Stat(path)
+		if err != nil {
+			problems = append(problems, FileProblem{Path: f.Path, Message: "missing"})
+			continue
+		}
+		if info.Size() != f.Size {
+			problems = append(problems, FileProblem{Path: f.Path, Message: fmt.Sprintf("size %d, expected %d", info.Size(), f.Size)})
+			continue
+		}
+		sum, err := fileSHA256(path)
+		if err != nil {
+			return nil, err
+		}
+		if sum != f.SHA256 {
+			problems = append(problems, FileProblem{Path: f.Path, Message: fmt.Sprintf("sha256 %s, expected %s", sum, f.SHA256)})
+		}
+	}
+	return problems, nil
+}
+
+// GGUFFiles returns the .gguf files of the named model, relative to its
+// directory
+func (s *Store) GGUFFiles(name string) ([]string, error) {
+	m, err := s.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	files, err := modelFiles(m.Dir)
+	if err != nil {
+		return nil, err
+	}
+	var gguf []string
+	for _, f := range files {
+		if strings.HasSuffix(strings.ToLower(f.Path), ".gguf") {
+			gguf = append(gguf, f.Path)
+		}
+	}
+	return gguf, nil
+}
+
+// ReadManifest reads the manifest of the model directory dir
+func ReadManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestName))
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filepath.Join(dir, ManifestName), err)
+	}
+	return &m, nil
+}
+
+// writeManifest writes the manifest into the model directory dir
+func writeManifest(dir string, m *Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, ManifestName), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// modelFiles returns the regular files under dir other than the manifest
+// and partial downloads, with their sizes
+func modelFiles(dir string) ([]File, error) {
+	var files []File
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || d.Name() == ManifestName || strings.HasSuffix(d.Name(), partSuffix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, File{Path: filepath.ToSlash(rel), Size: info.Size()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model files: %w", err)
+	}
+	return files, nil
+}
+
+// fileSHA256 returns the hex sha256 of the file at path
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,258 @@
+package registry
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxTagPages bounds tag list pagination
+const maxTagPages = 20
+
+// Client queries registries through the OCI distribution API
+type Client struct {
+	config   Config
+	secure   *http.Client
+	insecure *http.Client
+}
+
+// NewClient creates a client using the mirrors, credentials and TLS
+// settings of cfg
+func NewClient(cfg Config) (*Client, error) {
+	tlsConfig := &tls.Config{}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read registry CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return &Client{
+		config: cfg,
+		secure: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig, Proxy: http.ProxyFromEnvironment},
+		},
+		insecure: &http.Client{
+			Timeout: 10 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+				Proxy:           http.ProxyFromEnvironment,
+			},
+		},
+	}, nil
+}
+
+// Tags lists the tags of the repository of ref, looked up on its mirror if
+// one is configured
+func (c *Client) Tags(ctx context.Context, ref string) ([]string, error) {
+	r := ParseReference(c.config.Rewrite(ref))
+	host := r.apiHost()
+
+	next := fmt.Sprintf("/v2/%s/tags/list?n=1000", r.Path)
+	var tags []string
+	for page := 0; next != "" && page < maxTagPages; page++ {
+		resp, err := c.get(ctx, r, host, next)
+		if err != nil {
+			return nil, err
+		}
+
+		var list struct {
+			Tags []string `json:"tags"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&list)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse tags of %s: %w", r.Repository(), err)
+		}
+		tags = append(tags, list.Tags...)
+		next = nextLink(resp.Header.Get("Link"))
+	}
+
+	return tags, nil
+}
+
+// get requests path on the registry, authenticating when challenged
+func (c *Client) get(ctx context.Context, r Reference, host, path string) (*http.Response, error) {
+	resp, err := c.do(ctx, host, path, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+
+		auth, err := c.authorize(ctx, r, challenge)
+		if err != nil {
+			return nil, err
+		}
+		if resp, err = c.do(ctx, host, path, auth); err != nil {
+			return nil, err
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("registry %s returned status %d for %s", host, resp.StatusCode, r.Path)
+	}
+	return resp, nil
+}
+
+// do sends a GET request over HTTPS, falling back to plain HTTP for
+// insecure registries
+func (c *Client) do(ctx context.Context, host, path, auth string) (*http.Response, error) {
+	client := c.secure
+	insecure := c.config.IsInsecure(host)
+	if insecure {
+		client = c.insecure
+	}
+
+	resp, err := c.send(ctx, client, "https://"+host+path, auth)
+	if err != nil && insecure {
+		resp, err = c.send(ctx, client, "http://"+host+path, auth)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach registry %s: %w", host, err)
+	}
+	return resp, nil
+}
+
+func (c *Client) send(ctx context.Context, client *http.Client, url, auth string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "silo-cli")
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	return client.Do(req)
+}
+
+// authorize answers a WWW-Authenticate challenge with basic credentials or
+// a bearer token from the registry's token service
+func (c *Client) authorize(ctx context.Context, r Reference, challenge string) (string, error) {
+	scheme, params := parseChallenge(challenge)
+	creds, hasCreds := c.config.CredentialsFor(r.Domain)
+
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if !hasCreds {
+			return "", fmt.Errorf("registry %s requires credentials", r.Domain)
+		}
+		password, err := creds.Secret()
+		if err != nil {
+			return "", err
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(creds.Username+":"+password)), nil
+
+	case "bearer":
+		token, err := c.token(ctx, r, params, creds, hasCreds)
+		if err != nil {
+			return "", err
+		}
+		return "Bearer " + token, nil
+	}
+
+	return "", fmt.Errorf("unsupported authentication challenge from %s: %q", r.Domain, challenge)
+}
+
+// token fetches a pull token for the repository from the realm of a bearer
+// challenge
+func (c *Client) token(ctx context.Context, r Reference, params map[string]string, creds Credentials, hasCreds bool) (string, error) {
+	realm := params["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("registry %s sent a bearer challenge without realm", r.Domain)
+	}
+
+	query := url.Values{}
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	query.Set("scope", "repository:"+r.Path+":pull")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", "silo-cli")
+	if hasCreds {
+		password, err := creds.Secret()
+		if err != nil {
+			return "", err
+		}
+		req.SetBasicAuth(creds.Username, password)
+	}
+
+	client := c.secure
+	if c.config.IsInsecure(r.Domain) {
+		client = c.insecure
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get registry token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token service %s returned status %d", realm, resp.StatusCode)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to parse registry token: %w", err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	return body.AccessToken, nil
+}
+
+var challengeParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// parseChallenge splits a WWW-Authenticate header into its scheme and
+// parameters
+func parseChallenge(header string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	params := make(map[string]string)
+	for _, m := range challengeParam.FindAllStringSubmatch(rest, -1) {
+		params[strings.ToLower(m[1])] = m[2]
+	}
+	return scheme, params
+}
+
+// nextLink returns the target of a rel="next" Link header
+func nextLink(header string) string {
+	for _, part := range strings.Split(header, ",") {
+		if !strings.Contains(part, `rel="next"`) {
+			continue
+		}
+		start := strings.Index(part, "<")
+		end := strings.Index(part, ">")
+		if start >= 0 && end > start {
+			return part[start+1 : end]
+		}
+	}
+	return ""
+}
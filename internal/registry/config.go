@@ -0,0 +1,139 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Config holds mirror, credential and TLS settings for image registries
+type Config struct {
+	// Mirrors maps a source registry (docker.io, ghcr.io, ...) to the
+	// registry host and optional path prefix that serves its images
+	Mirrors map[string]string `yaml:"mirrors,omitempty" json:"mirrors,omitempty" desc:"Source registry to mirror host and optional path prefix"`
+
+	// Credentials maps a registry host to the account silo logs in with
+	Credentials map[string]Credentials `yaml:"credentials,omitempty" json:"credentials,omitempty" desc:"Registry host to login account"`
+
+	// Insecure lists registry hosts served over plain HTTP or with
+	// certificates that cannot be verified
+	Insecure []string `yaml:"insecure,omitempty" json:"insecure,omitempty" desc:"Registry hosts served over HTTP or with unverifiable certificates"`
+
+	// CAFile is a PEM bundle trusted for the mirror registries
+	CAFile string `yaml:"ca_file,omitempty" json:"ca_file,omitempty" desc:"PEM bundle trusted for mirror registries"`
+}
+
+// Credentials is a registry account. The password is read from a file, so it
+// never appears in the world-readable config.yml.
+type Credentials struct {
+	Username     string `yaml:"username" json:"username" desc:"Registry user name"`
+	PasswordFile string `yaml:"password_file,omitempty" json:"password_file,omitempty" desc:"File holding the registry password"`
+
+	// Password is only decoded so Validate can reject it
+	Password string `yaml:"password,omitempty" json:"-" desc:"Not supported, set password_file"`
+}
+
+// Secret returns the password read from PasswordFile
+func (c Credentials) Secret() (string, error) {
+	if c.PasswordFile == "" {
+		return "", fmt.Errorf("no password_file")
+	}
+	data, err := os.ReadFile(c.PasswordFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password file: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// Rewrite returns the reference to use for ref: unchanged, or moved to the
+// mirror of its source registry
+func (c Config) Rewrite(ref string) string {
+	r := ParseReference(ref)
+	mirror, ok := c.Mirrors[r.Domain]
+	if !ok || mirror == "" {
+		return ref
+	}
+
+	rewritten := strings.TrimSuffix(mirror, "/") + "/" + r.Path
+	if r.Tag != "" {
+		rewritten += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		rewritten += "@" + r.Digest
+	}
+	return rewritten
+}
+
+// IsInsecure reports whether host is listed as insecure
+func (c Config) IsInsecure(host string) bool {
+	for _, h := range c.Insecure {
+		if h == host {
+			return true
+		}
+	}
+	return false
+}
+
+// CredentialsFor returns the credentials configured for host
+func (c Config) CredentialsFor(host string) (Credentials, bool) {
+	creds, ok := c.Credentials[host]
+	return creds, ok
+}
+
+// MirrorHosts returns the registry hosts of all mirrors, sorted
+func (c Config) MirrorHosts() []string {
+	seen := make(map[string]bool)
+	var hosts []string
+	for _, mirror := range c.Mirrors {
+		host, _, _ := strings.Cut(mirror, "/")
+		if host != "" && !seen[host] {
+			seen[host] = true
+			hosts = append(hosts, host)
+		}
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// Validate checks mirrors, credentials and the CA file and returns every
+// problem found, joined
+func (c Config) Validate() error {
+	var errs []error
+	for _, source := range sortedKeys(c.Mirrors) {
+		mirror := c.Mirrors[source]
+		if mirror == "" {
+			errs = append(errs, fmt.Errorf("mirrors.%s cannot be empty", source))
+		}
+		if strings.Contains(mirror, "://") {
+			errs = append(errs, fmt.Errorf("mirrors.%s must be a host and optional path, not a URL", source))
+		}
+	}
+	for _, host := range sortedKeys(c.Credentials) {
+		creds := c.Credentials[host]
+		if creds.Username == "" {
+			errs = append(errs, fmt.Errorf("credentials.%s.username cannot be empty", host))
+		}
+		if creds.Password != "" {
+			errs = append(errs, fmt.Errorf("credentials.%s.password would keep the password in config.yml, write it to a file readable by the owner only and set password_file", host))
+		} else if creds.PasswordFile == "" {
+			errs = append(errs, fmt.Errorf("credentials.%s needs password_file", host))
+		}
+	}
+	if c.CAFile != "" {
+		if _, err := os.Stat(c.CAFile); err != nil {
+			errs = append(errs, fmt.Errorf("ca_file: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
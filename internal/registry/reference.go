@@ -0,0 +1,112 @@
+package registry
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DockerHub is the registry domain of images without an explicit registry
+const DockerHub = "docker.io"
+
+// dockerHubAPI is the host serving the distribution API for Docker Hub
+const dockerHubAPI = "registry-1.docker.io"
+
+// Reference is a parsed image reference
+type Reference struct {
+	Domain string // registry host, e.g. docker.io or ghcr.io
+	Path   string // repository path, e.g. library/postgres
+	Tag    string
+	Digest string // e.g. sha256:...
+}
+
+var (
+	domainPattern    = regexp.MustCompile(`^(?:[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?)(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?)*(?::[0-9]+)?$`)
+	componentPattern = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*$`)
+	tagPattern       = regexp.MustCompile(`^[\w][\w.-]{0,127}$`)
+	digestPattern    = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*(?:[-_+.][A-Za-z][A-Za-z0-9]*)*:[0-9a-fA-F]{32,}$`)
+)
+
+// ValidateReference checks that ref is a well-formed image reference:
+// lower-case path components, a valid tag and a valid digest
+func ValidateReference(ref string) error {
+	if ref == "" {
+		return fmt.Errorf("empty image reference")
+	}
+	if strings.HasSuffix(ref, ":") || strings.HasSuffix(ref, "@") {
+		return fmt.Errorf("incomplete image reference %q", ref)
+	}
+	r := ParseReference(ref)
+	if !domainPattern.MatchString(r.Domain) {
+		return fmt.Errorf("invalid registry host %q in image %q", r.Domain, ref)
+	}
+	for _, component := range strings.Split(r.Path, "/") {
+		if !componentPattern.MatchString(component) {
+			return fmt.Errorf("invalid repository %q in image %q, expected lower-case letters, digits and separators", r.Path, ref)
+		}
+	}
+	if r.Tag != "" && !tagPattern.MatchString(r.Tag) {
+		return fmt.Errorf("invalid tag %q in image %q", r.Tag, ref)
+	}
+	if r.Digest != "" && !digestPattern.MatchString(r.Digest) {
+		return fmt.Errorf("invalid digest %q in image %q", r.Digest, ref)
+	}
+	return nil
+}
+
+// ParseReference splits an image reference the way docker does: a first
+// path component with a dot, a colon or "localhost" is the registry host,
+// anything else is on Docker Hub, where single names live under library/.
+func ParseReference(ref string) Reference {
+	var r Reference
+	if i := strings.Index(ref, "@"); i >= 0 {
+		r.Digest = ref[i+1:]
+		ref = ref[:i]
+	}
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		r.Tag = ref[i+1:]
+		ref = ref[:i]
+	}
+
+	first, rest, found := strings.Cut(ref, "/")
+	if found && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		r.Domain = first
+		r.Path = rest
+	} else {
+		r.Domain = DockerHub
+		r.Path = ref
+	}
+
+	if r.Domain == "index.docker.io" {
+		r.Domain = DockerHub
+	}
+	if r.Domain == DockerHub && !strings.Contains(r.Path, "/") {
+		r.Path = "library/" + r.Path
+	}
+	return r
+}
+
+// Repository returns the repository with its registry host
+func (r Reference) Repository() string {
+	return r.Domain + "/" + r.Path
+}
+
+// String returns the full reference with registry host, tag and digest
+func (r Reference) String() string {
+	s := r.Repository()
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// apiHost returns the host serving the distribution API of the registry
+func (r Reference) apiHost() string {
+	if r.Domain == DockerHub {
+		return dockerHubAPI
+	}
+	return r.Domain
+}
@@ -0,0 +1,155 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// passwordFile writes password to a file and returns its path
+func passwordFile(t *testing.T, password string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(path, []byte(password+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseReference(t *testing.T) {
+	tests := map[string]Reference{
+		"postgres":                              {Domain: "docker.io", Path: "library/postgres"},
+		"pgvector/pgvector:pg17":                {Domain: "docker.io", Path: "pgvector/pgvector", Tag: "pg17"},
+		"ghcr.io/eternisai/deep_research:sha-1": {Domain: "ghcr.io", Path: "eternisai/deep_research", Tag: "sha-1"},
+		"localhost:5000/silo@sha256:abc":        {Domain: "localhost:5000", Path: "silo", Digest: "sha256:abc"},
+		"index.docker.io/lmsysorg/sglang":       {Domain: "docker.io", Path: "lmsysorg/sglang"},
+	}
+	for ref, want := range tests {
+		if got := ParseReference(ref); got != want {
+			t.Errorf("ParseReference(%q) = %+v, want %+v", ref, got, want)
+		}
+	}
+}
+
+func TestValidateReference(t *testing.T) {
+	valid := []string{
+		"postgres",
+		"pgvector/pgvector:pg17",
+		"ghcr.io/eternisai/deep_research:sha-2e9f2ef",
+		"localhost:5000/silo/backend:1.0",
+		"lmsysorg/sglang@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+	}
+	for _, ref := range valid {
+		if err := ValidateReference(ref); err != nil {
+			t.Errorf("ValidateReference(%q) = %v", ref, err)
+		}
+	}
+
+	invalid := []string{"", "Eternis/Backend", "backend:", "backend:bad tag", "backend@sha256:xyz", "silo//backend", "-bad.host/x"}
+	for _, ref := range invalid {
+		if ValidateReference(ref) == nil {
+			t.Errorf("Expected ValidateReference(%q) to fail", ref)
+		}
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	cfg := Config{
+		Mirrors: map[string]string{
+			"docker.io": "registry.local:5000/hub/",
+			"ghcr.io":   "registry.local:5000",
+		},
+	}
+
+	tests := map[string]string{
+		"postgres:17":                     "registry.local:5000/hub/library/postgres:17",
+		"pgvector/pgvector@sha256:abc":    "registry.local:5000/hub/pgvector/pgvector@sha256:abc",
+		"ghcr.io/eternisai/deep_research": "registry.local:5000/eternisai/deep_research",
+		"quay.io/other/image:1":           "quay.io/other/image:1",
+	}
+	for ref, want := range tests {
+		if got := cfg.Rewrite(ref); got != want {
+			t.Errorf("Rewrite(%q) = %q, want %q", ref, got, want)
+		}
+	}
+
+	if got := (Config{}).Rewrite("pgvector/pgvector:pg17"); got != "pgvector/pgvector:pg17" {
+		t.Errorf("Expected reference to be unchanged without mirrors, got %q", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := Config{
+		Mirrors:     map[string]string{"docker.io": "registry.local/hub"},
+		Credentials: map[string]Credentials{"registry.local": {Username: "silo", PasswordFile: passwordFile(t, "secret")}},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected valid config, got %v", err)
+	}
+
+	invalid := []Config{
+		{Mirrors: map[string]string{"docker.io": "https://registry.local"}},
+		{Credentials: map[string]Credentials{"registry.local": {Username: "silo"}}},
+		{Credentials: map[string]Credentials{"registry.local": {Username: "silo", Password: "secret"}}},
+		{CAFile: "/nonexistent/ca.pem"},
+	}
+	for _, cfg := range invalid {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", cfg)
+		}
+	}
+}
+
+func TestClientTags(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			user, pass, ok := r.BasicAuth()
+			if !ok || user != "silo" || pass != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.URL.Query().Get("scope") != "repository:hub/eternis/silo-box-backend:pull" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{"token": "abc"})
+
+		case r.Header.Get("Authorization") != "Bearer abc":
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+
+		case r.URL.Query().Get("last") == "":
+			w.Header().Set("Link", `</v2/hub/eternis/silo-box-backend/tags/list?n=1000&last=0.1.9>; rel="next"`)
+			_ = json.NewEncoder(w).Encode(map[string][]string{"tags": {"0.1.8", "0.1.9"}})
+
+		default:
+			_ = json.NewEncoder(w).Encode(map[string][]string{"tags": {"0.2.0", "latest"}})
+		}
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "https://")
+	client, err := NewClient(Config{
+		Mirrors:     map[string]string{"docker.io": host + "/hub"},
+		Credentials: map[string]Credentials{host: {Username: "silo", PasswordFile: passwordFile(t, "secret")}},
+		Insecure:    []string{host},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tags, err := client.Tags(context.Background(), "eternis/silo-box-backend")
+	if err != nil {
+		t.Fatalf("Tags failed: %v", err)
+	}
+	if strings.Join(tags, ",") != "0.1.8,0.1.9,0.2.0,latest" {
+		t.Errorf("Unexpected tags: %v", tags)
+	}
+}
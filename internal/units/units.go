@@ -0,0 +1,18 @@
+// Package units formats quantities for people to read
+package units
+
+import "fmt"
+
+// FormatBytes formats a byte count with a binary unit suffix, e.g. 1.5 GiB
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,20 @@
+package units
+
+import "testing"
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{
+		0:                 "0 B",
+		1023:              "1023 B",
+		1024:              "1.0 KiB",
+		3 << 29:           "1.5 GiB",
+		81559 << 20:       "79.6 GiB",
+		5 << 40:           "5.0 TiB",
+		1<<62 + (1 << 61): "6.0 EiB",
+	}
+	for n, want := range tests {
+		if got := FormatBytes(n); got != want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/eternisai/silo/internal/config"
 	"github.com/eternisai/silo/internal/docker"
+	"github.com/eternisai/silo/internal/installer"
 	"github.com/eternisai/silo/internal/version"
 	"github.com/eternisai/silo/pkg/logger"
 )
@@ -16,6 +17,12 @@ type Updater struct {
 	config *config.Config
 	paths  *config.Paths
 	logger *logger.Logger
+
+	// pullProgress receives image pull progress; nil passes compose output through
+	pullProgress docker.PullProgressFunc
+
+	// bundle is set for offline upgrades whose images are already loaded
+	bundle *config.BundleInfo
 }
 
 func New(cfg *config.Config, paths *config.Paths, log *logger.Logger) *Updater {
@@ -26,6 +33,17 @@ func New(cfg *config.Config, paths *config.Paths, log *logger.Logger) *Updater {
 	}
 }
 
+// SetBundle marks the upgrade as coming from an offline bundle. The config
+// must already name the bundle's images; version checks and pulls are skipped.
+func (u *Updater) SetBundle(info *config.BundleInfo) {
+	u.bundle = info
+}
+
+// SetPullProgress sets the handler that receives image pull progress
+func (u *Updater) SetPullProgress(fn docker.PullProgressFunc) {
+	u.pullProgress = fn
+}
+
 func (u *Updater) Update(ctx context.Context) error {
 	u.logger.Info("Starting Silo update...")
 
@@ -37,26 +55,48 @@ func (u *Updater) Update(ctx context.Context) error {
 		return fmt.Errorf("failed to backup config: %w", err)
 	}
 
-	checkCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	newTag, err := u.updateConfigWithLatestVersion(checkCtx)
-	cancel()
-
-	if err != nil {
-		u.logger.Warn("Could not auto-update to latest version: %v", err)
-		u.logger.Info("Continuing with current version %s", u.config.ImageTag)
+	// Resolve images by tag again so upgrades pick up moved tags
+	u.config.ImageDigests = nil
+	if err := config.GenerateDockerCompose(u.config, u.paths.ComposeFile); err != nil {
+		return fmt.Errorf("failed to regenerate docker-compose: %w", err)
 	}
 
-	// Update deep research image to latest default if outdated
-	if updated, info := config.UpdateDeepResearchImage(u.config, u.paths.ConfigFile); updated {
-		u.logger.Info("Deep research image %s", info)
-		// Regenerate docker-compose with new image
-		if err := config.GenerateDockerCompose(u.config, u.paths.ComposeFile); err != nil {
-			u.logger.Warn("Failed to regenerate docker-compose for deep research: %v", err)
+	var newTag string
+	if u.bundle != nil {
+		if err := u.applyBundle(); err != nil {
+			return err
+		}
+		newTag = u.bundle.ImageTag
+	} else {
+		checkCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		var err error
+		newTag, err = u.updateConfigWithLatestVersion(checkCtx)
+		cancel()
+
+		if err != nil {
+			u.logger.Warn("Could not auto-update to latest version: %v", err)
+			u.logger.Info("Continuing with current version %s", u.config.ImageTag)
+		}
+
+		// Update deep research image to latest default if outdated
+		if updated, info := config.UpdateDeepResearchImage(u.config, u.paths.ConfigFile); updated {
+			u.logger.Info("Deep research image %s", info)
+			// Regenerate docker-compose with new image
+			if err := config.GenerateDockerCompose(u.config, u.paths.ComposeFile); err != nil {
+				u.logger.Warn("Failed to regenerate docker-compose for deep research: %v", err)
+			}
+		}
+
+		if err := installer.PrepareRegistries(ctx, u.config.Registry, u.logger); err != nil {
+			return fmt.Errorf("failed to prepare registries: %w", err)
+		}
+		if err := u.pullImages(ctx); err != nil {
+			return fmt.Errorf("failed to pull images: %w", err)
 		}
 	}
 
-	if err := u.pullImages(ctx); err != nil {
-		return fmt.Errorf("failed to pull images: %w", err)
+	if err := installer.PinImages(ctx, u.config, u.paths.ComposeFile, u.logger); err != nil {
+		return fmt.Errorf("failed to pin images: %w", err)
 	}
 
 	if err := u.recreateContainers(ctx); err != nil {
@@ -72,6 +112,10 @@ func (u *Updater) Update(ctx context.Context) error {
 	} else {
 		u.logger.Success("Silo updated successfully!")
 	}
+
+	if secrets, err := config.LoadSecrets(u.config.SecretsFile); err == nil && secrets[config.SecretPostgresPassword] == config.LegacyPostgresPassword {
+		u.logger.Warn("PostgreSQL still uses the legacy default password, run 'silo secrets rotate %s'", config.SecretPostgresPassword)
+	}
 	return nil
 }
 
@@ -84,7 +128,7 @@ func (u *Updater) backupConfig() error {
 		return err
 	}
 
-	if err := os.WriteFile(backupPath, data, 0644); err != nil {
+	if err := os.WriteFile(backupPath, data, 0600); err != nil {
 		return err
 	}
 
@@ -95,7 +139,7 @@ func (u *Updater) backupConfig() error {
 func (u *Updater) updateConfigWithLatestVersion(ctx context.Context) (string, error) {
 	u.logger.Info("Checking for latest Docker image versions...")
 
-	imageVersions, err := version.CheckImageVersions(ctx, u.config.ImageTag)
+	imageVersions, err := version.CheckImageVersions(ctx, u.config.Registry, u.config.ImageTag)
 	if err != nil {
 		return "", fmt.Errorf("failed to check image versions: %w", err)
 	}
@@ -128,17 +172,39 @@ func (u *Updater) updateConfigWithLatestVersion(ctx context.Context) (string, er
 	return latestTag, nil
 }
 
+// applyBundle saves the config naming the bundle's images and regenerates
+// docker-compose.yml from it
+func (u *Updater) applyBundle() error {
+	u.logger.Info("Using images from offline bundle (image tag %s)", u.bundle.ImageTag)
+
+	if err := config.Validate(u.config); err != nil {
+		return fmt.Errorf("config validation failed: %w", err)
+	}
+	if err := config.Save(u.paths.ConfigFile, u.config); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	if err := config.GenerateDockerCompose(u.config, u.paths.ComposeFile); err != nil {
+		return fmt.Errorf("failed to regenerate docker-compose: %w", err)
+	}
+
+	u.logger.Success("Configuration updated from bundle")
+	return nil
+}
+
 func (u *Updater) pullImages(ctx context.Context) error {
 	u.logger.Info("Pulling latest Docker images...")
 
 	// Determine which services to pull
-	services := []string{"backend", "frontend"}
+	services := []string{"postgres", "backend", "frontend"}
+	if u.config.EnableProxyAgent {
+		services = append(services, "silo-proxy-agent")
+	}
 	if u.config.EnableDeepResearch {
 		services = append(services, "deep-research")
 	}
 
 	// Pull each service, tracking failures
-	results := docker.Pull(ctx, u.paths.ComposeFile, services...)
+	results := docker.PullWithProgress(ctx, u.paths.ComposeFile, u.pullProgress, services...)
 
 	var failed []string
 	for _, r := range results {
@@ -188,6 +254,8 @@ func (u *Updater) updateState() error {
 	}
 
 	state.LastUpdated = time.Now().Format(time.RFC3339)
+	state.Bundle = u.bundle
+	state.Images = u.config.ImageDigests
 
 	if err := config.SaveState(u.paths.StateFile, state); err != nil {
 		return err
@@ -7,6 +7,9 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/eternisai/silo/internal/config"
+	"github.com/eternisai/silo/internal/registry"
 )
 
 type ReleaseInfo struct {
@@ -22,15 +25,6 @@ type VersionInfo struct {
 	NeedsUpdate bool   `json:"needs_update"`
 }
 
-type DockerHubTag struct {
-	Name        string    `json:"name"`
-	LastUpdated time.Time `json:"last_updated"`
-}
-
-type DockerHubResponse struct {
-	Results []DockerHubTag `json:"results"`
-}
-
 type ImageVersionInfo struct {
 	ImageName   string
 	Current     string
@@ -95,35 +89,15 @@ func Check(ctx context.Context, currentVersion string) (*VersionInfo, error) {
 	}, nil
 }
 
-func CheckDockerHubImage(ctx context.Context, namespace, repository string) (string, error) {
-	client := &http.Client{
-		Timeout: 5 * time.Second,
-	}
-
-	url := fmt.Sprintf("https://hub.docker.com/v2/repositories/%s/%s/tags?page_size=100", namespace, repository)
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("User-Agent", "silo-cli")
-
-	resp, err := client.Do(req)
+// LatestImageTag returns the highest semantic version tag of the image's
+// repository, looked up through the configured registry mirror if any
+func LatestImageTag(ctx context.Context, client *registry.Client, image string) (string, error) {
+	tags, err := client.Tags(ctx, image)
 	if err != nil {
 		return "", fmt.Errorf("failed to fetch tags: %w", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("Docker Hub API returned status %d", resp.StatusCode)
-	}
 
-	var response DockerHubResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return "", fmt.Errorf("failed to parse tags: %w", err)
-	}
-
-	latestTag := findLatestSemanticTag(response.Results)
+	latestTag := findLatestSemanticTag(tags)
 	if latestTag == "" {
 		return "", fmt.Errorf("no semantic version tags found")
 	}
@@ -131,18 +105,17 @@ func CheckDockerHubImage(ctx context.Context, namespace, repository string) (str
 	return latestTag, nil
 }
 
-func findLatestSemanticTag(tags []DockerHubTag) string {
+func findLatestSemanticTag(tags []string) string {
 	var latestTag string
 	var latestVersion string
-	for _, tag := range tags {
-		name := tag.Name
+	for _, name := range tags {
 		if name == "latest" || name == "dev" {
 			continue
 		}
 		trimmedName := strings.TrimPrefix(name, "v")
 		if isSemanticVersion(trimmedName) {
 			if latestVersion == "" || compareSemanticVersions(trimmedName, latestVersion) > 0 {
-				latestTag = tag.Name
+				latestTag = name
 				latestVersion = trimmedName
 			}
 		}
@@ -195,19 +168,23 @@ func compareSemanticVersions(v1, v2 string) int {
 	return 0
 }
 
-func CheckImageVersions(ctx context.Context, currentTag string) ([]ImageVersionInfo, error) {
+func CheckImageVersions(ctx context.Context, reg registry.Config, currentTag string) ([]ImageVersionInfo, error) {
+	client, err := registry.NewClient(reg)
+	if err != nil {
+		return nil, err
+	}
+
 	images := []struct {
-		namespace   string
 		repository  string
 		displayName string
 	}{
-		{"eternis", "silo-box-backend", "Backend"},
-		{"eternis", "silo-box-frontend", "Frontend"},
+		{config.BackendRepository, "Backend"},
+		{config.FrontendRepository, "Frontend"},
 	}
 
 	var results []ImageVersionInfo
 	for _, img := range images {
-		latest, err := CheckDockerHubImage(ctx, img.namespace, img.repository)
+		latest, err := LatestImageTag(ctx, client, img.repository)
 		if err != nil {
 			return nil, fmt.Errorf("failed to check %s: %w", img.displayName, err)
 		}